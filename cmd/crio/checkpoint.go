@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/urfave/cli"
+)
+
+var checkpointCommand = cli.Command{
+	Name:      "checkpoint",
+	Usage:     "checkpoint a running container of a crio daemon to an archive",
+	ArgsUsage: "<container id> <archive>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "socket, s",
+			Usage: "path to the crio socket (default: the listen path of the configuration)",
+		},
+		cli.BoolFlag{
+			Name:  "leave-running",
+			Usage: "keep the container running after the checkpoint, otherwise it is stopped",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return fmt.Errorf("expected a container id and an archive path")
+		}
+		exportPath, err := filepath.Abs(c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+		crioClient, err := newCrioClient(c, c.String("socket"))
+		if err != nil {
+			return err
+		}
+		return crioClient.CheckpointContainer(c.Args().Get(0), exportPath, c.Bool("leave-running"))
+	},
+}
+
+var restoreCommand = cli.Command{
+	Name:      "restore",
+	Usage:     "restore a container from a checkpoint archive into a pod of a crio daemon",
+	ArgsUsage: "<sandbox id> <archive>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "socket, s",
+			Usage: "path to the crio socket (default: the listen path of the configuration)",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 2 {
+			return fmt.Errorf("expected a sandbox id and an archive path")
+		}
+		importPath, err := filepath.Abs(c.Args().Get(1))
+		if err != nil {
+			return err
+		}
+		crioClient, err := newCrioClient(c, c.String("socket"))
+		if err != nil {
+			return err
+		}
+		id, err := crioClient.RestoreContainer(c.Args().Get(0), importPath)
+		if err != nil {
+			return err
+		}
+		fmt.Println(id)
+		return nil
+	},
+}
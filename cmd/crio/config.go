@@ -18,6 +18,10 @@ var commentedConfigTemplate = template.Must(template.New("config").Parse(`
 #
 # Please refer to crio.conf(5) for details of all configuration options.
 
+# Drop-in configuration files with the ".conf" suffix within the
+# /etc/crio/crio.conf.d directory are merged in lexical order on top of this
+# file. They only need to contain the options they change.
+
 # CRI-O supports partial configuration reload during runtime, which can be
 # done by sending SIGHUP to the running process. Currently supported options
 # are explicitly mentioned with: 'This option supports live configuration
@@ -83,6 +87,16 @@ grpc_max_send_msg_size = {{ .GRPCMaxSendMsgSize }}
 # Maximum grpc receive message size. If not set or <= 0, then CRI-O will default to 16 * 1024 * 1024.
 grpc_max_recv_msg_size = {{ .GRPCMaxRecvMsgSize }}
 
+# Enable the export of OpenTelemetry traces of the CRI calls.
+enable_tracing = {{ .EnableTracing }}
+
+# Address of the OTLP HTTP collector to which the traces are exported.
+tracing_endpoint = "{{ .TracingEndpoint }}"
+
+# Number of sampled traces per million, for traces which are not already
+# sampled by the kubelet. Traces sampled by the kubelet are always exported.
+tracing_sampling_rate_per_million = {{ .TracingSamplingRatePerMillion }}
+
 # The crio.runtime table contains settings pertaining to the OCI runtime used
 # and options for how to set up and manage the OCI runtime.
 [crio.runtime]
@@ -119,10 +133,33 @@ selinux = {{ .SELinux }}
 # Path to the seccomp.json profile which is used as the default seccomp profile
 # for the runtime. If not specified, then the internal default seccomp profile
 # will be used.
+# This option supports live configuration reload.
 seccomp_profile = "{{ .SeccompProfile }}"
 
+# Path to the audit log or syslog file from which the syscalls of containers in
+# seccomp audit mode are collected. Pods request the audit mode via the
+# io.kubernetes.cri-o.seccomp-audit annotation, which has to be part of the
+# allowed_annotations of the runtime handler. Their containers run with a
+# seccomp profile logging every syscall instead of denying it.
+seccomp_audit_source = "{{ .SeccompAuditSource }}"
+
+# Path to the directory to which the seccomp profiles generated from the
+# syscalls of containers in seccomp audit mode are written as <container ID>.json.
+# The profiles are updated while the containers run and are complete once the
+# containers exit.
+seccomp_audit_profile_dir = "{{ .SeccompAuditProfileDir }}"
+
+# Number of syscalls blocked by the seccomp profile after which a container in
+# seccomp notifier mode gets stopped. Pods request the notifier mode via the
+# io.kubernetes.cri-o.seccomp-notifier annotation, which has to be part of the
+# allowed_annotations of the runtime handler. The blocked syscalls of their
+# containers are recorded in the io.kubernetes.cri-o.SeccompBlockedSyscalls
+# container status annotation. A value of 0 never stops containers.
+seccomp_notifier_stop_threshold = {{ .SeccompNotifierStopThreshold }}
+
 # Used to change the name of the default AppArmor profile of CRI-O. The default
 # profile name is "crio-default-" followed by the version string of CRI-O.
+# This option supports live configuration reload.
 apparmor_profile = "{{ .ApparmorProfile }}"
 
 # Cgroup management implementation used for the runtime.
@@ -131,11 +168,13 @@ cgroup_manager = "{{ .CgroupManager }}"
 # List of default capabilities for containers. If it is empty or commented out,
 # only the capabilities defined in the containers json file by the user/kube
 # will be added.
+# This option supports live configuration reload.
 default_capabilities = [
 {{ range $capability := .DefaultCapabilities}}{{ printf "\t%q, \n" $capability}}{{ end }}]
 
 # List of default sysctls. If it is empty or commented out, only the sysctls
 # defined in the container json file by the user/kube will be added.
+# This option supports live configuration reload.
 default_sysctls = [
 {{ range $sysctl := .DefaultSysctls}}{{ printf "\t%q, \n" $sysctl}}{{ end }}]
 
@@ -152,6 +191,7 @@ hooks_dir = [
 
 # List of default mounts for each container. **Deprecated:** this option will
 # be removed in future versions in favor of default_mounts_file.
+# This option supports live configuration reload.
 default_mounts = [
 {{ range $mount := .DefaultMounts }}{{ printf "\t%q, \n" $mount }}{{ end }}]
 
@@ -171,6 +211,7 @@ default_mounts = [
 #default_mounts_file = "{{ .DefaultMountsFile }}"
 
 # Maximum number of processes allowed in a container.
+# This option supports live configuration reload.
 pids_limit = {{ .PidsLimit }}
 
 # Maximum sized allowed for the container log file. Negative numbers indicate
@@ -179,6 +220,20 @@ pids_limit = {{ .PidsLimit }}
 # limit is never exceeded.
 log_size_max = {{ .LogSizeMax }}
 
+# Number of rotated log files kept for a container once its log file reaches
+# log_size_max. The rotated files are named <log>.1 to <log>.N, with <log>.1
+# being the most recent one. Zero means that the log file is truncated instead.
+# Pods can override log_size_max, log_max_files and log_compress with the
+# io.kubernetes.cri-o.LogSizeMax, io.kubernetes.cri-o.LogMaxFiles and
+# io.kubernetes.cri-o.LogCompress annotations, which have to be part of the
+# allowed_annotations of the runtime handler. The overrides never exceed the
+# configured log_size_max and log_max_files.
+log_max_files = {{ .LogMaxFiles }}
+
+# Whether rotated container log files are compressed with gzip, which adds the
+# .gz suffix to their names.
+log_compress = {{ .LogCompress }}
+
 # Whether container output should be logged to journald in addition to the kuberentes log file
 log_to_journald = {{ .LogToJournald }}
 
@@ -212,6 +267,13 @@ uid_mappings = "{{ .UIDMappings }}"
 # separated by comma.
 gid_mappings = "{{ .GIDMappings }}"
 
+# The range of host IDs in the form HostID:Size, from which pods get a
+# dedicated range allocated if they request their own user namespace via the
+# io.kubernetes.cri-o.userns-mode annotation, for example "auto:size=65536".
+# The annotation has to be part of the allowed_annotations of the runtime
+# handler.
+userns_pool = "{{ .UsernsPool }}"
+
 # The minimal amount of time in seconds to wait before issuing a timeout
 # regarding the proper termination of the container.
 ctr_stop_timeout = {{ .CtrStopTimeout }}
@@ -220,15 +282,43 @@ ctr_stop_timeout = {{ .CtrStopTimeout }}
 # and manage its lifecycle.
 manage_network_ns_lifecycle = {{ .ManageNetworkNSLifecycle }}
 
+# DropInfraCtr determines whether pod sandboxes are created without an infra
+# container, as long as they do not share their PID namespace. The namespaces
+# of such sandboxes are pinned by CRI-O instead, which requires
+# manage_network_ns_lifecycle to be enabled.
+drop_infra_ctr = {{ .DropInfraCtr }}
+
 # The "crio.runtime.runtimes" table defines a list of OCI compatible runtimes.
 # The runtime to use is picked based on the runtime_handler provided by the CRI.
 # If no runtime_handler is provided, the runtime will be picked based on the level
 # of trust of the workload.
+# Runtime handlers can be added, changed or removed via live configuration
+# reload, as long as the default_runtime and the runtime handlers in use by
+# existing pods remain available.
+# Each runtime handler supports the following settings in addition to its
+# runtime_path, runtime_type and runtime_root:
+# - allowed_annotations: The experimental annotations honored for the pods and
+#   containers of the handler, like "io.kubernetes.cri-o.userns-mode". All
+#   other experimental annotations are ignored.
+# - privileged_without_host_devices: If true, the host devices are not added
+#   to privileged containers, for example for VM based runtimes.
+# - conmon, conmon_cgroup and conmon_env: Override the global settings of the
+#   same name for the handler, if set.
+# - monitor_exec_cgroup: If set to "container", the conmon processes
+#   monitoring exec sync requests are moved into the cgroup of the container.
 {{ range $runtime_name, $runtime_handler := .Runtimes  }}
 [crio.runtime.runtimes.{{ $runtime_name }}]
 runtime_path = "{{ $runtime_handler.RuntimePath }}"
 runtime_type = "{{ $runtime_handler.RuntimeType }}"
 runtime_root = "{{ $runtime_handler.RuntimeRoot }}"
+allowed_annotations = [
+{{ range $annotation := $runtime_handler.AllowedAnnotations }}{{ printf "\t%q,\n" $annotation }}{{ end }}]
+privileged_without_host_devices = {{ $runtime_handler.PrivilegedWithoutHostDevices }}
+conmon = "{{ $runtime_handler.Conmon }}"
+conmon_cgroup = "{{ $runtime_handler.ConmonCgroup }}"
+conmon_env = [
+{{ range $env := $runtime_handler.ConmonEnv }}{{ printf "\t%q,\n" $env }}{{ end }}]
+monitor_exec_cgroup = "{{ $runtime_handler.MonitorExecCgroup }}"
 {{ end }}
 
 # The crio.image table contains settings pertaining to the management of OCI images.
@@ -265,11 +355,20 @@ pause_command = "{{ .PauseCommand }}"
 # this option be used, as the default behavior of using the system-wide default
 # policy (i.e., /etc/containers/policy.json) is most often preferred. Please
 # refer to containers-policy.json(5) for more details.
+# This option supports live configuration reload.
 signature_policy = "{{ .SignaturePolicyPath }}"
 
+# Path to a directory of signature policy files per Kubernetes namespace. The
+# images pulled for a pod are verified against the file "<namespace>.json" of
+# its namespace in this directory. If the file does not exist, the
+# signature_policy is used.
+# This option supports live configuration reload.
+signature_policy_dir = "{{ .SignaturePolicyDir }}"
+
 # List of registries to skip TLS verification for pulling images. Please
 # consider configuring the registries via /etc/containers/registries.conf before
 # changing them here.
+# This option supports live configuration reload.
 #insecure_registries = "{{ .InsecureRegistries }}"
 
 # Controls how image volumes are handled. The valid values are mkdir, bind and
@@ -281,9 +380,41 @@ image_volumes = "{{ .ImageVolumes }}"
 # compatibility reasons. Depending on your workload and usecase you may add more
 # registries (e.g., "quay.io", "registry.fedoraproject.org",
 # "registry.opensuse.org", etc.).
+# This option supports live configuration reload.
 #registries = [
 # {{ range $opt := .Registries }}{{ printf "\t%q,\n#" $opt }}{{ end }}]
 
+# List of images which are never removed, for example by the kubelet image
+# garbage collection. Every entry can be an exact image name, a name prefix
+# ending with "*", an image ID or a digest. Name prefixes are qualified like
+# image names, so "busybox*" matches "docker.io/library/busybox". Removing a
+# name of an image with other names is only refused if that name is pinned.
+# The pause_image is always pinned implicitly.
+# This option supports live configuration reload.
+pinned_images = [
+{{ range $image := .PinnedImages }}{{ printf "\t%q,\n" $image }}{{ end }}]
+
+# The number of seconds after which an image pull which made no progress gets
+# aborted. Transferred bytes and blobs already in storage count as progress.
+# The timeout does not apply once the pull commits the image to the storage.
+# The default value of 0 disables the timeout.
+pull_progress_timeout = {{ .PullProgressTimeout }}
+
+# The "crio.image.registry_mirrors" table maps registries to the locations of
+# their mirrors, for example "mirror.example.com/docker.io". The mirrors are
+# tried in order before the registry itself when pulling images. The signature
+# policy of the registry applies to the images pulled from its mirrors.
+# This option supports live configuration reload.
+[crio.image.registry_mirrors]
+{{ range $registry, $mirrors := .RegistryMirrors }}{{ printf "%q = [" $registry }}{{ range $i, $mirror := $mirrors }}{{ if $i }}, {{ end }}{{ printf "%q" $mirror }}{{ end }}]
+{{ end }}
+# The "crio.image.short_name_aliases" table maps short image names to fully
+# qualified image names without tag or digest, for example
+# "nginx" = "docker.io/library/nginx". Aliased short names are never resolved
+# against the registries.
+# This option supports live configuration reload.
+[crio.image.short_name_aliases]
+{{ range $name, $alias := .ShortNameAliases }}{{ printf "%q = %q\n" $name $alias }}{{ end }}
 
 # The crio.network table containers settings pertaining to the management of
 # CNI plugins.
@@ -19,6 +19,7 @@ import (
 	"github.com/containers/storage/pkg/reexec"
 	libconfig "github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/pkg/signals"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
 	"github.com/cri-o/cri-o/internal/version"
 	"github.com/cri-o/cri-o/server"
 	"github.com/cri-o/cri-o/utils"
@@ -63,6 +64,14 @@ func mergeConfig(config *libconfig.Config, ctx *cli.Context) (string, error) {
 		}
 	}
 
+	// Merge the drop-in configuration files on top of the main one. A
+	// directory which doesn't exist yet is picked up by a later reload.
+	if dir := ctx.GlobalString("config-dir"); dir != "" {
+		if err := config.UpdateFromDropInDir(dir); err != nil {
+			return path, err
+		}
+	}
+
 	// Override options set with the CLI.
 	if ctx.GlobalIsSet("conmon") {
 		config.Conmon = ctx.GlobalString("conmon")
@@ -82,6 +91,12 @@ func mergeConfig(config *libconfig.Config, ctx *cli.Context) (string, error) {
 	if ctx.GlobalIsSet("signature-policy") {
 		config.SignaturePolicyPath = ctx.GlobalString("signature-policy")
 	}
+	if ctx.GlobalIsSet("pull-progress-timeout") {
+		config.PullProgressTimeout = ctx.GlobalInt64("pull-progress-timeout")
+	}
+	if ctx.GlobalIsSet("signature-policy-dir") {
+		config.SignaturePolicyDir = ctx.GlobalString("signature-policy-dir")
+	}
 	if ctx.GlobalIsSet("root") {
 		config.Root = ctx.GlobalString("root")
 	}
@@ -137,6 +152,15 @@ func mergeConfig(config *libconfig.Config, ctx *cli.Context) (string, error) {
 	if ctx.GlobalIsSet("seccomp-profile") {
 		config.SeccompProfile = ctx.GlobalString("seccomp-profile")
 	}
+	if ctx.GlobalIsSet("seccomp-audit-source") {
+		config.SeccompAuditSource = ctx.GlobalString("seccomp-audit-source")
+	}
+	if ctx.GlobalIsSet("seccomp-audit-profile-dir") {
+		config.SeccompAuditProfileDir = ctx.GlobalString("seccomp-audit-profile-dir")
+	}
+	if ctx.GlobalIsSet("seccomp-notifier-stop-threshold") {
+		config.SeccompNotifierStopThreshold = ctx.GlobalUint64("seccomp-notifier-stop-threshold")
+	}
 	if ctx.GlobalIsSet("apparmor-profile") {
 		config.ApparmorProfile = ctx.GlobalString("apparmor-profile")
 	}
@@ -170,6 +194,12 @@ func mergeConfig(config *libconfig.Config, ctx *cli.Context) (string, error) {
 	if ctx.GlobalIsSet("log-size-max") {
 		config.LogSizeMax = ctx.GlobalInt64("log-size-max")
 	}
+	if ctx.GlobalIsSet("log-max-files") {
+		config.LogMaxFiles = ctx.GlobalInt("log-max-files")
+	}
+	if ctx.GlobalIsSet("log-compress") {
+		config.LogCompress = ctx.GlobalBool("log-compress")
+	}
 	if ctx.GlobalIsSet("log-journald") {
 		config.LogToJournald = ctx.GlobalBool("log-journald")
 	}
@@ -218,22 +248,27 @@ func catchShutdown(ctx context.Context, cancel context.CancelFunc, gserver *grpc
 	sig := make(chan os.Signal, 2048)
 	signal.Notify(sig, signals.Interrupt, signals.Term, unix.SIGUSR1, unix.SIGPIPE, signals.Hup)
 	go func() {
-		for s := range sig {
-			logrus.WithFields(logrus.Fields{
-				"signal": s,
-			}).Debug("received signal")
-			switch s {
-			case unix.SIGUSR1:
-				writeCrioGoroutineStacks()
-				continue
-			case unix.SIGPIPE:
-				continue
-			case signals.Interrupt:
-				logrus.Debugf("Caught SIGINT")
-			case signals.Term:
-				logrus.Debugf("Caught SIGTERM")
-			default:
-				continue
+		for {
+			select {
+			case s := <-sig:
+				logrus.WithFields(logrus.Fields{
+					"signal": s,
+				}).Debug("received signal")
+				switch s {
+				case unix.SIGUSR1:
+					writeCrioGoroutineStacks()
+					continue
+				case unix.SIGPIPE:
+					continue
+				case signals.Interrupt:
+					logrus.Debugf("Caught SIGINT")
+				case signals.Term:
+					logrus.Debugf("Caught SIGTERM")
+				default:
+					continue
+				}
+			case <-sserver.ShutdownRequestedChan():
+				logrus.Debugf("Shutdown requested")
 			}
 			*signalled = true
 			gserver.GracefulStop()
@@ -289,6 +324,11 @@ func main() {
 			Value: libconfig.CrioConfigPath,
 			Usage: "path to configuration file",
 		},
+		cli.StringFlag{
+			Name:  "config-dir, d",
+			Value: libconfig.CrioConfigDropInPath,
+			Usage: "path to the directory of drop-in configuration files, which are merged in lexical order on top of the configuration file",
+		},
 		cli.StringFlag{
 			Name:  "conmon",
 			Usage: fmt.Sprintf("path to the conmon executable (default: %q)", defConf.Conmon),
@@ -349,6 +389,14 @@ func main() {
 			Name:  "signature-policy",
 			Usage: fmt.Sprintf("path to signature policy file (default: %q)", defConf.SignaturePolicyPath),
 		},
+		cli.Int64Flag{
+			Name:  "pull-progress-timeout",
+			Usage: fmt.Sprintf("number of seconds after which an image pull which made no progress gets aborted, 0 to disable (default: %d)", defConf.PullProgressTimeout),
+		},
+		cli.StringFlag{
+			Name:  "signature-policy-dir",
+			Usage: fmt.Sprintf("path to a directory of per namespace signature policy files named '<namespace>.json' (default: %q)", defConf.SignaturePolicyDir),
+		},
 		cli.StringFlag{
 			Name:  "root, r",
 			Usage: fmt.Sprintf("crio root dir (default: %q)", defConf.Root),
@@ -395,6 +443,18 @@ func main() {
 			Name:  "seccomp-profile",
 			Usage: fmt.Sprintf("default seccomp profile path (default: %q)", defConf.SeccompProfile),
 		},
+		cli.StringFlag{
+			Name:  "seccomp-audit-source",
+			Usage: fmt.Sprintf("audit log or syslog file from which the syscalls of containers in seccomp audit mode are collected (default: %q)", defConf.SeccompAuditSource),
+		},
+		cli.StringFlag{
+			Name:  "seccomp-audit-profile-dir",
+			Usage: fmt.Sprintf("directory to which the seccomp profiles generated for containers in seccomp audit mode are written (default: %q)", defConf.SeccompAuditProfileDir),
+		},
+		cli.Uint64Flag{
+			Name:  "seccomp-notifier-stop-threshold",
+			Usage: "number of blocked syscalls after which a container in seccomp notifier mode gets stopped, 0 to never stop containers (default: 0)",
+		},
 		cli.StringFlag{
 			Name:  "apparmor-profile",
 			Usage: fmt.Sprintf("default apparmor profile name (default: %q)", defConf.ApparmorProfile),
@@ -417,6 +477,15 @@ func main() {
 			Value: libconfig.DefaultLogSizeMax,
 			Usage: "maximum log size in bytes for a container",
 		},
+		cli.IntFlag{
+			Name:  "log-max-files",
+			Value: libconfig.DefaultLogMaxFiles,
+			Usage: "number of rotated log files to keep for a container once it reaches the maximum log size, 0 truncates the log file instead",
+		},
+		cli.BoolFlag{
+			Name:  "log-compress",
+			Usage: fmt.Sprintf("Compress rotated container log files with gzip (default: %t)", defConf.LogCompress),
+		},
 		cli.BoolFlag{
 			Name:  "log-journald",
 			Usage: fmt.Sprintf("Log to journald in addition to kubernetes log file (default: %t)", defConf.LogToJournald),
@@ -498,13 +567,28 @@ func main() {
 			Name:  "additional-devices",
 			Usage: fmt.Sprintf("devices to add to the containers (default: %q)", defConf.AdditionalDevices),
 		},
+		cli.StringFlag{
+			Name:   "address",
+			Usage:  "socket to publish the events of runtime shims to",
+			Hidden: true,
+		},
 	}
 
 	sort.Sort(cli.FlagsByName(app.Flags))
 	sort.Sort(cli.FlagsByName(configCommand.Flags))
+	sort.Sort(cli.FlagsByName(statusCommand.Flags))
+	sort.Sort(cli.FlagsByName(shutdownCommand.Flags))
+	sort.Sort(cli.FlagsByName(checkpointCommand.Flags))
+	sort.Sort(cli.FlagsByName(restoreCommand.Flags))
+	sort.Sort(cli.FlagsByName(publishCommand.Flags))
 
 	app.Commands = []cli.Command{
 		configCommand,
+		statusCommand,
+		shutdownCommand,
+		checkpointCommand,
+		restoreCommand,
+		publishCommand,
 	}
 
 	var configPath string
@@ -594,10 +678,19 @@ func main() {
 			logrus.Fatalf("failed to listen: %v", err)
 		}
 
-		grpcServer := grpc.NewServer(
+		grpcOptions := []grpc.ServerOption{
 			grpc.MaxSendMsgSize(config.GRPCMaxSendMsgSize),
 			grpc.MaxRecvMsgSize(config.GRPCMaxRecvMsgSize),
-		)
+		}
+		var tracer *tracing.Tracer
+		if config.EnableTracing {
+			logrus.Infof("exporting traces to %s", config.TracingEndpoint)
+			tracer = tracing.New(config.TracingEndpoint, config.TracingSamplingRatePerMillion)
+			tracing.SetTracer(tracer)
+			grpcOptions = append(grpcOptions,
+				grpc.UnaryInterceptor(tracing.UnaryServerInterceptor(tracer)))
+		}
+		grpcServer := grpc.NewServer(grpcOptions...)
 
 		service, err := server.New(ctx, systemContext, configPath, config)
 		if err != nil {
@@ -624,6 +717,7 @@ func main() {
 					logrus.Fatalf("Failed to serve metrics endpoint: %v", err)
 				}
 			}()
+			go service.StartMetricsUpdater()
 		}
 
 		runtime.RegisterRuntimeServiceServer(grpcServer, service)
@@ -656,6 +750,9 @@ func main() {
 			Handler:     infoMux,
 			ReadTimeout: 5 * time.Second,
 		}
+		// End the long-lived event streams, otherwise they would block the
+		// graceful shutdown of the HTTP server
+		httpServer.RegisterOnShutdown(service.CloseEventSubscriptions)
 
 		graceful := false
 		catchShutdown(ctx, cancel, grpcServer, service, httpServer, &graceful)
@@ -709,6 +806,14 @@ func main() {
 		<-serverCloseCh
 		logrus.Debug("closed main server")
 
+		if tracer != nil {
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancelShutdown()
+			if err := tracer.Shutdown(shutdownCtx); err != nil {
+				logrus.Warnf("unable to export pending traces: %v", err)
+			}
+		}
+
 		return nil
 	}
 
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/urfave/cli"
+)
+
+// publishCommand is executed by the shims of VM runtimes to publish the
+// events of their containers, as `crio --address <socket> publish`.
+var publishCommand = cli.Command{
+	Name:   "publish",
+	Usage:  "forward an event of a runtime shim read from stdin",
+	Hidden: true,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "topic",
+			Usage: "topic of the event",
+		},
+		cli.StringFlag{
+			Name:  "namespace",
+			Usage: "namespace of the event",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 0 {
+			return fmt.Errorf("unexpected arguments %v", c.Args())
+		}
+		address := c.GlobalString("address")
+		if address == "" {
+			return fmt.Errorf("no address to publish the event to")
+		}
+		return oci.PublishShimEvent(address, c.String("topic"), c.String("namespace"), os.Stdin)
+	},
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/cri-o/cri-o/server"
+	"github.com/urfave/cli"
+)
+
+var shutdownCommand = cli.Command{
+	Name:  "shutdown",
+	Usage: "shut down a running crio daemon",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "socket, s",
+			Usage: "path to the crio socket (default: the listen path of the configuration)",
+		},
+		cli.BoolFlag{
+			Name:  "drain",
+			Usage: "stop all pods before shutting down, otherwise they are kept running",
+		},
+		cli.DurationFlag{
+			Name:  "timeout",
+			Value: server.DefaultDrainTimeout,
+			Usage: "maximum time to wait for all pods to stop when draining",
+		},
+	},
+	Action: func(c *cli.Context) error {
+		if c.NArg() != 0 {
+			return fmt.Errorf("unexpected arguments %v", c.Args())
+		}
+		crioClient, err := newCrioClient(c, c.String("socket"))
+		if err != nil {
+			return err
+		}
+		return crioClient.Shutdown(c.Bool("drain"), c.Duration("timeout"))
+	},
+}
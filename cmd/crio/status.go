@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/containers/storage/pkg/idtools"
+	"github.com/cri-o/cri-o/internal/client"
+	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/urfave/cli"
+)
+
+const (
+	outputJSON  = "json"
+	outputTable = "table"
+)
+
+var statusCommand = cli.Command{
+	Name:    "status",
+	Aliases: []string{"inspect"},
+	Usage:   "display status information of a running crio daemon",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "socket, s",
+			Usage: "path to the crio socket (default: the listen path of the configuration)",
+		},
+		cli.StringFlag{
+			Name:  "output, o",
+			Value: outputTable,
+			Usage: "output format ('table' or 'json')",
+		},
+	},
+	Subcommands: []cli.Command{
+		{
+			Name:   "info",
+			Usage:  "display the information about the daemon",
+			Action: statusInfo,
+		},
+		{
+			Name:   "config",
+			Usage:  "display the TOML configuration of the daemon",
+			Action: statusConfig,
+		},
+		{
+			Name:      "containers",
+			Usage:     "display the information about a container",
+			ArgsUsage: "<id>",
+			Action:    statusContainers,
+		},
+		{
+			Name:      "sandboxes",
+			Usage:     "list all sandboxes, or the containers of the sandbox <id>",
+			ArgsUsage: "[<id>]",
+			Action:    statusSandboxes,
+		},
+		{
+			Name:  "events",
+			Usage: "stream the lifecycle events of containers and sandboxes",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "sandbox",
+					Usage: "only show the events of the sandbox with this ID",
+				},
+				cli.StringSliceFlag{
+					Name:  "label",
+					Usage: "only show the events with this label (as 'key=value', can be repeated)",
+				},
+				cli.StringSliceFlag{
+					Name:  "type",
+					Usage: "only show the events of this type (can be repeated)",
+				},
+				cli.BoolFlag{
+					Name:  "replay",
+					Usage: "show the recent events before streaming new ones",
+				},
+			},
+			Action: statusEvents,
+		},
+		{
+			Name:   "goroutines",
+			Usage:  "display the goroutine stacks of the daemon",
+			Action: statusGoroutines,
+		},
+	},
+}
+
+// statusOptions returns a client for the daemon and the output format of the
+// `crio status` subcommand context `c`.
+func statusOptions(c *cli.Context) (client.CrioClient, string, error) {
+	parent := c.Parent()
+	output := parent.String("output")
+	if output != outputTable && output != outputJSON {
+		return nil, "", fmt.Errorf("unknown output format %q", output)
+	}
+
+	crioClient, err := newCrioClient(c, parent.String("socket"))
+	if err != nil {
+		return nil, "", err
+	}
+	return crioClient, output, nil
+}
+
+// newCrioClient returns a client for the daemon listening on `socket`, which
+// defaults to the listen path of the configuration.
+func newCrioClient(c *cli.Context, socket string) (client.CrioClient, error) {
+	if socket == "" {
+		conf, ok := c.App.Metadata["config"].(*config.Config)
+		if !ok {
+			return nil, fmt.Errorf("type assertion error when accessing server config")
+		}
+		socket = conf.Listen
+	}
+	return client.New(socket)
+}
+
+func printJSON(v interface{}) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func printTable(header []string, rows [][]string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	if header != nil {
+		fmt.Fprintln(w, strings.Join(header, "\t"))
+	}
+	for _, row := range rows {
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+func statusInfo(c *cli.Context) error {
+	crioClient, output, err := statusOptions(c)
+	if err != nil {
+		return err
+	}
+	info, err := crioClient.DaemonInfo()
+	if err != nil {
+		return err
+	}
+	if output == outputJSON {
+		return printJSON(info)
+	}
+	return printTable(nil, [][]string{
+		{"storage driver:", info.StorageDriver},
+		{"storage root:", info.StorageRoot},
+		{"cgroup driver:", info.CgroupDriver},
+		{"cgroup version:", info.CgroupVersion},
+		{"default UID mappings:", formatIDMappings(info.DefaultIDMappings.Uids)},
+		{"default GID mappings:", formatIDMappings(info.DefaultIDMappings.Gids)},
+	})
+}
+
+func statusConfig(c *cli.Context) error {
+	crioClient, _, err := statusOptions(c)
+	if err != nil {
+		return err
+	}
+	// The configuration is always printed in TOML, which can be used as
+	// configuration file again.
+	conf, err := crioClient.ConfigInfo()
+	if err != nil {
+		return err
+	}
+	fmt.Print(conf)
+	return nil
+}
+
+func statusContainers(c *cli.Context) error {
+	if c.NArg() != 1 {
+		return fmt.Errorf("expected exactly one container ID")
+	}
+	crioClient, output, err := statusOptions(c)
+	if err != nil {
+		return err
+	}
+	info, err := crioClient.ContainerInfo(c.Args().First())
+	if err != nil {
+		return err
+	}
+	if output == outputJSON {
+		return printJSON(info)
+	}
+	return printTable(nil, [][]string{
+		{"id:", info.ID},
+		{"name:", info.Name},
+		{"pid:", fmt.Sprint(info.Pid)},
+		{"image:", info.Image},
+		{"image ref:", info.ImageRef},
+		{"created:", formatTime(info.CreatedTime)},
+		{"sandbox:", info.Sandbox},
+		{"ips:", strings.Join(info.IPs, ", ")},
+		{"root:", info.Root},
+		{"log path:", info.LogPath},
+	})
+}
+
+func statusSandboxes(c *cli.Context) error {
+	if c.NArg() > 1 {
+		return fmt.Errorf("expected at most one sandbox ID")
+	}
+	crioClient, output, err := statusOptions(c)
+	if err != nil {
+		return err
+	}
+
+	if c.NArg() == 1 {
+		infos, err := crioClient.SandboxContainersInfo(c.Args().First())
+		if err != nil {
+			return err
+		}
+		if output == outputJSON {
+			return printJSON(infos)
+		}
+		rows := make([][]string, 0, len(infos))
+		for i := range infos {
+			rows = append(rows, []string{
+				infos[i].ID, infos[i].Name, fmt.Sprint(infos[i].Pid),
+				infos[i].Image, formatTime(infos[i].CreatedTime),
+			})
+		}
+		return printTable([]string{"ID", "NAME", "PID", "IMAGE", "CREATED"}, rows)
+	}
+
+	infos, err := crioClient.SandboxesInfo()
+	if err != nil {
+		return err
+	}
+	if output == outputJSON {
+		return printJSON(infos)
+	}
+	rows := make([][]string, 0, len(infos))
+	for i := range infos {
+		state := "ready"
+		if infos[i].Stopped {
+			state = "stopped"
+		}
+		rows = append(rows, []string{
+			infos[i].ID, infos[i].Name, infos[i].Namespace, state,
+			infos[i].RuntimeHandler, fmt.Sprint(len(infos[i].Containers)),
+		})
+	}
+	return printTable([]string{"ID", "NAME", "NAMESPACE", "STATE", "RUNTIME", "CONTAINERS"}, rows)
+}
+
+func statusEvents(c *cli.Context) error {
+	crioClient, output, err := statusOptions(c)
+	if err != nil {
+		return err
+	}
+	filter := &types.EventFilter{SandboxID: c.String("sandbox")}
+	for _, label := range c.StringSlice("label") {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid label %q, expected key=value", label)
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[kv[0]] = kv[1]
+	}
+	for _, t := range c.StringSlice("type") {
+		filter.Types = append(filter.Types, types.EventType(t))
+	}
+
+	// Events are printed as they arrive, so the table is not aligned
+	if output == outputTable {
+		fmt.Println("TIME\tTYPE\tSANDBOX\tCONTAINER\tEXIT CODE")
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	var printErr error
+	handler := func(event *types.Event) {
+		if printErr != nil {
+			return
+		}
+		if output == outputJSON {
+			printErr = encoder.Encode(event)
+			return
+		}
+		exitCode := ""
+		if event.ExitCode != nil {
+			exitCode = fmt.Sprint(*event.ExitCode)
+			if event.OOMKilled {
+				exitCode += " (OOM killed)"
+			}
+		}
+		_, printErr = fmt.Printf("%s\t%s\t%s\t%s\t%s\n", formatTime(event.Timestamp),
+			event.Type, event.SandboxID, event.ContainerID, exitCode)
+	}
+	if err := crioClient.Events(context.Background(), filter, c.Bool("replay"), handler); err != nil {
+		return err
+	}
+	return printErr
+}
+
+func statusGoroutines(c *cli.Context) error {
+	crioClient, _, err := statusOptions(c)
+	if err != nil {
+		return err
+	}
+	stacks, err := crioClient.GoroutineStacks()
+	if err != nil {
+		return err
+	}
+	fmt.Print(stacks)
+	return nil
+}
+
+func formatIDMappings(mappings []idtools.IDMap) string {
+	formatted := make([]string, 0, len(mappings))
+	for _, m := range mappings {
+		formatted = append(formatted, fmt.Sprintf("%d:%d:%d", m.ContainerID, m.HostID, m.Size))
+	}
+	return strings.Join(formatted, ", ")
+}
+
+func formatTime(unixNano int64) string {
+	return time.Unix(0, unixNano).Format(time.RFC3339)
+}
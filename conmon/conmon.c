@@ -69,6 +69,8 @@ static gchar **opt_log_path = NULL;
 static char *opt_exit_dir = NULL;
 static int opt_timeout = 0;
 static int64_t opt_log_size_max = -1;
+static int opt_log_max_files = 0;
+static gboolean opt_log_compress = FALSE;
 static char *opt_socket_path = DEFAULT_SOCKET_PATH;
 static gboolean opt_no_new_keyring = FALSE;
 static char *opt_exit_command = NULL;
@@ -106,6 +108,9 @@ static GOptionEntry opt_entries[] = {
 	{"log-path", 'l', 0, G_OPTION_ARG_STRING_ARRAY, &opt_log_path, "Log file path", NULL},
 	{"timeout", 'T', 0, G_OPTION_ARG_INT, &opt_timeout, "Timeout in seconds", NULL},
 	{"log-size-max", 0, 0, G_OPTION_ARG_INT64, &opt_log_size_max, "Maximum size of log file", NULL},
+	{"log-max-files", 0, 0, G_OPTION_ARG_INT, &opt_log_max_files,
+	 "Number of rotated log files to keep once the log file reaches the maximum size, 0 truncates the log file instead", NULL},
+	{"log-compress", 0, 0, G_OPTION_ARG_NONE, &opt_log_compress, "Compress rotated log files with gzip", NULL},
 	{"socket-dir-path", 0, 0, G_OPTION_ARG_STRING, &opt_socket_path, "Location of container attach sockets", NULL},
 	{"version", 0, 0, G_OPTION_ARG_NONE, &opt_version, "Print the version and exit", NULL},
 	{"syslog", 0, 0, G_OPTION_ARG_NONE, &opt_syslog, "Log to syslog (use with cgroupfs cgroup manager)", NULL},
@@ -1158,7 +1163,10 @@ int main(int argc, char *argv[])
 		opt_container_pid_file = default_pid_file;
 	}
 
-	configure_log_drivers(opt_log_path, opt_log_size_max, opt_cuuid, opt_name);
+	if (opt_log_max_files < 0)
+		nexit("Number of rotated log files must not be negative");
+
+	configure_log_drivers(opt_log_path, opt_log_size_max, opt_log_max_files, opt_log_compress, opt_cuuid, opt_name);
 
 	start_pipe_fd = get_pipe_fd_from_env("_OCI_STARTPIPE");
 	if (start_pipe_fd >= 0) {
@@ -1,6 +1,9 @@
 #define _GNU_SOURCE
 #include "ctr_logging.h"
 #include <string.h>
+#include <sys/stat.h>
+#include <sys/wait.h>
+#include <zlib.h>
 
 // if the systemd development files were found, we can log to systemd
 #ifdef USE_JOURNALD
@@ -31,9 +34,19 @@ static const char *const JOURNALD_FILE_STRING = "journald";
 /* Max log size for any log file types */
 static int64_t log_size_max = -1;
 
+/* Number of rotated log files to keep, 0 truncates the log file instead */
+static int log_max_files = 0;
+
+/* Whether rotated log files are compressed with gzip */
+static gboolean log_compress = FALSE;
+
+/* The process compressing the most recently rotated log file, if any */
+static pid_t compress_pid = -1;
+
 /* k8s log file parameters */
 static int k8s_log_fd = -1;
 static char *k8s_log_path = NULL;
+static int64_t k8s_bytes_written = 0;
 
 /* journald log file parameters */
 #define TRUNC_ID_LEN 12
@@ -61,16 +74,24 @@ static ssize_t writev_buffer_append_segment(int fd, writev_buffer_t *buf, const
 static ssize_t writev_buffer_flush(int fd, writev_buffer_t *buf);
 static int set_k8s_timestamp(char *buf, ssize_t buflen, const char *pipename);
 static void reopen_k8s_file(void);
+static void rotate_k8s_file(void);
+static void compress_log_file_async(const char *path);
+static void wait_for_compression(void);
+static int compress_log_file(const char *path);
 
 
 /* configures container log specific information, such as the drivers the user
  * called with and the max log size for log file types. For the log file types
  * (currently just k8s log file), it will also open the log_fd for that specific
- * log file.
+ * log file. Once a log file reaches the max log size, it is rotated if
+ * log_max_files_ is positive and truncated otherwise.
  */
-void configure_log_drivers(gchar **log_drivers, int64_t log_size_max_, char *cuuid_, char *name_)
+void configure_log_drivers(gchar **log_drivers, int64_t log_size_max_, int log_max_files_, gboolean log_compress_, char *cuuid_,
+			   char *name_)
 {
 	log_size_max = log_size_max_;
+	log_max_files = log_max_files_;
+	log_compress = log_compress_;
 	if (log_drivers == NULL)
 		nexit("Log driver not provided. Use --log-path");
 	for (int driver = 0; log_drivers[driver]; ++driver) {
@@ -81,6 +102,11 @@ void configure_log_drivers(gchar **log_drivers, int64_t log_size_max_, char *cuu
 		k8s_log_fd = open(k8s_log_path, O_WRONLY | O_APPEND | O_CREAT | O_CLOEXEC, 0600);
 		if (k8s_log_fd < 0)
 			pexit("Failed to open log file");
+
+		/* Account for the existing content, so the size limit still holds */
+		struct stat st;
+		if (fstat(k8s_log_fd, &st) == 0)
+			k8s_bytes_written = st.st_size;
 	}
 
 	if (use_journald_logging) {
@@ -232,7 +258,6 @@ static int write_k8s_log(stdpipe_t pipe, const char *buf, ssize_t buflen)
 {
 	char tsbuf[TSBUFLEN];
 	writev_buffer_t bufv = {0};
-	static int64_t bytes_written = 0;
 	int64_t bytes_to_be_written = 0;
 
 	/*
@@ -257,13 +282,12 @@ static int write_k8s_log(stdpipe_t pipe, const char *buf, ssize_t buflen)
 		}
 
 		/*
-		 * We re-open the log file if writing out the bytes will exceed the max
-		 * log size. We also reset the state so that the new file is started with
-		 * a timestamp.
+		 * We rotate or re-open the log file if writing out the bytes will exceed
+		 * the max log size. Every line starts with its own timestamp and stream,
+		 * so the new file stays in the CRI log format. A partial line continues
+		 * with its next "P" or "F" entry in the new file.
 		 */
-		if ((log_size_max > 0) && (bytes_written + bytes_to_be_written) > log_size_max) {
-			bytes_written = 0;
-
+		if ((log_size_max > 0) && (k8s_bytes_written > 0) && (k8s_bytes_written + bytes_to_be_written) > log_size_max) {
 			if (writev_buffer_flush(k8s_log_fd, &bufv) < 0) {
 				nwarn("failed to flush buffer to log");
 				/*
@@ -272,7 +296,10 @@ static int write_k8s_log(stdpipe_t pipe, const char *buf, ssize_t buflen)
 				 */
 				bufv.iovcnt = 0;
 			}
-			reopen_k8s_file();
+			if (log_max_files > 0)
+				rotate_k8s_file();
+			else
+				reopen_k8s_file();
 		}
 
 		/* Output the timestamp */
@@ -308,7 +335,7 @@ static int write_k8s_log(stdpipe_t pipe, const char *buf, ssize_t buflen)
 			}
 		}
 
-		bytes_written += bytes_to_be_written;
+		k8s_bytes_written += bytes_to_be_written;
 	next:
 		/* Update the head of the buffer remaining to output. */
 		buf += line_len;
@@ -475,11 +502,158 @@ static void reopen_k8s_file(void)
 	if (rename(k8s_log_path_tmp, k8s_log_path) < 0) {
 		pexit("Failed to rename log file");
 	}
+
+	k8s_bytes_written = 0;
+}
+
+/* rotate the k8s log file. The current file becomes <path>.1 and the
+ * previously rotated files are shifted by one, dropping the oldest one once
+ * log_max_files are kept. Rotated files get the .gz suffix if compressed.
+ */
+static void rotate_k8s_file(void)
+{
+	const char *suffix = log_compress ? ".gz" : "";
+
+	/* Sync the logs to disk */
+	if (fsync(k8s_log_fd) < 0) {
+		pwarn("Failed to sync log file on rotation");
+	}
+
+	/* Close the current k8s_log_fd */
+	close(k8s_log_fd);
+
+	/* The rotated files must not move while the previous one gets compressed */
+	wait_for_compression();
+
+	_cleanup_free_ char *oldest = g_strdup_printf("%s.%d%s", k8s_log_path, log_max_files, suffix);
+	if (unlink(oldest) < 0 && errno != ENOENT)
+		nwarnf("Failed to remove rotated log file %s: %s", oldest, strerror(errno));
+
+	for (int i = log_max_files - 1; i > 0; i--) {
+		_cleanup_free_ char *from = g_strdup_printf("%s.%d%s", k8s_log_path, i, suffix);
+		_cleanup_free_ char *to = g_strdup_printf("%s.%d%s", k8s_log_path, i + 1, suffix);
+		if (rename(from, to) < 0 && errno != ENOENT)
+			nwarnf("Failed to rename rotated log file %s: %s", from, strerror(errno));
+	}
+
+	/* The log file may be gone if it got moved away before a reopen */
+	_cleanup_free_ char *rotated = g_strdup_printf("%s.1", k8s_log_path);
+	if (rename(k8s_log_path, rotated) < 0) {
+		if (errno != ENOENT)
+			nwarnf("Failed to rotate log file %s: %s", k8s_log_path, strerror(errno));
+	} else if (log_compress) {
+		compress_log_file_async(rotated);
+	}
+
+	/* Start a new log file */
+	k8s_log_fd = open(k8s_log_path, O_WRONLY | O_APPEND | O_CREAT | O_TRUNC | O_CLOEXEC, 0600);
+	if (k8s_log_fd < 0)
+		pexitf("Failed to open log file %s", k8s_log_path);
+
+	k8s_bytes_written = 0;
+}
+
+/* compress the file at path in a child process, so that the container output
+ * does not block on gzip. If the fork fails, the file is compressed in place.
+ */
+static void compress_log_file_async(const char *path)
+{
+	pid_t pid = fork();
+	if (pid < 0) {
+		pwarn("Failed to fork log compression");
+		if (compress_log_file(path) < 0)
+			nwarnf("Failed to compress rotated log file %s", path);
+		return;
+	}
+
+	if (pid == 0) {
+		if (compress_log_file(path) < 0) {
+			nwarnf("Failed to compress rotated log file %s", path);
+			_exit(EXIT_FAILURE);
+		}
+		_exit(EXIT_SUCCESS);
+	}
+
+	compress_pid = pid;
+}
+
+/* wait until the compression of the previously rotated log file is done. The
+ * process may already have been reaped by the SIGCHLD handling of conmon.
+ */
+static void wait_for_compression(void)
+{
+	if (compress_pid <= 0)
+		return;
+
+	while (waitpid(compress_pid, NULL, 0) < 0 && errno == EINTR)
+		;
+	compress_pid = -1;
+}
+
+/* compress the file at path into <path>.gz and remove the uncompressed file.
+ * The compressed file is written to a temporary file first, so that <path>.gz
+ * only ever shows up complete.
+ */
+static int compress_log_file(const char *path)
+{
+	_cleanup_free_ char *gz_path = g_strdup_printf("%s.gz", path);
+	_cleanup_free_ char *gz_path_tmp = g_strdup_printf("%s.gz.tmp", path);
+	char buf[8192];
+	ssize_t num_read;
+
+	_cleanup_close_ int fd = open(path, O_RDONLY | O_CLOEXEC);
+	if (fd < 0)
+		return -1;
+
+	int gz_fd = open(gz_path_tmp, O_WRONLY | O_CREAT | O_TRUNC | O_CLOEXEC, 0600);
+	if (gz_fd < 0)
+		return -1;
+
+	gzFile gz = gzdopen(gz_fd, "wb");
+	if (gz == NULL) {
+		close(gz_fd);
+		unlink(gz_path_tmp);
+		return -1;
+	}
+
+	while ((num_read = read(fd, buf, sizeof buf)) != 0) {
+		if (num_read < 0) {
+			if (errno == EINTR)
+				continue;
+			goto fail;
+		}
+		if (gzwrite(gz, buf, num_read) != num_read)
+			goto fail;
+	}
+
+	/* gzclose also closes gz_fd */
+	if (gzclose(gz) != Z_OK) {
+		unlink(gz_path_tmp);
+		return -1;
+	}
+
+	if (rename(gz_path_tmp, gz_path) < 0) {
+		unlink(gz_path_tmp);
+		return -1;
+	}
+
+	if (unlink(path) < 0)
+		nwarnf("Failed to remove compressed log file %s: %s", path, strerror(errno));
+
+	return 0;
+
+fail:
+	gzclose(gz);
+	unlink(gz_path_tmp);
+	return -1;
 }
 
 
 void sync_logs(void)
 {
+	/* Leave no partially compressed log file behind */
+	wait_for_compression();
+
 	/* Sync the logs to disk */
 	if (k8s_log_fd > 0) {
 		if (fsync(k8s_log_fd) < 0) {
@@ -7,7 +7,8 @@
 
 void reopen_log_files(void);
 bool write_to_logs(stdpipe_t pipe, char *buf, ssize_t num_read);
-void configure_log_drivers(gchar **log_drivers, int64_t log_size_max_, char *cuuid_, char *name_);
+void configure_log_drivers(gchar **log_drivers, int64_t log_size_max_, int log_max_files_, gboolean log_compress_, char *cuuid_,
+			   char *name_);
 void sync_logs(void);
 
 #endif /* !defined(CTR_LOGGING_H) */
@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/url"
+	"strings"
 	"syscall"
 	"time"
 
@@ -20,6 +24,15 @@ const (
 type CrioClient interface {
 	DaemonInfo() (types.CrioInfo, error)
 	ContainerInfo(string) (*types.ContainerInfo, error)
+	ContainerStats(string) (*types.ContainerStats, error)
+	ConfigInfo() (string, error)
+	SandboxesInfo() ([]types.SandboxInfo, error)
+	SandboxContainersInfo(string) ([]types.ContainerInfo, error)
+	GoroutineStacks() (string, error)
+	Shutdown(drain bool, timeout time.Duration) error
+	CheckpointContainer(id, exportPath string, leaveRunning bool) error
+	RestoreContainer(sandboxID, importPath string) (string, error)
+	Events(ctx context.Context, filter *types.EventFilter, replay bool, handler func(*types.Event)) error
 }
 
 type crioClientImpl struct {
@@ -54,8 +67,8 @@ func New(crioSocketPath string) (CrioClient, error) {
 	}, nil
 }
 
-func (c *crioClientImpl) getRequest(path string) (*http.Request, error) {
-	req, err := http.NewRequest("GET", path, nil)
+func (c *crioClientImpl) newRequest(method, path string) (*http.Request, error) {
+	req, err := http.NewRequest(method, path, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -67,38 +80,207 @@ func (c *crioClientImpl) getRequest(path string) (*http.Request, error) {
 	return req, nil
 }
 
+// get requests `path` from the cri-o info endpoint and returns the response
+// body, or an error if the request did not succeed.
+func (c *crioClientImpl) get(path string) ([]byte, error) {
+	return c.do("GET", path)
+}
+
+// do sends a `method` request for `path` to the cri-o info endpoint and
+// returns the response body, or an error if the request did not succeed.
+func (c *crioClientImpl) do(method, path string) ([]byte, error) {
+	req, err := c.newRequest(method, path)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("request %s failed with status %q: %s",
+			path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
 // DaemonInfo return cri-o daemon info from the cri-o
 // info endpoint.
 func (c *crioClientImpl) DaemonInfo() (types.CrioInfo, error) {
 	info := types.CrioInfo{}
-	req, err := c.getRequest("/info")
-	if err != nil {
-		return info, err
-	}
-	resp, err := c.client.Do(req)
+	body, err := c.get("/info")
 	if err != nil {
 		return info, err
 	}
-	defer resp.Body.Close()
-	err = json.NewDecoder(resp.Body).Decode(&info)
+	err = json.Unmarshal(body, &info)
 	return info, err
 }
 
 // ContainerInfo returns container info by querying
 // the cri-o container endpoint.
 func (c *crioClientImpl) ContainerInfo(id string) (*types.ContainerInfo, error) {
-	req, err := c.getRequest("/containers/" + id)
+	body, err := c.get("/containers/" + id)
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.client.Do(req)
+	cInfo := types.ContainerInfo{}
+	if err := json.Unmarshal(body, &cInfo); err != nil {
+		return nil, err
+	}
+	return &cInfo, nil
+}
+
+// ContainerStats returns the resource usage statistics of the container
+// `id` from the cri-o info endpoint.
+func (c *crioClientImpl) ContainerStats(id string) (*types.ContainerStats, error) {
+	body, err := c.get("/containers/" + id + "/stats")
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	cInfo := types.ContainerInfo{}
-	if err := json.NewDecoder(resp.Body).Decode(&cInfo); err != nil {
+	stats := types.ContainerStats{}
+	if err := json.Unmarshal(body, &stats); err != nil {
 		return nil, err
 	}
-	return &cInfo, nil
+	return &stats, nil
+}
+
+// ConfigInfo returns the TOML configuration of the running
+// cri-o daemon.
+func (c *crioClientImpl) ConfigInfo() (string, error) {
+	body, err := c.get("/config")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// SandboxesInfo returns the info of all sandboxes by querying
+// the cri-o sandboxes endpoint.
+func (c *crioClientImpl) SandboxesInfo() ([]types.SandboxInfo, error) {
+	body, err := c.get("/sandboxes")
+	if err != nil {
+		return nil, err
+	}
+	sbInfos := []types.SandboxInfo{}
+	if err := json.Unmarshal(body, &sbInfos); err != nil {
+		return nil, err
+	}
+	return sbInfos, nil
+}
+
+// SandboxContainersInfo returns the info of all containers of
+// the sandbox `id`.
+func (c *crioClientImpl) SandboxContainersInfo(id string) ([]types.ContainerInfo, error) {
+	body, err := c.get("/sandboxes/" + id + "/containers")
+	if err != nil {
+		return nil, err
+	}
+	cInfos := []types.ContainerInfo{}
+	if err := json.Unmarshal(body, &cInfos); err != nil {
+		return nil, err
+	}
+	return cInfos, nil
+}
+
+// GoroutineStacks returns the stacks of all goroutines of the
+// cri-o daemon.
+func (c *crioClientImpl) GoroutineStacks() (string, error) {
+	body, err := c.get("/debug/goroutines")
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// Shutdown requests the shutdown of the cri-o daemon. All pod
+// sandboxes get stopped within `timeout` beforehand if `drain` is
+// set, otherwise they are kept running.
+func (c *crioClientImpl) Shutdown(drain bool, timeout time.Duration) error {
+	query := url.Values{}
+	if drain {
+		query.Set("drain", "true")
+		query.Set("timeout", timeout.String())
+	}
+	_, err := c.do("POST", "/shutdown?"+query.Encode())
+	return err
+}
+
+// CheckpointContainer checkpoints the container `id` to the archive at the
+// absolute `exportPath` of the daemon host. The container keeps running if
+// `leaveRunning` is set.
+func (c *crioClientImpl) CheckpointContainer(id, exportPath string, leaveRunning bool) error {
+	query := url.Values{}
+	query.Set("path", exportPath)
+	if leaveRunning {
+		query.Set("leave-running", "true")
+	}
+	_, err := c.do("POST", "/containers/"+url.PathEscape(id)+"/checkpoint?"+query.Encode())
+	return err
+}
+
+// RestoreContainer restores a container from the checkpoint archive at the
+// absolute `importPath` of the daemon host inside the sandbox `sandboxID` and
+// returns the ID of the new container.
+func (c *crioClientImpl) RestoreContainer(sandboxID, importPath string) (string, error) {
+	query := url.Values{}
+	query.Set("path", importPath)
+	body, err := c.do("POST", "/sandboxes/"+url.PathEscape(sandboxID)+"/restore?"+query.Encode())
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// Events streams the events matching the `filter` to the `handler` until the
+// `ctx` is done or the daemon ends the stream. Already published events are
+// replayed first if `replay` is set.
+func (c *crioClientImpl) Events(ctx context.Context, filter *types.EventFilter, replay bool, handler func(*types.Event)) error {
+	query := url.Values{}
+	if filter.SandboxID != "" {
+		query.Set("sandbox", filter.SandboxID)
+	}
+	for key, value := range filter.Labels {
+		query.Add("label", key+"="+value)
+	}
+	for _, t := range filter.Types {
+		query.Add("type", string(t))
+	}
+	if replay {
+		query.Set("replay", "true")
+	}
+	path := "/events?" + query.Encode()
+	req, err := c.newRequest("GET", path)
+	if err != nil {
+		return err
+	}
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("request %s failed with status %q: %s",
+			path, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		event := types.Event{}
+		if err := decoder.Decode(&event); err != nil {
+			if err == io.EOF || ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		handler(&event)
+	}
 }
@@ -0,0 +1,120 @@
+package lib
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/storage/pkg/archive"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// checkpointDirectory is the directory of the CRIU images within a
+	// checkpoint archive
+	checkpointDirectory = "checkpoint"
+	// specDumpFile is the OCI spec of the container within a checkpoint
+	// archive
+	specDumpFile = "config.json"
+	// stateDumpFile is the container state within a checkpoint archive
+	stateDumpFile = "state.json"
+	// rootfsDiffFile is the tar of the root filesystem changes within a
+	// checkpoint archive
+	rootfsDiffFile = "rootfs-diff.tar"
+)
+
+// ContainerCheckpoint checkpoints a running container and exports it together
+// with its spec, state and root filesystem changes as a tar archive to
+// `exportPath`. The container keeps running if `leaveRunning` is set.
+func (c *ContainerServer) ContainerCheckpoint(container, exportPath string, leaveRunning bool) (string, error) {
+	if exportPath == "" {
+		return "", fmt.Errorf("checkpoint export path should not be empty")
+	}
+	ctr, err := c.LookupContainer(container)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find container %s", container)
+	}
+
+	cStatus := ctr.State()
+	if cStatus.Status != oci.ContainerStateRunning {
+		return "", fmt.Errorf("container %s is not running", ctr.ID())
+	}
+
+	if err := c.runtime.CheckpointContainer(ctr, leaveRunning); err != nil {
+		return "", errors.Wrapf(err, "failed to checkpoint container %s", ctr.ID())
+	}
+	defer func() {
+		if err := os.RemoveAll(ctr.CheckpointPath()); err != nil {
+			logrus.Warnf("unable to remove checkpoint of container %s: %v", ctr.ID(), err)
+		}
+	}()
+
+	// The container is stopped by the checkpoint unless it is left running
+	if err := c.runtime.UpdateContainerStatus(ctr); err != nil {
+		return "", errors.Wrapf(err, "failed to update status of container %s", ctr.ID())
+	}
+	if err := c.ContainerStateToDisk(ctr); err != nil {
+		return "", errors.Wrapf(err, "failed to write state of container %s", ctr.ID())
+	}
+
+	if err := c.exportCheckpoint(ctr, exportPath); err != nil {
+		return "", errors.Wrapf(err, "failed to export checkpoint of container %s", ctr.ID())
+	}
+
+	return ctr.ID(), nil
+}
+
+// exportCheckpoint writes the checkpoint archive of the container to
+// `exportPath`.
+func (c *ContainerServer) exportCheckpoint(ctr *oci.Container, exportPath string) error {
+	rootfsDiffPath := filepath.Join(ctr.Dir(), rootfsDiffFile)
+	if err := c.writeRootfsDiff(ctr, rootfsDiffPath); err != nil {
+		return err
+	}
+	defer os.Remove(rootfsDiffPath)
+
+	input, err := archive.TarWithOptions(ctr.Dir(), &archive.TarOptions{
+		Compression: archive.Uncompressed,
+		IncludeFiles: []string{
+			checkpointDirectory,
+			specDumpFile,
+			stateDumpFile,
+			rootfsDiffFile,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	output, err := os.OpenFile(exportPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+
+	_, err = io.Copy(output, input)
+	return err
+}
+
+// writeRootfsDiff writes the changes of the container's root filesystem to
+// `path`.
+func (c *ContainerServer) writeRootfsDiff(ctr *oci.Container, path string) error {
+	diff, err := c.storageRuntimeServer.GetContainerRootfsDiff(ctr.ID())
+	if err != nil {
+		return err
+	}
+	defer diff.Close()
+
+	diffFile, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer diffFile.Close()
+
+	_, err = io.Copy(diffFile, diff)
+	return err
+}
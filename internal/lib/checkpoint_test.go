@@ -0,0 +1,177 @@
+package lib_test
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cstorage "github.com/containers/storage"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/opencontainers/runtime-spec/specs-go"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// The actual test suite
+var _ = t.Describe("ContainerCheckpoint", func() {
+	// Prepare the sut
+	BeforeEach(beforeEach)
+
+	var exportPath string
+
+	BeforeEach(func() {
+		var err error
+		myContainer, err = oci.NewContainer(containerID, "", "", "", "",
+			make(map[string]string), make(map[string]string),
+			make(map[string]string), "", "", "",
+			&pb.ContainerMetadata{}, sandboxID, false, false,
+			false, false, "", t.MustTempDir("container"), time.Now(), "")
+		Expect(err).To(BeNil())
+		myContainer.SetState(&oci.ContainerState{
+			State: specs.State{Status: oci.ContainerStateRunning},
+		})
+		Expect(ioutil.WriteFile(filepath.Join(myContainer.Dir(), "config.json"),
+			[]byte("{}"), 0644)).To(BeNil())
+		exportPath = filepath.Join(t.MustTempDir("export"), "checkpoint.tar")
+	})
+
+	It("should succeed", func() {
+		// Given
+		sut.SetRuntime(ociRuntimeMock)
+		addContainerAndSandbox()
+		gomock.InOrder(
+			ociRuntimeMock.EXPECT().CheckpointContainer(gomock.Any(), false).
+				Return(nil),
+			ociRuntimeMock.EXPECT().UpdateContainerStatus(gomock.Any()).
+				Return(nil).Times(2),
+			storeMock.EXPECT().Container(containerID).
+				Return(&cstorage.Container{LayerID: "layer"}, nil),
+			storeMock.EXPECT().Diff("", "layer", gomock.Any()).
+				Return(ioutil.NopCloser(strings.NewReader("diff")), nil),
+		)
+
+		// When
+		res, err := sut.ContainerCheckpoint(containerID, exportPath, false)
+
+		// Then
+		Expect(err).To(BeNil())
+		Expect(res).To(Equal(containerID))
+
+		export, err := os.Open(exportPath)
+		Expect(err).To(BeNil())
+		defer export.Close()
+		files := []string{}
+		reader := tar.NewReader(export)
+		for {
+			header, err := reader.Next()
+			if err != nil {
+				break
+			}
+			files = append(files, header.Name)
+		}
+		Expect(files).To(ConsistOf("config.json", "state.json", "rootfs-diff.tar"))
+		Expect(filepath.Join(myContainer.Dir(), "rootfs-diff.tar")).NotTo(BeAnExistingFile())
+	})
+
+	It("should fail when the rootfs diff errors", func() {
+		// Given
+		sut.SetRuntime(ociRuntimeMock)
+		addContainerAndSandbox()
+		gomock.InOrder(
+			ociRuntimeMock.EXPECT().CheckpointContainer(gomock.Any(), true).
+				Return(nil),
+			ociRuntimeMock.EXPECT().UpdateContainerStatus(gomock.Any()).
+				Return(nil).Times(2),
+			storeMock.EXPECT().Container(containerID).
+				Return(nil, t.TestError),
+		)
+
+		// When
+		res, err := sut.ContainerCheckpoint(containerID, exportPath, true)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail when the status update errors", func() {
+		// Given
+		sut.SetRuntime(ociRuntimeMock)
+		addContainerAndSandbox()
+		gomock.InOrder(
+			ociRuntimeMock.EXPECT().CheckpointContainer(gomock.Any(), false).
+				Return(nil),
+			ociRuntimeMock.EXPECT().UpdateContainerStatus(gomock.Any()).
+				Return(t.TestError),
+		)
+
+		// When
+		res, err := sut.ContainerCheckpoint(containerID, exportPath, false)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+		Expect(exportPath).NotTo(BeAnExistingFile())
+	})
+
+	It("should fail when container checkpoint errors", func() {
+		// Given
+		sut.SetRuntime(ociRuntimeMock)
+		addContainerAndSandbox()
+		gomock.InOrder(
+			ociRuntimeMock.EXPECT().CheckpointContainer(gomock.Any(), false).
+				Return(t.TestError),
+		)
+
+		// When
+		res, err := sut.ContainerCheckpoint(containerID, exportPath, false)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+		Expect(exportPath).NotTo(BeAnExistingFile())
+	})
+
+	It("should fail when container is not running", func() {
+		// Given
+		sut.SetRuntime(ociRuntimeMock)
+		myContainer.SetState(&oci.ContainerState{
+			State: specs.State{Status: oci.ContainerStatePaused},
+		})
+		addContainerAndSandbox()
+
+		// When
+		res, err := sut.ContainerCheckpoint(containerID, exportPath, false)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail with empty export path", func() {
+		// Given
+		addContainerAndSandbox()
+
+		// When
+		res, err := sut.ContainerCheckpoint(containerID, "", false)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail with invalid container ID", func() {
+		// Given
+		// When
+		res, err := sut.ContainerCheckpoint("", exportPath, false)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+})
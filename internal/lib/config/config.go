@@ -10,13 +10,17 @@ import (
 	"strings"
 
 	"github.com/BurntSushi/toml"
+	"github.com/containers/image/docker/reference"
 	"github.com/containers/image/pkg/sysregistriesv2"
+	"github.com/containers/image/signature"
 	"github.com/containers/image/types"
 	"github.com/containers/libpod/pkg/rootless"
 	createconfig "github.com/containers/libpod/pkg/spec"
 	"github.com/containers/storage"
 	cstorage "github.com/containers/storage"
+	"github.com/cri-o/cri-o/internal/pkg/userns"
 	"github.com/cri-o/cri-o/internal/version"
+	"github.com/cri-o/cri-o/pkg/annotations"
 	"github.com/cri-o/cri-o/utils"
 	units "github.com/docker/go-units"
 	selinux "github.com/opencontainers/selinux/go-selinux"
@@ -36,6 +40,8 @@ const (
 	DefaultApparmorProfile = "crio-default-" + version.Version
 	defaultGRPCMaxMsgSize  = 16 * 1024 * 1024
 	OCIBufSize             = 8192
+	dropInConfigSuffix     = ".conf"
+	defaultTracingEndpoint = "127.0.0.1:4318"
 )
 
 // Config represents the entire set of configuration values that can be set for
@@ -46,6 +52,10 @@ type Config struct {
 	RuntimeConfig
 	ImageConfig
 	NetworkConfig
+
+	// dropInConfigDir is the directory of drop-in configuration files which
+	// have been merged on top of the main configuration file.
+	dropInConfigDir string
 }
 
 // Iface provides a config interface for data encapsulation
@@ -90,6 +100,11 @@ const (
 	// allowed for a container. Negative values mean that no limit is imposed.
 	DefaultLogSizeMax = -1
 
+	// DefaultLogMaxFiles is the default number of rotated log files kept for
+	// a container. Zero means that the log file is truncated instead of being
+	// rotated.
+	DefaultLogMaxFiles = 0
+
 	// DefaultLogToJournald is the default value for whether conmon should
 	// log to journald in addition to kubernetes log file.
 	DefaultLogToJournald = false
@@ -136,12 +151,73 @@ type RootConfig struct {
 	LogDir string `toml:"log_dir"`
 }
 
+// MonitorExecCgroupContainer is the monitor_exec_cgroup value which moves
+// the conmon processes of exec sync requests into the cgroup of the container.
+const MonitorExecCgroupContainer = "container"
+
+// runtimeTypeVM is the runtime type of handlers which do not use conmon.
+const runtimeTypeVM = "vm"
+
 // RuntimeHandler represents each item of the "crio.runtime.runtimes" TOML
 // config table.
 type RuntimeHandler struct {
 	RuntimePath string `toml:"runtime_path"`
 	RuntimeType string `toml:"runtime_type"`
 	RuntimeRoot string `toml:"runtime_root"`
+
+	// AllowedAnnotations is the list of experimental annotations which are
+	// honored for the pods and containers of the handler. All other
+	// experimental annotations are ignored.
+	AllowedAnnotations []string `toml:"allowed_annotations"`
+
+	// PrivilegedWithoutHostDevices avoids adding all host devices to the
+	// privileged containers of the handler, for example for VM runtimes.
+	PrivilegedWithoutHostDevices bool `toml:"privileged_without_host_devices"`
+
+	// Conmon, ConmonCgroup and ConmonEnv override the global settings of
+	// the same name for the containers of the handler, if set.
+	Conmon       string   `toml:"conmon"`
+	ConmonCgroup string   `toml:"conmon_cgroup"`
+	ConmonEnv    []string `toml:"conmon_env"`
+
+	// MonitorExecCgroup is the cgroup of the conmon processes monitoring
+	// exec sync requests. They stay in the cgroup of CRI-O if empty, or get
+	// moved into the cgroup of the container if set to "container".
+	MonitorExecCgroup string `toml:"monitor_exec_cgroup"`
+}
+
+// Validate checks the handler specific settings of the runtime handler
+// `name`. Checks which access the file system are part of
+// RuntimeConfig.ValidateRuntimePaths.
+func (r *RuntimeHandler) Validate(name string) error {
+	if r.ConmonCgroup != "" && !(r.ConmonCgroup == "pod" || strings.HasSuffix(r.ConmonCgroup, ".slice")) {
+		return fmt.Errorf("conmon cgroup of runtime handler %s should be 'pod' or a systemd slice", name)
+	}
+	if r.MonitorExecCgroup != "" && r.MonitorExecCgroup != MonitorExecCgroupContainer {
+		return fmt.Errorf("monitor exec cgroup of runtime handler %s should be empty or %q",
+			name, MonitorExecCgroupContainer)
+	}
+	if r.RuntimeType == runtimeTypeVM &&
+		(r.Conmon != "" || r.ConmonCgroup != "" || len(r.ConmonEnv) > 0 || r.MonitorExecCgroup != "") {
+		return fmt.Errorf("runtime handler %s of type %q does not support conmon settings", name, runtimeTypeVM)
+	}
+	for _, allowed := range r.AllowedAnnotations {
+		if !annotations.IsExperimental(allowed) {
+			return fmt.Errorf("unknown allowed annotation %q for runtime handler %s", allowed, name)
+		}
+	}
+	return nil
+}
+
+// AllowsAnnotation returns whether the experimental annotation `key` is
+// honored for the handler.
+func (r *RuntimeHandler) AllowsAnnotation(key string) bool {
+	for _, allowed := range r.AllowedAnnotations {
+		if allowed == key {
+			return true
+		}
+	}
+	return false
 }
 
 // Multiple runtime Handlers in a map
@@ -188,6 +264,19 @@ type RuntimeConfig struct {
 	// default for the runtime.
 	SeccompProfile string `toml:"seccomp_profile"`
 
+	// SeccompAuditSource is the audit log or syslog file from which the
+	// syscalls of containers in seccomp audit mode are collected.
+	SeccompAuditSource string `toml:"seccomp_audit_source"`
+
+	// SeccompAuditProfileDir is the directory to which the seccomp profiles
+	// generated for the containers in seccomp audit mode are written.
+	SeccompAuditProfileDir string `toml:"seccomp_audit_profile_dir"`
+
+	// SeccompNotifierStopThreshold is the number of blocked syscalls after
+	// which a container in seccomp notifier mode gets stopped. Zero disables
+	// stopping containers.
+	SeccompNotifierStopThreshold uint64 `toml:"seccomp_notifier_stop_threshold"`
+
 	// ApparmorProfile is the apparmor profile name which is used as the
 	// default for the runtime.
 	ApparmorProfile string `toml:"apparmor_profile"`
@@ -220,6 +309,11 @@ type RuntimeConfig struct {
 	// ranges are separated by comma.
 	GIDMappings string `toml:"gid_mappings"`
 
+	// UsernsPool is the range of host IDs in the form HostID:Size, from
+	// which pods requesting their own user namespace get a non-overlapping
+	// range of IDs allocated.
+	UsernsPool string `toml:"userns_pool"`
+
 	// LogLevel determines the verbosity of the logs based on the level it is set to.
 	// Options are fatal, panic, error (default), warn, info, and debug.
 	LogLevel string `toml:"log_level"`
@@ -240,6 +334,15 @@ type RuntimeConfig struct {
 	// Negative values indicate that the log file won't be truncated.
 	LogSizeMax int64 `toml:"log_size_max"`
 
+	// LogMaxFiles is the number of rotated log files kept for a container
+	// once its log file reaches LogSizeMax. Zero means that the log file
+	// gets truncated instead.
+	LogMaxFiles int `toml:"log_max_files"`
+
+	// LogCompress indicates whether rotated log files are compressed with
+	// gzip.
+	LogCompress bool `toml:"log_compress"`
+
 	// CtrStopTimeout specifies the time to wait before to generate an
 	// error because the container state is still tagged as "running".
 	CtrStopTimeout int64 `toml:"ctr_stop_timeout"`
@@ -258,6 +361,11 @@ type RuntimeConfig struct {
 	// and manage its lifecycle
 	ManageNetworkNSLifecycle bool `toml:"manage_network_ns_lifecycle"`
 
+	// DropInfraCtr determines whether pod sandboxes are created without an
+	// infra container, as long as they do not share their PID namespace.
+	// The namespaces of such sandboxes are pinned by CRI-O instead.
+	DropInfraCtr bool `toml:"drop_infra_ctr"`
+
 	// ReadOnly run all pods/containers in read-only mode.
 	// This mode will mount tmpfs on /run, /tmp and /var/tmp, if those are not mountpoints
 	// Will also set the readonly flag in the OCI Runtime Spec.  In this mode containers
@@ -290,6 +398,11 @@ type ImageConfig struct {
 	// that this be left unspecified so that the default system-wide policy
 	// will be used.
 	SignaturePolicyPath string `toml:"signature_policy"`
+	// SignaturePolicyDir is the directory of the per Kubernetes namespace
+	// signature policies. A pull requested for a pod in a namespace uses the
+	// policy file named "<namespace>.json" in this directory, if it exists,
+	// and the SignaturePolicyPath otherwise.
+	SignaturePolicyDir string `toml:"signature_policy_dir"`
 	// InsecureRegistries is a list of registries that must be contacted w/o
 	// TLS verification.
 	InsecureRegistries []string `toml:"insecure_registries"`
@@ -297,6 +410,23 @@ type ImageConfig struct {
 	ImageVolumes ImageVolumesType `toml:"image_volumes"`
 	// Registries holds a list of registries used to pull unqualified images
 	Registries []string `toml:"registries"`
+	// PinnedImages is a list of images which are never removed. Every entry
+	// can be an exact image name, a name prefix ending with "*", an image ID
+	// or a digest. The PauseImage is always pinned implicitly.
+	PinnedImages []string `toml:"pinned_images"`
+	// RegistryMirrors maps registries to the locations of their mirrors,
+	// which are tried in order before the registry itself when pulling
+	// images. A mirror location replaces the registry in the image name, for
+	// example "mirror.example.com/docker.io".
+	RegistryMirrors map[string][]string `toml:"registry_mirrors"`
+	// ShortNameAliases maps short image names like "nginx" to a single fully
+	// qualified image name like "docker.io/library/nginx". An aliased short
+	// name is never resolved against the unqualified search registries.
+	ShortNameAliases map[string]string `toml:"short_name_aliases"`
+	// PullProgressTimeout is the number of seconds after which an image
+	// pull which made no progress before committing the image gets aborted.
+	// Zero disables the timeout.
+	PullProgressTimeout int64 `toml:"pull_progress_timeout"`
 }
 
 // NetworkConfig represents the "crio.network" TOML config table
@@ -345,6 +475,17 @@ type APIConfig struct {
 
 	// HostIP is the IP address that the server uses where it needs to use the primary host IP.
 	HostIP string `toml:"host_ip"`
+
+	// EnableTracing enables the export of OpenTelemetry traces.
+	EnableTracing bool `toml:"enable_tracing"`
+
+	// TracingEndpoint is the address of the OTLP HTTP collector to which
+	// the traces are exported.
+	TracingEndpoint string `toml:"tracing_endpoint"`
+
+	// TracingSamplingRatePerMillion is the number of sampled traces per
+	// million, for traces which are not already sampled by the kubelet.
+	TracingSamplingRatePerMillion int `toml:"tracing_sampling_rate_per_million"`
 }
 
 // tomlConfig is another way of looking at a Config, which is
@@ -397,6 +538,44 @@ func (c *Config) UpdateFromFile(path string) error {
 	return nil
 }
 
+// UpdateFromDropInDir populates the Config from the TOML-encoded drop-in
+// files within the given directory. Every file with the ".conf" suffix is
+// applied in lexical order, which means that keys set in later files override
+// the same keys of earlier ones. The directory is remembered, so that a
+// configuration reload merges the same drop-in files again. A directory which
+// does not exist is treated as empty, so that it gets picked up by a reload
+// once it got created.
+func (c *Config) UpdateFromDropInDir(path string) error {
+	c.dropInConfigDir = path
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != dropInConfigSuffix {
+			continue
+		}
+		filePath := filepath.Join(path, file.Name())
+		if err := c.UpdateFromFile(filePath); err != nil {
+			return err
+		}
+		logrus.Debugf("merged drop-in configuration file %q", filePath)
+	}
+
+	return nil
+}
+
+// DropInConfigDir returns the directory of drop-in configuration files which
+// have been merged into the Config, or an empty string if there is none.
+func (c *Config) DropInConfigDir() string {
+	return c.dropInConfigDir
+}
+
 // ToFile outputs the given Config as a TOML-encoded file at the given path.
 // Returns errors encountered when generating or writing the file, or nil
 // otherwise.
@@ -445,6 +624,7 @@ func DefaultConfig() (*Config, error) {
 			StreamPort:         "0",
 			GRPCMaxSendMsgSize: defaultGRPCMaxMsgSize,
 			GRPCMaxRecvMsgSize: defaultGRPCMaxMsgSize,
+			TracingEndpoint:    defaultTracingEndpoint,
 		},
 		RuntimeConfig: RuntimeConfig{
 			DefaultRuntime: defaultRuntime,
@@ -462,6 +642,8 @@ func DefaultConfig() (*Config, error) {
 			ConmonCgroup:             "pod",
 			SELinux:                  selinuxEnabled(),
 			SeccompProfile:           "",
+			SeccompAuditSource:       seccompAuditSource,
+			SeccompAuditProfileDir:   seccompAuditProfileDir,
 			ApparmorProfile:          DefaultApparmorProfile,
 			CgroupManager:            cgroupManager,
 			DefaultMountsFile:        "",
@@ -469,8 +651,9 @@ func DefaultConfig() (*Config, error) {
 			ContainerExitsDir:        containerExitsDir,
 			ContainerAttachSocketDir: ContainerAttachSocketDir,
 			LogSizeMax:               DefaultLogSizeMax,
+			LogMaxFiles:              DefaultLogMaxFiles,
 			LogToJournald:            DefaultLogToJournald,
-			DefaultCapabilities:      DefaultCapabilities,
+			DefaultCapabilities:      append([]string{}, DefaultCapabilities...),
 			LogLevel:                 "error",
 			DefaultSysctls:           []string{},
 			DefaultUlimits:           []string{},
@@ -483,9 +666,13 @@ func DefaultConfig() (*Config, error) {
 			PauseImageAuthFile:  "",
 			PauseCommand:        pauseCommand,
 			SignaturePolicyPath: "",
+			SignaturePolicyDir:  "",
 			ImageVolumes:        ImageVolumesMkdir,
 			Registries:          []string{},
 			InsecureRegistries:  []string{},
+			PinnedImages:        []string{},
+			RegistryMirrors:     map[string][]string{},
+			ShortNameAliases:    map[string]string{},
 		},
 		NetworkConfig: NetworkConfig{
 			NetworkDir: cniConfigDir,
@@ -507,6 +694,26 @@ func (c *Config) Validate(systemContext *types.SystemContext, onExecution bool)
 		return fmt.Errorf("unrecognized image volume type specified")
 	}
 
+	if err := validatePinnedImages(c.PinnedImages); err != nil {
+		return errors.Wrapf(err, "image config")
+	}
+
+	if err := validateSignaturePolicyDir(c.SignaturePolicyDir); err != nil {
+		return errors.Wrapf(err, "image config")
+	}
+
+	if err := validateRegistryMirrors(c.RegistryMirrors); err != nil {
+		return errors.Wrapf(err, "image config")
+	}
+
+	if err := validateShortNameAliases(c.ShortNameAliases); err != nil {
+		return errors.Wrapf(err, "image config")
+	}
+
+	if c.PullProgressTimeout < 0 {
+		return fmt.Errorf("image config: invalid negative pull_progress_timeout %d", c.PullProgressTimeout)
+	}
+
 	if err := c.RootConfig.Validate(onExecution); err != nil {
 		return errors.Wrapf(err, "root config")
 	}
@@ -542,6 +749,16 @@ func (c *APIConfig) Validate(onExecution bool) error {
 		c.GRPCMaxRecvMsgSize = defaultGRPCMaxMsgSize
 	}
 
+	if c.EnableTracing {
+		if c.TracingEndpoint == "" {
+			return fmt.Errorf("tracing endpoint must be set if tracing is enabled")
+		}
+		if c.TracingSamplingRatePerMillion < 0 || c.TracingSamplingRatePerMillion > 1000000 {
+			return fmt.Errorf("invalid tracing sampling rate per million %d",
+				c.TracingSamplingRatePerMillion)
+		}
+	}
+
 	if onExecution {
 		if err := os.MkdirAll(filepath.Dir(c.Listen), 0755); err != nil {
 			return err
@@ -572,6 +789,101 @@ func (c *RootConfig) Validate(onExecution bool) error {
 	return nil
 }
 
+// AllPinnedImages returns the configured PinnedImages together with the
+// PauseImage, which is always pinned implicitly.
+func (c *ImageConfig) AllPinnedImages() []string {
+	return append(append([]string{}, c.PinnedImages...), c.PauseImage)
+}
+
+// validatePinnedImages checks that every pinned image is non-empty and uses
+// the "*" wildcard only as suffix.
+func validatePinnedImages(pinnedImages []string) error {
+	for _, pinnedImage := range pinnedImages {
+		if pinnedImage == "" {
+			return fmt.Errorf("invalid empty pinned_images entry")
+		}
+		if strings.Contains(strings.TrimSuffix(pinnedImage, "*"), "*") {
+			return fmt.Errorf("invalid pinned_images entry %q: wildcard is only supported as suffix", pinnedImage)
+		}
+	}
+	return nil
+}
+
+// validateRegistryMirrors checks that every registry and mirror location is a
+// host with an optional port and repository path prefix.
+func validateRegistryMirrors(registryMirrors map[string][]string) error {
+	for registry, mirrors := range registryMirrors {
+		if strings.TrimSpace(registry) == "" || strings.Contains(registry, "/") {
+			return fmt.Errorf("invalid registry_mirrors registry %q", registry)
+		}
+		for _, mirror := range mirrors {
+			if _, err := reference.ParseNamed(mirror + "/image"); err != nil {
+				return fmt.Errorf("invalid registry_mirrors mirror %q of registry %q: %v",
+					mirror, registry, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateShortNameAliases checks that every alias maps a short image name to
+// a fully qualified image name, both without tag or digest.
+func validateShortNameAliases(shortNameAliases map[string]string) error {
+	for name, alias := range shortNameAliases {
+		named, err := reference.ParseNormalizedNamed(name)
+		if err != nil || !reference.IsNameOnly(named) ||
+			strings.HasPrefix(name, reference.Domain(named)+"/") {
+			return fmt.Errorf("invalid short_name_aliases name %q: expected a short name without tag or digest", name)
+		}
+		named, err = reference.ParseNormalizedNamed(alias)
+		if err != nil || !reference.IsNameOnly(named) ||
+			!strings.HasPrefix(alias, reference.Domain(named)+"/") {
+			return fmt.Errorf("invalid short_name_aliases alias %q of %q: expected a fully qualified name without tag or digest", alias, name)
+		}
+	}
+	return nil
+}
+
+// SignaturePolicy returns the path of the signature policy for pulls requested
+// by a pod in the Kubernetes `namespace`. This is the namespace policy file in
+// the SignaturePolicyDir if it exists, otherwise the SignaturePolicyPath.
+func (c *ImageConfig) SignaturePolicy(namespace string) (string, error) {
+	if c.SignaturePolicyDir == "" || namespace == "" {
+		return c.SignaturePolicyPath, nil
+	}
+	if strings.ContainsRune(namespace, filepath.Separator) ||
+		strings.HasPrefix(namespace, ".") {
+		return "", fmt.Errorf("invalid namespace %q", namespace)
+	}
+	policyPath := filepath.Join(c.SignaturePolicyDir, namespace+".json")
+	if _, err := os.Stat(policyPath); err != nil {
+		if os.IsNotExist(err) {
+			return c.SignaturePolicyPath, nil
+		}
+		return "", err
+	}
+	return policyPath, nil
+}
+
+// validateSignaturePolicyDir checks that every policy file in the signature
+// policy directory `dir` can be loaded. A non existing directory is valid,
+// because the policies are looked up on every pull.
+func validateSignaturePolicyDir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	policyPaths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, policyPath := range policyPaths {
+		if _, err := signature.NewPolicyFromFile(policyPath); err != nil {
+			return fmt.Errorf("invalid signature policy %q: %v", policyPath, err)
+		}
+	}
+	return nil
+}
+
 // Validate is the main entry point for runtime configuration validation
 // The parameter `onExecution` specifies if the validation should include
 // execution checks. It returns an `error` on validation failure, otherwise
@@ -642,17 +954,38 @@ func (c *RuntimeConfig) Validate(systemContext *types.SystemContext, onExecution
 		return errors.New("conmon cgroup should be 'pod' or a systemd slice")
 	}
 
+	for name, handler := range c.Runtimes {
+		if err := handler.Validate(name); err != nil {
+			return err
+		}
+	}
+
 	if c.UIDMappings != "" && c.ManageNetworkNSLifecycle {
 		return fmt.Errorf("cannot use UIDMappings with ManageNetworkNSLifecycle")
 	}
 	if c.GIDMappings != "" && c.ManageNetworkNSLifecycle {
 		return fmt.Errorf("cannot use GIDMappings with ManageNetworkNSLifecycle")
 	}
+	if c.UsernsPool != "" {
+		if c.ManageNetworkNSLifecycle {
+			return fmt.Errorf("cannot use UsernsPool with ManageNetworkNSLifecycle")
+		}
+		if _, err := userns.ParseRange(c.UsernsPool); err != nil {
+			return errors.Wrapf(err, "invalid userns_pool")
+		}
+	}
+	if c.DropInfraCtr && !c.ManageNetworkNSLifecycle {
+		return fmt.Errorf("cannot use DropInfraCtr without ManageNetworkNSLifecycle")
+	}
 
 	if c.LogSizeMax >= 0 && c.LogSizeMax < OCIBufSize {
 		return fmt.Errorf("log size max should be negative or >= %d", OCIBufSize)
 	}
 
+	if c.LogMaxFiles < 0 {
+		return fmt.Errorf("log max files should not be negative")
+	}
+
 	// check for validation on execution
 	if onExecution {
 		if err := c.ValidateRuntimePaths(); err != nil {
@@ -698,6 +1031,11 @@ func (c *RuntimeConfig) ValidateRuntimePaths() error {
 			return fmt.Errorf("invalid runtime_path for runtime '%s': %q",
 				runtime, err)
 		}
+		if handler.Conmon != "" {
+			if _, err := os.Stat(handler.Conmon); err != nil {
+				return errors.Wrapf(err, "invalid conmon path for runtime %q", runtime)
+			}
+		}
 		logrus.Debugf("found valid runtime %q for runtime_path %q",
 			runtime, handler.RuntimePath)
 	}
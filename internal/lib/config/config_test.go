@@ -6,6 +6,7 @@ import (
 	"path"
 
 	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/pkg/annotations"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -47,6 +48,17 @@ var _ = t.Describe("Config", func() {
 			Expect(err).To(BeNil())
 		})
 
+		It("should fail with invalid pinned images", func() {
+			// Given
+			sut.PinnedImages = []string{"quay.io/*/image"}
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
 		It("should fail with invalid root config", func() {
 			// Given
 			sut.RootConfig.LogDir = "/dev/null"
@@ -118,6 +130,75 @@ var _ = t.Describe("Config", func() {
 			Expect(err).NotTo(BeNil())
 		})
 
+		It("should succeed with registry mirrors and short name aliases", func() {
+			// Given
+			sut.RegistryMirrors = map[string][]string{
+				"docker.io": {"mirror.example.com:5000/docker.io", "localhost:5000"},
+			}
+			sut.ShortNameAliases = map[string]string{
+				"nginx":      "docker.io/library/nginx",
+				"crio/pause": "quay.io/crio/pause",
+			}
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should fail on invalid registry mirror", func() {
+			// Given
+			sut.RegistryMirrors = map[string][]string{
+				"docker.io": {"https://mirror.example.com"},
+			}
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail on fully qualified short name alias name", func() {
+			// Given
+			sut.ShortNameAliases = map[string]string{
+				"quay.io/nginx": "docker.io/library/nginx",
+			}
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail on short name alias with tag", func() {
+			// Given
+			sut.ShortNameAliases = map[string]string{
+				"nginx": "docker.io/library/nginx:latest",
+			}
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail on invalid signature policy in directory", func() {
+			// Given
+			sut.SignaturePolicyDir = t.MustTempDir("policies")
+			Expect(ioutil.WriteFile(path.Join(sut.SignaturePolicyDir, "ns.json"),
+				[]byte("invalid"), 0644)).To(BeNil())
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
 	})
 
 	t.Describe("ValidateAPIConfig", func() {
@@ -143,6 +224,42 @@ var _ = t.Describe("Config", func() {
 			Expect(err).To(BeNil())
 		})
 
+		It("should succeed with tracing enabled", func() {
+			// Given
+			sut.EnableTracing = true
+			sut.TracingSamplingRatePerMillion = 1000000
+
+			// When
+			err := sut.APIConfig.Validate(false)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should fail with tracing enabled and empty endpoint", func() {
+			// Given
+			sut.EnableTracing = true
+			sut.TracingEndpoint = ""
+
+			// When
+			err := sut.APIConfig.Validate(false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with tracing enabled and invalid sampling rate", func() {
+			// Given
+			sut.EnableTracing = true
+			sut.TracingSamplingRatePerMillion = 1000001
+
+			// When
+			err := sut.APIConfig.Validate(false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
 		It("should fail on invalid Listen directory", func() {
 			// Given
 			sut = runtimeValidConfig()
@@ -367,6 +484,41 @@ var _ = t.Describe("Config", func() {
 			Expect(err).NotTo(BeNil())
 		})
 
+		It("should fail with invalid user namespace pool", func() {
+			// Given
+			sut.UsernsPool = "100000"
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with user namespace pool and managed network namespaces", func() {
+			// Given
+			sut.UsernsPool = "100000:65536"
+			sut.ManageNetworkNSLifecycle = true
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail to drop the infra container without managed network namespaces", func() {
+			// Given
+			sut.DropInfraCtr = true
+			sut.ManageNetworkNSLifecycle = false
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
 		It("should fail wrong max log size", func() {
 			// Given
 			sut.LogSizeMax = 1
@@ -378,6 +530,17 @@ var _ = t.Describe("Config", func() {
 			Expect(err).NotTo(BeNil())
 		})
 
+		It("should fail on negative max log files", func() {
+			// Given
+			sut.LogMaxFiles = -1
+
+			// When
+			err := sut.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
 		It("should fail on invalid conmon cgroup", func() {
 			// Given
 			sut.ConmonCgroup = "wrong"
@@ -446,6 +609,103 @@ var _ = t.Describe("Config", func() {
 			// Then
 			Expect(err).NotTo(BeNil())
 		})
+
+		It("should fail with wrong but set conmon of runtime handler", func() {
+			// Given
+			sut.Runtimes["runc"] = &config.RuntimeHandler{
+				RuntimePath: validFilePath,
+				Conmon:      path.Join(validDirPath, "conmon"),
+			}
+
+			// When
+			err := sut.RuntimeConfig.ValidateRuntimePaths()
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	t.Describe("ValidateRuntimeHandler", func() {
+		It("should succeed with handler specific settings", func() {
+			// Given
+			handler := &config.RuntimeHandler{
+				RuntimePath:                  validFilePath,
+				AllowedAnnotations:           []string{annotations.UsernsMode},
+				PrivilegedWithoutHostDevices: true,
+				Conmon:                       validFilePath,
+				ConmonCgroup:                 "system.slice",
+				ConmonEnv:                    []string{"PATH=/usr/bin"},
+				MonitorExecCgroup:            config.MonitorExecCgroupContainer,
+			}
+
+			// When
+			err := handler.Validate("runc")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(handler.AllowsAnnotation(annotations.UsernsMode)).To(BeTrue())
+			Expect(handler.AllowsAnnotation("other")).To(BeFalse())
+		})
+
+		It("should fail with invalid conmon cgroup", func() {
+			// Given
+			handler := &config.RuntimeHandler{ConmonCgroup: "invalid"}
+
+			// When
+			err := handler.Validate("runc")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid monitor exec cgroup", func() {
+			// Given
+			handler := &config.RuntimeHandler{MonitorExecCgroup: "invalid"}
+
+			// When
+			err := handler.Validate("runc")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with conmon settings for VM runtime", func() {
+			// Given
+			handler := &config.RuntimeHandler{
+				RuntimeType: "vm",
+				ConmonEnv:   []string{"PATH=/usr/bin"},
+			}
+
+			// When
+			err := handler.Validate("kata")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with unknown allowed annotation", func() {
+			// Given
+			handler := &config.RuntimeHandler{
+				AllowedAnnotations: []string{"io.kubernetes.cri-o.unknown"},
+			}
+
+			// When
+			err := handler.Validate("runc")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail the runtime config validation with invalid handler", func() {
+			// Given
+			sut.Runtimes["runc"].MonitorExecCgroup = "invalid"
+
+			// When
+			err := sut.RuntimeConfig.Validate(nil, false)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
 	})
 
 	t.Describe("ValidateConmonPath", func() {
@@ -662,6 +922,20 @@ var _ = t.Describe("Config", func() {
 		})
 	})
 
+	t.Describe("AllPinnedImages", func() {
+		It("should always include the pause image", func() {
+			// Given
+			sut.PinnedImages = []string{"quay.io/crio/*"}
+
+			// When
+			res := sut.AllPinnedImages()
+
+			// Then
+			Expect(res).To(Equal([]string{"quay.io/crio/*", sut.PauseImage}))
+			Expect(sut.PinnedImages).To(HaveLen(1))
+		})
+	})
+
 	t.Describe("UpdateFromFile", func() {
 		It("should succeed with default config", func() {
 			// Given
@@ -674,6 +948,23 @@ var _ = t.Describe("Config", func() {
 			Expect(sut.PidsLimit).To(BeEquivalentTo(2048))
 		})
 
+		It("should not modify the default capabilities", func() {
+			// Given
+			filePath := t.MustTempFile("config")
+			Expect(ioutil.WriteFile(filePath, []byte(`
+[crio.runtime]
+default_capabilities = ["SYS_ADMIN"]
+`), 0644)).To(BeNil())
+
+			// When
+			err := sut.UpdateFromFile(filePath)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.DefaultCapabilities).To(Equal([]string{"SYS_ADMIN"}))
+			Expect(config.DefaultCapabilities[0]).To(Equal("CHOWN"))
+		})
+
 		It("should fail when file does not exist", func() {
 			// Given
 			// When
@@ -693,6 +984,91 @@ var _ = t.Describe("Config", func() {
 		})
 	})
 
+	t.Describe("UpdateFromDropInDir", func() {
+		var writeDropIn = func(dir, name, content string) {
+			Expect(ioutil.WriteFile(path.Join(dir, name),
+				[]byte(content), 0644)).To(BeNil())
+		}
+
+		It("should succeed with empty directory", func() {
+			// Given
+			dir := t.MustTempDir("crio.conf.d")
+
+			// When
+			err := sut.UpdateFromDropInDir(dir)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.DropInConfigDir()).To(Equal(dir))
+		})
+
+		It("should merge files in lexical order", func() {
+			// Given
+			Expect(sut.UpdateFromFile("testdata/config.toml")).To(BeNil())
+			dir := t.MustTempDir("crio.conf.d")
+			writeDropIn(dir, "10-first.conf", `
+[crio.runtime]
+pids_limit = 4096
+log_level = "info"
+
+[crio.runtime.runtimes.kata]
+runtime_path = "/usr/bin/kata-runtime"
+runtime_type = "vm"
+`)
+			writeDropIn(dir, "20-second.conf", `
+[crio.runtime]
+pids_limit = 8192
+`)
+			writeDropIn(dir, "30-ignored.toml", `
+[crio.runtime]
+pids_limit = 1
+`)
+
+			// When
+			err := sut.UpdateFromDropInDir(dir)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.Storage).To(Equal("overlay2"))
+			Expect(sut.PidsLimit).To(BeEquivalentTo(8192))
+			Expect(sut.LogLevel).To(Equal("info"))
+			Expect(sut.Runtimes).To(HaveKey("runc"))
+			Expect(sut.Runtimes).To(HaveKey("kata"))
+			Expect(sut.Runtimes["kata"].RuntimeType).To(Equal("vm"))
+		})
+
+		It("should remember a directory which does not exist yet", func() {
+			// Given
+			// When
+			err := sut.UpdateFromDropInDir("/invalid/dir")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.DropInConfigDir()).To(Equal("/invalid/dir"))
+		})
+
+		It("should fail when the path is no directory", func() {
+			// Given
+			// When
+			err := sut.UpdateFromDropInDir("testdata/config.toml")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail when toml decode fails", func() {
+			// Given
+			dir := t.MustTempDir("crio.conf.d")
+			writeDropIn(dir, "00-invalid.conf", "invalid")
+
+			// When
+			err := sut.UpdateFromDropInDir(dir)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
 	t.Describe("GetData", func() {
 		It("should succeed with default config", func() {
 			// Given
@@ -728,6 +1104,70 @@ var _ = t.Describe("Config", func() {
 		})
 	})
 
+	t.Describe("SignaturePolicy", func() {
+		const namespace = "namespace"
+
+		BeforeEach(func() {
+			sut.SignaturePolicyPath = "/etc/containers/policy.json"
+			sut.SignaturePolicyDir = t.MustTempDir("policies")
+		})
+
+		It("should succeed with namespace policy", func() {
+			// Given
+			policyPath := path.Join(sut.SignaturePolicyDir, namespace+".json")
+			Expect(ioutil.WriteFile(policyPath, []byte("{}"), 0644)).To(BeNil())
+
+			// When
+			res, err := sut.SignaturePolicy(namespace)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(policyPath))
+		})
+
+		It("should fall back to the global policy without namespace policy", func() {
+			// Given
+			// When
+			res, err := sut.SignaturePolicy(namespace)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(sut.SignaturePolicyPath))
+		})
+
+		It("should fall back to the global policy without namespace", func() {
+			// Given
+			// When
+			res, err := sut.SignaturePolicy("")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(sut.SignaturePolicyPath))
+		})
+
+		It("should fall back to the global policy without policy directory", func() {
+			// Given
+			sut.SignaturePolicyDir = ""
+
+			// When
+			res, err := sut.SignaturePolicy(namespace)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(sut.SignaturePolicyPath))
+		})
+
+		It("should fail with invalid namespace", func() {
+			// Given
+			// When
+			res, err := sut.SignaturePolicy("../namespace")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(res).To(BeEmpty())
+		})
+	})
+
 	t.Describe("ToBytes", func() {
 		It("should succeed", func() {
 			// Given
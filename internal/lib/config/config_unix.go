@@ -8,10 +8,16 @@ const (
 	cniBinDir                = "/opt/cni/bin/"
 	containerExitsDir        = "/var/run/crio/exits"
 	ContainerAttachSocketDir = "/var/run/crio"
+	seccompAuditSource       = "/var/log/audit/audit.log"
+	seccompAuditProfileDir   = "/var/lib/crio/seccomp"
 
 	// CrioConfigPath is the default location for the conf file
 	CrioConfigPath = "/etc/crio/crio.conf"
 
+	// CrioConfigDropInPath is the default location for the drop-in config
+	// files
+	CrioConfigDropInPath = "/etc/crio/crio.conf.d"
+
 	// CrioSocketPath is where the unix socket is located
 	CrioSocketPath = "/var/run/crio/crio.sock"
 
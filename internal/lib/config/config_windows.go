@@ -10,10 +10,16 @@ const (
 	cniBinDir                = "C:\\cni\\bin\\"
 	containerExitsDir        = "C:\\crio\\run\\exits\\"
 	ContainerAttachSocketDir = "C:\\crio\\run\\"
+	seccompAuditSource       = ""
+	seccompAuditProfileDir   = "C:\\crio\\seccomp\\"
 
 	//CrioConfigPath is the default location for the conf file
 	CrioConfigPath = "C:\\crio\\etc\\crio.conf"
 
+	// CrioConfigDropInPath is the default location for the drop-in config
+	// files
+	CrioConfigDropInPath = "C:\\crio\\etc\\crio.conf.d"
+
 	// CrioSocketPath is where the unix socket is located
 	CrioSocketPath = "C:\\crio\\run\\crio.sock"
 
@@ -1,32 +1,95 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"os"
+	"reflect"
+	"strings"
 
+	"github.com/containers/image/signature"
+	"github.com/cri-o/cri-o/utils"
+	seccomp "github.com/seccomp/containers-golang"
 	"github.com/sirupsen/logrus"
+	"github.com/syndtr/gocapability/capability"
 )
 
 // Reload reloads the configuration with the config at the provided `fileName`
-// path. The method errors in case of any read or update failure.
+// path. If drop-in configuration files have been merged into the current
+// config, they get merged on top of the file again. The method errors in case
+// of any read or update failure. The reload is atomic, which means that no
+// option gets applied if any of the reloadable options is invalid.
 func (c *Config) Reload(fileName string) error {
-	// Reload the config
-	newConfig, err := DefaultConfig()
+	newConfig, err := c.ReadReloadConfig(fileName)
 	if err != nil {
-		return fmt.Errorf("unable to create default config")
+		return err
 	}
-	if err := newConfig.UpdateFromFile(fileName); err != nil {
+	if err := c.ValidateReload(newConfig); err != nil {
 		return err
 	}
+	return c.ApplyReload(newConfig)
+}
 
-	// Reload all available options
-	if err := c.ReloadLogLevel(newConfig); err != nil {
-		return err
+// ReadReloadConfig reads a new configuration from the default config, the
+// provided `fileName` and the drop-in configuration directory merged into the
+// current config. It does not modify the current configuration.
+func (c *Config) ReadReloadConfig(fileName string) (*Config, error) {
+	newConfig, err := DefaultConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create default config")
 	}
-	if err := c.ReloadPauseImage(newConfig); err != nil {
-		return err
+	if err := newConfig.UpdateFromFile(fileName); err != nil {
+		return nil, err
 	}
+	if c.dropInConfigDir != "" {
+		if err := newConfig.UpdateFromDropInDir(c.dropInConfigDir); err != nil {
+			return nil, err
+		}
+	}
+	return newConfig, nil
+}
 
+// ValidateReload validates every reloadable option of the `newConfig` which
+// differs from the current configuration, without applying any of them. It
+// errors on the first invalid option.
+func (c *Config) ValidateReload(newConfig *Config) error {
+	for _, validate := range []func(*Config) error{
+		c.validateLogLevel,
+		c.validatePauseImage,
+		c.validateRegistries,
+		c.validatePinnedImages,
+		c.validateSignaturePolicy,
+		c.validateContainerDefaults,
+		c.validateRuntimes,
+		c.validateSecurityProfiles,
+	} {
+		if err := validate(newConfig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyReload applies all reloadable options of the provided `newConfig` to
+// the current configuration. The `newConfig` should have been validated via
+// `ValidateReload` before to avoid partially applied configurations.
+func (c *Config) ApplyReload(newConfig *Config) error {
+	for _, reload := range []func(*Config) error{
+		c.ReloadLogLevel,
+		c.ReloadPauseImage,
+		c.ReloadRegistries,
+		c.ReloadPinnedImages,
+		c.ReloadSignaturePolicy,
+		c.ReloadContainerDefaults,
+		c.ReloadRuntimes,
+		c.ReloadSecurityProfiles,
+	} {
+		if err := reload(newConfig); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -37,6 +100,15 @@ func logConfig(option, value string) {
 	logrus.Infof("set config %s to %q", option, value)
 }
 
+func (c *Config) validateLogLevel(newConfig *Config) error {
+	if c.LogLevel != newConfig.LogLevel {
+		if _, err := logrus.ParseLevel(newConfig.LogLevel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // ReloadLogLevel updates the LogLevel with the provided `newConfig`. It errors
 // if the level is not parsable.
 func (c *Config) ReloadLogLevel(newConfig *Config) error {
@@ -55,17 +127,27 @@ func (c *Config) ReloadLogLevel(newConfig *Config) error {
 	return nil
 }
 
+func (c *Config) validatePauseImage(newConfig *Config) error {
+	if c.PauseImageAuthFile != newConfig.PauseImageAuthFile &&
+		newConfig.PauseImageAuthFile != "" {
+		if _, err := os.Stat(newConfig.PauseImageAuthFile); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReloadPauseImage updates the pause image options with the provided
+// `newConfig`. It errors if the pause_image_auth_file is not accessible.
 func (c *Config) ReloadPauseImage(newConfig *Config) error {
+	if err := c.validatePauseImage(newConfig); err != nil {
+		return err
+	}
 	if c.PauseImage != newConfig.PauseImage {
 		c.PauseImage = newConfig.PauseImage
 		logConfig("pause_image", c.PauseImage)
 	}
 	if c.PauseImageAuthFile != newConfig.PauseImageAuthFile {
-		if newConfig.PauseImageAuthFile != "" {
-			if _, err := os.Stat(newConfig.PauseImageAuthFile); err != nil {
-				return err
-			}
-		}
 		c.PauseImageAuthFile = newConfig.PauseImageAuthFile
 		logConfig("pause_image_auth_file", c.PauseImageAuthFile)
 	}
@@ -75,3 +157,261 @@ func (c *Config) ReloadPauseImage(newConfig *Config) error {
 	}
 	return nil
 }
+
+func (c *Config) validateRegistries(newConfig *Config) error {
+	for _, registry := range newConfig.Registries {
+		if strings.TrimSpace(registry) == "" {
+			return fmt.Errorf("invalid empty registries entry")
+		}
+	}
+	for _, registry := range newConfig.InsecureRegistries {
+		if strings.TrimSpace(registry) == "" {
+			return fmt.Errorf("invalid empty insecure_registries entry")
+		}
+		// Entries which look like a subnet have to be valid CIDRs, because
+		// they would be treated as registry names otherwise
+		if strings.Contains(registry, "/") {
+			if _, _, err := net.ParseCIDR(registry); err != nil {
+				return fmt.Errorf("invalid insecure_registries entry %q: %v",
+					registry, err)
+			}
+		}
+	}
+	if err := validateRegistryMirrors(newConfig.RegistryMirrors); err != nil {
+		return err
+	}
+	return validateShortNameAliases(newConfig.ShortNameAliases)
+}
+
+// ReloadRegistries updates the registries, insecure_registries,
+// registry_mirrors and short_name_aliases with the provided `newConfig`. It
+// errors if any of the entries is invalid.
+func (c *Config) ReloadRegistries(newConfig *Config) error {
+	if err := c.validateRegistries(newConfig); err != nil {
+		return err
+	}
+	if !utils.StringSlicesEqual(c.Registries, newConfig.Registries) {
+		c.Registries = newConfig.Registries
+		logConfig("registries", strings.Join(c.Registries, ", "))
+	}
+	if !utils.StringSlicesEqual(c.InsecureRegistries, newConfig.InsecureRegistries) {
+		c.InsecureRegistries = newConfig.InsecureRegistries
+		logConfig("insecure_registries", strings.Join(c.InsecureRegistries, ", "))
+	}
+	if !reflect.DeepEqual(c.RegistryMirrors, newConfig.RegistryMirrors) {
+		c.RegistryMirrors = newConfig.RegistryMirrors
+		logConfig("registry_mirrors", fmt.Sprint(c.RegistryMirrors))
+	}
+	if !reflect.DeepEqual(c.ShortNameAliases, newConfig.ShortNameAliases) {
+		c.ShortNameAliases = newConfig.ShortNameAliases
+		logConfig("short_name_aliases", fmt.Sprint(c.ShortNameAliases))
+	}
+	return nil
+}
+
+func (c *Config) validatePinnedImages(newConfig *Config) error {
+	return validatePinnedImages(newConfig.PinnedImages)
+}
+
+// ReloadPinnedImages updates the pinned_images with the provided `newConfig`.
+// It errors if any of the entries is invalid.
+func (c *Config) ReloadPinnedImages(newConfig *Config) error {
+	if err := c.validatePinnedImages(newConfig); err != nil {
+		return err
+	}
+	if !utils.StringSlicesEqual(c.PinnedImages, newConfig.PinnedImages) {
+		c.PinnedImages = newConfig.PinnedImages
+		logConfig("pinned_images", strings.Join(c.PinnedImages, ", "))
+	}
+	return nil
+}
+
+func (c *Config) validateSignaturePolicy(newConfig *Config) error {
+	if c.SignaturePolicyPath != newConfig.SignaturePolicyPath &&
+		newConfig.SignaturePolicyPath != "" {
+		if _, err := signature.NewPolicyFromFile(newConfig.SignaturePolicyPath); err != nil {
+			return fmt.Errorf("invalid signature_policy %q: %v",
+				newConfig.SignaturePolicyPath, err)
+		}
+	}
+	if c.SignaturePolicyDir != newConfig.SignaturePolicyDir {
+		if err := validateSignaturePolicyDir(newConfig.SignaturePolicyDir); err != nil {
+			return fmt.Errorf("invalid signature_policy_dir %q: %v",
+				newConfig.SignaturePolicyDir, err)
+		}
+	}
+	return nil
+}
+
+// ReloadSignaturePolicy updates the signature_policy and signature_policy_dir
+// with the provided `newConfig`. It errors if a policy file cannot be loaded.
+func (c *Config) ReloadSignaturePolicy(newConfig *Config) error {
+	if err := c.validateSignaturePolicy(newConfig); err != nil {
+		return err
+	}
+	if c.SignaturePolicyPath != newConfig.SignaturePolicyPath {
+		c.SignaturePolicyPath = newConfig.SignaturePolicyPath
+		logConfig("signature_policy", c.SignaturePolicyPath)
+	}
+	if c.SignaturePolicyDir != newConfig.SignaturePolicyDir {
+		c.SignaturePolicyDir = newConfig.SignaturePolicyDir
+		logConfig("signature_policy_dir", c.SignaturePolicyDir)
+	}
+	return nil
+}
+
+func (c *Config) validateContainerDefaults(newConfig *Config) error {
+	knownCapabilities := make(map[string]bool)
+	for _, cap := range capability.List() {
+		knownCapabilities[strings.ToUpper(cap.String())] = true
+	}
+	for _, cap := range newConfig.DefaultCapabilities {
+		if !knownCapabilities[strings.TrimPrefix(strings.ToUpper(cap), "CAP_")] {
+			return fmt.Errorf("invalid default_capabilities entry: unknown capability %q", cap)
+		}
+	}
+	for _, sysctl := range newConfig.DefaultSysctls {
+		split := strings.SplitN(sysctl, "=", 2)
+		if len(split) != 2 || split[0] == "" {
+			return fmt.Errorf("invalid default_sysctls entry %q: not of the format sysctl_name=value", sysctl)
+		}
+	}
+	for _, mount := range newConfig.DefaultMounts {
+		split := strings.Split(mount, ":")
+		if len(split) != 2 || split[0] == "" || split[1] == "" {
+			return fmt.Errorf("invalid default_mounts entry %q: not of the format host-path:container-path", mount)
+		}
+	}
+	if newConfig.PidsLimit < -1 {
+		return fmt.Errorf("invalid pids_limit %d: should be positive or -1 for no limit", newConfig.PidsLimit)
+	}
+	return nil
+}
+
+// ReloadContainerDefaults updates the default_capabilities, default_sysctls,
+// default_mounts and pids_limit with the provided `newConfig`. It errors if
+// any of the values is invalid.
+func (c *Config) ReloadContainerDefaults(newConfig *Config) error {
+	if err := c.validateContainerDefaults(newConfig); err != nil {
+		return err
+	}
+	if !utils.StringSlicesEqual(c.DefaultCapabilities, newConfig.DefaultCapabilities) {
+		c.DefaultCapabilities = newConfig.DefaultCapabilities
+		logConfig("default_capabilities", strings.Join(c.DefaultCapabilities, ", "))
+	}
+	if !utils.StringSlicesEqual(c.DefaultSysctls, newConfig.DefaultSysctls) {
+		c.DefaultSysctls = newConfig.DefaultSysctls
+		logConfig("default_sysctls", strings.Join(c.DefaultSysctls, ", "))
+	}
+	if !utils.StringSlicesEqual(c.DefaultMounts, newConfig.DefaultMounts) {
+		c.DefaultMounts = newConfig.DefaultMounts
+		logConfig("default_mounts", strings.Join(c.DefaultMounts, ", "))
+	}
+	if c.PidsLimit != newConfig.PidsLimit {
+		c.PidsLimit = newConfig.PidsLimit
+		logConfig("pids_limit", fmt.Sprint(c.PidsLimit))
+	}
+	return nil
+}
+
+// validateRuntimes validates all added or changed runtime handlers of the
+// `newConfig` without modifying it.
+func (c *Config) validateRuntimes(newConfig *Config) error {
+	if _, ok := newConfig.Runtimes[c.DefaultRuntime]; !ok {
+		return fmt.Errorf("default_runtime %q cannot be removed from the runtimes", c.DefaultRuntime)
+	}
+	for name, handler := range newConfig.Runtimes {
+		if c.unchangedRuntime(name, handler) != nil {
+			continue
+		}
+		if err := handler.Validate(name); err != nil {
+			return err
+		}
+		runtimeConfig := RuntimeConfig{Runtimes: Runtimes{name: handler}}
+		if err := runtimeConfig.ValidateRuntimePaths(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unchangedRuntime returns the current instance of the runtime handler `name`
+// if `handler` does not change it, and nil otherwise. A runtime path which got
+// resolved from $PATH counts as unchanged if `handler` leaves it empty.
+func (c *Config) unchangedRuntime(name string, handler *RuntimeHandler) *RuntimeHandler {
+	current, ok := c.Runtimes[name]
+	if !ok {
+		return nil
+	}
+	resolved := *handler
+	if resolved.RuntimePath == "" {
+		resolved.RuntimePath = current.RuntimePath
+	}
+	if !reflect.DeepEqual(resolved, *current) {
+		return nil
+	}
+	return current
+}
+
+// ReloadRuntimes updates the runtimes with the provided `newConfig`. Runtime
+// handlers can be added, changed or removed, whereas the default_runtime
+// cannot be removed. Unchanged runtime handlers keep their current instances.
+// It errors if any new runtime handler is invalid.
+func (c *Config) ReloadRuntimes(newConfig *Config) error {
+	if err := c.validateRuntimes(newConfig); err != nil {
+		return err
+	}
+	runtimes := make(Runtimes, len(newConfig.Runtimes))
+	for name, handler := range newConfig.Runtimes {
+		if current := c.unchangedRuntime(name, handler); current != nil {
+			runtimes[name] = current
+			continue
+		}
+		runtimes[name] = handler
+		logConfig("runtimes."+name, handler.RuntimePath)
+	}
+	for name := range c.Runtimes {
+		if _, ok := runtimes[name]; !ok {
+			logrus.Infof("remove config runtimes.%s", name)
+		}
+	}
+	c.Runtimes = runtimes
+	return nil
+}
+
+func (c *Config) validateSecurityProfiles(newConfig *Config) error {
+	if c.SeccompProfile != newConfig.SeccompProfile &&
+		newConfig.SeccompProfile != "" {
+		profile, err := ioutil.ReadFile(newConfig.SeccompProfile)
+		if err != nil {
+			return fmt.Errorf("opening seccomp profile (%s) failed: %v",
+				newConfig.SeccompProfile, err)
+		}
+		var seccompConfig seccomp.Seccomp
+		if err := json.Unmarshal(profile, &seccompConfig); err != nil {
+			return fmt.Errorf("decoding seccomp profile failed: %v", err)
+		}
+	}
+	if newConfig.ApparmorProfile == "" {
+		return fmt.Errorf("invalid empty apparmor_profile")
+	}
+	return nil
+}
+
+// ReloadSecurityProfiles updates the seccomp_profile and apparmor_profile with
+// the provided `newConfig`. It errors if the seccomp profile cannot be read or
+// decoded, or if the apparmor profile is empty.
+func (c *Config) ReloadSecurityProfiles(newConfig *Config) error {
+	if err := c.validateSecurityProfiles(newConfig); err != nil {
+		return err
+	}
+	if c.SeccompProfile != newConfig.SeccompProfile {
+		c.SeccompProfile = newConfig.SeccompProfile
+		logConfig("seccomp_profile", c.SeccompProfile)
+	}
+	if c.ApparmorProfile != newConfig.ApparmorProfile {
+		c.ApparmorProfile = newConfig.ApparmorProfile
+		logConfig("apparmor_profile", c.ApparmorProfile)
+	}
+	return nil
+}
@@ -2,8 +2,11 @@ package config_test
 
 import (
 	"io/ioutil"
+	"os"
+	"path"
 	"strings"
 
+	"github.com/cri-o/cri-o/internal/lib/config"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -48,6 +51,61 @@ var _ = t.Describe("Config", func() {
 			Expect(err).NotTo(BeNil())
 		})
 
+		It("should merge the drop-in configuration files again", func() {
+			// Given
+			filePath := t.MustTempFile("config")
+			Expect(sut.ToFile(filePath)).To(BeNil())
+			dir := t.MustTempDir("crio.conf.d")
+			Expect(sut.UpdateFromDropInDir(dir)).To(BeNil())
+			Expect(ioutil.WriteFile(path.Join(dir, "10-pause.conf"), []byte(`
+[crio.image]
+pause_image = "localhost/pause:latest"
+`), 0644)).To(BeNil())
+
+			// When
+			err := sut.Reload(filePath)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.PauseImage).To(Equal("localhost/pause:latest"))
+		})
+
+		It("should merge a drop-in directory created after startup", func() {
+			// Given
+			filePath := t.MustTempFile("config")
+			Expect(sut.ToFile(filePath)).To(BeNil())
+			dir := path.Join(t.MustTempDir("crio"), "crio.conf.d")
+			Expect(sut.UpdateFromDropInDir(dir)).To(BeNil())
+			Expect(os.Mkdir(dir, 0755)).To(BeNil())
+			Expect(ioutil.WriteFile(path.Join(dir, "10-pause.conf"), []byte(`
+[crio.image]
+pause_image = "localhost/pause:latest"
+`), 0644)).To(BeNil())
+
+			// When
+			err := sut.Reload(filePath)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.PauseImage).To(Equal("localhost/pause:latest"))
+		})
+
+		It("should fail with invalid drop-in configuration file", func() {
+			// Given
+			filePath := t.MustTempFile("config")
+			Expect(sut.ToFile(filePath)).To(BeNil())
+			dir := t.MustTempDir("crio.conf.d")
+			Expect(sut.UpdateFromDropInDir(dir)).To(BeNil())
+			Expect(ioutil.WriteFile(path.Join(dir, "10-invalid.conf"),
+				[]byte("invalid"), 0644)).To(BeNil())
+
+			// When
+			err := sut.Reload(filePath)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
 		It("should fail with invalid log_level", func() {
 			// Given
 			filePath := modifyDefaultConfig(
@@ -75,6 +133,497 @@ var _ = t.Describe("Config", func() {
 			// Then
 			Expect(err).NotTo(BeNil())
 		})
+
+		It("should succeed with pids_limit change", func() {
+			// Given
+			filePath := modifyDefaultConfig(
+				`pids_limit = 1024`,
+				`pids_limit = 2048`,
+			)
+
+			// When
+			err := sut.Reload(filePath)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.PidsLimit).To(BeEquivalentTo(2048))
+		})
+
+		It("should not apply any option if one option is invalid", func() {
+			// Given
+			filePath := t.MustTempFile("config")
+			newConfig := defaultConfig()
+			newConfig.PauseImage = "my-pause"
+			newConfig.PidsLimit = -2
+			Expect(newConfig.ToFile(filePath)).To(BeNil())
+
+			// When
+			err := sut.Reload(filePath)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.PauseImage).NotTo(Equal("my-pause"))
+			Expect(sut.PidsLimit).To(BeEquivalentTo(1024))
+		})
+	})
+
+	t.Describe("ValidateReload", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ValidateReload(defaultConfig())
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should not modify the config", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.PauseImage = "my-pause"
+
+			// When
+			err := sut.ValidateReload(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.PauseImage).NotTo(Equal("my-pause"))
+		})
+
+		It("should not modify the runtimes of the new config", func() {
+			// Given
+			newConfig := defaultConfig()
+			handler := newConfig.Runtimes[sut.DefaultRuntime]
+
+			// When
+			err := sut.ValidateReload(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(newConfig.Runtimes[sut.DefaultRuntime]).To(BeIdenticalTo(handler))
+			Expect(sut.Runtimes[sut.DefaultRuntime]).NotTo(BeIdenticalTo(handler))
+		})
+
+		It("should fail with invalid log_level", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.LogLevel = "invalid"
+
+			// When
+			err := sut.ValidateReload(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	t.Describe("ReloadRegistries", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ReloadRegistries(sut)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should succeed with registries change", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.Registries = []string{"quay.io", "docker.io"}
+			newConfig.InsecureRegistries = []string{"localhost:5000", "10.0.0.0/8"}
+
+			// When
+			err := sut.ReloadRegistries(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.Registries).To(Equal(newConfig.Registries))
+			Expect(sut.InsecureRegistries).To(Equal(newConfig.InsecureRegistries))
+		})
+
+		It("should succeed with registry_mirrors and short_name_aliases change", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.RegistryMirrors = map[string][]string{
+				"docker.io": {"mirror.example.com/docker.io"},
+			}
+			newConfig.ShortNameAliases = map[string]string{
+				"nginx": "docker.io/library/nginx",
+			}
+
+			// When
+			err := sut.ReloadRegistries(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.RegistryMirrors).To(Equal(newConfig.RegistryMirrors))
+			Expect(sut.ShortNameAliases).To(Equal(newConfig.ShortNameAliases))
+		})
+
+		It("should fail with invalid short_name_aliases", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.ShortNameAliases = map[string]string{"nginx": "nginx"}
+
+			// When
+			err := sut.ReloadRegistries(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.ShortNameAliases).To(BeEmpty())
+		})
+
+		It("should fail with empty registry", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.Registries = []string{""}
+
+			// When
+			err := sut.ReloadRegistries(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid insecure registry CIDR", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.InsecureRegistries = []string{"10.0.0.0/99"}
+
+			// When
+			err := sut.ReloadRegistries(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.InsecureRegistries).To(BeEmpty())
+		})
+	})
+
+	t.Describe("ReloadPinnedImages", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ReloadPinnedImages(sut)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should succeed with pinned_images change", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.PinnedImages = []string{"quay.io/crio/*", "sha256:" + strings.Repeat("a", 64)}
+
+			// When
+			err := sut.ReloadPinnedImages(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.PinnedImages).To(Equal(newConfig.PinnedImages))
+		})
+
+		It("should fail with empty pinned image", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.PinnedImages = []string{""}
+
+			// When
+			err := sut.ReloadPinnedImages(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.PinnedImages).To(BeEmpty())
+		})
+	})
+
+	t.Describe("ReloadSignaturePolicy", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ReloadSignaturePolicy(sut)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should succeed with signature_policy change", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.SignaturePolicyPath = "../../../test/policy.json"
+
+			// When
+			err := sut.ReloadSignaturePolicy(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.SignaturePolicyPath).To(Equal(newConfig.SignaturePolicyPath))
+		})
+
+		It("should fail with invalid signature_policy", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.SignaturePolicyPath = invalidPath
+
+			// When
+			err := sut.ReloadSignaturePolicy(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.SignaturePolicyPath).To(BeEmpty())
+		})
+
+		It("should succeed with signature_policy_dir change", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.SignaturePolicyDir = "../../../test"
+
+			// When
+			err := sut.ReloadSignaturePolicy(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.SignaturePolicyDir).To(Equal(newConfig.SignaturePolicyDir))
+		})
+
+		It("should fail with invalid policy in signature_policy_dir", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.SignaturePolicyDir = t.MustTempDir("policies")
+			Expect(ioutil.WriteFile(path.Join(newConfig.SignaturePolicyDir,
+				"namespace.json"), []byte("invalid"), 0644)).To(BeNil())
+
+			// When
+			err := sut.ReloadSignaturePolicy(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.SignaturePolicyDir).To(BeEmpty())
+		})
+	})
+
+	t.Describe("ReloadContainerDefaults", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ReloadContainerDefaults(sut)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should succeed with config change", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.DefaultCapabilities = []string{"CHOWN", "CAP_KILL", "net_raw"}
+			newConfig.DefaultSysctls = []string{"net.ipv4.ping_group_range=0 0"}
+			newConfig.DefaultMounts = []string{"/host:/container"}
+			newConfig.PidsLimit = -1
+
+			// When
+			err := sut.ReloadContainerDefaults(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.DefaultCapabilities).To(Equal(newConfig.DefaultCapabilities))
+			Expect(sut.DefaultSysctls).To(Equal(newConfig.DefaultSysctls))
+			Expect(sut.DefaultMounts).To(Equal(newConfig.DefaultMounts))
+			Expect(sut.PidsLimit).To(BeEquivalentTo(-1))
+		})
+
+		It("should fail with unknown capability", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.DefaultCapabilities = []string{"CAP_INVALID"}
+
+			// When
+			err := sut.ReloadContainerDefaults(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid sysctl", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.DefaultSysctls = []string{"net.ipv4.ping_group_range"}
+
+			// When
+			err := sut.ReloadContainerDefaults(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid mount", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.DefaultMounts = []string{"/host"}
+
+			// When
+			err := sut.ReloadContainerDefaults(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid pids_limit", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.PidsLimit = -2
+
+			// When
+			err := sut.ReloadContainerDefaults(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	t.Describe("ReloadRuntimes", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ReloadRuntimes(defaultConfig())
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should succeed to add a runtime", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.Runtimes["new"] = &config.RuntimeHandler{
+				RuntimePath: validFilePath,
+			}
+
+			// When
+			err := sut.ReloadRuntimes(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.Runtimes).To(HaveKey("new"))
+		})
+
+		It("should succeed to remove a runtime", func() {
+			// Given
+			sut.Runtimes["old"] = &config.RuntimeHandler{
+				RuntimePath: validFilePath,
+			}
+
+			// When
+			err := sut.ReloadRuntimes(defaultConfig())
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.Runtimes).NotTo(HaveKey("old"))
+		})
+
+		It("should keep unchanged runtimes", func() {
+			// Given
+			current := sut.Runtimes[sut.DefaultRuntime]
+			current.RuntimePath = validFilePath
+
+			// When
+			err := sut.ReloadRuntimes(defaultConfig())
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.Runtimes[sut.DefaultRuntime]).To(BeIdenticalTo(current))
+		})
+
+		It("should fail to remove the default runtime", func() {
+			// Given
+			newConfig := defaultConfig()
+			delete(newConfig.Runtimes, sut.DefaultRuntime)
+
+			// When
+			err := sut.ReloadRuntimes(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid runtime_path", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.Runtimes["new"] = &config.RuntimeHandler{
+				RuntimePath: "/not-existing",
+			}
+
+			// When
+			err := sut.ReloadRuntimes(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(sut.Runtimes).NotTo(HaveKey("new"))
+		})
+	})
+
+	t.Describe("ReloadSecurityProfiles", func() {
+		It("should succeed without any config change", func() {
+			// Given
+			// When
+			err := sut.ReloadSecurityProfiles(sut)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should succeed with config change", func() {
+			// Given
+			profile := t.MustTempFile("seccomp")
+			Expect(ioutil.WriteFile(profile, []byte("{}"), 0644)).To(BeNil())
+			newConfig := defaultConfig()
+			newConfig.SeccompProfile = profile
+			newConfig.ApparmorProfile = "my-profile"
+
+			// When
+			err := sut.ReloadSecurityProfiles(newConfig)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.SeccompProfile).To(Equal(profile))
+			Expect(sut.ApparmorProfile).To(Equal("my-profile"))
+		})
+
+		It("should fail with invalid seccomp_profile path", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.SeccompProfile = invalidPath
+
+			// When
+			err := sut.ReloadSecurityProfiles(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with invalid seccomp_profile content", func() {
+			// Given
+			profile := t.MustTempFile("seccomp")
+			Expect(ioutil.WriteFile(profile, []byte("invalid"), 0644)).To(BeNil())
+			newConfig := defaultConfig()
+			newConfig.SeccompProfile = profile
+
+			// When
+			err := sut.ReloadSecurityProfiles(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail with empty apparmor_profile", func() {
+			// Given
+			newConfig := defaultConfig()
+			newConfig.ApparmorProfile = ""
+
+			// When
+			err := sut.ReloadSecurityProfiles(newConfig)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
 	})
 
 	t.Describe("ReloadLogLevel", func() {
@@ -18,6 +18,7 @@ import (
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/oci"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
 	"github.com/cri-o/cri-o/utils"
 	"github.com/docker/docker/pkg/ioutils"
 	"github.com/docker/docker/pkg/truncindex"
@@ -52,6 +53,14 @@ func (c *ContainerServer) Runtime() oci.RuntimeImpl {
 	return c.runtime
 }
 
+// UpdateRuntimes replaces the runtime handlers of the oci runtime, which apply
+// to containers created afterwards.
+func (c *ContainerServer) UpdateRuntimes(runtimes libconfig.Runtimes) {
+	if runtime, ok := c.runtime.(*oci.Runtime); ok {
+		runtime.UpdateRuntimes(runtimes)
+	}
+}
+
 // Store returns the Store for the ContainerServer
 func (c *ContainerServer) Store() cstorage.Store {
 	return c.store
@@ -111,6 +120,8 @@ func New(ctx context.Context, systemContext *types.SystemContext, configIface li
 	if err != nil {
 		return nil, err
 	}
+	imageService.UpdatePinnedImages(config.AllPinnedImages())
+	imageService.UpdateRegistryMirrors(config.RegistryMirrors, config.ShortNameAliases)
 
 	storageRuntimeService := storage.GetRuntimeService(ctx, imageService)
 
@@ -337,6 +348,16 @@ func (c *ContainerServer) LoadSandbox(id string) error {
 	sb.SetSeccompProfilePath(spp)
 	sb.SetNamespaceOptions(&nsOpts)
 
+	// The IPs are not stored by older versions or if the network gets set up
+	// after the infra container has been created
+	if ipsJSON, ok := m.Annotations[crioannotations.IPs]; ok {
+		ips := []string{}
+		if err := json.Unmarshal([]byte(ipsJSON), &ips); err != nil {
+			return err
+		}
+		sb.AddIPs(ips)
+	}
+
 	// We add a netNS only if we can load a permanent one.
 	// Otherwise, the sandbox will live in the host namespace.
 	if c.config.ManageNetworkNSLifecycle {
@@ -352,6 +373,12 @@ func (c *ContainerServer) LoadSandbox(id string) error {
 		}
 	}
 
+	// Sandboxes without infra container process have pinned namespaces
+	spoofed := isTrue(m.Annotations[crioannotations.SpoofedContainer])
+	if spoofed {
+		sb.SetPinnedNamespaces(configNsPath(&m, rspec.IPCNamespace), configNsPath(&m, rspec.UTSNamespace))
+	}
+
 	if err := c.AddSandbox(sb); err != nil {
 		return err
 	}
@@ -395,6 +422,9 @@ func (c *ContainerServer) LoadSandbox(id string) error {
 	}
 	scontainer.SetSpec(&m)
 	scontainer.SetMountPoint(m.Annotations[annotations.MountPoint])
+	if spoofed {
+		scontainer.SetSpoofed()
+	}
 
 	if m.Annotations[annotations.Volumes] != "" {
 		containerVolumes := []oci.ContainerVolume{}
@@ -442,6 +472,17 @@ func configNetNsPath(spec *rspec.Spec) (string, error) {
 	return "", fmt.Errorf("missing networking namespace")
 }
 
+// configNsPath returns the path of the namespace `nsType` within the spec,
+// which is empty if the namespace is not joined via a path.
+func configNsPath(spec *rspec.Spec, nsType rspec.LinuxNamespaceType) string {
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Type == nsType {
+			return ns.Path
+		}
+	}
+	return ""
+}
+
 // LoadContainer loads a container from the disk into the container store
 func (c *ContainerServer) LoadContainer(id string) error {
 	config, err := c.store.FromContainerDirectory(id, "config.json")
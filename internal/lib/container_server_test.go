@@ -366,6 +366,33 @@ var _ = t.Describe("ContainerServer", func() {
 			Expect(err).To(BeNil())
 		})
 
+		It("should succeed with spoofed infra container", func() {
+			// Given
+			createDummyState()
+			manifest := bytes.Replace(testManifest,
+				[]byte(`{"type": "network", "path": "default"}`),
+				[]byte(`{"type": "network", "path": "default"},`+
+					`{"type": "ipc", "path": "/ipc"},{"type": "uts", "path": "/uts"}`), 1,
+			)
+			manifest = bytes.Replace(manifest,
+				[]byte(`"io.kubernetes.cri-o.CNIResult": "{}"`),
+				[]byte(`"io.kubernetes.cri-o.CNIResult": "{}", "io.kubernetes.cri-o.Spoofed": "true"`), 1,
+			)
+			mockDirs(manifest)
+
+			// When
+			err := sut.LoadSandbox("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			sb := sut.GetSandbox("id")
+			Expect(sb).NotTo(BeNil())
+			Expect(sb.InfraContainer().Spoofed()).To(BeTrue())
+			Expect(sb.IpcNsPath()).To(Equal("/ipc"))
+			Expect(sb.UtsNsPath()).To(Equal("/uts"))
+			Expect(sb.PidNsPath()).To(BeEmpty())
+		})
+
 		It("should succeed with invalid network namespace", func() {
 			// Given
 			createDummyState()
@@ -1,11 +1,14 @@
 package lib
 
 import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
 	"path"
 	"strings"
 	"time"
-
-	"github.com/hpcloud/tail"
 )
 
 // LogOptions contains all of the options for displaying logs in podman
@@ -16,7 +19,9 @@ type LogOptions struct {
 	Tail      uint64
 }
 
-// GetLogs gets each line of a log file and, if it matches the criteria in logOptions, sends it down logChan
+// GetLogs gets each line of the log files of a container, including the
+// rotated ones, and, if it matches the criteria in logOptions, sends it down
+// logChan
 func (c *ContainerServer) GetLogs(container string, logChan chan string, opts LogOptions) error {
 	defer close(logChan)
 	// Get the full ID of the container
@@ -30,25 +35,93 @@ func (c *ContainerServer) GetLogs(container string, logChan chan string, opts Lo
 	if sandbox == "" {
 		sandbox = containerID
 	}
-	// Read the log line by line and pass it into the pipe
 	logsFile := path.Join(c.config.LogDir, sandbox, containerID+".log")
 
-	seekInfo := &tail.SeekInfo{Offset: 0, Whence: 0}
-	if opts.Tail > 0 {
-		// seek to correct position in logs files
-		seekInfo.Offset = int64(opts.Tail)
-		seekInfo.Whence = 2
+	// Only the last opts.Tail matching lines are sent once all files are read
+	var tail []string
+	send := func(line string) {
+		if opts.Tail == 0 {
+			logChan <- line
+			return
+		}
+		if uint64(len(tail)) == opts.Tail {
+			tail = tail[1:]
+		}
+		tail = append(tail, line)
+	}
+
+	// Read the logs line by line, from the oldest rotated file to the current
+	// one, and pass them into the pipe
+	for _, file := range logFiles(logsFile) {
+		if err := readLogFile(file, func(line string) {
+			if since, err := logSinceTime(opts.SinceTime, line); err != nil || !since {
+				return
+			}
+			send(line[secondSpaceIndex(line):])
+		}); err != nil {
+			return err
+		}
+	}
+	for _, line := range tail {
+		logChan <- line
+	}
+	return nil
+}
+
+// logFiles returns the rotated log files of the log file `logPath`, ordered
+// from the oldest to the most recent one, followed by `logPath` itself.
+// Rotated files are named <logPath>.1 to <logPath>.N, where <logPath>.1 is
+// the most recent one, and have the .gz suffix if they are compressed.
+func logFiles(logPath string) []string {
+	files := []string{logPath}
+	for i := 1; ; i++ {
+		rotated := fmt.Sprintf("%s.%d", logPath, i)
+		if _, err := os.Stat(rotated + ".gz"); err == nil {
+			rotated += ".gz"
+		} else if _, err := os.Stat(rotated); err != nil {
+			break
+		}
+		files = append([]string{rotated}, files...)
+	}
+	return files
+}
+
+// readLogFile calls `handle` for each line of the log file `file`, which gets
+// decompressed if it has the .gz suffix. Files which do not exist are skipped,
+// since they might have been rotated in the meantime.
+func readLogFile(file string, handle func(string)) error {
+	f, err := os.Open(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
 	}
+	defer f.Close()
 
-	t, err := tail.TailFile(logsFile, tail.Config{Follow: false, ReOpen: false, Location: seekInfo})
-	for line := range t.Lines {
-		if since, err := logSinceTime(opts.SinceTime, line.Text); err != nil || !since {
-			continue
+	var r io.Reader = f
+	if strings.HasSuffix(file, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("unable to decompress log file %s: %v", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if line = strings.TrimSuffix(line, "\n"); line != "" {
+			handle(line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read log file %s: %v", file, err)
 		}
-		logMessage := line.Text[secondSpaceIndex(line.Text):]
-		logChan <- logMessage
 	}
-	return err
 }
 
 func logSinceTime(sinceTime time.Time, logStr string) (bool, error) {
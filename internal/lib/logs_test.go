@@ -1,6 +1,8 @@
 package lib_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"io/ioutil"
 	"os"
 	"path"
@@ -47,6 +49,67 @@ var _ = t.Describe("ContainerServer", func() {
 			Expect(<-e).To(BeNil())
 		})
 
+		It("should succeed across rotated log files", func() {
+			// Given
+			c := make(chan string, 10)
+			addContainerAndSandbox()
+
+			// Prepare the current and the rotated log files
+			logFile := path.Join(sandboxID, containerID+".log")
+			Expect(os.MkdirAll(sandboxID, 0755)).To(BeNil())
+			defer os.RemoveAll(sandboxID)
+
+			var compressed bytes.Buffer
+			gz := gzip.NewWriter(&compressed)
+			_, err := gz.Write([]byte("2001-01-02T15:04:05.0-07:00 stdout F First\n"))
+			Expect(err).To(BeNil())
+			Expect(gz.Close()).To(BeNil())
+			Expect(ioutil.WriteFile(logFile+".2.gz", compressed.Bytes(), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(logFile+".1", []byte(
+				"2002-01-02T15:04:05.0-07:00 stdout F Second\n"+
+					"2003-01-02T15:04:05.0-07:00 stdout P Third\n"), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(logFile, []byte(
+				"2004-01-02T15:04:05.0-07:00 stdout F Fourth\n"), 0644)).To(BeNil())
+
+			// When
+			err = sut.GetLogs(containerID, c, lib.LogOptions{Tail: 3})
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(<-c).To(ContainSubstring("Second"))
+			Expect(<-c).To(ContainSubstring("Third"))
+			Expect(<-c).To(ContainSubstring("Fourth"))
+			Expect(<-c).To(BeEmpty())
+		})
+
+		It("should succeed with since time across rotated log files", func() {
+			// Given
+			c := make(chan string, 10)
+			addContainerAndSandbox()
+
+			// Prepare the current and the rotated log files
+			logFile := path.Join(sandboxID, containerID+".log")
+			Expect(os.MkdirAll(sandboxID, 0755)).To(BeNil())
+			defer os.RemoveAll(sandboxID)
+			Expect(ioutil.WriteFile(logFile+".2", []byte(
+				"2001-01-02T15:04:05.0-07:00 stdout F First\n"), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(logFile+".1", []byte(
+				"2002-01-02T15:04:05.0-07:00 stdout F Second\n"), 0644)).To(BeNil())
+			Expect(ioutil.WriteFile(logFile, []byte(
+				"2004-01-02T15:04:05.0-07:00 stdout F Fourth\n"), 0644)).To(BeNil())
+
+			// When
+			err := sut.GetLogs(containerID, c, lib.LogOptions{
+				SinceTime: time.Date(2002, 1, 1, 0, 0, 0, 0, time.UTC),
+			})
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(<-c).To(ContainSubstring("Second"))
+			Expect(<-c).To(ContainSubstring("Fourth"))
+			Expect(<-c).To(BeEmpty())
+		})
+
 		It("should succeed with seek info", func() {
 			// Given
 			c := make(chan string)
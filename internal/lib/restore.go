@@ -0,0 +1,316 @@
+package lib
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/containers/image/types"
+	"github.com/containers/libpod/pkg/annotations"
+	"github.com/containers/storage/pkg/archive"
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/docker/docker/pkg/stringid"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/selinux/go-selinux/label"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// ContainerRestore recreates a container from the checkpoint archive at
+// `importPath`, as written by ContainerCheckpoint, inside the existing
+// sandbox `sandboxID` and restores its processes. The image of the container
+// has to be available in the local storage.
+func (c *ContainerServer) ContainerRestore(systemContext *types.SystemContext, sandboxID, importPath string) (id string, err error) {
+	sb, err := c.LookupSandbox(sandboxID)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find sandbox %s", sandboxID)
+	}
+	if sb.InfraContainer() == nil {
+		return "", fmt.Errorf("sandbox %s has no infra container", sb.ID())
+	}
+
+	specDir, err := ioutil.TempDir("", "crio-restore-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(specDir)
+
+	// The checkpoint images are extracted later on into the directory of the
+	// new container
+	if err := untarCheckpoint(importPath, specDir, &archive.TarOptions{
+		ExcludePatterns: []string{checkpointDirectory},
+	}); err != nil {
+		return "", errors.Wrapf(err, "failed to extract checkpoint archive %s", importPath)
+	}
+
+	specData, err := ioutil.ReadFile(filepath.Join(specDir, specDumpFile))
+	if err != nil {
+		return "", err
+	}
+	var spec rspec.Spec
+	if err := json.Unmarshal(specData, &spec); err != nil {
+		return "", err
+	}
+	if spec.Linux == nil || spec.Process == nil || spec.Root == nil {
+		return "", fmt.Errorf("checkpoint archive %s contains an incomplete spec", importPath)
+	}
+
+	var metadata pb.ContainerMetadata
+	if err := json.Unmarshal([]byte(spec.Annotations[annotations.Metadata]), &metadata); err != nil {
+		return "", err
+	}
+	labels := make(map[string]string)
+	if err := json.Unmarshal([]byte(spec.Annotations[annotations.Labels]), &labels); err != nil {
+		return "", err
+	}
+	kubeAnnotations := make(map[string]string)
+	if err := json.Unmarshal([]byte(spec.Annotations[annotations.Annotations]), &kubeAnnotations); err != nil {
+		return "", err
+	}
+
+	id = stringid.GenerateNonCryptoID()
+	name, err := c.ReserveContainerName(id, restoredContainerName(sb.Metadata(), &metadata))
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			c.ReleaseContainerName(name)
+		}
+	}()
+
+	labelOptions, err := label.DupSecOpt(sb.ProcessLabel())
+	if err != nil {
+		return "", err
+	}
+	containerInfo, err := c.storageRuntimeServer.CreateContainer(systemContext,
+		sb.Name(), sb.ID(),
+		spec.Annotations[annotations.ImageName], "",
+		name, id,
+		metadata.Name,
+		metadata.Attempt,
+		nil,
+		labelOptions)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if err2 := c.storageRuntimeServer.DeleteContainer(id); err2 != nil {
+				logrus.Warnf("failed to cleanup container directory: %v", err2)
+			}
+		}
+	}()
+
+	mountPoint, err := c.storageRuntimeServer.StartContainer(id)
+	if err != nil {
+		return "", fmt.Errorf("failed to mount container %s(%s): %v", name, id, err)
+	}
+
+	if err := c.applyRootfsDiff(id, filepath.Join(specDir, rootfsDiffFile)); err != nil {
+		return "", errors.Wrapf(err, "failed to apply root filesystem changes of container %s", id)
+	}
+
+	if err := untarCheckpoint(importPath, containerInfo.Dir, &archive.TarOptions{
+		IncludeFiles: []string{checkpointDirectory},
+	}); err != nil {
+		return "", errors.Wrapf(err, "failed to extract checkpoint archive %s", importPath)
+	}
+
+	created := time.Now()
+	logPath := filepath.Join(sb.LogDir(), id+".log")
+	if err := c.restoreSpec(&spec, sb, id, name, mountPoint, logPath, created); err != nil {
+		return "", err
+	}
+	if spec.Linux.MountLabel != "" {
+		spec.Linux.MountLabel = containerInfo.MountLabel
+	}
+	if spec.Process.SelinuxLabel != "" {
+		spec.Process.SelinuxLabel = containerInfo.ProcessLabel
+	}
+
+	specData, err = json.Marshal(&spec)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range []string{containerInfo.Dir, containerInfo.RunDir} {
+		if err := ioutil.WriteFile(filepath.Join(dir, specDumpFile), specData, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	ctr, err := oci.NewContainer(id, name, containerInfo.RunDir, logPath, sb.NetNs().Path(), labels, spec.Annotations, kubeAnnotations, spec.Annotations[annotations.Image], spec.Annotations[annotations.ImageName], spec.Annotations[annotations.ImageRef], &metadata, sb.ID(), isTrue(spec.Annotations[annotations.TTY]), isTrue(spec.Annotations[annotations.Stdin]), isTrue(spec.Annotations[annotations.StdinOnce]), sb.Privileged(), sb.RuntimeHandler(), containerInfo.Dir, created, spec.Annotations["org.opencontainers.image.stopSignal"])
+	if err != nil {
+		return "", err
+	}
+	ctr.SetSpec(&spec)
+	ctr.SetMountPoint(mountPoint)
+	ctr.SetSeccompProfilePath(spec.Annotations[annotations.SeccompProfilePath])
+
+	c.AddContainer(ctr)
+	defer func() {
+		if err != nil {
+			c.RemoveContainer(ctr)
+		}
+	}()
+	if err := c.ctrIDIndex.Add(id); err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			if err2 := c.ctrIDIndex.Delete(id); err2 != nil {
+				logrus.Warnf("couldn't delete ctr id %s from idIndex", id)
+			}
+		}
+	}()
+
+	if err := c.runtime.RestoreContainer(ctr, sb.CgroupParent()); err != nil {
+		return "", errors.Wrapf(err, "failed to restore container %s", id)
+	}
+	defer func() {
+		if err != nil {
+			if err2 := c.runtime.StopContainer(context.Background(), ctr, 0); err2 != nil {
+				logrus.Warnf("failed to stop restored container %s: %v", id, err2)
+			}
+			if err2 := c.runtime.DeleteContainer(ctr); err2 != nil {
+				logrus.Warnf("failed to delete restored container %s: %v", id, err2)
+			}
+		}
+	}()
+	if err := c.runtime.UpdateContainerStatus(ctr); err != nil {
+		return "", errors.Wrapf(err, "failed to update status of container %s", id)
+	}
+
+	if err := c.ContainerStateToDisk(ctr); err != nil {
+		logrus.Warnf("unable to write containers %s state to disk: %v", ctr.ID(), err)
+	}
+
+	return id, nil
+}
+
+// restoreSpec adapts the spec of a checkpointed container to the new
+// container `id` inside of the sandbox `sb`.
+func (c *ContainerServer) restoreSpec(spec *rspec.Spec, sb *sandbox.Sandbox, id, name, mountPoint, logPath string, created time.Time) error {
+	oldID := spec.Annotations[annotations.ContainerID]
+	spec.Annotations[annotations.ContainerID] = id
+	spec.Annotations[annotations.Name] = name
+	spec.Annotations[annotations.SandboxID] = sb.ID()
+	spec.Annotations[annotations.SandboxName] = sb.Name()
+	spec.Annotations[annotations.MountPoint] = mountPoint
+	spec.Annotations[annotations.LogPath] = logPath
+	spec.Annotations[annotations.Created] = created.Format(time.RFC3339Nano)
+	spec.Annotations[annotations.IP] = sb.IP()
+	spec.Root.Path = mountPoint
+
+	spec.Linux.CgroupsPath = restoreCgroupsPath(spec.Linux.CgroupsPath,
+		oldID, id, sb.CgroupParent(),
+		c.config.CgroupManager == oci.SystemdCgroupsManager)
+
+	// Join the namespaces of the new sandbox
+	namespaces := make([]rspec.LinuxNamespace, 0, len(spec.Linux.Namespaces))
+	for _, ns := range spec.Linux.Namespaces {
+		if ns.Path != "" {
+			switch ns.Type {
+			case rspec.NetworkNamespace:
+				ns.Path = sb.NetNsPath()
+			case rspec.IPCNamespace:
+				ns.Path = sb.IpcNsPath()
+				if ns.Path == "" {
+					// The sandbox uses the IPC namespace of the host
+					continue
+				}
+			case rspec.UTSNamespace:
+				ns.Path = sb.UtsNsPath()
+			case rspec.PIDNamespace:
+				ns.Path = sb.PidNsPath()
+				if ns.Path == "" {
+					return fmt.Errorf("sandbox %s has no infra container to share its PID namespace", sb.ID())
+				}
+			case rspec.UserNamespace:
+				ns.Path = sb.UserNsPath()
+			}
+		}
+		namespaces = append(namespaces, ns)
+	}
+	spec.Linux.Namespaces = namespaces
+
+	// Use the files which are shared by the new sandbox
+	sandboxFiles := map[string]string{
+		"/etc/resolv.conf": sb.ResolvPath(),
+		"/etc/hostname":    sb.HostnamePath(),
+		"/dev/shm":         sb.ShmPath(),
+	}
+	for i, m := range spec.Mounts {
+		if source, ok := sandboxFiles[m.Destination]; ok && source != "" {
+			spec.Mounts[i].Source = source
+		}
+	}
+	return nil
+}
+
+// restoreCgroupsPath moves the cgroups path of a checkpointed container to
+// the container `newID` below the cgroup parent of its new sandbox.
+func restoreCgroupsPath(path, oldID, newID, cgroupParent string, systemd bool) string {
+	if systemd {
+		parts := strings.Split(path, ":")
+		if len(parts) != 3 {
+			return path
+		}
+		if cgroupParent != "" {
+			parts[0] = cgroupParent
+		}
+		parts[2] = newID
+		return strings.Join(parts, ":")
+	}
+	parent, scope := filepath.Split(path)
+	if cgroupParent != "" {
+		parent = cgroupParent
+	}
+	return filepath.Join(parent, strings.TrimSuffix(scope, oldID)+newID)
+}
+
+// restoredContainerName returns the name of a restored container within the
+// sandbox described by `sandboxMetadata`.
+func restoredContainerName(sandboxMetadata *pb.PodSandboxMetadata, metadata *pb.ContainerMetadata) string {
+	return strings.Join([]string{
+		"k8s",
+		metadata.Name,
+		sandboxMetadata.Name,
+		sandboxMetadata.Namespace,
+		sandboxMetadata.Uid,
+		fmt.Sprintf("%d", metadata.Attempt),
+	}, "_")
+}
+
+// applyRootfsDiff applies the root filesystem changes at `path` to the
+// container `id`. A missing file is not treated as an error.
+func (c *ContainerServer) applyRootfsDiff(id, path string) error {
+	diff, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer diff.Close()
+
+	return c.storageRuntimeServer.ApplyContainerRootfsDiff(id, diff)
+}
+
+// untarCheckpoint extracts the checkpoint archive at `path` to `dest`.
+func untarCheckpoint(path, dest string, options *archive.TarOptions) error {
+	input, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	return archive.Untar(input, dest, options)
+}
@@ -0,0 +1,98 @@
+package lib_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/containers/storage/pkg/archive"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The actual test suite
+var _ = t.Describe("ContainerRestore", func() {
+	// Prepare the sut
+	BeforeEach(beforeEach)
+
+	var writeArchive = func(spec string) string {
+		dir := t.MustTempDir("checkpoint")
+		Expect(ioutil.WriteFile(filepath.Join(dir, "config.json"),
+			[]byte(spec), 0644)).To(BeNil())
+		input, err := archive.Tar(dir, archive.Uncompressed)
+		Expect(err).To(BeNil())
+		defer input.Close()
+
+		archivePath := filepath.Join(t.MustTempDir("archive"), "checkpoint.tar")
+		output, err := os.Create(archivePath)
+		Expect(err).To(BeNil())
+		defer output.Close()
+		_, err = io.Copy(output, input)
+		Expect(err).To(BeNil())
+		return archivePath
+	}
+
+	It("should fail with incomplete spec", func() {
+		// Given
+		Expect(mySandbox.SetInfraContainer(myContainer)).To(BeNil())
+		addContainerAndSandbox()
+		archivePath := writeArchive("{}")
+
+		// When
+		res, err := sut.ContainerRestore(nil, sandboxID, archivePath)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail with invalid spec", func() {
+		// Given
+		Expect(mySandbox.SetInfraContainer(myContainer)).To(BeNil())
+		addContainerAndSandbox()
+		archivePath := writeArchive("invalid")
+
+		// When
+		res, err := sut.ContainerRestore(nil, sandboxID, archivePath)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail with non existing archive", func() {
+		// Given
+		Expect(mySandbox.SetInfraContainer(myContainer)).To(BeNil())
+		addContainerAndSandbox()
+
+		// When
+		res, err := sut.ContainerRestore(nil, sandboxID, "/not/existing")
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail without infra container", func() {
+		// Given
+		addContainerAndSandbox()
+
+		// When
+		res, err := sut.ContainerRestore(nil, sandboxID, "/not/existing")
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+
+	It("should fail with invalid sandbox ID", func() {
+		// Given
+		// When
+		res, err := sut.ContainerRestore(nil, "", "/not/existing")
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(res).To(BeEmpty())
+	})
+})
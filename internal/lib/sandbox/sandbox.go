@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	"github.com/containernetworking/plugins/pkg/ns"
+	"github.com/containers/storage/pkg/idtools"
 	"github.com/cri-o/cri-o/internal/oci"
 	"github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/fields"
@@ -85,20 +87,23 @@ type Sandbox struct {
 	processLabel   string
 	mountLabel     string
 	netns          NetNsIface
+	ipcNsPath      string
+	utsNsPath      string
 	shmPath        string
 	cgroupParent   string
 	runtimeHandler string
 	resolvPath     string
 	hostnamePath   string
 	hostname       string
-	// ipv4 or ipv6 cache
-	ip                 string
+	// ipv4 and ipv6 cache, the first one is the primary IP
+	ips                []string
 	seccompProfilePath string
 	labels             fields.Set
 	annotations        map[string]string
 	infraContainer     *oci.Container
 	metadata           *pb.PodSandboxMetadata
 	nsOpts             *pb.NamespaceOption
+	idMappings         *idtools.IDMappings
 	stopMutex          sync.RWMutex
 	created            bool
 	stopped            bool
@@ -133,6 +138,12 @@ const (
 	// NsRunDir is the default directory in which running network namespaces
 	// are stored
 	NsRunDir = "/var/run/netns"
+	// IpcNsRunDir is the default directory in which the pinned IPC
+	// namespaces of sandboxes without infra process are stored
+	IpcNsRunDir = "/var/run/ipcns"
+	// UtsNsRunDir is the default directory in which the pinned UTS
+	// namespaces of sandboxes without infra process are stored
+	UtsNsRunDir = "/var/run/utsns"
 	// PodInfraCommand is the default command when starting a pod infrastructure
 	// container
 	PodInfraCommand = "/pause"
@@ -185,9 +196,21 @@ func (s *Sandbox) SeccompProfilePath() string {
 	return s.seccompProfilePath
 }
 
-// AddIP stores the ip in the sandbox
-func (s *Sandbox) AddIP(ip string) {
-	s.ip = ip
+// SetIDMappings sets the ID mappings of the user namespace of the sandbox,
+// if the sandbox got its own one
+func (s *Sandbox) SetIDMappings(mappings *idtools.IDMappings) {
+	s.idMappings = mappings
+}
+
+// IDMappings returns the ID mappings of the user namespace of the sandbox,
+// or nil if the sandbox uses the default mappings
+func (s *Sandbox) IDMappings() *idtools.IDMappings {
+	return s.idMappings
+}
+
+// AddIPs stores the ips in the sandbox
+func (s *Sandbox) AddIPs(ips []string) {
+	s.ips = ips
 }
 
 // SetNamespaceOptions sets whether the pod is running using host network
@@ -205,9 +228,17 @@ func (s *Sandbox) StopMutex() *sync.RWMutex {
 	return &s.stopMutex
 }
 
-// IP returns the ip of the sandbox
+// IP returns the primary ip of the sandbox
 func (s *Sandbox) IP() string {
-	return s.ip
+	if len(s.ips) == 0 {
+		return ""
+	}
+	return s.ips[0]
+}
+
+// IPs returns all ips of the sandbox
+func (s *Sandbox) IPs() []string {
+	return s.ips
 }
 
 // ID returns the id of the sandbox
@@ -372,10 +403,7 @@ func (s *Sandbox) NetNs() *NetNs {
 func (s *Sandbox) NetNsPath() string {
 	if s.netns == nil || s.netns.Get() == nil ||
 		s.netns.Get().symlink == nil {
-		if s.infraContainer != nil {
-			return fmt.Sprintf("/proc/%v/ns/net", s.infraContainer.State().Pid)
-		}
-		return ""
+		return s.infraNsPath("net")
 	}
 
 	return s.netns.Get().symlink.Name()
@@ -384,10 +412,89 @@ func (s *Sandbox) NetNsPath() string {
 // UserNsPath returns the path to the user namespace of the sandbox.
 // If the sandbox uses the host namespace, nil is returned
 func (s *Sandbox) UserNsPath() string {
-	if s.infraContainer != nil {
-		return fmt.Sprintf("/proc/%v/ns/user", s.infraContainer.State().Pid)
+	return s.infraNsPath("user")
+}
+
+// IpcNsPath returns the path to the IPC namespace of the sandbox.
+// If the sandbox has no infra process and uses the host namespace, an empty
+// string is returned
+func (s *Sandbox) IpcNsPath() string {
+	if s.ipcNsPath != "" {
+		return s.ipcNsPath
+	}
+	return s.infraNsPath("ipc")
+}
+
+// UtsNsPath returns the path to the UTS namespace of the sandbox.
+func (s *Sandbox) UtsNsPath() string {
+	if s.utsNsPath != "" {
+		return s.utsNsPath
 	}
-	return ""
+	return s.infraNsPath("uts")
+}
+
+// PidNsPath returns the path to the PID namespace of the sandbox.
+// If the sandbox has no infra process, an empty string is returned
+func (s *Sandbox) PidNsPath() string {
+	return s.infraNsPath("pid")
+}
+
+// infraNsPath returns the path to the namespace `nsType` of the infra
+// container process, if there is one.
+func (s *Sandbox) infraNsPath(nsType string) string {
+	if s.infraContainer == nil || s.infraContainer.Spoofed() {
+		return ""
+	}
+	return fmt.Sprintf("/proc/%v/ns/%s", s.infraContainer.State().Pid, nsType)
+}
+
+// PinNamespaces creates the UTS namespace with the hostname of the sandbox
+// and, unless `hostIPC` is set, the IPC namespace of a sandbox without infra
+// process. Both are pinned by bind mounting them like the network namespace.
+func (s *Sandbox) PinNamespaces(hostIPC bool) (err error) {
+	utsNsPath := filepath.Join(UtsNsRunDir, s.id)
+	if err := pinNamespace("uts", utsNsPath, s.hostname); err != nil {
+		return fmt.Errorf("failed to pin UTS namespace: %v", err)
+	}
+	s.utsNsPath = utsNsPath
+	defer func() {
+		if err != nil {
+			if err2 := s.UnpinNamespaces(); err2 != nil {
+				logrus.Warnf("failed to unpin namespaces of sandbox %s: %v", s.id, err2)
+			}
+		}
+	}()
+
+	if !hostIPC {
+		ipcNsPath := filepath.Join(IpcNsRunDir, s.id)
+		if err := pinNamespace("ipc", ipcNsPath, ""); err != nil {
+			return fmt.Errorf("failed to pin IPC namespace: %v", err)
+		}
+		s.ipcNsPath = ipcNsPath
+	}
+	return nil
+}
+
+// SetPinnedNamespaces sets the paths of the pinned IPC and UTS namespaces
+// when restoring a sandbox without infra process
+func (s *Sandbox) SetPinnedNamespaces(ipcNsPath, utsNsPath string) {
+	s.ipcNsPath = ipcNsPath
+	s.utsNsPath = utsNsPath
+}
+
+// UnpinNamespaces removes the pinned IPC and UTS namespaces of the sandbox.
+// It can be called multiple times without returning an error.
+func (s *Sandbox) UnpinNamespaces() error {
+	for _, nsPath := range []*string{&s.ipcNsPath, &s.utsNsPath} {
+		if *nsPath == "" {
+			continue
+		}
+		if err := unpinNamespace(*nsPath); err != nil {
+			return err
+		}
+		*nsPath = ""
+	}
+	return nil
 }
 
 // NetNsCreate creates a new network namespace for the sandbox
@@ -258,6 +258,74 @@ func NewNS() (ns.NetNS, error) {
 	return ns.GetNS(nsPath)
 }
 
+// pinNamespace creates a new namespace of type `nsType` ("ipc" or "uts") and
+// bind mounts it to `nsPath`, so that it persists without any process inside.
+// The hostname is set within new UTS namespaces.
+func pinNamespace(nsType, nsPath, hostname string) (err error) {
+	var cloneFlag int
+	switch nsType {
+	case "ipc":
+		cloneFlag = unix.CLONE_NEWIPC
+	case "uts":
+		cloneFlag = unix.CLONE_NEWUTS
+	default:
+		return fmt.Errorf("unsupported namespace type %q", nsType)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(nsPath), 0755); err != nil {
+		return err
+	}
+
+	// create an empty file at the mount point
+	mountPointFd, err := os.Create(nsPath)
+	if err != nil {
+		return err
+	}
+	mountPointFd.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	// The thread is locked without unlocking it again, so that the runtime
+	// terminates it together with the goroutine instead of reusing it within
+	// the new namespace.
+	go (func() {
+		defer wg.Done()
+		runtime.LockOSThread()
+
+		if err = unix.Unshare(cloneFlag); err != nil {
+			return
+		}
+		if hostname != "" {
+			if err = unix.Sethostname([]byte(hostname)); err != nil {
+				return
+			}
+		}
+
+		// bind mount the new namespace of the current thread onto the mount point
+		threadNsPath := fmt.Sprintf("/proc/%d/task/%d/ns/%s", os.Getpid(), unix.Gettid(), nsType)
+		err = unix.Mount(threadNsPath, nsPath, "none", unix.MS_BIND, "")
+	})()
+	wg.Wait()
+
+	if err != nil {
+		if removeErr := os.RemoveAll(nsPath); removeErr != nil {
+			logrus.Warnf("unable to remove %v: %v", nsPath, removeErr)
+		}
+		return fmt.Errorf("failed to create %s namespace: %v", nsType, err)
+	}
+	return nil
+}
+
+// unpinNamespace unmounts and removes the namespace pinned at `nsPath`.
+func unpinNamespace(nsPath string) error {
+	if err := unix.Unmount(nsPath, unix.MNT_DETACH); err != nil &&
+		err != unix.EINVAL && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "unable to unmount %v", nsPath)
+	}
+	return os.RemoveAll(nsPath)
+}
+
 func getCurrentThreadNetNSPath() string {
 	// /proc/self/ns/net returns the namespace of the main thread, not
 	// of whatever thread this goroutine is running on.  Make sure we
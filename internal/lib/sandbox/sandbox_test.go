@@ -87,17 +87,19 @@ var _ = t.Describe("Sandbox", func() {
 		})
 	})
 
-	t.Describe("AddIP", func() {
+	t.Describe("AddIPs", func() {
 		It("should succeed", func() {
 			// Given
-			newIP := "10.0.0.1"
-			Expect(testSandbox.IP()).NotTo(Equal(newIP))
+			newIPs := []string{"10.0.0.1", "fd00::1"}
+			Expect(testSandbox.IP()).To(BeEmpty())
+			Expect(testSandbox.IPs()).To(BeEmpty())
 
 			// When
-			testSandbox.AddIP(newIP)
+			testSandbox.AddIPs(newIPs)
 
 			// Then
-			Expect(testSandbox.IP()).To(Equal(newIP))
+			Expect(testSandbox.IP()).To(Equal("10.0.0.1"))
+			Expect(testSandbox.IPs()).To(Equal(newIPs))
 		})
 	})
 
@@ -221,6 +223,28 @@ var _ = t.Describe("Sandbox", func() {
 			Expect(testSandbox.NetNsPath()).To(Equal(""))
 		})
 
+		It("should succeed to add a spoofed infra container", func() {
+			// Given
+			testContainer.SetSpoofed()
+
+			// When
+			err := testSandbox.SetInfraContainer(testContainer)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(testSandbox.UserNsPath()).To(Equal(""))
+			Expect(testSandbox.IpcNsPath()).To(Equal(""))
+			Expect(testSandbox.UtsNsPath()).To(Equal(""))
+			Expect(testSandbox.PidNsPath()).To(Equal(""))
+
+			// And When
+			testSandbox.SetPinnedNamespaces("/ipc", "/uts")
+
+			// Then
+			Expect(testSandbox.IpcNsPath()).To(Equal("/ipc"))
+			Expect(testSandbox.UtsNsPath()).To(Equal("/uts"))
+		})
+
 		It("should fail add an infra container twice", func() {
 			// Given
 			Expect(testSandbox.InfraContainer()).To(BeNil())
@@ -254,6 +278,42 @@ var _ = t.Describe("Sandbox", func() {
 		})
 	})
 
+	t.Describe("PinNamespaces", func() {
+		It("should succeed", func() {
+			// Given
+			// When
+			err := testSandbox.PinNamespaces(false)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(testSandbox.IpcNsPath()).To(BeAnExistingFile())
+			Expect(testSandbox.UtsNsPath()).To(BeAnExistingFile())
+
+			// And When
+			ipcNsPath := testSandbox.IpcNsPath()
+			utsNsPath := testSandbox.UtsNsPath()
+			err = testSandbox.UnpinNamespaces()
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(ipcNsPath).NotTo(BeAnExistingFile())
+			Expect(utsNsPath).NotTo(BeAnExistingFile())
+			Expect(testSandbox.UnpinNamespaces()).To(BeNil())
+		})
+
+		It("should succeed with host IPC", func() {
+			// Given
+			// When
+			err := testSandbox.PinNamespaces(true)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(testSandbox.IpcNsPath()).To(Equal(""))
+			Expect(testSandbox.UtsNsPath()).To(BeAnExistingFile())
+			Expect(testSandbox.UnpinNamespaces()).To(BeNil())
+		})
+	})
+
 	t.Describe("NetNsJoin", func() {
 		It("should fail when network namespace not exists", func() {
 			// Given
@@ -52,3 +52,11 @@ func (n *NetNs) Remove() error {
 func hostNetNsPath() (string, error) {
 	return "", fmt.Errorf("netns is not implemented for this platform")
 }
+
+func pinNamespace(nsType, nsPath, hostname string) error {
+	return fmt.Errorf("namespace pinning is not implemented for this platform")
+}
+
+func unpinNamespace(nsPath string) error {
+	return nil
+}
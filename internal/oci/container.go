@@ -12,6 +12,7 @@ import (
 
 	"github.com/containers/libpod/pkg/cgroups"
 	"github.com/containers/storage/pkg/idtools"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 	"github.com/docker/docker/pkg/signal"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
@@ -53,11 +54,30 @@ type Container struct {
 	opLock             sync.RWMutex
 	spec               *specs.Spec
 	idMappings         *idtools.IDMappings
+	logRotation        *LogRotation
 	terminal           bool
 	stdin              bool
 	stdinOnce          bool
 	privileged         bool
 	created            bool
+	spoofed            bool
+	// runtimeDeleted is set once the runtime deleted the container, guarded
+	// by the runtimeImplMapMutex of the Runtime
+	runtimeDeleted bool
+}
+
+// LogRotation contains the size limit and rotation settings of a container
+// log file.
+type LogRotation struct {
+	// SizeMax is the maximum size of the log file in bytes, negative values
+	// disable the limit
+	SizeMax int64
+	// MaxFiles is the number of rotated log files to keep, zero truncates
+	// the log file instead
+	MaxFiles int
+	// Compress indicates whether the rotated log files are compressed with
+	// gzip
+	Compress bool
 }
 
 // ContainerVolume is a bind mount for the container.
@@ -76,6 +96,10 @@ type ContainerState struct {
 	ExitCode  int32     `json:"exitCode,omitempty"`
 	OOMKilled bool      `json:"oomKilled,omitempty"`
 	Error     string    `json:"error,omitempty"`
+	// OOMKillCount is the number of processes of the container which got
+	// OOM killed, including the ones other than the init process
+	OOMKillCount uint64    `json:"oomKillCount,omitempty"`
+	LastOOMKill  time.Time `json:"lastOOMKill,omitempty"`
 }
 
 // NewContainer creates a container object.
@@ -169,6 +193,12 @@ func (c *Container) StatePath() string {
 	return filepath.Join(c.dir, "state.json")
 }
 
+// CheckpointPath returns the directory containing the checkpoint images of
+// the container
+func (c *Container) CheckpointPath() string {
+	return filepath.Join(c.dir, "checkpoint")
+}
+
 // CreatedAt returns the container creation time
 func (c *Container) CreatedAt() time.Time {
 	return c.state.Created
@@ -184,15 +214,28 @@ func (c *Container) ID() string {
 	return c.id
 }
 
+// RuntimeHandler returns the runtime handler of the container, which is empty
+// for the default runtime.
+func (c *Container) RuntimeHandler() string {
+	return c.runtimeHandler
+}
+
 // CleanupConmonCgroup cleans up conmon's group when using cgroupfs.
 func (c *Container) CleanupConmonCgroup() {
 	path := c.ConmonCgroupfsPath()
 	if path == "" {
 		return
 	}
+	if cgroupv2.Enabled() {
+		if err := cgroupv2.Remove(path); err != nil {
+			logrus.Infof("error deleting conmon cgroup of container %s: %v", c.ID(), err)
+		}
+		return
+	}
 	cg, err := cgroups.Load(path)
 	if err != nil {
 		logrus.Infof("error loading conmon cgroup of container %s: %v", c.ID(), err)
+		return
 	}
 	if err := cg.Delete(); err != nil {
 		logrus.Infof("error deleting conmon cgroup of container %s: %v", c.ID(), err)
@@ -320,6 +363,18 @@ func (c *Container) IDMappings() *idtools.IDMappings {
 	return c.idMappings
 }
 
+// SetLogRotation sets the log rotation settings of the container, which
+// override the ones of the runtime configuration
+func (c *Container) SetLogRotation(rotation *LogRotation) {
+	c.logRotation = rotation
+}
+
+// LogRotation returns the log rotation settings of the container, or nil if
+// the ones of the runtime configuration apply
+func (c *Container) LogRotation() *LogRotation {
+	return c.logRotation
+}
+
 // SetCreated sets the created flag to true once container is created
 func (c *Container) SetCreated() {
 	c.created = true
@@ -330,6 +385,18 @@ func (c *Container) Created() bool {
 	return c.created
 }
 
+// SetSpoofed marks the container as placeholder without any process, which
+// is used as infra container of pod sandboxes whose namespaces are pinned by
+// CRI-O
+func (c *Container) SetSpoofed() {
+	c.spoofed = true
+}
+
+// Spoofed returns whether the container is a placeholder without any process
+func (c *Container) Spoofed() bool {
+	return c.spoofed
+}
+
 // SetStartFailed sets the container state appropriately after a start failure
 func (c *Container) SetStartFailed(err error) {
 	c.opLock.Lock()
@@ -341,6 +408,15 @@ func (c *Container) SetStartFailed(err error) {
 	}
 }
 
+// AddOOMKills records `kills` OOM kills of processes of the container, where
+// the last one happened at the time `at`.
+func (c *Container) AddOOMKills(kills uint64, at time.Time) {
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+	c.state.OOMKillCount += kills
+	c.state.LastOOMKill = at
+}
+
 // Description returns a description for the container
 func (c *Container) Description() string {
 	return fmt.Sprintf("%s/%s/%s", c.Labels()[types.KubernetesPodNamespaceLabel], c.Labels()[types.KubernetesPodNameLabel], c.Labels()[types.KubernetesContainerNameLabel])
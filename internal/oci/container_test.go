@@ -43,6 +43,7 @@ var _ = t.Describe("Container", func() {
 		Expect(sut.Dir()).To(Equal("dir"))
 		Expect(sut.NetNsPath()).To(Equal("netns"))
 		Expect(sut.StatePath()).To(Equal("dir/state.json"))
+		Expect(sut.CheckpointPath()).To(Equal("dir/checkpoint"))
 		Expect(sut.Metadata()).To(Equal(&pb.ContainerMetadata{}))
 		Expect(sut.StateNoLock().Version).To(BeEmpty())
 		Expect(sut.GetStopSignal()).To(Equal("TERM"))
@@ -72,6 +73,17 @@ var _ = t.Describe("Container", func() {
 		Expect(sut.Created()).To(BeTrue())
 	})
 
+	It("should succeed to set spoofed", func() {
+		// Given
+		Expect(sut.Spoofed()).To(BeFalse())
+
+		// When
+		sut.SetSpoofed()
+
+		// Then
+		Expect(sut.Spoofed()).To(BeTrue())
+	})
+
 	It("should succeed to set ID mappings", func() {
 		// Given
 		mappings := &idtools.IDMappings{}
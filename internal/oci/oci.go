@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"net"
 	"sync"
 	"syscall"
 	"time"
 
 	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
 	rspec "github.com/opencontainers/runtime-spec/specs-go"
 	"golang.org/x/net/context"
 	"k8s.io/client-go/tools/remotecommand"
@@ -44,9 +46,16 @@ const (
 // Runtime is the generic structure holding both global and specific
 // information about the runtime.
 type Runtime struct {
-	config              *config.Config
+	config *config.Config
+	// runtimesLock guards the runtime handlers of the config, which get
+	// replaced on configuration reloads
+	runtimesLock        sync.RWMutex
 	runtimeImplMap      map[string]RuntimeImpl
 	runtimeImplMapMutex sync.RWMutex
+
+	// shimEvents receives the events published by the shims of VM runtimes
+	shimEvents     net.Listener
+	shimEventsLock sync.Mutex
 }
 
 // RuntimeImpl is an interface used by the caller to interact with the
@@ -57,7 +66,7 @@ type Runtime struct {
 // runtimes. Assumptions based on the fact that a container process runs
 // on the host will be limited to the RuntimeOCI implementation.
 type RuntimeImpl interface {
-	CreateContainer(*Container, string) error
+	CreateContainer(context.Context, *Container, string) error
 	StartContainer(*Container) error
 	ExecContainer(*Container, []string, io.Reader, io.WriteCloser, io.WriteCloser,
 		bool, <-chan remotecommand.TerminalSize) error
@@ -75,6 +84,8 @@ type RuntimeImpl interface {
 	PortForwardContainer(*Container, int32, io.ReadWriter) error
 	ReopenContainerLog(*Container) error
 	WaitContainerStateStopped(context.Context, *Container) error
+	CheckpointContainer(*Container, bool) error
+	RestoreContainer(*Container, string) error
 }
 
 // New creates a new Runtime with options provided
@@ -87,9 +98,19 @@ func New(c *config.Config) *Runtime {
 
 // Runtimes returns the map of OCI runtimes.
 func (r *Runtime) Runtimes() config.Runtimes {
+	r.runtimesLock.RLock()
+	defer r.runtimesLock.RUnlock()
 	return r.config.Runtimes
 }
 
+// UpdateRuntimes replaces the map of OCI runtimes. The runtime handlers apply
+// to containers whose runtime implementation gets created afterwards.
+func (r *Runtime) UpdateRuntimes(runtimes config.Runtimes) {
+	r.runtimesLock.Lock()
+	defer r.runtimesLock.Unlock()
+	r.config.Runtimes = runtimes
+}
+
 // ValidateRuntimeHandler returns an error if the runtime handler string
 // provided does not match any valid use case.
 func (r *Runtime) ValidateRuntimeHandler(handler string) (*config.RuntimeHandler, error) {
@@ -97,14 +118,18 @@ func (r *Runtime) ValidateRuntimeHandler(handler string) (*config.RuntimeHandler
 		return nil, fmt.Errorf("empty runtime handler")
 	}
 
-	runtimeHandler, ok := r.config.Runtimes[handler]
+	runtimes := r.Runtimes()
+	runtimeHandler, ok := runtimes[handler]
 	if !ok {
 		return nil, fmt.Errorf("failed to find runtime handler %s from runtime list %v",
-			handler, r.config.Runtimes)
+			handler, runtimes)
 	}
 	if runtimeHandler.RuntimePath == "" {
 		return nil, fmt.Errorf("empty runtime path for runtime handler %s", handler)
 	}
+	if err := runtimeHandler.Validate(handler); err != nil {
+		return nil, err
+	}
 
 	return runtimeHandler, nil
 }
@@ -173,8 +198,14 @@ func (r *Runtime) WaitContainerStateStopped(ctx context.Context, c *Container) (
 }
 
 func (r *Runtime) newRuntimeImpl(c *Container) (RuntimeImpl, error) {
+	// Spoofed containers have no process which could be managed by any
+	// runtime handler.
+	if c.Spoofed() {
+		return newRuntimeSpoofed(), nil
+	}
+
 	// Define the current runtime handler as the default runtime handler.
-	rh := r.config.Runtimes[r.config.DefaultRuntime]
+	rh := r.Runtimes()[r.config.DefaultRuntime]
 
 	// Override the current runtime handler with the runtime handler
 	// corresponding to the runtime handler key provided with this
@@ -189,7 +220,11 @@ func (r *Runtime) newRuntimeImpl(c *Container) (RuntimeImpl, error) {
 	}
 
 	if rh.RuntimeType == RuntimeTypeVM {
-		return newRuntimeVM(rh.RuntimePath), nil
+		eventsAddress, err := r.listenShimEvents()
+		if err != nil {
+			return nil, err
+		}
+		return newRuntimeVM(rh.RuntimePath, eventsAddress, r.config.ContainerExitsDir, *r.defaultLogRotation()), nil
 	}
 
 	// If the runtime type is different from "vm", then let's fallback
@@ -197,20 +232,55 @@ func (r *Runtime) newRuntimeImpl(c *Container) (RuntimeImpl, error) {
 	return newRuntimeOCI(r, rh), nil
 }
 
-// RuntimeImpl returns the runtime implementation for a given container
+// defaultLogRotation returns the log rotation settings of the configuration,
+// which apply to containers without their own.
+func (r *Runtime) defaultLogRotation() *LogRotation {
+	return &LogRotation{
+		SizeMax:  r.config.LogSizeMax,
+		MaxFiles: r.config.LogMaxFiles,
+		Compress: r.config.LogCompress,
+	}
+}
+
+// RuntimeImpl returns the runtime implementation for a given container. The
+// implementation created for a container restored after a restart is kept, so
+// that it can keep its connection to a runtime shim.
 func (r *Runtime) RuntimeImpl(c *Container) (RuntimeImpl, error) {
 	r.runtimeImplMapMutex.RLock()
 	impl, ok := r.runtimeImplMap[c.ID()]
 	r.runtimeImplMapMutex.RUnlock()
-	if !ok {
-		return r.newRuntimeImpl(c)
+	if ok {
+		return impl, nil
+	}
+
+	impl, err := r.newRuntimeImpl(c)
+	if err != nil {
+		return nil, err
+	}
+
+	r.runtimeImplMapMutex.Lock()
+	defer r.runtimeImplMapMutex.Unlock()
+	if existing, ok := r.runtimeImplMap[c.ID()]; ok {
+		return existing, nil
+	}
+	// The implementation of a deleted container would never be removed again
+	if !c.runtimeDeleted {
+		r.runtimeImplMap[c.ID()] = impl
 	}
 
 	return impl, nil
 }
 
 // CreateContainer creates a container.
-func (r *Runtime) CreateContainer(c *Container, cgroupParent string) error {
+func (r *Runtime) CreateContainer(ctx context.Context, c *Container, cgroupParent string) (err error) {
+	ctx, span := tracing.StartSpan(ctx, "runtime.CreateContainer")
+	span.SetAttribute("container.id", c.ID())
+	span.SetAttribute("runtime.handler", c.runtimeHandler)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
 	// Instantiate a new runtime implementation for this new container
 	impl, err := r.newRuntimeImpl(c)
 	if err != nil {
@@ -222,7 +292,7 @@ func (r *Runtime) CreateContainer(c *Container, cgroupParent string) error {
 	r.runtimeImplMap[c.ID()] = impl
 	r.runtimeImplMapMutex.Unlock()
 
-	return impl.CreateContainer(c, cgroupParent)
+	return impl.CreateContainer(ctx, c, cgroupParent)
 }
 
 // StartContainer starts a container.
@@ -282,13 +352,16 @@ func (r *Runtime) DeleteContainer(c *Container) error {
 		return err
 	}
 
-	defer func() {
-		r.runtimeImplMapMutex.Lock()
-		delete(r.runtimeImplMap, c.ID())
-		r.runtimeImplMapMutex.Unlock()
-	}()
+	if err := impl.DeleteContainer(c); err != nil {
+		return err
+	}
 
-	return impl.DeleteContainer(c)
+	r.runtimeImplMapMutex.Lock()
+	delete(r.runtimeImplMap, c.ID())
+	c.runtimeDeleted = true
+	r.runtimeImplMapMutex.Unlock()
+
+	return nil
 }
 
 // UpdateContainerStatus refreshes the status of the container.
@@ -371,6 +444,32 @@ func (r *Runtime) ReopenContainerLog(c *Container) error {
 	return impl.ReopenContainerLog(c)
 }
 
+// CheckpointContainer checkpoints a container into its checkpoint path.
+func (r *Runtime) CheckpointContainer(c *Container, leaveRunning bool) error {
+	impl, err := r.RuntimeImpl(c)
+	if err != nil {
+		return err
+	}
+
+	return impl.CheckpointContainer(c, leaveRunning)
+}
+
+// RestoreContainer creates a container from its checkpoint path.
+func (r *Runtime) RestoreContainer(c *Container, cgroupParent string) error {
+	// Instantiate a new runtime implementation for this new container
+	impl, err := r.newRuntimeImpl(c)
+	if err != nil {
+		return err
+	}
+
+	// Assign this runtime implementation to the current container
+	r.runtimeImplMapMutex.Lock()
+	r.runtimeImplMap[c.ID()] = impl
+	r.runtimeImplMapMutex.Unlock()
+
+	return impl.RestoreContainer(c, cgroupParent)
+}
+
 // ExecSyncResponse is returned from ExecSync.
 type ExecSyncResponse struct {
 	Stdout   []byte
@@ -6,13 +6,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/containers/libpod/pkg/cgroups"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 	"github.com/cri-o/cri-o/utils"
 	"github.com/opencontainers/runc/libcontainer"
+	libcgroups "github.com/opencontainers/runc/libcontainer/cgroups"
 	rspec "github.com/opencontainers/runtime-spec/specs-go"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
@@ -28,7 +31,7 @@ func createConmonUnitName(name string) string {
 
 func (r *runtimeOCI) createContainerPlatform(c *Container, cgroupParent string, pid int) {
 	// Move conmon to specified cgroup
-	if r.config.ConmonCgroup == "pod" || r.config.ConmonCgroup == "" {
+	if r.conmonCgroup == "pod" || r.conmonCgroup == "" {
 		switch r.config.CgroupManager {
 		case SystemdCgroupsManager:
 			logrus.Debugf("Running conmon under slice %s and unitName %s", cgroupParent, createConmonUnitName(c.id))
@@ -37,6 +40,14 @@ func (r *runtimeOCI) createContainerPlatform(c *Container, cgroupParent string,
 			}
 		case CgroupfsCgroupsManager:
 			cgroupPath := filepath.Join(cgroupParent, "/crio-conmon-"+c.id)
+			if cgroupv2.Enabled() {
+				if err := cgroupv2.AddPid(cgroupPath, pid); err != nil {
+					logrus.Warnf("Failed to add conmon to cgroupfs sandbox cgroup: %v", err)
+					break
+				}
+				c.conmonCgroupfsPath = cgroupPath
+				break
+			}
 			control, err := cgroups.New(cgroupPath, &rspec.LinuxResources{})
 			if err != nil {
 				logrus.Warnf("Failed to add conmon to cgroupfs sandbox cgroup: %v", err)
@@ -60,14 +71,37 @@ func (r *runtimeOCI) createContainerPlatform(c *Container, cgroupParent string,
 			// error for an unknown cgroups manager
 			logrus.Errorf("unknown cgroups manager %q for sandbox cgroup", r.config.CgroupManager)
 		}
-	} else if strings.HasSuffix(r.config.ConmonCgroup, ".slice") {
-		logrus.Debugf("Running conmon under custom slice %s and unitName %s", r.config.ConmonCgroup, createConmonUnitName(c.id))
-		if err := utils.RunUnderSystemdScope(pid, r.config.ConmonCgroup, createConmonUnitName(c.id)); err != nil {
+	} else if strings.HasSuffix(r.conmonCgroup, ".slice") {
+		logrus.Debugf("Running conmon under custom slice %s and unitName %s", r.conmonCgroup, createConmonUnitName(c.id))
+		if err := utils.RunUnderSystemdScope(pid, r.conmonCgroup, createConmonUnitName(c.id)); err != nil {
 			logrus.Warnf("Failed to add conmon to custom systemd sandbox cgroup: %v", err)
 		}
 	}
 }
 
+// moveToContainerCgroup moves the process `pid` into the cgroups of the
+// container process
+func moveToContainerCgroup(c *Container, pid int) error {
+	ctrPid := c.State().Pid
+	if cgroupv2.Enabled() {
+		cgroupPath, err := cgroupv2.PidCgroup(ctrPid)
+		if err != nil {
+			return err
+		}
+		return cgroupv2.AddPid(cgroupPath, pid)
+	}
+	cgroupPaths, err := libcgroups.ParseCgroupFile(fmt.Sprintf("/proc/%d/cgroup", ctrPid))
+	if err != nil {
+		return err
+	}
+	paths := make(map[string]string, len(cgroupPaths))
+	for subsystem, path := range cgroupPaths {
+		subsystem = strings.TrimPrefix(subsystem, "name=")
+		paths[subsystem] = filepath.Join("/sys/fs/cgroup", subsystem, path)
+	}
+	return libcgroups.EnterPid(paths, pid)
+}
+
 func sysProcAttrPlatform() *syscall.SysProcAttr {
 	return &syscall.SysProcAttr{
 		Setpgid: true,
@@ -106,6 +140,9 @@ func (r *runtimeOCI) libcontainerStats(ctr *Container) (*libcontainer.Stats, err
 }
 
 func (r *runtimeOCI) containerStats(ctr *Container) (*ContainerStats, error) {
+	if cgroupv2.Enabled() {
+		return containerStatsV2(ctr)
+	}
 	libcontainerStats, err := r.libcontainerStats(ctr)
 	if err != nil {
 		return nil, err
@@ -126,6 +163,36 @@ func (r *runtimeOCI) containerStats(ctr *Container) (*ContainerStats, error) {
 	return stats, nil
 }
 
+// containerStatsV2 gets the stats for the container from the cgroup v2
+// unified hierarchy, which is not supported by runc/libcontainer
+func containerStatsV2(ctr *Container) (*ContainerStats, error) {
+	cgroupPath, err := cgroupv2.PidCgroup(ctr.state.Pid)
+	if err != nil {
+		return nil, err
+	}
+	cgroupStats, err := cgroupv2.GetStats(filepath.Join(cgroupv2.Root, cgroupPath))
+	if err != nil {
+		return nil, err
+	}
+	stats := new(ContainerStats)
+	stats.Container = ctr.ID()
+	stats.CPUNano = cgroupStats.CPUUsageNano
+	stats.SystemNano = time.Now().UnixNano()
+	stats.CPU = genericCalculateCPUPercent(stats.CPUNano, runtime.NumCPU())
+	stats.MemUsage = cgroupStats.MemoryCurrent
+	stats.MemLimit = getMemLimit(cgroupStats.MemoryMax)
+	stats.MemPerc = float64(stats.MemUsage) / float64(stats.MemLimit)
+	stats.PIDs = cgroupStats.PidsCurrent
+	stats.BlockInput, stats.BlockOutput = cgroupStats.IOBytes()
+	stats.MemStat = cgroupStats.MemoryStat
+	stats.IOStat = cgroupStats.IOStat
+	stats.CPUPressure = cgroupStats.CPUPressure
+	stats.MemoryPressure = cgroupStats.MemoryPressure
+	stats.IOPressure = cgroupStats.IOPressure
+
+	return stats, nil
+}
+
 func metricsToCtrStats(c *Container, m *cgroups.Metrics) *ContainerStats {
 	var (
 		cpu         float64
@@ -144,7 +211,7 @@ func metricsToCtrStats(c *Container, m *cgroups.Metrics) *ContainerStats {
 		pids = m.Pids.Current
 
 		cpuNano = m.CPU.Usage.Total
-		cpu = genericCalculateCPUPercent(cpuNano, m.CPU.Usage.PerCPU)
+		cpu = genericCalculateCPUPercent(cpuNano, len(m.CPU.Usage.PerCPU))
 
 		memUsage = m.Memory.Usage.Usage
 		memLimit = getMemLimit(m.Memory.Usage.Limit)
@@ -1,10 +1,15 @@
 package oci_test
 
 import (
+	"context"
+	"time"
+
 	"github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/oci"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
 // The actual test suite
@@ -31,6 +36,8 @@ var _ = t.Describe("Oci", func() {
 		const (
 			invalidRuntime = "invalid"
 			defaultRuntime = "runc"
+			vmRuntime      = "vm"
+			badRuntime     = "bad"
 		)
 		runtimes := config.Runtimes{
 			defaultRuntime: {
@@ -38,6 +45,14 @@ var _ = t.Describe("Oci", func() {
 				RuntimeType: "",
 				RuntimeRoot: "/run/runc",
 			}, invalidRuntime: {},
+			vmRuntime: {
+				RuntimePath: "/bin/sh",
+				RuntimeType: oci.RuntimeTypeVM,
+			},
+			badRuntime: {
+				RuntimePath:  "/bin/sh",
+				ConmonCgroup: "invalid",
+			},
 		}
 
 		BeforeEach(func() {
@@ -68,6 +83,117 @@ var _ = t.Describe("Oci", func() {
 			Expect(err).To(BeNil())
 			Expect(handler).To(Equal(runtimes[defaultRuntime]))
 		})
+
+		It("should fail to validate a runtime handler with invalid settings", func() {
+			// Given
+			// When
+			handler, err := sut.ValidateRuntimeHandler(badRuntime)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(handler).To(BeNil())
+		})
+
+		It("should fail to checkpoint a container of a VM runtime", func() {
+			// Given
+			container, err := oci.NewContainer("id", "name", "bundlePath",
+				"logPath", "netns", map[string]string{}, map[string]string{},
+				map[string]string{}, "image", "imageName", "imageRef",
+				&pb.ContainerMetadata{}, "sandbox", false, false, false, false,
+				vmRuntime, "dir", time.Now(), "")
+			Expect(err).To(BeNil())
+
+			// When
+			err = sut.CheckpointContainer(container, false)
+
+			// Then
+			Expect(err).To(Equal(oci.ErrCheckpointNotSupported))
+		})
+
+		It("should fail to restore a container without checkpoint", func() {
+			// Given
+			container, err := oci.NewContainer("id", "name", "bundlePath",
+				"logPath", "netns", map[string]string{}, map[string]string{},
+				map[string]string{}, "image", "imageName", "imageRef",
+				&pb.ContainerMetadata{}, "sandbox", false, false, false, false,
+				defaultRuntime, t.MustTempDir("container"), time.Now(), "")
+			Expect(err).To(BeNil())
+
+			// When
+			err = sut.RestoreContainer(container, "")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should succeed to manage the state of a spoofed container", func() {
+			// Given
+			container, err := oci.NewContainer("id", "name", "bundlePath",
+				"logPath", "netns", map[string]string{}, map[string]string{},
+				map[string]string{}, "image", "imageName", "imageRef",
+				&pb.ContainerMetadata{}, "sandbox", false, false, false, false,
+				invalidRuntime, "dir", time.Now(), "")
+			Expect(err).To(BeNil())
+			container.SetSpoofed()
+
+			// When
+			Expect(sut.CreateContainer(context.Background(), container, "")).To(BeNil())
+			Expect(sut.StartContainer(container)).To(BeNil())
+			Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+
+			// Then
+			Expect(container.State().Status).To(Equal(oci.ContainerStateRunning))
+
+			// When
+			Expect(sut.StopContainer(context.Background(), container, 10)).To(BeNil())
+			Expect(sut.WaitContainerStateStopped(context.Background(), container)).To(BeNil())
+			Expect(sut.DeleteContainer(container)).To(BeNil())
+
+			// Then
+			Expect(container.State().Status).To(Equal(oci.ContainerStateStopped))
+		})
+
+		It("should succeed to stop a spoofed container without namespaces", func() {
+			// Given
+			container, err := oci.NewContainer("id", "name", "bundlePath",
+				"logPath", "netns", map[string]string{}, map[string]string{},
+				map[string]string{}, "image", "imageName", "imageRef",
+				&pb.ContainerMetadata{}, "sandbox", false, false, false, false,
+				defaultRuntime, "dir", time.Now(), "")
+			Expect(err).To(BeNil())
+			container.SetSpoofed()
+			container.SetSpec(&specs.Spec{Linux: &specs.Linux{
+				Namespaces: []specs.LinuxNamespace{
+					{Type: specs.UTSNamespace, Path: "/not/existing"},
+				},
+			}})
+			Expect(sut.StartContainer(container)).To(BeNil())
+
+			// When
+			err = sut.UpdateContainerStatus(container)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(container.State().Status).To(Equal(oci.ContainerStateStopped))
+		})
+
+		It("should fail to exec into a spoofed container", func() {
+			// Given
+			container, err := oci.NewContainer("id", "name", "bundlePath",
+				"logPath", "netns", map[string]string{}, map[string]string{},
+				map[string]string{}, "image", "imageName", "imageRef",
+				&pb.ContainerMetadata{}, "sandbox", false, false, false, false,
+				defaultRuntime, "dir", time.Now(), "")
+			Expect(err).To(BeNil())
+			container.SetSpoofed()
+
+			// When
+			res, err := sut.ExecSyncContainer(container, []string{"true"}, 0)
+
+			// Then
+			Expect(err).To(Equal(oci.ErrSpoofedContainer))
+			Expect(res).To(BeNil())
+		})
 	})
 
 	t.Describe("ExecSyncError", func() {
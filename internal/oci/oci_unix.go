@@ -37,10 +37,10 @@ func getExitCode(err error) int32 {
 
 func calculateCPUPercent(stats *libcontainer.Stats) float64 {
 	return genericCalculateCPUPercent(stats.CgroupStats.CpuStats.CpuUsage.TotalUsage,
-		stats.CgroupStats.CpuStats.CpuUsage.PercpuUsage)
+		len(stats.CgroupStats.CpuStats.CpuUsage.PercpuUsage))
 }
 
-func genericCalculateCPUPercent(cpuTotal uint64, perCPU []uint64) float64 {
+func genericCalculateCPUPercent(cpuTotal uint64, numCPUs int) float64 {
 	var (
 		cpuPercent = 0.0
 		cpuUsage   = float64(cpuTotal)
@@ -49,7 +49,7 @@ func genericCalculateCPUPercent(cpuTotal uint64, perCPU []uint64) float64 {
 	if systemTime > 0.0 && cpuUsage > 0.0 {
 		// gets a ratio of container cpu usage total, multiplies it by the number of cores (4 cores running
 		// at 100% utilization should be 400% utilization), and multiplies that by 100 to get a percentage
-		cpuPercent = (cpuUsage / systemTime) * float64(len(perCPU)) * 100
+		cpuPercent = (cpuUsage / systemTime) * float64(numCPUs) * 100
 	}
 	return cpuPercent
 }
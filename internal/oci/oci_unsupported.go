@@ -10,6 +10,10 @@ import (
 func (r *Runtime) createContainerPlatform(c *Container, cgroupParent string, pid int) {
 }
 
+func moveToContainerCgroup(c *Container, pid int) error {
+	return nil
+}
+
 func sysProcAttrPlatform() *syscall.SysProcAttr {
 	return &syscall.SysProcAttr{}
 }
@@ -16,7 +16,9 @@ import (
 	"time"
 
 	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 	"github.com/cri-o/cri-o/internal/pkg/findprocess"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
 	"github.com/cri-o/cri-o/utils"
 	"github.com/docker/docker/pkg/pools"
 	"github.com/fsnotify/fsnotify"
@@ -45,6 +47,14 @@ type runtimeOCI struct {
 
 	path string
 	root string
+
+	// conmon, conmonCgroup and conmonEnv are the conmon settings of the
+	// runtime handler, which default to the global ones, whereas
+	// monitorExecCgroup is the cgroup of the exec sync monitors
+	conmon            string
+	conmonCgroup      string
+	conmonEnv         []string
+	monitorExecCgroup string
 }
 
 // newRuntimeOCI creates a new runtimeOCI instance
@@ -53,10 +63,26 @@ func newRuntimeOCI(r *Runtime, handler *config.RuntimeHandler) RuntimeImpl {
 	if handler.RuntimeRoot != "" {
 		runRoot = handler.RuntimeRoot
 	}
+	conmon := r.config.Conmon
+	if handler.Conmon != "" {
+		conmon = handler.Conmon
+	}
+	conmonCgroup := r.config.ConmonCgroup
+	if handler.ConmonCgroup != "" {
+		conmonCgroup = handler.ConmonCgroup
+	}
+	conmonEnv := r.config.ConmonEnv
+	if len(handler.ConmonEnv) > 0 {
+		conmonEnv = handler.ConmonEnv
+	}
 	return &runtimeOCI{
-		Runtime: r,
-		path:    handler.RuntimePath,
-		root:    runRoot,
+		Runtime:           r,
+		path:              handler.RuntimePath,
+		root:              runRoot,
+		conmon:            conmon,
+		conmonCgroup:      conmonCgroup,
+		conmonEnv:         conmonEnv,
+		monitorExecCgroup: handler.MonitorExecCgroup,
 	}
 }
 
@@ -73,7 +99,13 @@ type exitCodeInfo struct {
 }
 
 // CreateContainer creates a container.
-func (r *runtimeOCI) CreateContainer(c *Container, cgroupParent string) (err error) {
+func (r *runtimeOCI) CreateContainer(ctx context.Context, c *Container, cgroupParent string) error {
+	return r.createContainer(ctx, c, cgroupParent, false)
+}
+
+// createContainer creates a container using conmon. The container process
+// gets restored from the checkpoint path of the container if `restore` is set.
+func (r *runtimeOCI) createContainer(ctx context.Context, c *Container, cgroupParent string, restore bool) (err error) {
 	var stderrBuf bytes.Buffer
 	parentPipe, childPipe, err := newPipe()
 	childStartPipe, parentStartPipe, err := newPipe()
@@ -103,8 +135,18 @@ func (r *runtimeOCI) CreateContainer(c *Container, cgroupParent string) (err err
 		"--socket-dir-path", r.config.ContainerAttachSocketDir,
 		"--log-level", logrus.GetLevel().String(),
 		"--runtime-arg", fmt.Sprintf("%s=%s", rootFlag, r.root))
-	if r.config.LogSizeMax >= 0 {
-		args = append(args, "--log-size-max", fmt.Sprintf("%v", r.config.LogSizeMax))
+	rotation := c.LogRotation()
+	if rotation == nil {
+		rotation = r.defaultLogRotation()
+	}
+	if rotation.SizeMax >= 0 {
+		args = append(args, "--log-size-max", fmt.Sprintf("%v", rotation.SizeMax))
+		if rotation.MaxFiles > 0 {
+			args = append(args, "--log-max-files", fmt.Sprintf("%v", rotation.MaxFiles))
+		}
+		if rotation.Compress {
+			args = append(args, "--log-compress")
+		}
 	}
 	if r.config.LogToJournald {
 		args = append(args, "--log-path", "journald:")
@@ -112,6 +154,9 @@ func (r *runtimeOCI) CreateContainer(c *Container, cgroupParent string) (err err
 	if r.config.NoPivot {
 		args = append(args, "--no-pivot")
 	}
+	if restore {
+		args = append(args, "--restore", c.CheckpointPath())
+	}
 	if c.terminal {
 		args = append(args, "-t")
 	} else if c.stdin {
@@ -122,9 +167,18 @@ func (r *runtimeOCI) CreateContainer(c *Container, cgroupParent string) (err err
 	}
 	logrus.WithFields(logrus.Fields{
 		"args": args,
-	}).Debugf("running conmon: %s", r.config.Conmon)
+	}).Debugf("running conmon: %s", r.conmon)
 
-	cmd := exec.Command(r.config.Conmon, args...)
+	// The span lasts until conmon reports the container pid
+	_, span := tracing.StartSpan(ctx, "conmon.CreateContainer")
+	span.SetAttribute("container.id", c.id)
+	span.SetAttribute("restore", restore)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	cmd := exec.Command(r.conmon, args...)
 	cmd.Dir = c.bundlePath
 	cmd.SysProcAttr = sysProcAttrPlatform()
 	cmd.Stdin = os.Stdin
@@ -135,7 +189,7 @@ func (r *runtimeOCI) CreateContainer(c *Container, cgroupParent string) (err err
 	}
 	cmd.ExtraFiles = append(cmd.ExtraFiles, childPipe, childStartPipe)
 	// 0, 1 and 2 are stdin, stdout and stderr
-	cmd.Env = r.config.ConmonEnv
+	cmd.Env = r.conmonEnv
 	cmd.Env = append(cmd.Env,
 		fmt.Sprintf("_OCI_SYNCPIPE=%d", 3),
 		fmt.Sprintf("_OCI_STARTPIPE=%d", 4))
@@ -401,20 +455,40 @@ func (r *runtimeOCI) ExecSyncContainer(c *Container, command []string, timeout i
 		"--exec-process-spec", processFile.Name(),
 		"--runtime-arg", fmt.Sprintf("%s=%s", rootFlag, r.root))
 
-	cmd := exec.Command(r.config.Conmon, args...)
+	cmd := exec.Command(r.conmon, args...)
 
 	var stdoutBuf, stderrBuf bytes.Buffer
 	cmd.Stdout = &stdoutBuf
 	cmd.Stderr = &stderrBuf
 	cmd.ExtraFiles = append(cmd.ExtraFiles, childPipe)
 	// 0, 1 and 2 are stdin, stdout and stderr
-	cmd.Env = r.config.ConmonEnv
+	cmd.Env = r.conmonEnv
 	cmd.Env = append(cmd.Env, fmt.Sprintf("_OCI_SYNCPIPE=%d", 3))
 	if v, found := os.LookupEnv("XDG_RUNTIME_DIR"); found {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("XDG_RUNTIME_DIR=%s", v))
 	}
 
+	// conmon blocks on the start pipe until it has been moved into the
+	// cgroup of the container, so that the exec process gets spawned there
+	var parentStartPipe, childStartPipe *os.File
+	if r.monitorExecCgroup == config.MonitorExecCgroupContainer {
+		parentStartPipe, childStartPipe, err = newPipe()
+		if err != nil {
+			childPipe.Close()
+			return nil, &ExecSyncError{
+				ExitCode: -1,
+				Err:      err,
+			}
+		}
+		defer parentStartPipe.Close()
+		cmd.ExtraFiles = append(cmd.ExtraFiles, childStartPipe)
+		cmd.Env = append(cmd.Env, fmt.Sprintf("_OCI_STARTPIPE=%d", 4))
+	}
+
 	err = cmd.Start()
+	if childStartPipe != nil {
+		childStartPipe.Close()
+	}
 	if err != nil {
 		childPipe.Close()
 		return nil, &ExecSyncError{
@@ -428,6 +502,23 @@ func (r *runtimeOCI) ExecSyncContainer(c *Container, command []string, timeout i
 	// We don't need childPipe on the parent side
 	childPipe.Close()
 
+	if parentStartPipe != nil {
+		if err := moveToContainerCgroup(c, cmd.Process.Pid); err != nil {
+			logrus.Warnf("Failed to move exec monitor into cgroup of container %s: %v", c.id, err)
+		}
+		if _, err := parentStartPipe.Write([]byte{0}); err != nil {
+			if waitErr := cmd.Wait(); waitErr != nil {
+				err = errors.Wrap(err, waitErr.Error())
+			}
+			return nil, &ExecSyncError{
+				Stdout:   stdoutBuf,
+				Stderr:   stderrBuf,
+				ExitCode: -1,
+				Err:      err,
+			}
+		}
+	}
+
 	err = cmd.Wait()
 	if err != nil {
 		return nil, &ExecSyncError{
@@ -486,6 +577,9 @@ func (r *runtimeOCI) ExecSyncContainer(c *Container, command []string, timeout i
 
 // UpdateContainer updates container resources
 func (r *runtimeOCI) UpdateContainer(c *Container, res *rspec.LinuxResources) error {
+	if cgroupv2.Enabled() {
+		return updateContainerV2(c, res)
+	}
 	cmd := exec.Command(r.path, rootFlag, r.root, "update", "--resources", "-", c.id)
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
@@ -506,6 +600,20 @@ func (r *runtimeOCI) UpdateContainer(c *Container, res *rspec.LinuxResources) er
 	return nil
 }
 
+// updateContainerV2 applies the resources directly to the cgroup of the
+// container, because the OCI runtime update only supports cgroup v1
+// controllers.
+func updateContainerV2(c *Container, res *rspec.LinuxResources) error {
+	cgroupPath, err := cgroupv2.PidCgroup(c.State().Pid)
+	if err != nil {
+		return fmt.Errorf("unable to find cgroup of container %q: %v", c.id, err)
+	}
+	if err := cgroupv2.Update(filepath.Join(cgroupv2.Root, cgroupPath), res); err != nil {
+		return fmt.Errorf("updating resources for container %q failed: %v", c.id, err)
+	}
+	return nil
+}
+
 func waitContainerStop(ctx context.Context, c *Container, timeout time.Duration, ignoreKill bool) error {
 	done := make(chan struct{})
 	// we could potentially re-use "done" channel to exit the loop on timeout,
@@ -723,6 +831,43 @@ func (r *runtimeOCI) WaitContainerStateStopped(ctx context.Context, c *Container
 	return nil
 }
 
+// CheckpointContainer checkpoints a container by using CRIU. The container
+// process gets terminated unless `leaveRunning` is set.
+func (r *runtimeOCI) CheckpointContainer(c *Container, leaveRunning bool) error {
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+
+	args := []string{rootFlag, r.root, "checkpoint",
+		"--image-path", c.CheckpointPath(),
+		"--work-path", c.dir,
+	}
+	if leaveRunning {
+		args = append(args, "--leave-running")
+	}
+	args = append(args, c.id)
+
+	if _, err := utils.ExecCmd(r.path, args...); err != nil {
+		return fmt.Errorf("failed to checkpoint container %s: %v", c.id, err)
+	}
+	return nil
+}
+
+// RestoreContainer creates a container and restores its process from the
+// checkpoint path of the container.
+func (r *runtimeOCI) RestoreContainer(c *Container, cgroupParent string) error {
+	if _, err := os.Stat(c.CheckpointPath()); err != nil {
+		return fmt.Errorf("failed to find checkpoint of container %s: %v", c.id, err)
+	}
+	if err := r.createContainer(context.Background(), c, cgroupParent, true); err != nil {
+		return err
+	}
+
+	c.opLock.Lock()
+	c.state.Started = time.Now()
+	c.opLock.Unlock()
+	return nil
+}
+
 // ContainerStats provides statistics of a container.
 func (r *runtimeOCI) ContainerStats(c *Container) (*ContainerStats, error) {
 	c.opLock.Lock()
@@ -808,13 +953,22 @@ func (r *runtimeOCI) AttachContainer(c *Container, inputStream io.Reader, output
 
 // PortForwardContainer forwards the specified port provides statistics of a container.
 func (r *runtimeOCI) PortForwardContainer(c *Container, port int32, stream io.ReadWriter) error {
-	containerPid := c.State().Pid
+	netNsPath, err := c.NetNsPath()
+	if err != nil {
+		return err
+	}
+	return portForward(netNsPath, port, stream)
+}
+
+// portForward forwards the specified port within the network namespace at
+// `netNsPath` to the stream.
+func portForward(netNsPath string, port int32, stream io.ReadWriter) error {
 	socatPath, lookupErr := exec.LookPath("socat")
 	if lookupErr != nil {
 		return fmt.Errorf("unable to do port forwarding: socat not found")
 	}
 
-	args := []string{"-t", fmt.Sprintf("%d", containerPid), "-n", socatPath, "-", fmt.Sprintf("TCP4:localhost:%d", port)}
+	args := []string{"--net=" + netNsPath, socatPath, "-", fmt.Sprintf("TCP4:localhost:%d", port)}
 
 	nsenterPath, lookupErr := exec.LookPath("nsenter")
 	if lookupErr != nil {
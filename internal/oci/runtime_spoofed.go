@@ -0,0 +1,158 @@
+package oci
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"time"
+
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// ErrSpoofedContainer is returned for operations which require a container
+// process, when they are called for a spoofed container.
+var ErrSpoofedContainer = errors.New("operation is not supported by spoofed containers without a process")
+
+// runtimeSpoofed is the Runtime interface implementation for spoofed
+// containers. Those are used as placeholder infra containers of pod sandboxes
+// without a process, which means that their state is fully managed by CRI-O.
+type runtimeSpoofed struct{}
+
+// newRuntimeSpoofed creates a new runtimeSpoofed instance
+func newRuntimeSpoofed() RuntimeImpl {
+	return &runtimeSpoofed{}
+}
+
+// CreateContainer marks the spoofed container as created.
+func (r *runtimeSpoofed) CreateContainer(ctx context.Context, c *Container, cgroupParent string) error {
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+
+	c.state.Status = ContainerStateCreated
+	return nil
+}
+
+// StartContainer marks the spoofed container as running.
+func (r *runtimeSpoofed) StartContainer(c *Container) error {
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+
+	c.state.Status = ContainerStateRunning
+	c.state.Started = time.Now()
+	return nil
+}
+
+// ExecContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) ExecContainer(c *Container, cmd []string, stdin io.Reader, stdout, stderr io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize) error {
+	return ErrSpoofedContainer
+}
+
+// ExecSyncContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) ExecSyncContainer(c *Container, command []string, timeout int64) (*ExecSyncResponse, error) {
+	return nil, ErrSpoofedContainer
+}
+
+// UpdateContainer is a no-op, because the resources of the pod sandbox are
+// not limited by its spoofed container.
+func (r *runtimeSpoofed) UpdateContainer(c *Container, res *rspec.LinuxResources) error {
+	return nil
+}
+
+// StopContainer marks the spoofed container as stopped.
+func (r *runtimeSpoofed) StopContainer(ctx context.Context, c *Container, timeout int64) error {
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+
+	c.state.Status = ContainerStateStopped
+	c.state.Finished = time.Now()
+	return nil
+}
+
+// DeleteContainer is a no-op, because spoofed containers are unknown to any
+// OCI runtime.
+func (r *runtimeSpoofed) DeleteContainer(c *Container) error {
+	return nil
+}
+
+// UpdateContainerStatus marks a running spoofed container as stopped if its
+// pinned namespaces are gone, for example after a reboot. Otherwise, the
+// status of spoofed containers is only changed by CRI-O itself.
+func (r *runtimeSpoofed) UpdateContainerStatus(c *Container) error {
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+
+	if c.state.Status != ContainerStateRunning || c.spec == nil || c.spec.Linux == nil {
+		return nil
+	}
+	for _, ns := range c.spec.Linux.Namespaces {
+		if ns.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(ns.Path); os.IsNotExist(err) {
+			c.state.Status = ContainerStateStopped
+			c.state.Finished = time.Now()
+			c.state.ExitCode = 255
+			return nil
+		}
+	}
+	return nil
+}
+
+// PauseContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) PauseContainer(c *Container) error {
+	return ErrSpoofedContainer
+}
+
+// UnpauseContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) UnpauseContainer(c *Container) error {
+	return ErrSpoofedContainer
+}
+
+// ContainerStats returns empty statistics, because there is no process
+// consuming any resources.
+func (r *runtimeSpoofed) ContainerStats(c *Container) (*ContainerStats, error) {
+	return &ContainerStats{Container: c.ID()}, nil
+}
+
+// SignalContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) SignalContainer(c *Container, sig syscall.Signal) error {
+	return ErrSpoofedContainer
+}
+
+// AttachContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) AttachContainer(c *Container, inputStream io.Reader, outputStream, errorStream io.WriteCloser, tty bool, resize <-chan remotecommand.TerminalSize) error {
+	return ErrSpoofedContainer
+}
+
+// PortForwardContainer forwards the specified port within the pinned network
+// namespace of the spoofed container.
+func (r *runtimeSpoofed) PortForwardContainer(c *Container, port int32, stream io.ReadWriter) error {
+	if c.netns == "" {
+		return errors.Errorf("spoofed container %s has no network namespace", c.ID())
+	}
+	return portForward(c.netns, port, stream)
+}
+
+// ReopenContainerLog is a no-op, because spoofed containers do not log.
+func (r *runtimeSpoofed) ReopenContainerLog(c *Container) error {
+	return nil
+}
+
+// WaitContainerStateStopped is a no-op, because stopping a spoofed container
+// is immediate.
+func (r *runtimeSpoofed) WaitContainerStateStopped(ctx context.Context, c *Container) error {
+	return nil
+}
+
+// CheckpointContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) CheckpointContainer(c *Container, leaveRunning bool) error {
+	return ErrSpoofedContainer
+}
+
+// RestoreContainer is not supported by spoofed containers.
+func (r *runtimeSpoofed) RestoreContainer(c *Container, cgroupParent string) error {
+	return ErrSpoofedContainer
+}
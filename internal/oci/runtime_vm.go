@@ -2,15 +2,20 @@ package oci
 
 import (
 	"bytes"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	tasktypes "github.com/containerd/containerd/api/types/task"
+	containerdio "github.com/containerd/containerd/cio"
 	"github.com/containerd/containerd/namespaces"
 	client "github.com/containerd/containerd/runtime/v2/shim"
 	"github.com/containerd/containerd/runtime/v2/task"
@@ -35,28 +40,56 @@ import (
 const (
 	fifoGlobalDir = "/tmp/crio/fifo"
 
+	// shimStateFile is the file in the bundle of a container persisting
+	// its shimState
+	shimStateFile = "shim.json"
+
 	// RuntimeTypeVM is the type representing the RuntimeVM implementation.
 	RuntimeTypeVM = "vm"
 )
 
+// ErrCheckpointNotSupported is returned when checkpointing or restoring a
+// container of a runtime which does not support it.
+var ErrCheckpointNotSupported = errors.Errorf("checkpoint/restore is not supported by runtime type %q", RuntimeTypeVM)
+
 // runtimeVM is the Runtime interface implementation that is more appropriate
 // for VM based container runtimes.
 type runtimeVM struct {
 	path string
+	// eventsAddress is where the shim publishes the events of the container
+	eventsAddress string
+	// exitsDir is the directory of the exit files, next to which the OOM
+	// kills reported by the shim are recorded
+	exitsDir string
+	// logRotation are the log rotation settings of the configuration, used
+	// for containers without their own
+	logRotation LogRotation
 
 	ctx    context.Context
 	client *ttrpc.Client
 	task   task.TaskService
 
 	ctrs map[string]containerInfo
+
+	// restoreLock serializes reconnecting to the shim after a restart
+	restoreLock sync.Mutex
 }
 
 type containerInfo struct {
 	cio *cio.ContainerIO
 }
 
+// shimState is persisted in the bundle of a container to reconnect to its
+// runtime shim after CRI-O got restarted.
+type shimState struct {
+	// Address is the ttrpc address of the shim
+	Address string `json:"address"`
+	// IO are the fifos the shim forwards the stdio of the container to
+	IO containerdio.Config `json:"io"`
+}
+
 // newRuntimeVM creates a new runtimeVM instance
-func newRuntimeVM(path string) RuntimeImpl {
+func newRuntimeVM(path, eventsAddress, exitsDir string, logRotation LogRotation) RuntimeImpl {
 	logrus.Debug("oci.newRuntimeVM() start")
 	defer logrus.Debug("oci.newRuntimeVM() end")
 
@@ -73,14 +106,17 @@ func newRuntimeVM(path string) RuntimeImpl {
 	typeurl.Register(&rspec.WindowsResources{}, prefix, "opencontainers/runtime-spec", major, "WindowsResources")
 
 	return &runtimeVM{
-		path: path,
-		ctx:  context.Background(),
-		ctrs: make(map[string]containerInfo),
+		path:          path,
+		eventsAddress: eventsAddress,
+		exitsDir:      exitsDir,
+		logRotation:   logRotation,
+		ctx:           namespaces.WithNamespace(context.Background(), namespaces.Default),
+		ctrs:          make(map[string]containerInfo),
 	}
 }
 
 // CreateContainer creates a container.
-func (r *runtimeVM) CreateContainer(c *Container, cgroupParent string) (err error) {
+func (r *runtimeVM) CreateContainer(ctx context.Context, c *Container, cgroupParent string) (err error) {
 	logrus.Debug("runtimeVM.createContainer() start")
 	defer logrus.Debug("runtimeVM.createContainer() end")
 
@@ -89,7 +125,8 @@ func (r *runtimeVM) CreateContainer(c *Container, cgroupParent string) (err erro
 	defer c.opLock.Unlock()
 
 	// First thing, we need to start the runtime daemon
-	if err := r.startRuntimeDaemon(c); err != nil {
+	address, err := r.startRuntimeDaemon(c)
+	if err != nil {
 		return err
 	}
 
@@ -106,18 +143,25 @@ func (r *runtimeVM) CreateContainer(c *Container, cgroupParent string) (err erro
 		}
 	}()
 
-	f, err := os.OpenFile(c.LogPath(), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	stdout, stderr, err := r.newContainerLoggers(c)
 	if err != nil {
 		return err
 	}
 
-	containerIO.AddOutput("logfile", f, f)
+	containerIO.AddOutput("log", stdout, stderr)
 	containerIO.Pipe()
 
 	r.ctrs[c.ID()] = containerInfo{
 		cio: containerIO,
 	}
 
+	if err := writeShimState(c.BundlePath(), &shimState{
+		Address: address,
+		IO:      containerIO.Config(),
+	}); err != nil {
+		return errors.Wrap(err, "unable to persist runtime shim state")
+	}
+
 	defer func() {
 		if err != nil {
 			delete(r.ctrs, c.ID())
@@ -160,7 +204,9 @@ func (r *runtimeVM) CreateContainer(c *Container, cgroupParent string) (err erro
 	return nil
 }
 
-func (r *runtimeVM) startRuntimeDaemon(c *Container) error {
+// startRuntimeDaemon starts the runtime shim of the container `c`, connects
+// to it and returns its address.
+func (r *runtimeVM) startRuntimeDaemon(c *Container) (string, error) {
 	logrus.Debug("runtimeVM.startRuntimeDaemon() start")
 	defer logrus.Debug("runtimeVM.startRuntimeDaemon() end")
 
@@ -174,21 +220,42 @@ func (r *runtimeVM) startRuntimeDaemon(c *Container) error {
 	// Modify the runtime path so that it complies with v2 shim API
 	newRuntimePath := strings.Replace(r.path, "-", ".", -1) // nolint: gocritic
 
-	// Setup default namespace
-	r.ctx = namespaces.WithNamespace(r.ctx, namespaces.Default)
-
 	// Prepare the command to exec
 	cmd, err := client.Command(
 		r.ctx,
 		newRuntimePath,
-		"",
+		r.eventsAddress,
 		c.BundlePath(),
 		args...,
 	)
 	if err != nil {
-		return err
+		return "", err
+	}
+
+	if err := r.forwardShimLog(c); err != nil {
+		return "", err
 	}
 
+	// Start the server
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrapf(err, "%s", out)
+	}
+
+	// Retrieve the address from the output
+	address := strings.TrimSpace(string(out))
+
+	// Now the RPC server is running, let's connect to it
+	if err := r.connect(address); err != nil {
+		return "", err
+	}
+
+	return address, nil
+}
+
+// forwardShimLog copies the logs of the runtime shim of the container `c` to
+// the output of CRI-O.
+func (r *runtimeVM) forwardShimLog(c *Container) error {
 	// Create the log file expected by shim-v2 API
 	f, err := fifo.OpenFifo(r.ctx, filepath.Join(c.BundlePath(), "log"),
 		unix.O_RDONLY|unix.O_CREAT|unix.O_NONBLOCK, 0700)
@@ -206,16 +273,11 @@ func (r *runtimeVM) startRuntimeDaemon(c *Container) error {
 		}
 	}()
 
-	// Start the server
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		return errors.Wrapf(err, "%s", out)
-	}
-
-	// Retrieve the address from the output
-	address := strings.TrimSpace(string(out))
+	return nil
+}
 
-	// Now the RPC server is running, let's connect to it
+// connect connects to the task service of the runtime shim at `address`.
+func (r *runtimeVM) connect(address string) error {
 	conn, err := client.Connect(address, client.AnonDialer)
 	if err != nil {
 		return err
@@ -231,6 +293,94 @@ func (r *runtimeVM) startRuntimeDaemon(c *Container) error {
 	return nil
 }
 
+// restore reconnects to the runtime shim of the container `c` if CRI-O got
+// restarted since the container has been created. It re-creates the
+// forwarding of the container output into its log.
+func (r *runtimeVM) restore(c *Container) error {
+	r.restoreLock.Lock()
+	defer r.restoreLock.Unlock()
+
+	if r.task != nil {
+		return nil
+	}
+	logrus.Debugf("reconnecting to runtime shim of container %s", c.ID())
+
+	state, err := readShimState(c.BundlePath())
+	if err != nil {
+		return errors.Wrapf(err, "unable to read runtime shim state of container %s", c.ID())
+	}
+	if err := r.forwardShimLog(c); err != nil {
+		return err
+	}
+	if err := r.connect(state.Address); err != nil {
+		return errors.Wrapf(err, "unable to reconnect to runtime shim of container %s", c.ID())
+	}
+
+	containerIO, err := cio.NewContainerIO(c.ID(), cio.WithExistingFIFOs(state.IO))
+	if err != nil {
+		return err
+	}
+	stdout, stderr, err := r.newContainerLoggers(c)
+	if err != nil {
+		containerIO.Close()
+		return err
+	}
+	containerIO.AddOutput("log", stdout, stderr)
+	containerIO.Pipe()
+	r.ctrs[c.ID()] = containerInfo{
+		cio: containerIO,
+	}
+
+	return nil
+}
+
+// isShimGone returns whether the error `err` of reconnecting to a runtime
+// shim means that the shim or its state does not exist anymore.
+func isShimGone(err error) bool {
+	err = errors.Cause(err)
+	if opErr, ok := err.(*net.OpError); ok {
+		err = opErr.Err
+	}
+	if sysErr, ok := err.(*os.SyscallError); ok {
+		err = sysErr.Err
+	}
+	return os.IsNotExist(err) || err == syscall.ECONNREFUSED
+}
+
+// vmOOMFilePath returns the path of the file which records that the shim
+// reported an OOM kill of the container `id`. Like the oom file of conmon, it
+// survives restarts of CRI-O.
+func vmOOMFilePath(exitsDir, id string) string {
+	return filepath.Join(exitsDir, id+".oom")
+}
+
+// writeShimState persists the shimState `state` in the bundle at `bundle`.
+func writeShimState(bundle string, state *shimState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(bundle, shimStateFile)
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readShimState reads the shimState persisted in the bundle at `bundle`.
+func readShimState(bundle string) (*shimState, error) {
+	data, err := ioutil.ReadFile(filepath.Join(bundle, shimStateFile))
+	if err != nil {
+		return nil, err
+	}
+	var state shimState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
 // StartContainer starts a container.
 func (r *runtimeVM) StartContainer(c *Container) error {
 	logrus.Debug("runtimeVM.startContainer() start")
@@ -240,21 +390,23 @@ func (r *runtimeVM) StartContainer(c *Container) error {
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
-	if err := r.start(r.ctx, c.ID(), ""); err != nil {
+	if err := r.restore(c); err != nil {
 		return err
 	}
 
-	// Spawn a goroutine waiting for the container to terminate. Once it
-	// happens, the container status is retrieved to be updated.
-	var err error
-	go func() {
-		_, _, err = r.wait(r.ctx, c.ID(), "")
-		if err == nil {
-			err = r.UpdateContainerStatus(c)
-		}
-	}()
+	// The exit of the container is published by the shim as event, which
+	// gets fed into the exit monitor.
+	pid, err := r.start(r.ctx, c.ID(), "")
+	if err != nil {
+		return err
+	}
+	// The pid is the one of the VM for most runtimes, which allows watching
+	// its memory cgroup for OOM kills right away
+	if pid > 0 {
+		c.state.Pid = int(pid)
+	}
 
-	return errors.Wrapf(err, "start container")
+	return nil
 }
 
 // ExecContainer prepares a streaming endpoint to execute a command in the container.
@@ -305,6 +457,10 @@ func (r *runtimeVM) execContainerCommon(c *Container, cmd []string, timeout int6
 	logrus.Debug("runtimeVM.execContainer() start")
 	defer logrus.Debug("runtimeVM.execContainer() end")
 
+	if err := r.restore(c); err != nil {
+		return -1, err
+	}
+
 	// Cancel the context before returning to ensure goroutines are stopped.
 	ctx, cancel := context.WithCancel(r.ctx)
 	defer cancel()
@@ -365,7 +521,7 @@ func (r *runtimeVM) execContainerCommon(c *Container, cmd []string, timeout int6
 	}()
 
 	// Start the process
-	if err := r.start(ctx, c.ID(), execID); err != nil {
+	if _, err := r.start(ctx, c.ID(), execID); err != nil {
 		return -1, err
 	}
 
@@ -434,6 +590,10 @@ func (r *runtimeVM) UpdateContainer(c *Container, res *rspec.LinuxResources) err
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
 	// Convert resources into protobuf Any type
 	any, err := typeurl.MarshalAny(res)
 	if err != nil {
@@ -459,6 +619,10 @@ func (r *runtimeVM) StopContainer(ctx context.Context, c *Container, timeout int
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
 	// Cancel the context before returning to ensure goroutines are stopped.
 	ctx, cancel := context.WithCancel(r.ctx)
 	defer cancel()
@@ -522,6 +686,15 @@ func (r *runtimeVM) DeleteContainer(c *Container) error {
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		if !isShimGone(err) {
+			return err
+		}
+		// Nothing is left to delete if the shim is gone
+		logrus.Warnf("unable to delete container %s: %v", c.ID(), err)
+		return nil
+	}
+
 	cInfo, ok := r.ctrs[c.ID()]
 	if !ok {
 		return errors.New("Could not retrieve container information")
@@ -553,6 +726,14 @@ func (r *runtimeVM) UpdateContainerStatus(c *Container) error {
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		if c.state.Status == ContainerStateStopped {
+			// The shim of an exited container may be gone already
+			return nil
+		}
+		return err
+	}
+
 	response, err := r.task.State(r.ctx, &task.StateRequest{
 		ID: c.ID(),
 	})
@@ -576,8 +757,18 @@ func (r *runtimeVM) UpdateContainerStatus(c *Container) error {
 	}
 
 	c.state.Status = status
+	// The pid is the one of the VM for most runtimes, which allows
+	// watching its memory cgroup for OOM kills
+	if response.Pid > 0 {
+		c.state.Pid = int(response.Pid)
+	}
 	c.state.Finished = response.ExitedAt
 	c.state.ExitCode = int32(response.ExitStatus)
+	if status == ContainerStateStopped {
+		if _, err := os.Stat(vmOOMFilePath(r.exitsDir, c.ID())); err == nil {
+			c.state.OOMKilled = true
+		}
+	}
 
 	return nil
 }
@@ -591,6 +782,10 @@ func (r *runtimeVM) PauseContainer(c *Container) error {
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
 	if _, err := r.task.Pause(r.ctx, &task.PauseRequest{
 		ID: c.ID(),
 	}); err != nil {
@@ -609,6 +804,10 @@ func (r *runtimeVM) UnpauseContainer(c *Container) error {
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
 	if _, err := r.task.Resume(r.ctx, &task.ResumeRequest{
 		ID: c.ID(),
 	}); err != nil {
@@ -627,6 +826,10 @@ func (r *runtimeVM) ContainerStats(c *Container) (*ContainerStats, error) {
 	c.opLock.RLock()
 	defer c.opLock.RUnlock()
 
+	if err := r.restore(c); err != nil {
+		return nil, err
+	}
+
 	resp, err := r.task.Stats(r.ctx, &task.StatsRequest{
 		ID: c.ID(),
 	})
@@ -659,6 +862,10 @@ func (r *runtimeVM) SignalContainer(c *Container, sig syscall.Signal) error {
 	c.opLock.Lock()
 	defer c.opLock.Unlock()
 
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
 	return r.kill(r.ctx, c.ID(), "", sig, true)
 }
 
@@ -667,6 +874,10 @@ func (r *runtimeVM) AttachContainer(c *Container, inputStream io.Reader, outputS
 	logrus.Debug("runtimeVM.AttachContainer() start")
 	defer logrus.Debug("runtimeVM.AttachContainer() end")
 
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
 	// Initialize terminal resizing
 	kubecontainer.HandleResizing(resize, func(size remotecommand.TerminalSize) {
 		logrus.Debugf("Got a resize event: %+v", size)
@@ -708,6 +919,34 @@ func (r *runtimeVM) ReopenContainerLog(c *Container) error {
 	logrus.Debug("runtimeVM.ReopenContainerLog() start")
 	defer logrus.Debug("runtimeVM.ReopenContainerLog() end")
 
+	// Lock the container
+	c.opLock.Lock()
+	defer c.opLock.Unlock()
+
+	if err := r.restore(c); err != nil {
+		return err
+	}
+
+	cInfo, ok := r.ctrs[c.ID()]
+	if !ok {
+		return errors.New("Could not retrieve container information")
+	}
+
+	stdout, stderr, err := r.newContainerLoggers(c)
+	if err != nil {
+		return errors.Wrapf(err, "failed to reopen log file of container %s", c.ID())
+	}
+
+	// Replace the loggers, which closes the previous log file once they
+	// are done
+	oldStdout, oldStderr := cInfo.cio.AddOutput("log", stdout, stderr)
+	if oldStdout != nil {
+		oldStdout.Close()
+	}
+	if oldStderr != nil {
+		oldStderr.Close()
+	}
+
 	return nil
 }
 
@@ -715,15 +954,26 @@ func (r *runtimeVM) WaitContainerStateStopped(ctx context.Context, c *Container)
 	return nil
 }
 
-func (r *runtimeVM) start(ctx context.Context, ctrID, execID string) error {
-	if _, err := r.task.Start(ctx, &task.StartRequest{
+// CheckpointContainer is not supported by VM based runtimes.
+func (r *runtimeVM) CheckpointContainer(c *Container, leaveRunning bool) error {
+	return ErrCheckpointNotSupported
+}
+
+// RestoreContainer is not supported by VM based runtimes.
+func (r *runtimeVM) RestoreContainer(c *Container, cgroupParent string) error {
+	return ErrCheckpointNotSupported
+}
+
+func (r *runtimeVM) start(ctx context.Context, ctrID, execID string) (uint32, error) {
+	resp, err := r.task.Start(ctx, &task.StartRequest{
 		ID:     ctrID,
 		ExecID: execID,
-	}); err != nil {
-		return errdefs.FromGRPC(err)
+	})
+	if err != nil {
+		return 0, errdefs.FromGRPC(err)
 	}
 
-	return nil
+	return resp.Pid, nil
 }
 
 func (r *runtimeVM) wait(ctx context.Context, ctrID, execID string) (int32, time.Time, error) {
@@ -762,7 +1012,7 @@ func (r *runtimeVM) remove(ctx context.Context, ctrID, execID string) error {
 	return nil
 }
 
-func (r runtimeVM) resizePty(ctx context.Context, ctrID, execID string, size remotecommand.TerminalSize) error {
+func (r *runtimeVM) resizePty(ctx context.Context, ctrID, execID string, size remotecommand.TerminalSize) error {
 	_, err := r.task.ResizePty(ctx, &task.ResizePtyRequest{
 		ID:     ctrID,
 		ExecID: execID,
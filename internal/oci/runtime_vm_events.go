@@ -0,0 +1,189 @@
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	ptypes "github.com/gogo/protobuf/types"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// shimEventsSocket is the socket in the ContainerAttachSocketDir on
+	// which the events published by runtime shims are received
+	shimEventsSocket = "shim-events.sock"
+
+	// shimEventTimeout is the time after which reading an event from a
+	// runtime shim is given up
+	shimEventTimeout = 5 * time.Second
+
+	// shimEventsBuffer is the number of received events which may wait
+	// for being handled
+	shimEventsBuffer = 64
+
+	taskExitEventTopic = "/tasks/exit"
+	taskOOMEventTopic  = "/tasks/oom"
+)
+
+// shimEvent is an event published by a runtime shim, which forwards it by
+// executing `crio --address <socket> publish`.
+type shimEvent struct {
+	Topic     string `json:"topic"`
+	Namespace string `json:"namespace"`
+	// Event is the marshaled types.Any of the event
+	Event []byte `json:"event"`
+}
+
+// taskExit mirrors the TaskExit event of containerd, which is not vendored.
+type taskExit struct {
+	ContainerID string            `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3"`
+	ID          string            `protobuf:"bytes,2,opt,name=id,proto3"`
+	Pid         uint32            `protobuf:"varint,3,opt,name=pid,proto3"`
+	ExitStatus  uint32            `protobuf:"varint,4,opt,name=exit_status,json=exitStatus,proto3"`
+	ExitedAt    *ptypes.Timestamp `protobuf:"bytes,5,opt,name=exited_at,json=exitedAt,proto3"`
+}
+
+func (e *taskExit) Reset()         { *e = taskExit{} }
+func (e *taskExit) String() string { return proto.CompactTextString(e) }
+func (*taskExit) ProtoMessage()    {}
+
+// taskOOM mirrors the TaskOOM event of containerd, which is not vendored.
+type taskOOM struct {
+	ContainerID string `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3"`
+}
+
+func (e *taskOOM) Reset()         { *e = taskOOM{} }
+func (e *taskOOM) String() string { return proto.CompactTextString(e) }
+func (*taskOOM) ProtoMessage()    {}
+
+// PublishShimEvent forwards the marshaled types.Any `event` published by a
+// runtime shim on `topic` to the CRI-O instance listening on `address`.
+func PublishShimEvent(address, topic, namespace string, event io.Reader) error {
+	data, err := ioutil.ReadAll(event)
+	if err != nil {
+		return errors.Wrap(err, "unable to read event")
+	}
+	conn, err := net.Dial("unix", address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return json.NewEncoder(conn).Encode(&shimEvent{
+		Topic:     topic,
+		Namespace: namespace,
+		Event:     data,
+	})
+}
+
+// listenShimEvents starts receiving the events published by runtime shims
+// unless it already happened, and returns the address the shims have to
+// publish them to.
+func (r *Runtime) listenShimEvents() (string, error) {
+	r.shimEventsLock.Lock()
+	defer r.shimEventsLock.Unlock()
+
+	address := filepath.Join(r.config.ContainerAttachSocketDir, shimEventsSocket)
+	if r.shimEvents != nil {
+		return address, nil
+	}
+	if err := os.MkdirAll(r.config.ContainerAttachSocketDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.Remove(address); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	listener, err := net.Listen("unix", address)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to listen for runtime shim events")
+	}
+	r.shimEvents = listener
+
+	// The shims publish the events of a container one after the other, so
+	// they are received and handled in the order of their connections. This
+	// keeps the OOM kill of a container ahead of its exit.
+	events := make(chan *shimEvent, shimEventsBuffer)
+	go func() {
+		defer close(events)
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				logrus.Debugf("stopped receiving runtime shim events: %v", err)
+				return
+			}
+			if event := receiveShimEvent(conn); event != nil {
+				events <- event
+			}
+		}
+	}()
+	go func() {
+		for event := range events {
+			if err := r.handleShimEvent(event); err != nil {
+				logrus.Warnf("unable to handle runtime shim event %s: %v", event.Topic, err)
+			}
+		}
+	}()
+
+	return address, nil
+}
+
+// receiveShimEvent reads a single shimEvent from `conn`, giving up after the
+// shimEventTimeout so that a stuck publisher does not hold back the others.
+func receiveShimEvent(conn net.Conn) *shimEvent {
+	defer conn.Close()
+
+	if err := conn.SetReadDeadline(time.Now().Add(shimEventTimeout)); err != nil {
+		logrus.Warnf("unable to set deadline of runtime shim event: %v", err)
+	}
+	var event shimEvent
+	if err := json.NewDecoder(conn).Decode(&event); err != nil {
+		logrus.Warnf("unable to decode runtime shim event: %v", err)
+		return nil
+	}
+	return &event
+}
+
+// handleShimEvent feeds the exit of a container into the exit monitor by
+// writing its exit file, and records the OOM kills of containers.
+func (r *Runtime) handleShimEvent(event *shimEvent) error {
+	var any ptypes.Any
+	if err := proto.Unmarshal(event.Event, &any); err != nil {
+		return err
+	}
+
+	switch event.Topic {
+	case taskExitEventTopic:
+		var exit taskExit
+		if err := proto.Unmarshal(any.Value, &exit); err != nil {
+			return err
+		}
+		// Exits of exec processes are reported to their callers
+		if exit.ID != exit.ContainerID {
+			return nil
+		}
+		logrus.Debugf("runtime shim reported exit of container %s", exit.ContainerID)
+		return ioutil.WriteFile(
+			filepath.Join(r.config.ContainerExitsDir, exit.ContainerID),
+			[]byte(fmt.Sprintf("%d", exit.ExitStatus)), 0644,
+		)
+
+	case taskOOMEventTopic:
+		var oom taskOOM
+		if err := proto.Unmarshal(any.Value, &oom); err != nil {
+			return err
+		}
+		logrus.Debugf("runtime shim reported OOM of container %s", oom.ContainerID)
+		return ioutil.WriteFile(
+			vmOOMFilePath(r.config.ContainerExitsDir, oom.ContainerID), []byte{}, 0644,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,244 @@
+package oci
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/cri-o/cri-o/internal/lib/config"
+	cio "github.com/cri-o/cri-o/utils/io"
+	"github.com/sirupsen/logrus"
+)
+
+// maxLogLineSize is the length after which lines of VM containers are split
+// into partial log lines, which matches the buffer size of conmon.
+const maxLogLineSize = config.OCIBufSize
+
+// logFile is the log file of a VM container. Like the one written by conmon,
+// it is rotated once it reaches the size limit if rotated files are kept, and
+// truncated otherwise.
+type logFile struct {
+	path     string
+	rotation LogRotation
+
+	lock    sync.Mutex
+	file    *os.File
+	written int64
+
+	// compressing is done once the last rotated file got compressed
+	compressing <-chan struct{}
+}
+
+// openLogFile opens the log file at `path`, accounting for its existing
+// content so that the size limit still holds.
+func openLogFile(path string, rotation LogRotation) (*logFile, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &logFile{
+		path:     path,
+		rotation: rotation,
+		file:     f,
+		written:  info.Size(),
+	}, nil
+}
+
+// Write writes the log lines `p`, rotating or truncating the log file first
+// if they would exceed its size limit. Every line starts with its own
+// timestamp and stream, so the new file stays in the CRI log format.
+func (l *logFile) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if l.rotation.SizeMax > 0 && l.written > 0 && l.written+int64(len(p)) > l.rotation.SizeMax {
+		if err := l.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := l.file.Write(p)
+	l.written += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (l *logFile) Close() error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.waitForCompression()
+	return l.file.Close()
+}
+
+// rotate starts a new log file. The current one becomes <path>.1 and the
+// previously rotated ones are shifted by one if rotated files are kept.
+func (l *logFile) rotate() error {
+	if err := l.file.Sync(); err != nil {
+		logrus.Warnf("failed to sync log file %s: %v", l.path, err)
+	}
+	l.file.Close()
+
+	path := l.path + ".tmp"
+	if l.rotation.MaxFiles > 0 {
+		// The rotated files may only be shifted once the last one is
+		// compressed
+		l.waitForCompression()
+		rotated := rotateLogFiles(l.path, l.rotation.MaxFiles, l.rotation.Compress)
+		if rotated != "" && l.rotation.Compress {
+			l.compressing = compressLogFileAsync(rotated)
+		}
+		path = l.path
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if path != l.path {
+		// Replace the previous file
+		if err := os.Rename(path, l.path); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	l.file = f
+	l.written = 0
+	return nil
+}
+
+// waitForCompression waits until the last rotated file got compressed.
+func (l *logFile) waitForCompression() {
+	if l.compressing != nil {
+		<-l.compressing
+		l.compressing = nil
+	}
+}
+
+// rotateLogFiles moves the log file at `path` to <path>.1 and shifts the
+// previously rotated files by one, dropping the oldest one once `maxFiles`
+// are kept. Rotated files get the .gz suffix if they are compressed, which is
+// up to the caller for <path>.1. It returns the path of the rotated file, or
+// an empty string if there was no log file to rotate.
+func rotateLogFiles(path string, maxFiles int, compress bool) string {
+	suffix := ""
+	if compress {
+		suffix = ".gz"
+	}
+
+	oldest := fmt.Sprintf("%s.%d%s", path, maxFiles, suffix)
+	if err := os.Remove(oldest); err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("failed to remove rotated log file %s: %v", oldest, err)
+	}
+	for i := maxFiles - 1; i > 0; i-- {
+		from := fmt.Sprintf("%s.%d%s", path, i, suffix)
+		to := fmt.Sprintf("%s.%d%s", path, i+1, suffix)
+		if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+			logrus.Warnf("failed to rename rotated log file %s: %v", from, err)
+		}
+	}
+
+	// The log file may be gone if it got moved away before a reopen
+	rotated := path + ".1"
+	if err := os.Rename(path, rotated); err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Warnf("failed to rotate log file %s: %v", path, err)
+		}
+		return ""
+	}
+	return rotated
+}
+
+// compressLogFileAsync compresses the rotated file at `path` in the
+// background, so that writing the log does not block on it. The returned
+// channel gets closed once the compression is done.
+func compressLogFileAsync(path string) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := compressLogFile(path); err != nil {
+			logrus.Warnf("failed to compress rotated log file %s: %v", path, err)
+		}
+	}()
+	return done
+}
+
+// compressLogFile compresses the file at `path` into <path>.gz and removes
+// the uncompressed file. The compressed file is written to a temporary file
+// first, so that <path>.gz only ever shows up complete.
+func compressLogFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		logrus.Warnf("failed to remove compressed log file %s: %v", path, err)
+	}
+	return nil
+}
+
+// newContainerLoggers opens the log file of the container `c` and returns
+// the writers logging its stdout and stderr into it in the CRI log format,
+// like conmon does. The log file is closed once both got closed.
+func (r *runtimeVM) newContainerLoggers(c *Container) (stdout, stderr io.WriteCloser, err error) {
+	rotation := c.LogRotation()
+	if rotation == nil {
+		rotation = &r.logRotation
+	}
+	f, err := openLogFile(c.LogPath(), *rotation)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var stdoutCh, stderrCh <-chan struct{}
+	stdout, stdoutCh = cio.NewCRILogger(c.LogPath(), f, cio.Stdout, maxLogLineSize)
+	if c.terminal {
+		// The output of a terminal is only written to stdout
+		stderr = cio.NewDiscardLogger()
+	} else {
+		stderr, stderrCh = cio.NewCRILogger(c.LogPath(), f, cio.Stderr, maxLogLineSize)
+	}
+	go func() {
+		<-stdoutCh
+		if stderrCh != nil {
+			<-stderrCh
+		}
+		if err := f.Close(); err != nil {
+			logrus.Warnf("failed to close log file %s: %v", c.LogPath(), err)
+		}
+	}()
+
+	return stdout, stderr, nil
+}
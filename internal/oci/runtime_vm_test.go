@@ -0,0 +1,503 @@
+package oci_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	tasktypes "github.com/containerd/containerd/api/types/task"
+	"github.com/containerd/containerd/runtime/v2/task"
+	"github.com/containerd/ttrpc"
+	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/gogo/protobuf/proto"
+	ptypes "github.com/gogo/protobuf/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/context"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+// fakeTaskService is an in-process task service of a runtime shim running a
+// single container
+type fakeTaskService struct {
+	lock   sync.Mutex
+	status tasktypes.Status
+	pid    uint32
+	exited chan struct{}
+	killed []uint32
+}
+
+func newFakeTaskService() *fakeTaskService {
+	return &fakeTaskService{
+		status: tasktypes.StatusRunning,
+		pid:    42,
+		exited: make(chan struct{}),
+	}
+}
+
+// exit terminates the container of the task service
+func (f *fakeTaskService) exit() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.status != tasktypes.StatusStopped {
+		f.status = tasktypes.StatusStopped
+		close(f.exited)
+	}
+}
+
+func (f *fakeTaskService) State(ctx context.Context, req *task.StateRequest) (*task.StateResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return &task.StateResponse{ID: req.ID, Status: f.status, Pid: f.pid}, nil
+}
+
+func (f *fakeTaskService) Create(ctx context.Context, req *task.CreateTaskRequest) (*task.CreateTaskResponse, error) {
+	return &task.CreateTaskResponse{Pid: f.pid}, nil
+}
+
+func (f *fakeTaskService) Start(ctx context.Context, req *task.StartRequest) (*task.StartResponse, error) {
+	return &task.StartResponse{Pid: f.pid}, nil
+}
+
+func (f *fakeTaskService) Delete(ctx context.Context, req *task.DeleteRequest) (*task.DeleteResponse, error) {
+	return &task.DeleteResponse{}, nil
+}
+
+func (f *fakeTaskService) Pids(ctx context.Context, req *task.PidsRequest) (*task.PidsResponse, error) {
+	return &task.PidsResponse{}, nil
+}
+
+func (f *fakeTaskService) Pause(ctx context.Context, req *task.PauseRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) Resume(ctx context.Context, req *task.ResumeRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) Checkpoint(ctx context.Context, req *task.CheckpointTaskRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) Kill(ctx context.Context, req *task.KillRequest) (*ptypes.Empty, error) {
+	f.lock.Lock()
+	f.killed = append(f.killed, req.Signal)
+	f.lock.Unlock()
+	f.exit()
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) Exec(ctx context.Context, req *task.ExecProcessRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) ResizePty(ctx context.Context, req *task.ResizePtyRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) CloseIO(ctx context.Context, req *task.CloseIORequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) Update(ctx context.Context, req *task.UpdateTaskRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+func (f *fakeTaskService) Wait(ctx context.Context, req *task.WaitRequest) (*task.WaitResponse, error) {
+	select {
+	case <-f.exited:
+		return &task.WaitResponse{ExitStatus: 137}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (f *fakeTaskService) Stats(ctx context.Context, req *task.StatsRequest) (*task.StatsResponse, error) {
+	return &task.StatsResponse{}, nil
+}
+
+func (f *fakeTaskService) Connect(ctx context.Context, req *task.ConnectRequest) (*task.ConnectResponse, error) {
+	return &task.ConnectResponse{}, nil
+}
+
+func (f *fakeTaskService) Shutdown(ctx context.Context, req *task.ShutdownRequest) (*ptypes.Empty, error) {
+	return &ptypes.Empty{}, nil
+}
+
+// taskExitEvent is the TaskExit event published by runtime shims
+type taskExitEvent struct {
+	ContainerID string `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3"`
+	ID          string `protobuf:"bytes,2,opt,name=id,proto3"`
+	Pid         uint32 `protobuf:"varint,3,opt,name=pid,proto3"`
+	ExitStatus  uint32 `protobuf:"varint,4,opt,name=exit_status,json=exitStatus,proto3"`
+}
+
+func (e *taskExitEvent) Reset()         { *e = taskExitEvent{} }
+func (e *taskExitEvent) String() string { return proto.CompactTextString(e) }
+func (*taskExitEvent) ProtoMessage()    {}
+
+// taskOOMEvent is the TaskOOM event published by runtime shims
+type taskOOMEvent struct {
+	ContainerID string `protobuf:"bytes,1,opt,name=container_id,json=containerId,proto3"`
+}
+
+func (e *taskOOMEvent) Reset()         { *e = taskOOMEvent{} }
+func (e *taskOOMEvent) String() string { return proto.CompactTextString(e) }
+func (*taskOOMEvent) ProtoMessage()    {}
+
+// The actual test suite
+var _ = t.Describe("RuntimeVM", func() {
+	const vmRuntime = "vm"
+
+	var (
+		sut       *oci.Runtime
+		cfg       *config.Config
+		shim      *fakeTaskService
+		server    *ttrpc.Server
+		dir       string
+		container *oci.Container
+	)
+
+	// persistShimState writes the state of a shim listening on `address`
+	// like it got persisted by a previous CRI-O instance
+	persistShimState := func(address string) {
+		state := map[string]interface{}{
+			"address": address,
+			"io": map[string]interface{}{
+				"Stdout": filepath.Join(dir, "fifo", "id-stdout"),
+				"Stderr": filepath.Join(dir, "fifo", "id-stderr"),
+			},
+		}
+		data, err := json.Marshal(state)
+		Expect(err).To(BeNil())
+		Expect(ioutil.WriteFile(filepath.Join(dir, "shim.json"), data, 0600)).To(BeNil())
+	}
+
+	// publish publishes `event` on `topic` like the runtime shim does
+	publish := func(topic string, event proto.Message) error {
+		value, err := proto.Marshal(event)
+		Expect(err).To(BeNil())
+		data, err := proto.Marshal(&ptypes.Any{
+			TypeUrl: "containerd.events." + proto.MessageName(event),
+			Value:   value,
+		})
+		Expect(err).To(BeNil())
+		return oci.PublishShimEvent(filepath.Join(dir, "shim-events.sock"),
+			topic, "default", bytes.NewReader(data))
+	}
+
+	// openFIFO opens the stdio fifo `name` of the container like the shim
+	openFIFO := func(name string) *os.File {
+		f, err := os.OpenFile(filepath.Join(dir, "fifo", name), os.O_WRONLY, 0)
+		Expect(err).To(BeNil())
+		return f
+	}
+
+	readLog := func() string {
+		data, _ := ioutil.ReadFile(container.LogPath())
+		return string(data)
+	}
+
+	exitFile := func() string {
+		return filepath.Join(dir, "exits", "id")
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "runtimevm")
+		Expect(err).To(BeNil())
+		Expect(os.MkdirAll(filepath.Join(dir, "fifo"), 0700)).To(BeNil())
+
+		// Serve the fake shim on an abstract socket like the real shims do
+		address := fmt.Sprintf("crio-test/%d/%s/shim.sock", os.Getpid(), filepath.Base(dir))
+		listener, err := net.Listen("unix", "\x00"+address)
+		Expect(err).To(BeNil())
+		shim = newFakeTaskService()
+		server, err = ttrpc.NewServer()
+		Expect(err).To(BeNil())
+		task.RegisterTaskService(server, shim)
+		go server.Serve(context.Background(), listener) // nolint: errcheck
+		persistShimState(address)
+
+		c, err := config.DefaultConfig()
+		Expect(err).To(BeNil())
+		c.ContainerAttachSocketDir = dir
+		c.ContainerExitsDir = filepath.Join(dir, "exits")
+		Expect(os.MkdirAll(c.ContainerExitsDir, 0700)).To(BeNil())
+		c.Runtimes[vmRuntime] = &config.RuntimeHandler{
+			RuntimePath: "/bin/sh",
+			RuntimeType: oci.RuntimeTypeVM,
+		}
+		sut = oci.New(c)
+		cfg = c
+
+		container, err = oci.NewContainer("id", "name", dir,
+			filepath.Join(dir, "container.log"), "", map[string]string{}, map[string]string{},
+			map[string]string{}, "image", "imageName", "imageRef",
+			&pb.ContainerMetadata{}, "sandbox", false, false, false, false,
+			vmRuntime, dir, time.Now(), "")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		shim.exit()
+		server.Close()
+		Expect(os.RemoveAll(dir)).To(BeNil())
+	})
+
+	It("should reconnect to the shim of a restored container", func() {
+		// Given
+		// When
+		err := sut.UpdateContainerStatus(container)
+
+		// Then
+		Expect(err).To(BeNil())
+		Expect(container.State().Status).To(Equal(oci.ContainerStateRunning))
+		Expect(container.State().Pid).To(Equal(42))
+	})
+
+	It("should forward the output of a restored container into its log", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+
+		// When
+		stdout := openFIFO("id-stdout")
+		_, err := stdout.WriteString("hello\n")
+		Expect(err).To(BeNil())
+		Expect(stdout.Close()).To(BeNil())
+
+		// Then
+		Eventually(readLog).Should(MatchRegexp(`^\S+ stdout F hello\n$`))
+	})
+
+	It("should split long lines of a container into partial log lines", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		line := strings.Repeat("a", 10000)
+
+		// When
+		stderr := openFIFO("id-stderr")
+		_, err := stderr.WriteString(line + "\n")
+		Expect(err).To(BeNil())
+		Expect(stderr.Close()).To(BeNil())
+
+		// Then
+		readLines := func() []string {
+			return strings.Split(strings.TrimSuffix(readLog(), "\n"), "\n")
+		}
+		Eventually(readLines).Should(HaveLen(2))
+		lines := readLines()
+		Expect(strings.Fields(lines[0])[1:]).To(Equal([]string{"stderr", "P", line[:8192]}))
+		Expect(strings.Fields(lines[1])[1:]).To(Equal([]string{"stderr", "F", line[8192:]}))
+	})
+
+	It("should reopen the log of a container", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		stdout := openFIFO("id-stdout")
+		defer stdout.Close()
+		_, err := stdout.WriteString("before\n")
+		Expect(err).To(BeNil())
+		Eventually(readLog).Should(ContainSubstring("before"))
+		Expect(os.Rename(container.LogPath(), container.LogPath()+".old")).To(BeNil())
+
+		// When
+		err = sut.ReopenContainerLog(container)
+
+		// Then
+		Expect(err).To(BeNil())
+		_, err = stdout.WriteString("after\n")
+		Expect(err).To(BeNil())
+		Eventually(readLog).Should(MatchRegexp(`^\S+ stdout F after\n$`))
+	})
+
+	It("should rotate the log of a container", func() {
+		// Given
+		container.SetLogRotation(&oci.LogRotation{SizeMax: 100, MaxFiles: 1})
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+
+		// When
+		stdout := openFIFO("id-stdout")
+		for i := 0; i < 5; i++ {
+			_, err := stdout.WriteString("some log line\n")
+			Expect(err).To(BeNil())
+		}
+		Expect(stdout.Close()).To(BeNil())
+
+		// Then
+		Eventually(container.LogPath() + ".1").Should(BeAnExistingFile())
+		Eventually(func() int {
+			return strings.Count(readLog(), "\n")
+		}).Should(BeNumerically(">", 0))
+		info, err := os.Stat(container.LogPath())
+		Expect(err).To(BeNil())
+		Expect(info.Size()).To(BeNumerically("<=", 100))
+	})
+
+	It("should compress the rotated logs of a container", func() {
+		// Given
+		container.SetLogRotation(&oci.LogRotation{SizeMax: 100, MaxFiles: 2, Compress: true})
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+
+		// When
+		stdout := openFIFO("id-stdout")
+		for i := 0; i < 10; i++ {
+			_, err := stdout.WriteString("some log line\n")
+			Expect(err).To(BeNil())
+		}
+		_, err := stdout.WriteString("last log line\n")
+		Expect(err).To(BeNil())
+		Expect(stdout.Close()).To(BeNil())
+
+		// Then
+		Eventually(readLog).Should(ContainSubstring("last log line"))
+		Eventually(container.LogPath() + ".2.gz").Should(BeAnExistingFile())
+		Eventually(container.LogPath() + ".1.gz").Should(BeAnExistingFile())
+		Eventually(container.LogPath() + ".1").ShouldNot(BeAnExistingFile())
+	})
+
+	It("should feed the exit of a container into the exit monitor", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		shim.exit()
+
+		// When
+		err := publish("/tasks/exit", &taskExitEvent{
+			ContainerID: "id", ID: "id", Pid: 42, ExitStatus: 137,
+		})
+
+		// Then
+		Expect(err).To(BeNil())
+		Eventually(func() string {
+			data, _ := ioutil.ReadFile(exitFile())
+			return string(data)
+		}).Should(Equal("137"))
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		Expect(container.State().Status).To(Equal(oci.ContainerStateStopped))
+		Expect(container.State().OOMKilled).To(BeFalse())
+	})
+
+	It("should ignore the exit of an exec process", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+
+		// When
+		err := publish("/tasks/exit", &taskExitEvent{
+			ContainerID: "id", ID: "exec", Pid: 43, ExitStatus: 1,
+		})
+
+		// Then
+		Expect(err).To(BeNil())
+		Consistently(exitFile).ShouldNot(BeAnExistingFile())
+	})
+
+	It("should record the OOM kill of a container", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		Expect(publish("/tasks/oom", &taskOOMEvent{ContainerID: "id"})).To(BeNil())
+		shim.exit()
+
+		// When
+		Expect(publish("/tasks/exit", &taskExitEvent{
+			ContainerID: "id", ID: "id", Pid: 42, ExitStatus: 137,
+		})).To(BeNil())
+
+		// Then
+		Eventually(exitFile).Should(BeAnExistingFile())
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		Expect(container.State().OOMKilled).To(BeTrue())
+	})
+
+	It("should keep the OOM kill of a container over restarts", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+		Expect(publish("/tasks/oom", &taskOOMEvent{ContainerID: "id"})).To(BeNil())
+		shim.exit()
+		Expect(publish("/tasks/exit", &taskExitEvent{
+			ContainerID: "id", ID: "id", Pid: 42, ExitStatus: 137,
+		})).To(BeNil())
+		Eventually(exitFile).Should(BeAnExistingFile())
+
+		// When
+		restarted := oci.New(cfg)
+		Expect(restarted.UpdateContainerStatus(container)).To(BeNil())
+
+		// Then
+		Expect(container.State().OOMKilled).To(BeTrue())
+	})
+
+	It("should set the pid of a started container", func() {
+		// Given
+		// When
+		err := sut.StartContainer(container)
+
+		// Then
+		Expect(err).To(BeNil())
+		Expect(container.State().Pid).To(Equal(42))
+	})
+
+	It("should stop a restored container", func() {
+		// Given
+		Expect(sut.UpdateContainerStatus(container)).To(BeNil())
+
+		// When
+		err := sut.StopContainer(context.Background(), container, 10)
+
+		// Then
+		Expect(err).To(BeNil())
+		Expect(shim.killed).To(ContainElement(uint32(syscall.SIGTERM)))
+	})
+
+	It("should fail to restore a container without shim state", func() {
+		// Given
+		Expect(os.Remove(filepath.Join(dir, "shim.json"))).To(BeNil())
+
+		// When
+		err := sut.UpdateContainerStatus(container)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should delete a container whose shim is gone", func() {
+		// Given
+		Expect(os.Remove(filepath.Join(dir, "shim.json"))).To(BeNil())
+
+		// When
+		err := sut.DeleteContainer(container)
+
+		// Then
+		Expect(err).To(BeNil())
+	})
+
+	It("should delete a container whose shim does not listen anymore", func() {
+		// Given
+		persistShimState(fmt.Sprintf("crio-test/%d/%s/gone.sock", os.Getpid(), filepath.Base(dir)))
+
+		// When
+		err := sut.DeleteContainer(container)
+
+		// Then
+		Expect(err).To(BeNil())
+	})
+
+	It("should fail to delete a container with invalid shim state", func() {
+		// Given
+		Expect(ioutil.WriteFile(filepath.Join(dir, "shim.json"), []byte("{"), 0600)).To(BeNil())
+
+		// When
+		err := sut.DeleteContainer(container)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 	"github.com/opencontainers/runc/libcontainer"
 )
 
@@ -21,6 +22,15 @@ type ContainerStats struct {
 	BlockInput  uint64
 	BlockOutput uint64
 	PIDs        uint64
+
+	// The following statistics are only collected on hosts using the
+	// cgroup v2 unified hierarchy, where MemStat are the values of
+	// memory.stat and IOStat the ones of io.stat per device.
+	MemStat        map[string]uint64
+	IOStat         map[string]map[string]uint64
+	CPUPressure    *cgroupv2.Pressure
+	MemoryPressure *cgroupv2.Pressure
+	IOPressure     *cgroupv2.Pressure
 }
 
 // Returns the total number of bytes transmitted and received for the given container stats
@@ -0,0 +1,410 @@
+// Package cgroupv2 provides access to the cgroup v2 unified hierarchy, which
+// is not supported by the vendored cgroup libraries.
+package cgroupv2
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/containers/libpod/pkg/cgroups"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+)
+
+// Root is the mount point of the unified hierarchy
+const Root = "/sys/fs/cgroup"
+
+// The values of the cgroup version reported by CRI-O
+const (
+	VersionV1 = "v1"
+	VersionV2 = "v2"
+)
+
+// Enabled returns whether the host uses the cgroup v2 unified hierarchy.
+func Enabled() bool {
+	unified, err := cgroups.IsCgroup2UnifiedMode()
+	return err == nil && unified
+}
+
+// Version returns the cgroup version used by the host.
+func Version() string {
+	if Enabled() {
+		return VersionV2
+	}
+	return VersionV1
+}
+
+// HasController returns whether the controller `name` is available for the
+// cgroups of the unified hierarchy.
+func HasController(name string) bool {
+	controllers, err := Controllers(Root)
+	if err != nil {
+		return false
+	}
+	for _, c := range controllers {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Controllers returns the controllers available in the cgroup `dir`.
+func Controllers(dir string) ([]string, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "cgroup.controllers"))
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(data)), nil
+}
+
+// PidCgroup returns the cgroup of the process `pid`, relative to Root.
+func PidCgroup(pid int) (string, error) {
+	f, err := os.Open(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The unified hierarchy is the entry "0::<path>"
+		if path := strings.TrimPrefix(scanner.Text(), "0::"); path != scanner.Text() {
+			return path, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no unified cgroup found for pid %d", pid)
+}
+
+// AddPid moves the process `pid` into the cgroup `path` relative to Root,
+// which gets created if it does not exist yet.
+func AddPid(path string, pid int) error {
+	dir := filepath.Join(Root, path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.Wrapf(err, "error creating cgroup %s", dir)
+	}
+	return writeFile(dir, "cgroup.procs", strconv.Itoa(pid))
+}
+
+// Remove removes the empty cgroup `path` relative to Root.
+func Remove(path string) error {
+	if err := os.Remove(filepath.Join(Root, path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Update applies the resources `res` to the cgroup `dir`. The cgroup v1
+// values of the OCI spec are converted to their unified counterparts, in the
+// same way as done by the OCI runtimes.
+func Update(dir string, res *rspec.LinuxResources) error {
+	if res == nil {
+		return nil
+	}
+	if cpu := res.CPU; cpu != nil {
+		if cpu.Shares != nil && *cpu.Shares != 0 {
+			weight := sharesToWeight(*cpu.Shares)
+			if err := writeFile(dir, "cpu.weight", strconv.FormatUint(weight, 10)); err != nil {
+				return err
+			}
+		}
+		if (cpu.Quota != nil && *cpu.Quota != 0) || (cpu.Period != nil && *cpu.Period != 0) {
+			max := "max"
+			if cpu.Quota != nil && *cpu.Quota > 0 {
+				max = strconv.FormatInt(*cpu.Quota, 10)
+			}
+			if cpu.Period != nil && *cpu.Period != 0 {
+				max += " " + strconv.FormatUint(*cpu.Period, 10)
+			}
+			if err := writeFile(dir, "cpu.max", max); err != nil {
+				return err
+			}
+		}
+		if cpu.Cpus != "" {
+			if err := writeFile(dir, "cpuset.cpus", cpu.Cpus); err != nil {
+				return err
+			}
+		}
+		if cpu.Mems != "" {
+			if err := writeFile(dir, "cpuset.mems", cpu.Mems); err != nil {
+				return err
+			}
+		}
+	}
+	if res.Memory != nil && res.Memory.Limit != nil && *res.Memory.Limit != 0 {
+		if err := writeFile(dir, "memory.max", limitString(*res.Memory.Limit)); err != nil {
+			return err
+		}
+	}
+	if res.Pids != nil && res.Pids.Limit != 0 {
+		if err := writeFile(dir, "pids.max", limitString(res.Pids.Limit)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sharesToWeight converts the cgroup v1 cpu shares from [2, 262144] to the
+// cgroup v2 cpu weight within [1, 10000].
+func sharesToWeight(shares uint64) uint64 {
+	if shares < 2 {
+		shares = 2
+	} else if shares > 262144 {
+		shares = 262144
+	}
+	return 1 + ((shares-2)*9999)/262142
+}
+
+// limitString returns the unified representation of a limit, where negative
+// values mean unlimited.
+func limitString(limit int64) string {
+	if limit < 0 {
+		return "max"
+	}
+	return strconv.FormatInt(limit, 10)
+}
+
+func writeFile(dir, file, value string) error {
+	path := filepath.Join(dir, file)
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return errors.Wrapf(err, "error writing %q to %s", value, path)
+	}
+	return nil
+}
+
+// PressureValues are the values of a single line of a PSI file.
+type PressureValues struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// Pressure is the pressure stall information of a resource. Some is the
+// share of time in which at least one task stalled on the resource, whereas
+// Full is the share in which all tasks stalled at once.
+type Pressure struct {
+	Some PressureValues `json:"some"`
+	Full PressureValues `json:"full"`
+}
+
+// Stats are the statistics of a cgroup within the unified hierarchy.
+type Stats struct {
+	// CPUUsageNano is the total CPU time consumed in nanoseconds
+	CPUUsageNano uint64
+	// MemoryCurrent is the memory usage in bytes (memory.current)
+	MemoryCurrent uint64
+	// MemoryMax is the memory limit in bytes, or math.MaxUint64 for none
+	MemoryMax uint64
+	// MemoryStat are the values of memory.stat
+	MemoryStat map[string]uint64
+	// IOStat are the values of io.stat per "major:minor" device
+	IOStat map[string]map[string]uint64
+	// PidsCurrent is the number of tasks in the cgroup
+	PidsCurrent uint64
+	// CPUPressure, MemoryPressure and IOPressure are the PSI values, which
+	// are nil if the kernel does not support them
+	CPUPressure    *Pressure
+	MemoryPressure *Pressure
+	IOPressure     *Pressure
+}
+
+// IOBytes returns the total number of read and written bytes of all devices.
+func (s *Stats) IOBytes() (read, write uint64) {
+	for _, values := range s.IOStat {
+		read += values["rbytes"]
+		write += values["wbytes"]
+	}
+	return read, write
+}
+
+// GetStats returns the statistics of the cgroup `dir`. Missing files of
+// controllers which are not enabled for the cgroup are skipped.
+func GetStats(dir string) (*Stats, error) {
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	stats := &Stats{MemoryMax: math.MaxUint64}
+	var err error
+
+	cpuStat, err := readKeyValues(filepath.Join(dir, "cpu.stat"))
+	if err != nil {
+		return nil, err
+	}
+	stats.CPUUsageNano = cpuStat["usage_usec"] * 1000
+
+	if stats.MemoryCurrent, err = readUint(filepath.Join(dir, "memory.current")); err != nil {
+		return nil, err
+	}
+	if stats.MemoryMax, err = readUint(filepath.Join(dir, "memory.max")); err != nil {
+		return nil, err
+	}
+	if stats.MemoryStat, err = readKeyValues(filepath.Join(dir, "memory.stat")); err != nil {
+		return nil, err
+	}
+	if stats.IOStat, err = readIOStat(filepath.Join(dir, "io.stat")); err != nil {
+		return nil, err
+	}
+	if stats.PidsCurrent, err = readUint(filepath.Join(dir, "pids.current")); err != nil {
+		return nil, err
+	}
+	if stats.CPUPressure, err = readPressure(filepath.Join(dir, "cpu.pressure")); err != nil {
+		return nil, err
+	}
+	if stats.MemoryPressure, err = readPressure(filepath.Join(dir, "memory.pressure")); err != nil {
+		return nil, err
+	}
+	if stats.IOPressure, err = readPressure(filepath.Join(dir, "io.pressure")); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// readLines returns the lines of `path`, or nil if it does not exist.
+func readLines(path string) ([]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return strings.Split(strings.TrimSpace(string(data)), "\n"), nil
+}
+
+// readUint reads a single value file, where "max" is math.MaxUint64. It
+// returns math.MaxUint64 for missing limit files and 0 for other ones.
+func readUint(path string) (uint64, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return 0, err
+	}
+	if lines == nil {
+		if strings.HasSuffix(path, ".max") {
+			return math.MaxUint64, nil
+		}
+		return 0, nil
+	}
+	return parseUint(path, lines[0])
+}
+
+func parseUint(path, value string) (uint64, error) {
+	if value == "max" {
+		return math.MaxUint64, nil
+	}
+	v, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "error parsing %s", path)
+	}
+	return v, nil
+}
+
+// readKeyValues reads a flat keyed file like memory.stat.
+func readKeyValues(path string) (map[string]uint64, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]uint64)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		v, err := parseUint(path, fields[1])
+		if err != nil {
+			return nil, err
+		}
+		values[fields[0]] = v
+	}
+	return values, nil
+}
+
+// readIOStat reads a nested keyed file like io.stat, with lines of the form
+// "8:0 rbytes=1 wbytes=2".
+func readIOStat(path string) (map[string]map[string]uint64, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return nil, err
+	}
+	stat := make(map[string]map[string]uint64)
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		values := make(map[string]uint64)
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			v, err := parseUint(path, kv[1])
+			if err != nil {
+				return nil, err
+			}
+			values[kv[0]] = v
+		}
+		stat[fields[0]] = values
+	}
+	return stat, nil
+}
+
+// readPressure reads a PSI file with lines of the form
+// "some avg10=0.00 avg60=0.00 avg300=0.00 total=0".
+func readPressure(path string) (*Pressure, error) {
+	lines, err := readLines(path)
+	if err != nil || lines == nil {
+		return nil, err
+	}
+	pressure := &Pressure{}
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var values *PressureValues
+		switch fields[0] {
+		case "some":
+			values = &pressure.Some
+		case "full":
+			values = &pressure.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			if kv[0] == "total" {
+				if values.Total, err = parseUint(path, kv[1]); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			avg, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error parsing %s", path)
+			}
+			switch kv[0] {
+			case "avg10":
+				values.Avg10 = avg
+			case "avg60":
+				values.Avg60 = avg
+			case "avg300":
+				values.Avg300 = avg
+			}
+		}
+	}
+	return pressure, nil
+}
@@ -0,0 +1,222 @@
+package cgroupv2_test
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	rspec "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// The actual test suite
+var _ = t.Describe("CgroupV2", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "cgroupv2-")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(BeNil())
+	})
+
+	writeFiles := func(files map[string]string) {
+		for file, content := range files {
+			Expect(ioutil.WriteFile(filepath.Join(dir, file),
+				[]byte(content), 0644)).To(BeNil())
+		}
+	}
+
+	readFile := func(file string) string {
+		content, err := ioutil.ReadFile(filepath.Join(dir, file))
+		Expect(err).To(BeNil())
+		return string(content)
+	}
+
+	t.Describe("GetStats", func() {
+		It("should succeed", func() {
+			// Given
+			writeFiles(map[string]string{
+				"cpu.stat":       "usage_usec 1500\nuser_usec 1000\nsystem_usec 500\n",
+				"memory.current": "4096\n",
+				"memory.max":     "8192\n",
+				"memory.stat":    "anon 1024\nfile 2048\ninactive_file 512\n",
+				"io.stat": "8:0 rbytes=100 wbytes=200 rios=1 wios=2\n" +
+					"8:16 rbytes=10 wbytes=20 rios=1 wios=2\n",
+				"pids.current":    "3\n",
+				"cpu.pressure":    "some avg10=1.50 avg60=0.25 avg300=0.00 total=1234\n",
+				"memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=1\nfull avg10=2.00 avg60=0.00 avg300=0.00 total=2\n",
+			})
+
+			// When
+			stats, err := cgroupv2.GetStats(dir)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(stats.CPUUsageNano).To(BeEquivalentTo(1500000))
+			Expect(stats.MemoryCurrent).To(BeEquivalentTo(4096))
+			Expect(stats.MemoryMax).To(BeEquivalentTo(8192))
+			Expect(stats.MemoryStat).To(HaveKeyWithValue("inactive_file", uint64(512)))
+			Expect(stats.IOStat).To(HaveLen(2))
+			Expect(stats.IOStat["8:0"]).To(HaveKeyWithValue("wbytes", uint64(200)))
+			read, write := stats.IOBytes()
+			Expect(read).To(BeEquivalentTo(110))
+			Expect(write).To(BeEquivalentTo(220))
+			Expect(stats.PidsCurrent).To(BeEquivalentTo(3))
+			Expect(stats.CPUPressure).NotTo(BeNil())
+			Expect(stats.CPUPressure.Some.Avg10).To(Equal(1.5))
+			Expect(stats.CPUPressure.Some.Total).To(BeEquivalentTo(1234))
+			Expect(stats.MemoryPressure.Full.Avg10).To(Equal(2.0))
+			Expect(stats.IOPressure).To(BeNil())
+		})
+
+		It("should succeed without optional controllers", func() {
+			// Given
+			writeFiles(map[string]string{
+				"cpu.stat":   "usage_usec 10\n",
+				"memory.max": "max\n",
+			})
+
+			// When
+			stats, err := cgroupv2.GetStats(dir)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(stats.CPUUsageNano).To(BeEquivalentTo(10000))
+			Expect(stats.MemoryMax).To(BeEquivalentTo(uint64(math.MaxUint64)))
+			Expect(stats.MemoryStat).To(BeEmpty())
+			Expect(stats.IOStat).To(BeEmpty())
+			Expect(stats.CPUPressure).To(BeNil())
+		})
+
+		It("should fail with invalid values", func() {
+			// Given
+			writeFiles(map[string]string{"memory.current": "invalid\n"})
+
+			// When
+			stats, err := cgroupv2.GetStats(dir)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(stats).To(BeNil())
+		})
+
+		It("should fail with invalid pressure", func() {
+			// Given
+			writeFiles(map[string]string{"io.pressure": "some avg10=x\n"})
+
+			// When
+			stats, err := cgroupv2.GetStats(dir)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(stats).To(BeNil())
+		})
+
+		It("should fail with non existing cgroup", func() {
+			// Given
+			// When
+			stats, err := cgroupv2.GetStats(filepath.Join(dir, "missing"))
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(stats).To(BeNil())
+		})
+	})
+
+	t.Describe("Controllers", func() {
+		It("should succeed", func() {
+			// Given
+			writeFiles(map[string]string{"cgroup.controllers": "cpuset cpu io memory pids\n"})
+
+			// When
+			controllers, err := cgroupv2.Controllers(dir)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(controllers).To(Equal([]string{"cpuset", "cpu", "io", "memory", "pids"}))
+		})
+
+		It("should fail without unified hierarchy", func() {
+			// Given
+			// When
+			controllers, err := cgroupv2.Controllers(dir)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(controllers).To(BeNil())
+		})
+	})
+
+	t.Describe("Update", func() {
+		It("should succeed", func() {
+			// Given
+			var (
+				shares uint64 = 1024
+				quota  int64  = 50000
+				period uint64 = 100000
+				limit  int64  = 1048576
+			)
+			res := &rspec.LinuxResources{
+				CPU: &rspec.LinuxCPU{
+					Shares: &shares, Quota: &quota, Period: &period, Cpus: "0-1",
+				},
+				Memory: &rspec.LinuxMemory{Limit: &limit},
+				Pids:   &rspec.LinuxPids{Limit: -1},
+			}
+
+			// When
+			err := cgroupv2.Update(dir, res)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(readFile("cpu.weight")).To(Equal("39"))
+			Expect(readFile("cpu.max")).To(Equal("50000 100000"))
+			Expect(readFile("cpuset.cpus")).To(Equal("0-1"))
+			Expect(readFile("memory.max")).To(Equal("1048576"))
+			Expect(readFile("pids.max")).To(Equal("max"))
+			_, err = os.Stat(filepath.Join(dir, "cpuset.mems"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("should succeed without quota", func() {
+			// Given
+			var period uint64 = 100000
+			res := &rspec.LinuxResources{CPU: &rspec.LinuxCPU{Period: &period}}
+
+			// When
+			err := cgroupv2.Update(dir, res)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(readFile("cpu.max")).To(Equal("max 100000"))
+		})
+
+		It("should succeed with nil resources", func() {
+			// Given
+			// When
+			err := cgroupv2.Update(dir, nil)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should fail with non existing cgroup", func() {
+			// Given
+			limit := int64(1024)
+			res := &rspec.LinuxResources{Memory: &rspec.LinuxMemory{Limit: &limit}}
+
+			// When
+			err := cgroupv2.Update(filepath.Join(dir, "missing"), res)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
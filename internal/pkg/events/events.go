@@ -0,0 +1,150 @@
+// Package events distributes the lifecycle events of containers and
+// sandboxes to their subscribers.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultReplaySize is the default number of events kept for replaying
+	// them to new subscribers
+	DefaultReplaySize = 1024
+
+	// subscriberBufferSize is the number of events buffered per subscriber.
+	// Subscribers which do not keep up get unsubscribed.
+	subscriberBufferSize = 256
+)
+
+// Matches returns whether the `event` is selected by the `filter`.
+func Matches(filter *types.EventFilter, event *types.Event) bool {
+	if filter.SandboxID != "" && filter.SandboxID != event.SandboxID {
+		return false
+	}
+	for key, value := range filter.Labels {
+		if v, ok := event.Labels[key]; !ok || v != value {
+			return false
+		}
+	}
+	if len(filter.Types) == 0 {
+		return true
+	}
+	for _, t := range filter.Types {
+		if t == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+type subscriber struct {
+	filter types.EventFilter
+	events chan types.Event
+}
+
+// Bus publishes events to all matching subscribers and keeps the latest
+// events for replaying them.
+type Bus struct {
+	lock        sync.Mutex
+	lastID      uint64
+	replay      []types.Event
+	replaySize  int
+	subscribers map[*subscriber]struct{}
+}
+
+// NewBus creates a new event bus which keeps the latest `replaySize` events.
+func NewBus(replaySize int) *Bus {
+	return &Bus{
+		replaySize:  replaySize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish assigns the next ID and the current time to the `event` and sends
+// it to all matching subscribers. It is a no-op on a nil bus.
+func (b *Bus) Publish(event types.Event) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.lastID++
+	event.ID = b.lastID
+	if event.Timestamp == 0 {
+		event.Timestamp = time.Now().UnixNano()
+	}
+
+	if b.replaySize > 0 {
+		if len(b.replay) == b.replaySize {
+			b.replay = append(b.replay[:0], b.replay[1:]...)
+		}
+		b.replay = append(b.replay, event)
+	}
+
+	for sub := range b.subscribers {
+		if !Matches(&sub.filter, &event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			logrus.Warnf("dropping event subscriber which does not keep up")
+			b.unsubscribe(sub)
+		}
+	}
+}
+
+// Subscribe returns a channel receiving all further events matching the
+// `filter`, preceded by the matching replayable events if `replay` is set.
+// The channel gets closed by the returned cancel function, by Close, or if
+// the subscriber does not keep up with the events.
+func (b *Bus) Subscribe(filter types.EventFilter, replay bool) (events <-chan types.Event, cancel func()) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var replayed []types.Event
+	if replay {
+		for i := range b.replay {
+			if Matches(&filter, &b.replay[i]) {
+				replayed = append(replayed, b.replay[i])
+			}
+		}
+	}
+	sub := &subscriber{
+		filter: filter,
+		events: make(chan types.Event, len(replayed)+subscriberBufferSize),
+	}
+	for _, event := range replayed {
+		sub.events <- event
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub.events, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		b.unsubscribe(sub)
+	}
+}
+
+// Close closes the channels of all subscribers.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for sub := range b.subscribers {
+		b.unsubscribe(sub)
+	}
+}
+
+// unsubscribe removes the subscriber `sub`, the caller has to hold the lock.
+func (b *Bus) unsubscribe(sub *subscriber) {
+	if _, ok := b.subscribers[sub]; !ok {
+		return
+	}
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
@@ -0,0 +1,152 @@
+package events_test
+
+import (
+	"github.com/cri-o/cri-o/internal/pkg/events"
+	"github.com/cri-o/cri-o/pkg/types"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The actual test suite
+var _ = t.Describe("Events", func() {
+	t.Describe("Matches", func() {
+		event := &types.Event{
+			Type:      types.EventContainerExited,
+			SandboxID: "sandbox",
+			Labels:    map[string]string{"app": "test"},
+		}
+
+		It("should match with empty filter", func() {
+			// Given
+			// When
+			// Then
+			Expect(events.Matches(&types.EventFilter{}, event)).To(BeTrue())
+		})
+
+		It("should match with all fields", func() {
+			// Given
+			filter := &types.EventFilter{
+				SandboxID: "sandbox",
+				Labels:    map[string]string{"app": "test"},
+				Types: []types.EventType{
+					types.EventContainerCreated, types.EventContainerExited,
+				},
+			}
+
+			// When
+			// Then
+			Expect(events.Matches(filter, event)).To(BeTrue())
+		})
+
+		It("should not match with different fields", func() {
+			for _, filter := range []*types.EventFilter{
+				{SandboxID: "other"},
+				{Labels: map[string]string{"app": "other"}},
+				{Labels: map[string]string{"missing": ""}},
+				{Types: []types.EventType{types.EventContainerCreated}},
+			} {
+				// Given
+				// When
+				// Then
+				Expect(events.Matches(filter, event)).To(BeFalse())
+			}
+		})
+	})
+
+	t.Describe("Bus", func() {
+		var sut *events.Bus
+
+		BeforeEach(func() {
+			sut = events.NewBus(2)
+		})
+
+		It("should publish to matching subscribers", func() {
+			// Given
+			exits, cancelExits := sut.Subscribe(types.EventFilter{
+				Types: []types.EventType{types.EventContainerExited},
+			}, false)
+			defer cancelExits()
+			all, cancelAll := sut.Subscribe(types.EventFilter{}, false)
+			defer cancelAll()
+
+			// When
+			sut.Publish(types.Event{Type: types.EventContainerCreated})
+			sut.Publish(types.Event{Type: types.EventContainerExited})
+
+			// Then
+			event := <-exits
+			Expect(event.ID).To(BeEquivalentTo(2))
+			Expect(event.Timestamp).NotTo(BeZero())
+			Expect(exits).NotTo(Receive())
+			Expect((<-all).Type).To(Equal(types.EventContainerCreated))
+			Expect((<-all).Type).To(Equal(types.EventContainerExited))
+		})
+
+		It("should replay the latest events", func() {
+			// Given
+			for i := 0; i < 3; i++ {
+				sut.Publish(types.Event{Type: types.EventContainerStarted})
+			}
+
+			// When
+			events, cancel := sut.Subscribe(types.EventFilter{}, true)
+			defer cancel()
+
+			// Then
+			Expect((<-events).ID).To(BeEquivalentTo(2))
+			Expect((<-events).ID).To(BeEquivalentTo(3))
+			Expect(events).NotTo(Receive())
+		})
+
+		It("should close the channel on cancel", func() {
+			// Given
+			events, cancel := sut.Subscribe(types.EventFilter{}, false)
+
+			// When
+			cancel()
+			cancel()
+
+			// Then
+			Expect(events).To(BeClosed())
+		})
+
+		It("should close the channel on Close", func() {
+			// Given
+			events, cancel := sut.Subscribe(types.EventFilter{}, false)
+			defer cancel()
+
+			// When
+			sut.Close()
+
+			// Then
+			Expect(events).To(BeClosed())
+		})
+
+		It("should drop subscribers which do not keep up", func() {
+			// Given
+			events, cancel := sut.Subscribe(types.EventFilter{}, false)
+			defer cancel()
+
+			// When
+			for i := 0; i < 1000; i++ {
+				sut.Publish(types.Event{Type: types.EventContainerStarted})
+			}
+
+			// Then
+			received := 0
+			for range events {
+				received++
+			}
+			Expect(received).To(BeNumerically("<", 1000))
+		})
+
+		It("should succeed to publish on nil bus", func() {
+			// Given
+			var bus *events.Bus
+
+			// When
+			// Then
+			bus.Publish(types.Event{})
+		})
+	})
+})
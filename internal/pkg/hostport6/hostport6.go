@@ -0,0 +1,175 @@
+// Package hostport6 provides a hostport.HostPortManager for pod sandboxes
+// with IPv6 addresses, which are rejected by the kubelet hostport manager.
+package hostport6
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/kubernetes/pkg/kubelet/dockershim/network/hostport"
+	utiliptables "k8s.io/kubernetes/pkg/util/iptables"
+)
+
+const (
+	// HostportsChain is the NAT chain which dispatches the IPv6 hostport
+	// traffic to the chains of the pod sandboxes
+	HostportsChain utiliptables.Chain = "CRIO-HOSTPORTS"
+
+	// sandboxChainPrefix is the prefix of the NAT chain of a pod sandbox
+	sandboxChainPrefix = "CRIO-HP-"
+)
+
+type hostportManager struct {
+	iptables utiliptables.Interface
+	mu       sync.Mutex
+}
+
+// NewHostportManager creates a new hostport.HostPortManager which sets up the
+// hostport mappings of IPv6 pod addresses via the provided ip6tables
+// interface. Opening the host ports is left to the IPv4 hostport manager,
+// because the sockets it opens are bound to both address families.
+func NewHostportManager(iptables utiliptables.Interface) hostport.HostPortManager {
+	return &hostportManager{iptables: iptables}
+}
+
+// SandboxChain returns the NAT chain used for the hostports of the pod
+// sandbox `id`.
+func SandboxChain(id string) utiliptables.Chain {
+	hash := sha256.Sum256([]byte(id))
+	return utiliptables.Chain(sandboxChainPrefix + fmt.Sprintf("%x", hash)[:16])
+}
+
+// Add sets up the DNAT rules for the hostports of the pod sandbox `id`.
+func (m *hostportManager) Add(id string, podPortMapping *hostport.PodPortMapping, natInterfaceName string) error {
+	if podPortMapping == nil || podPortMapping.HostNetwork {
+		return nil
+	}
+	portMappings := hostportMappings(podPortMapping)
+	if len(portMappings) == 0 {
+		return nil
+	}
+	if podPortMapping.IP == nil || podPortMapping.IP.To4() != nil {
+		return fmt.Errorf("invalid or missing IPv6 address of pod %s", podPortMapping.Name)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.ensureHostportsChain(); err != nil {
+		return err
+	}
+	chain := SandboxChain(id)
+	if _, err := m.iptables.EnsureChain(utiliptables.TableNAT, chain); err != nil {
+		return err
+	}
+	for _, pm := range portMappings {
+		if _, err := m.iptables.EnsureRule(utiliptables.Append,
+			utiliptables.TableNAT, HostportsChain, dispatchRule(podPortMapping, pm, chain)...,
+		); err != nil {
+			return err
+		}
+		protocol := strings.ToLower(string(pm.Protocol))
+		if _, err := m.iptables.EnsureRule(utiliptables.Append,
+			utiliptables.TableNAT, chain,
+			"-m", "comment", "--comment", comment(podPortMapping, pm),
+			"-m", protocol, "-p", protocol,
+			"--dport", strconv.Itoa(int(pm.HostPort)),
+			"-j", "DNAT", "--to-destination",
+			net.JoinHostPort(podPortMapping.IP.String(), strconv.Itoa(int(pm.ContainerPort))),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove cleans up the DNAT rules for the hostports of the pod sandbox `id`.
+func (m *hostportManager) Remove(id string, podPortMapping *hostport.PodPortMapping) error {
+	if podPortMapping == nil || podPortMapping.HostNetwork {
+		return nil
+	}
+	portMappings := hostportMappings(podPortMapping)
+	if len(portMappings) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	chain := SandboxChain(id)
+	existed, err := m.iptables.EnsureChain(utiliptables.TableNAT, chain)
+	if err != nil {
+		return err
+	}
+	errs := []error{}
+	if existed {
+		for _, pm := range portMappings {
+			if err := m.iptables.DeleteRule(utiliptables.TableNAT, HostportsChain,
+				dispatchRule(podPortMapping, pm, chain)...,
+			); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if err := m.iptables.FlushChain(utiliptables.TableNAT, chain); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := m.iptables.DeleteChain(utiliptables.TableNAT, chain); err != nil {
+		errs = append(errs, err)
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ensureHostportsChain ensures that the hostports chain exists and that the
+// locally addressed traffic jumps into it.
+func (m *hostportManager) ensureHostportsChain() error {
+	if _, err := m.iptables.EnsureChain(utiliptables.TableNAT, HostportsChain); err != nil {
+		return err
+	}
+	for _, chain := range []utiliptables.Chain{utiliptables.ChainPrerouting, utiliptables.ChainOutput} {
+		if _, err := m.iptables.EnsureRule(utiliptables.Prepend,
+			utiliptables.TableNAT, chain,
+			"-m", "comment", "--comment", "crio-hostports",
+			"-m", "addrtype", "--dst-type", "LOCAL",
+			"-j", string(HostportsChain),
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dispatchRule returns the rule which forwards the traffic of a single
+// hostport to the sandbox `chain`.
+func dispatchRule(podPortMapping *hostport.PodPortMapping, pm *hostport.PortMapping, chain utiliptables.Chain) []string {
+	protocol := strings.ToLower(string(pm.Protocol))
+	return []string{
+		"-m", "comment", "--comment", comment(podPortMapping, pm),
+		"-m", protocol, "-p", protocol,
+		"--dport", strconv.Itoa(int(pm.HostPort)),
+		"-j", string(chain),
+	}
+}
+
+// comment returns the iptables comment of a single hostport, which must not
+// contain any whitespace.
+func comment(podPortMapping *hostport.PodPortMapping, pm *hostport.PortMapping) string {
+	return fmt.Sprintf("%s:%d", podPortMapping.Name, pm.HostPort)
+}
+
+// hostportMappings returns the port mappings which need a hostport.
+func hostportMappings(podPortMapping *hostport.PodPortMapping) []*hostport.PortMapping {
+	mappings := []*hostport.PortMapping{}
+	for _, pm := range podPortMapping.PortMappings {
+		if pm.HostPort <= 0 {
+			continue
+		}
+		mappings = append(mappings, pm)
+	}
+	return mappings
+}
@@ -0,0 +1,111 @@
+package hostport6_test
+
+import (
+	"bytes"
+	"net"
+
+	"github.com/cri-o/cri-o/internal/pkg/hostport6"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"k8s.io/api/core/v1"
+	"k8s.io/kubernetes/pkg/kubelet/dockershim/network/hostport"
+	utiliptables "k8s.io/kubernetes/pkg/util/iptables"
+)
+
+// The actual test suite
+var _ = t.Describe("HostportManager", func() {
+	const sandboxID = "sandboxID"
+
+	var (
+		iptables       utiliptables.Interface
+		sut            hostport.HostPortManager
+		podPortMapping *hostport.PodPortMapping
+	)
+
+	BeforeEach(func() {
+		iptables = hostport.NewFakeIPTables()
+		sut = hostport6.NewHostportManager(iptables)
+		podPortMapping = &hostport.PodPortMapping{
+			Name: "pod",
+			IP:   net.ParseIP("fd00::2"),
+			PortMappings: []*hostport.PortMapping{
+				{HostPort: 8080, ContainerPort: 80, Protocol: v1.ProtocolTCP},
+				{ContainerPort: 443, Protocol: v1.ProtocolTCP},
+			},
+		}
+	})
+
+	var natRules = func() string {
+		buffer := &bytes.Buffer{}
+		Expect(iptables.SaveInto(utiliptables.TableNAT, buffer)).To(BeNil())
+		return buffer.String()
+	}
+
+	t.Describe("Add", func() {
+		It("should succeed", func() {
+			// Given
+			// When
+			err := sut.Add(sandboxID, podPortMapping, "lo")
+
+			// Then
+			Expect(err).To(BeNil())
+			rules := natRules()
+			Expect(rules).To(ContainSubstring(
+				"-A CRIO-HOSTPORTS -m comment --comment pod:8080 " +
+					"-m tcp -p tcp --dport 8080 -j " +
+					string(hostport6.SandboxChain(sandboxID)),
+			))
+			Expect(rules).To(ContainSubstring("--to-destination [fd00::2]:80"))
+			Expect(rules).NotTo(ContainSubstring("443"))
+		})
+
+		It("should succeed without hostports", func() {
+			// Given
+			podPortMapping.PortMappings = nil
+
+			// When
+			err := sut.Add(sandboxID, podPortMapping, "lo")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(iptables.SaveInto(utiliptables.TableNAT, &bytes.Buffer{})).NotTo(BeNil())
+		})
+
+		It("should fail with IPv4 address", func() {
+			// Given
+			podPortMapping.IP = net.ParseIP("10.0.0.2")
+
+			// When
+			err := sut.Add(sandboxID, podPortMapping, "lo")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	t.Describe("Remove", func() {
+		It("should succeed", func() {
+			// Given
+			Expect(sut.Add(sandboxID, podPortMapping, "lo")).To(BeNil())
+
+			// When
+			err := sut.Remove(sandboxID, podPortMapping)
+
+			// Then
+			Expect(err).To(BeNil())
+			rules := natRules()
+			Expect(rules).NotTo(ContainSubstring("fd00::2"))
+			Expect(rules).NotTo(ContainSubstring(
+				string(hostport6.SandboxChain(sandboxID))))
+		})
+
+		It("should succeed if nothing has been added", func() {
+			// Given
+			// When
+			err := sut.Remove(sandboxID, podPortMapping)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,270 @@
+// Package oom watches the memory cgroups of containers for OOM kills. Unlike
+// the oom marker file of conmon, this also covers the kills of processes
+// other than the container init process and containers of VM runtimes.
+package oom
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// memoryEventsFile contains the OOM kill counter on the cgroup v2
+	// unified hierarchy
+	memoryEventsFile = "memory.events"
+
+	// oomControlFile notifies about OOMs on cgroup v1, newer kernels also
+	// report the OOM kill counter in it
+	oomControlFile = "memory.oom_control"
+
+	// oomKillKey is the key of the OOM kill counter in both files
+	oomKillKey = "oom_kill"
+)
+
+// Handler gets called with the ID of the container whose memory cgroup saw
+// `kills` new OOM kills at the time `at`.
+type Handler func(id string, kills uint64, at time.Time)
+
+// Watcher watches the memory cgroups of containers for OOM kills.
+type Watcher struct {
+	handler Handler
+	lock    sync.Mutex
+	// cgroups are the watched memory cgroups by their directory
+	cgroups map[string]*cgroupWatch
+	// containers are the directories of the memory cgroups of the watched
+	// containers by their ID
+	containers map[string]string
+}
+
+// cgroupWatch is the watch of a memory cgroup, which can be shared by
+// several containers.
+type cgroupWatch struct {
+	dir    string
+	closer io.Closer
+	// kills is the latest OOM kill counter of the cgroup
+	kills uint64
+	// known are the OOM kills already reported per container of the cgroup
+	known map[string]uint64
+}
+
+// New creates a new Watcher which calls the `handler` on every OOM kill.
+func New(handler Handler) *Watcher {
+	return &Watcher{
+		handler:    handler,
+		cgroups:    make(map[string]*cgroupWatch),
+		containers: make(map[string]string),
+	}
+}
+
+// Add starts watching the memory cgroup `dir` of the container `id`, where
+// `knownKills` is the number of OOM kills already recorded for it. OOM kills
+// counted by the cgroup beyond `knownKills` get reported immediately, which
+// covers the kills which happened while the daemon was not running.
+//
+// Containers sharing their memory cgroup, like the containers of a VM
+// runtime, can't be told apart by the kernel, so every OOM kill of the cgroup
+// gets reported to all of them. A container joining an already watched
+// cgroup only gets the kills which happen afterwards.
+func (w *Watcher) Add(id, dir string, knownKills uint64) error {
+	w.lock.Lock()
+	if _, ok := w.containers[id]; ok {
+		w.lock.Unlock()
+		return nil
+	}
+	if cg, ok := w.cgroups[dir]; ok {
+		logrus.Debugf("memory cgroup %s of container %s is already watched", dir, id)
+		cg.known[id] = cg.kills
+		w.containers[id] = dir
+		w.lock.Unlock()
+		return nil
+	}
+
+	cg := &cgroupWatch{
+		dir:   dir,
+		known: map[string]uint64{id: knownKills},
+	}
+	var (
+		kills uint64
+		err   error
+	)
+	if _, statErr := os.Stat(filepath.Join(dir, memoryEventsFile)); statErr == nil {
+		cg.closer, kills, err = w.watchMemoryEvents(cg)
+	} else {
+		cg.closer, kills, err = w.watchOOMControl(cg)
+	}
+	if err != nil {
+		w.lock.Unlock()
+		return errors.Wrapf(err, "unable to watch memory cgroup %s for OOM kills", dir)
+	}
+	w.cgroups[dir] = cg
+	w.containers[id] = dir
+	w.lock.Unlock()
+
+	w.report(cg, kills)
+	return nil
+}
+
+// Remove stops watching the memory cgroup of the container `id`, unless it
+// is shared with other watched containers.
+func (w *Watcher) Remove(id string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.remove(id)
+}
+
+// Close stops watching the memory cgroups of all containers.
+func (w *Watcher) Close() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for id := range w.containers {
+		w.remove(id)
+	}
+}
+
+// remove drops the container `id` from the watch of its cgroup, which gets
+// stopped with its last container. The caller has to hold the lock.
+func (w *Watcher) remove(id string) {
+	dir, ok := w.containers[id]
+	if !ok {
+		return
+	}
+	delete(w.containers, id)
+	cg := w.cgroups[dir]
+	delete(cg.known, id)
+	if len(cg.known) > 0 {
+		return
+	}
+	delete(w.cgroups, dir)
+	if err := cg.closer.Close(); err != nil {
+		logrus.Debugf("unable to stop watching memory cgroup %s: %v", dir, err)
+	}
+}
+
+// report records the OOM kill counter `kills` of the cgroup `cg` and calls
+// the handler for every container of the cgroup with kills beyond its known
+// ones.
+func (w *Watcher) report(cg *cgroupWatch, kills uint64) {
+	w.lock.Lock()
+	if kills > cg.kills {
+		cg.kills = kills
+	}
+	reports := make(map[string]uint64)
+	for id, known := range cg.known {
+		if kills > known {
+			reports[id] = kills - known
+			cg.known[id] = kills
+		}
+	}
+	w.lock.Unlock()
+
+	w.notify(reports)
+}
+
+// reportKill records a single OOM kill of the cgroup `cg` for all of its
+// containers, which is used for kernels without an OOM kill counter.
+func (w *Watcher) reportKill(cg *cgroupWatch) {
+	w.lock.Lock()
+	cg.kills++
+	reports := make(map[string]uint64)
+	for id := range cg.known {
+		reports[id] = 1
+		cg.known[id]++
+	}
+	w.lock.Unlock()
+
+	w.notify(reports)
+}
+
+// notify calls the handler with the new OOM kills per container ID.
+func (w *Watcher) notify(reports map[string]uint64) {
+	now := time.Now()
+	for id, kills := range reports {
+		logrus.Infof("container %s got %d new OOM kills", id, kills)
+		w.handler(id, kills, now)
+	}
+}
+
+// watchMemoryEvents watches the memory.events file of the unified hierarchy,
+// which gets modified on every change of its counters. It returns the current
+// OOM kill counter.
+func (w *Watcher) watchMemoryEvents(cg *cgroupWatch) (io.Closer, uint64, error) {
+	path := filepath.Join(cg.dir, memoryEventsFile)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return nil, 0, err
+	}
+
+	kills, err := readOOMKills(path)
+	if err != nil {
+		watcher.Close()
+		return nil, 0, err
+	}
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&fsnotify.Remove == fsnotify.Remove {
+					// The cgroup has been removed
+					return
+				}
+				kills, err := readOOMKills(path)
+				if err != nil {
+					if os.IsNotExist(errors.Cause(err)) {
+						return
+					}
+					logrus.Warnf("unable to read OOM kills of memory cgroup %s: %v", cg.dir, err)
+					continue
+				}
+				w.report(cg, kills)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logrus.Debugf("OOM watch error for memory cgroup %s: %v", cg.dir, err)
+			}
+		}
+	}()
+	return watcher, kills, nil
+}
+
+// readOOMKills reads the value of the OOM kill counter in the flat keyed
+// file `path`, which is zero if the kernel does not provide it.
+func readOOMKills(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[0] != oomKillKey {
+			continue
+		}
+		kills, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "invalid %s value in %s", oomKillKey, path)
+		}
+		return kills, nil
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,104 @@
+// +build linux
+
+package oom
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
+	"github.com/opencontainers/runc/libcontainer/cgroups"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// CgroupDir returns the directory of the memory cgroup of the process `pid`.
+func CgroupDir(pid int) (string, error) {
+	if pid <= 0 {
+		return "", fmt.Errorf("invalid pid %d", pid)
+	}
+	if cgroupv2.Enabled() {
+		path, err := cgroupv2.PidCgroup(pid)
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(cgroupv2.Root, path), nil
+	}
+
+	paths, err := cgroups.ParseCgroupFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return "", err
+	}
+	path, ok := paths["memory"]
+	if !ok {
+		return "", fmt.Errorf("no memory cgroup found for pid %d", pid)
+	}
+	mountpoint, err := cgroups.FindCgroupMountpoint("", "memory")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(mountpoint, path), nil
+}
+
+// watchOOMControl registers an eventfd for the memory.oom_control file of
+// cgroup v1, which gets notified on every OOM of the cgroup, and returns the
+// current OOM kill counter. Kernels without an OOM kill counter in that file
+// get one kill reported per notification.
+func (w *Watcher) watchOOMControl(cg *cgroupWatch) (io.Closer, uint64, error) {
+	controlPath := filepath.Join(cg.dir, oomControlFile)
+	control, err := os.Open(controlPath)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer control.Close()
+
+	// The eventfd is non-blocking to be pollable, which allows closing it
+	// while it is being read
+	fd, err := unix.Eventfd(0, unix.EFD_CLOEXEC|unix.EFD_NONBLOCK)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "unable to create eventfd")
+	}
+	eventfd := os.NewFile(uintptr(fd), "eventfd")
+
+	eventControlPath := filepath.Join(cg.dir, "cgroup.event_control")
+	data := fmt.Sprintf("%d %d", eventfd.Fd(), control.Fd())
+	if err := ioutil.WriteFile(eventControlPath, []byte(data), 0700); err != nil {
+		eventfd.Close()
+		return nil, 0, err
+	}
+
+	kills, err := readOOMKills(controlPath)
+	if err != nil {
+		eventfd.Close()
+		return nil, 0, err
+	}
+
+	go func() {
+		buf := make([]byte, 8)
+		for {
+			if _, err := eventfd.Read(buf); err != nil {
+				return
+			}
+			// The eventfd also gets notified if the cgroup is removed
+			if _, err := os.Lstat(eventControlPath); os.IsNotExist(err) {
+				return
+			}
+			kills, err := readOOMKills(controlPath)
+			if err != nil {
+				logrus.Warnf("unable to read OOM kills of memory cgroup %s: %v", cg.dir, err)
+				continue
+			}
+			if kills == 0 {
+				// No OOM kill counter available
+				w.reportKill(cg)
+				continue
+			}
+			w.report(cg, kills)
+		}
+	}()
+	return eventfd, kills, nil
+}
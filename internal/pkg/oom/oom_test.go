@@ -0,0 +1,152 @@
+package oom_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cri-o/cri-o/internal/pkg/oom"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type oomKill struct {
+	id    string
+	kills uint64
+}
+
+// The actual test suite
+var _ = t.Describe("OOM", func() {
+	var (
+		sut   *oom.Watcher
+		dir   string
+		kills chan oomKill
+	)
+
+	writeMemoryEvents := func(dir string, kills int) {
+		Expect(ioutil.WriteFile(filepath.Join(dir, "memory.events"),
+			[]byte("low 0\nhigh 0\nmax 3\noom 2\noom_kill "+
+				strconv.Itoa(kills)+"\n"), 0644)).To(BeNil())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "oom")
+		Expect(err).To(BeNil())
+		writeMemoryEvents(dir, 0)
+
+		kills = make(chan oomKill, 10)
+		sut = oom.New(func(id string, n uint64, at time.Time) {
+			kills <- oomKill{id, n}
+		})
+	})
+
+	AfterEach(func() {
+		sut.Close()
+		Expect(os.RemoveAll(dir)).To(BeNil())
+	})
+
+	t.Describe("Add", func() {
+		It("should report new OOM kills", func() {
+			// Given
+			Expect(sut.Add("id", dir, 0)).To(BeNil())
+			Consistently(kills, 200*time.Millisecond).ShouldNot(Receive())
+
+			// When
+			writeMemoryEvents(dir, 2)
+
+			// Then
+			Eventually(kills).Should(Receive(Equal(oomKill{"id", 2})))
+		})
+
+		It("should report OOM kills beyond the known ones", func() {
+			// Given
+			writeMemoryEvents(dir, 3)
+
+			// When
+			Expect(sut.Add("id", dir, 1)).To(BeNil())
+
+			// Then
+			Eventually(kills).Should(Receive(Equal(oomKill{"id", 2})))
+		})
+
+		It("should report the OOM kills of a shared cgroup to all containers", func() {
+			// Given
+			Expect(sut.Add("first", dir, 0)).To(BeNil())
+			Expect(sut.Add("second", dir, 0)).To(BeNil())
+
+			// When
+			writeMemoryEvents(dir, 1)
+
+			// Then
+			received := []oomKill{}
+			for i := 0; i < 2; i++ {
+				var kill oomKill
+				Eventually(kills).Should(Receive(&kill))
+				received = append(received, kill)
+			}
+			Expect(received).To(ConsistOf(oomKill{"first", 1}, oomKill{"second", 1}))
+			Consistently(kills, 200*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("should not report earlier OOM kills of a shared cgroup to a joining container", func() {
+			// Given
+			writeMemoryEvents(dir, 2)
+			Expect(sut.Add("first", dir, 2)).To(BeNil())
+
+			// When
+			Expect(sut.Add("second", dir, 0)).To(BeNil())
+			writeMemoryEvents(dir, 3)
+
+			// Then
+			received := []oomKill{}
+			for i := 0; i < 2; i++ {
+				var kill oomKill
+				Eventually(kills).Should(Receive(&kill))
+				received = append(received, kill)
+			}
+			Expect(received).To(ConsistOf(oomKill{"first", 1}, oomKill{"second", 1}))
+		})
+
+		It("should fail without memory cgroup files", func() {
+			// Given
+			Expect(os.Remove(filepath.Join(dir, "memory.events"))).To(BeNil())
+
+			// When
+			err := sut.Add("id", dir, 0)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	t.Describe("Remove", func() {
+		It("should stop reporting OOM kills", func() {
+			// Given
+			Expect(sut.Add("id", dir, 0)).To(BeNil())
+
+			// When
+			sut.Remove("id")
+			writeMemoryEvents(dir, 1)
+
+			// Then
+			Consistently(kills, 200*time.Millisecond).ShouldNot(Receive())
+		})
+
+		It("should keep watching a shared cgroup for the other containers", func() {
+			// Given
+			Expect(sut.Add("first", dir, 0)).To(BeNil())
+			Expect(sut.Add("second", dir, 0)).To(BeNil())
+
+			// When
+			sut.Remove("first")
+			writeMemoryEvents(dir, 1)
+
+			// Then
+			Eventually(kills).Should(Receive(Equal(oomKill{"second", 1})))
+			Consistently(kills, 200*time.Millisecond).ShouldNot(Receive())
+		})
+	})
+})
@@ -0,0 +1,17 @@
+// +build !linux
+
+package oom
+
+import (
+	"fmt"
+	"io"
+)
+
+// CgroupDir returns the directory of the memory cgroup of the process `pid`.
+func CgroupDir(pid int) (string, error) {
+	return "", fmt.Errorf("memory cgroups are not supported on this platform")
+}
+
+func (w *Watcher) watchOOMControl(cg *cgroupWatch) (io.Closer, uint64, error) {
+	return nil, 0, fmt.Errorf("memory cgroups are not supported on this platform")
+}
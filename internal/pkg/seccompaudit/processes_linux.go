@@ -0,0 +1,142 @@
+// +build linux
+
+package seccompaudit
+
+import (
+	"encoding/binary"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// cnIdxProc is the index and value of the process events connector
+	cnIdxProc = 1
+
+	// procCnMcastListen enables the process events of the connector
+	procCnMcastListen = 1
+
+	// procEventFork and procEventExec are the proc_event types of forks and
+	// execs
+	procEventFork = 0x00000001
+	procEventExec = 0x00000002
+
+	// sizeofCnMsg is the size of the struct cn_msg header
+	sizeofCnMsg = 20
+
+	// sizeofProcEventHeader is the size of the struct proc_event fields
+	// preceding the event data
+	sizeofProcEventHeader = 16
+)
+
+// nativeEndian is the byte order of the connector messages.
+var nativeEndian binary.ByteOrder = func() binary.ByteOrder {
+	i := uint16(1)
+	if *(*byte)(unsafe.Pointer(&i)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// watchProcesses reports the forks and the execs of the processes of the
+// host, which are read from the process events connector, until `stop` gets
+// closed. The events are reported while they happen, so that the processes
+// can be looked up before they exit.
+func watchProcesses(stop <-chan struct{}, forked func(parent, child int), execed func(pid int)) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM|unix.SOCK_CLOEXEC, unix.NETLINK_CONNECTOR)
+	if err != nil {
+		return errors.Wrap(err, "unable to create process events socket")
+	}
+	if err := listenProcessEvents(fd); err != nil {
+		unix.Close(fd)
+		return err
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		buf := make([]byte, unix.Getpagesize())
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			n, _, err := unix.Recvfrom(fd, buf, 0)
+			if err != nil {
+				switch err {
+				case unix.EAGAIN, unix.EINTR:
+				case unix.ENOBUFS:
+					logrus.Debugf("lost process events while collecting seccomp audit records")
+				default:
+					logrus.Warnf("unable to read process events: %v", err)
+					return
+				}
+				continue
+			}
+			msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+			if err != nil {
+				logrus.Debugf("unable to parse process events: %v", err)
+				continue
+			}
+			for _, msg := range msgs {
+				parseProcessEvent(msg.Data, forked, execed)
+			}
+		}
+	}()
+	return nil
+}
+
+// listenProcessEvents subscribes the netlink socket `fd` to the process
+// events connector.
+func listenProcessEvents(fd int) error {
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{
+		Family: unix.AF_NETLINK,
+		Groups: cnIdxProc,
+	}); err != nil {
+		return errors.Wrap(err, "unable to bind process events socket")
+	}
+	// Wake up regularly to notice that the watch got stopped
+	timeout := unix.NsecToTimeval(pollInterval.Nanoseconds())
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &timeout); err != nil {
+		return errors.Wrap(err, "unable to set process events socket timeout")
+	}
+
+	msg := make([]byte, unix.SizeofNlMsghdr+sizeofCnMsg+4)
+	nativeEndian.PutUint32(msg[0:], uint32(len(msg)))
+	nativeEndian.PutUint16(msg[4:], unix.NLMSG_DONE)
+	cn := msg[unix.SizeofNlMsghdr:]
+	nativeEndian.PutUint32(cn[0:], cnIdxProc)
+	nativeEndian.PutUint32(cn[4:], cnIdxProc)
+	nativeEndian.PutUint16(cn[16:], 4)
+	nativeEndian.PutUint32(cn[sizeofCnMsg:], procCnMcastListen)
+	if err := unix.Sendto(fd, msg, 0, &unix.SockaddrNetlink{Family: unix.AF_NETLINK}); err != nil {
+		return errors.Wrap(err, "unable to enable process events")
+	}
+	return nil
+}
+
+// parseProcessEvent reports the fork or exec in the connector message `data`.
+func parseProcessEvent(data []byte, forked func(parent, child int), execed func(pid int)) {
+	if len(data) < sizeofCnMsg+sizeofProcEventHeader {
+		return
+	}
+	event := data[sizeofCnMsg:]
+	payload := event[sizeofProcEventHeader:]
+	switch nativeEndian.Uint32(event[0:]) {
+	case procEventFork:
+		// parent_pid, parent_tgid, child_pid, child_tgid
+		if len(payload) < 16 {
+			return
+		}
+		forked(int(nativeEndian.Uint32(payload[4:])), int(nativeEndian.Uint32(payload[12:])))
+	case procEventExec:
+		// process_pid, process_tgid
+		if len(payload) < 8 {
+			return
+		}
+		execed(int(nativeEndian.Uint32(payload[4:])))
+	}
+}
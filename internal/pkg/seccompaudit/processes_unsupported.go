@@ -0,0 +1,11 @@
+// +build !linux
+
+package seccompaudit
+
+import (
+	"fmt"
+)
+
+func watchProcesses(stop <-chan struct{}, forked func(parent, child int), execed func(pid int)) error {
+	return fmt.Errorf("process events are not supported on this platform")
+}
@@ -0,0 +1,587 @@
+// Package seccompaudit collects the syscalls used by containers running with
+// a seccomp profile in audit mode, where every syscall gets logged by the
+// kernel instead of being denied, and generates minimal seccomp profiles from
+// them.
+package seccompaudit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	seccomp "github.com/seccomp/containers-golang"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ActLog is the seccomp action which logs the syscall and allows it
+	ActLog seccomp.Action = "SCMP_ACT_LOG"
+
+	// retLog is the SECCOMP_RET_LOG code of the audit records of syscalls
+	// matched by ActLog
+	retLog = 0x7ffc0000
+
+	// pollInterval is the interval in which the audit source gets read
+	pollInterval = 500 * time.Millisecond
+)
+
+// auditArches maps the AUDIT_ARCH_* values of the audit records to the
+// seccomp architectures.
+var auditArches = map[uint32]seccomp.Arch{
+	0x40000003: seccomp.ArchX86,
+	0xc000003e: seccomp.ArchX86_64,
+	0x40000028: seccomp.ArchARM,
+	0xc00000b7: seccomp.ArchAARCH64,
+	0x80000015: seccomp.ArchPPC64,
+	0xc0000015: seccomp.ArchPPC64LE,
+	0x00000016: seccomp.ArchS390,
+	0x80000016: seccomp.ArchS390X,
+}
+
+// Resolver returns whether the process `pid` belongs to the container `id`.
+type Resolver func(pid int, id string) bool
+
+// ProcessInContainer is the Resolver matching the container ID against the
+// cgroups of the process, which contain the container ID for both the
+// cgroupfs and the systemd cgroup manager.
+func ProcessInContainer(pid int, id string) bool {
+	cgroups, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(cgroups), id)
+}
+
+// Collector collects the syscalls of containers in audit mode from the
+// kernel audit records, which are read from an audit log or syslog file. The
+// file is only read while at least one container is collected. The processes
+// of the containers are looked up when they fork or exec, so that the records
+// of processes which exited before their records got read are still assigned
+// to their containers. The profiles generated so far are persisted, so that
+// the collection continues after a restart.
+type Collector struct {
+	source  string
+	dir     string
+	resolve Resolver
+
+	lock       sync.Mutex
+	containers map[string]*syscalls
+	dirty      map[string]struct{}
+	pids       map[int]string
+	file       *os.File
+	reader     *bufio.Reader
+	offset     int64
+	pending    string
+	stop       chan struct{}
+}
+
+// syscalls are the syscalls observed for a container, per architecture.
+type syscalls map[seccomp.Arch]map[string]struct{}
+
+// New creates a new Collector reading the audit records from the file
+// `source`, which uses `resolve` to find the container of a process. The
+// profiles get written to the directory `dir`.
+func New(source, dir string, resolve Resolver) *Collector {
+	return &Collector{
+		source:     source,
+		dir:        dir,
+		resolve:    resolve,
+		containers: make(map[string]*syscalls),
+		dirty:      make(map[string]struct{}),
+		pids:       make(map[int]string),
+	}
+}
+
+// ProfilePath returns the path of the profile generated for the container
+// `id`.
+func (c *Collector) ProfilePath(id string) string {
+	return filepath.Join(c.dir, id+".json")
+}
+
+// Add starts collecting the syscalls of the container `id`. Only the audit
+// records written after the first collected container got added are taken
+// into account, in addition to the syscalls of a profile persisted for the
+// container before.
+func (c *Collector) Add(id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.containers[id]; ok {
+		return nil
+	}
+	observed, err := c.load(id)
+	if err != nil {
+		logrus.Warnf("unable to load the persisted seccomp profile of container %s: %v", id, err)
+		observed = &syscalls{}
+	}
+	if c.file == nil {
+		if err := c.open(true); err != nil {
+			return errors.Wrapf(err, "unable to open seccomp audit source %s", c.source)
+		}
+		c.stop = make(chan struct{})
+		go c.poll(c.stop)
+		if err := watchProcesses(c.stop, c.forked, c.execed); err != nil {
+			logrus.Warnf("unable to watch process events, records of exited processes are not assigned to containers: %v", err)
+		}
+	}
+	c.containers[id] = observed
+	return nil
+}
+
+// Finish stops collecting the syscalls of the container `id`, writes the
+// generated profile and returns it. It returns nil if the container is not
+// collected.
+func (c *Collector) Finish(id string) (*seccomp.Seccomp, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	observed, ok := c.containers[id]
+	if !ok {
+		return nil, nil
+	}
+	// Catch up with the records of the last moments of the container
+	c.collect()
+	c.remove(id)
+	profile := observed.profile()
+	return profile, writeProfile(c.ProfilePath(id), profile)
+}
+
+// Remove stops collecting the syscalls of the container `id` and removes its
+// persisted profile.
+func (c *Collector) Remove(id string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.containers[id]; !ok {
+		return nil
+	}
+	c.remove(id)
+	if err := os.Remove(c.ProfilePath(id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Profile returns the profile generated from the syscalls observed so far
+// for the container `id`, or nil if the container is not collected.
+func (c *Collector) Profile(id string) *seccomp.Seccomp {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	observed, ok := c.containers[id]
+	if !ok {
+		return nil
+	}
+	return observed.profile()
+}
+
+// Close stops collecting the syscalls of all containers. The syscalls
+// observed so far are persisted.
+func (c *Collector) Close() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.collect()
+	c.persist()
+	c.containers = make(map[string]*syscalls)
+	c.dirty = make(map[string]struct{})
+	c.pids = make(map[int]string)
+	c.close()
+}
+
+// remove stops collecting the syscalls of the container `id`. The caller has
+// to hold the lock.
+func (c *Collector) remove(id string) {
+	delete(c.containers, id)
+	delete(c.dirty, id)
+	for pid, pidID := range c.pids {
+		if pidID == id {
+			delete(c.pids, pid)
+		}
+	}
+	if len(c.containers) == 0 {
+		c.close()
+	}
+}
+
+// poll reads the audit source in intervals until `stop` gets closed.
+func (c *Collector) poll(stop chan struct{}) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.lock.Lock()
+			c.collect()
+			c.persist()
+			c.lock.Unlock()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// forked records that the process `child` belongs to the same container as
+// its `parent`. A child of a process outside of the collected containers may
+// reuse the pid of an exited container process, which gets forgotten then.
+func (c *Collector) forked(parent, child int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if id, ok := c.pids[parent]; ok {
+		c.pids[child] = id
+	} else {
+		delete(c.pids, child)
+	}
+}
+
+// execed looks up the container of the process `pid` which executed a new
+// program, like the first process of a container.
+func (c *Collector) execed(pid int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	delete(c.pids, pid)
+	c.lookup(pid)
+}
+
+// lookup returns the collected container of the process `pid`. The container
+// is remembered, so that the records of the process can be assigned even
+// after it exited. The caller has to hold the lock.
+func (c *Collector) lookup(pid int) (string, bool) {
+	if id, ok := c.pids[pid]; ok {
+		if _, ok := c.containers[id]; ok {
+			return id, true
+		}
+		delete(c.pids, pid)
+	}
+	for id := range c.containers {
+		if c.resolve(pid, id) {
+			c.pids[pid] = id
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// load returns the syscalls of the profile persisted for the container `id`,
+// which is empty if there is none. The caller has to hold the lock.
+func (c *Collector) load(id string) (*syscalls, error) {
+	observed := &syscalls{}
+	data, err := ioutil.ReadFile(c.ProfilePath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return observed, nil
+		}
+		return nil, err
+	}
+	profile := &seccomp.Seccomp{}
+	if err := json.Unmarshal(data, profile); err != nil {
+		return nil, err
+	}
+	for _, arch := range profile.Architectures {
+		for _, syscall := range profile.Syscalls {
+			if syscall.Action != seccomp.ActAllow {
+				continue
+			}
+			for _, name := range syscall.Names {
+				observed.add(arch, name)
+			}
+		}
+	}
+	return observed, nil
+}
+
+// persist writes the profiles of the containers with newly observed
+// syscalls. The caller has to hold the lock.
+func (c *Collector) persist() {
+	for id := range c.dirty {
+		if err := writeProfile(c.ProfilePath(id), c.containers[id].profile()); err != nil {
+			logrus.Warnf("unable to persist the seccomp profile of container %s: %v", id, err)
+			continue
+		}
+		delete(c.dirty, id)
+	}
+}
+
+// open opens the audit source, starting at its end if `atEnd` is set. The
+// caller has to hold the lock.
+func (c *Collector) open(atEnd bool) error {
+	f, err := os.Open(c.source)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if atEnd {
+		if offset, err = f.Seek(0, io.SeekEnd); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	c.file = f
+	c.reader = bufio.NewReader(f)
+	c.offset = offset
+	c.pending = ""
+	return nil
+}
+
+// close closes the audit source and stops polling it. The caller has to hold
+// the lock.
+func (c *Collector) close() {
+	if c.file == nil {
+		return
+	}
+	close(c.stop)
+	c.file.Close()
+	c.file = nil
+	c.reader = nil
+}
+
+// collect reads the new audit records from the audit source. The source gets
+// reopened from its start if it has been rotated. The caller has to hold the
+// lock.
+func (c *Collector) collect() {
+	if c.file == nil {
+		return
+	}
+	if rotated, err := c.rotated(); err != nil {
+		logrus.Debugf("unable to check seccomp audit source %s for rotation: %v", c.source, err)
+	} else if rotated {
+		// Read the rest of the old file before switching over
+		c.readRecords()
+		c.file.Close()
+		if err := c.open(false); err != nil {
+			// The next added container opens the source again
+			logrus.Warnf("unable to reopen rotated seccomp audit source %s: %v", c.source, err)
+			close(c.stop)
+			c.file = nil
+			c.reader = nil
+			return
+		}
+	}
+	c.readRecords()
+}
+
+// rotated returns whether the audit source got replaced or truncated since
+// it has been opened. The caller has to hold the lock.
+func (c *Collector) rotated() (bool, error) {
+	current, err := os.Stat(c.source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			// The new file did not show up yet
+			return false, nil
+		}
+		return false, err
+	}
+	opened, err := c.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	return !os.SameFile(current, opened) || current.Size() < c.offset, nil
+}
+
+// readRecords reads all complete lines of the audit source and records the
+// syscalls of the collected containers. The caller has to hold the lock.
+func (c *Collector) readRecords() {
+	for {
+		line, err := c.reader.ReadString('\n')
+		c.offset += int64(len(line))
+		if err != nil {
+			// Keep the incomplete line until it got written completely
+			c.pending += line
+			if err != io.EOF {
+				logrus.Debugf("unable to read seccomp audit source %s: %v", c.source, err)
+			}
+			return
+		}
+		line = c.pending + line
+		c.pending = ""
+		c.record(line)
+	}
+}
+
+// record adds the syscall of the audit record `line` to its container. The
+// caller has to hold the lock.
+func (c *Collector) record(line string) {
+	r, ok := ParseRecord(line)
+	if !ok {
+		return
+	}
+	id, ok := c.lookup(r.Pid)
+	if !ok {
+		return
+	}
+	arch, ok := auditArches[r.Arch]
+	if !ok {
+		logrus.Debugf("unknown audit architecture %#x of container %s", r.Arch, id)
+		return
+	}
+	name, err := syscallName(arch, r.Syscall)
+	if err != nil {
+		logrus.Debugf("unable to resolve syscall %d of container %s: %v", r.Syscall, id, err)
+		return
+	}
+	if c.containers[id].add(arch, name) {
+		c.dirty[id] = struct{}{}
+	}
+}
+
+// SyscallName returns the name of the syscall `nr` of the architecture with
+// the AUDIT_ARCH_* value `auditArch`.
+func SyscallName(auditArch uint32, nr int) (string, error) {
+	arch, ok := auditArches[auditArch]
+	if !ok {
+		return "", errors.Errorf("unknown audit architecture %#x", auditArch)
+	}
+	return syscallName(arch, nr)
+}
+
+// SyscallNames returns the sorted names of all syscalls of the architectures
+// `arches`, or of the native architecture if `arches` is empty.
+func SyscallNames(arches []seccomp.Arch) ([]string, error) {
+	if len(arches) == 0 {
+		arches = []seccomp.Arch{""}
+	}
+	unique := make(map[string]struct{})
+	for _, arch := range arches {
+		names, err := syscallNames(arch)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			unique[name] = struct{}{}
+		}
+	}
+	names := make([]string, 0, len(unique))
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Record is a seccomp audit record of a syscall matched by ActLog.
+type Record struct {
+	// Pid is the process which called the syscall
+	Pid int
+	// Arch is the AUDIT_ARCH_* value of the syscall
+	Arch uint32
+	// Syscall is the number of the syscall
+	Syscall int
+}
+
+// ParseRecord parses the seccomp audit record `line`, which is either in the
+// format of the audit log (type=SECCOMP) or of the kernel log (type=1326).
+// It returns false if the line is no such record or the syscall did not match
+// ActLog.
+func ParseRecord(line string) (Record, bool) {
+	var r Record
+	if !strings.Contains(line, "type=SECCOMP ") && !strings.Contains(line, "type=1326 ") {
+		return r, false
+	}
+	var hasPid, hasArch, hasSyscall, isLog bool
+	for _, field := range strings.Fields(line) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "pid":
+			pid, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return r, false
+			}
+			r.Pid, hasPid = pid, true
+		case "arch":
+			arch, err := strconv.ParseUint(kv[1], 16, 32)
+			if err != nil {
+				return r, false
+			}
+			r.Arch, hasArch = uint32(arch), true
+		case "syscall":
+			nr, err := strconv.Atoi(kv[1])
+			if err != nil {
+				return r, false
+			}
+			r.Syscall, hasSyscall = nr, true
+		case "code":
+			code, err := strconv.ParseUint(strings.TrimPrefix(kv[1], "0x"), 16, 32)
+			if err != nil {
+				return r, false
+			}
+			isLog = code == retLog
+		}
+	}
+	return r, hasPid && hasArch && hasSyscall && isLog
+}
+
+// add records the syscall `name` for the architecture `arch` and returns
+// whether it has not been recorded before.
+func (s *syscalls) add(arch seccomp.Arch, name string) bool {
+	if *s == nil {
+		*s = make(syscalls)
+	}
+	if (*s)[arch] == nil {
+		(*s)[arch] = make(map[string]struct{})
+	}
+	if _, ok := (*s)[arch][name]; ok {
+		return false
+	}
+	(*s)[arch][name] = struct{}{}
+	return true
+}
+
+// profile returns the minimal profile allowing the observed syscalls and
+// denying all others.
+func (s *syscalls) profile() *seccomp.Seccomp {
+	profile := &seccomp.Seccomp{
+		DefaultAction: seccomp.ActErrno,
+		Syscalls:      []*seccomp.Syscall{},
+	}
+	names := make(map[string]struct{})
+	for arch, archNames := range *s {
+		profile.Architectures = append(profile.Architectures, arch)
+		for name := range archNames {
+			names[name] = struct{}{}
+		}
+	}
+	sort.Slice(profile.Architectures, func(i, j int) bool {
+		return profile.Architectures[i] < profile.Architectures[j]
+	})
+	if len(names) == 0 {
+		return profile
+	}
+	syscall := &seccomp.Syscall{Action: seccomp.ActAllow, Args: []*seccomp.Arg{}}
+	for name := range names {
+		syscall.Names = append(syscall.Names, name)
+	}
+	sort.Strings(syscall.Names)
+	profile.Syscalls = append(profile.Syscalls, syscall)
+	return profile
+}
+
+// writeProfile writes the seccomp `profile` to `path`.
+func writeProfile(path string, profile *seccomp.Seccomp) error {
+	data, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
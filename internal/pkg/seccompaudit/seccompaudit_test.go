@@ -0,0 +1,261 @@
+package seccompaudit_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/cri-o/cri-o/internal/pkg/seccompaudit"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	seccomp "github.com/seccomp/containers-golang"
+)
+
+// auditRecord returns a seccomp audit log record of the x86_64 syscall `nr`
+// of the process `pid`
+func auditRecord(pid, nr int) string {
+	return fmt.Sprintf("type=SECCOMP msg=audit(1589370245.436:1234): auid=4294967295 "+
+		"uid=0 gid=0 ses=4294967295 pid=%d comm=\"sh\" exe=\"/bin/sh\" sig=0 "+
+		"arch=c000003e syscall=%d compat=0 ip=0x7f2f1dd2e7b7 code=0x7ffc0000\n", pid, nr)
+}
+
+// skipUnlessSeccomp skips the current test if the syscall names cannot be
+// resolved because seccomp support is not compiled in
+func skipUnlessSeccomp() {
+	if !seccomp.IsEnabled() {
+		Skip("seccomp support is not compiled in")
+	}
+}
+
+// The actual test suite
+var _ = t.Describe("SeccompAudit", func() {
+	t.Describe("ParseRecord", func() {
+		It("should parse an audit log record", func() {
+			// Given
+			line := auditRecord(42, 59)
+
+			// When
+			record, ok := seccompaudit.ParseRecord(line)
+
+			// Then
+			Expect(ok).To(BeTrue())
+			Expect(record).To(Equal(seccompaudit.Record{
+				Pid: 42, Arch: 0xc000003e, Syscall: 59,
+			}))
+		})
+
+		It("should parse a kernel log record", func() {
+			// Given
+			line := "kernel: audit: type=1326 audit(1589370245.436:1234): auid=4294967295 " +
+				"uid=0 gid=0 ses=4294967295 pid=42 comm=\"sh\" exe=\"/bin/sh\" sig=0 " +
+				"arch=c000003e syscall=1 compat=0 ip=0x7f2f1dd2e7b7 code=0x7ffc0000"
+
+			// When
+			record, ok := seccompaudit.ParseRecord(line)
+
+			// Then
+			Expect(ok).To(BeTrue())
+			Expect(record.Syscall).To(Equal(1))
+		})
+
+		It("should ignore records of other actions", func() {
+			// Given
+			line := "type=SECCOMP msg=audit(1589370245.436:1234): pid=42 " +
+				"arch=c000003e syscall=1 code=0x50000"
+
+			// When
+			_, ok := seccompaudit.ParseRecord(line)
+
+			// Then
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should ignore other records", func() {
+			// Given
+			line := "type=SYSCALL msg=audit(1589370245.436:1234): pid=42 " +
+				"arch=c000003e syscall=1 code=0x7ffc0000"
+
+			// When
+			_, ok := seccompaudit.ParseRecord(line)
+
+			// Then
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	t.Describe("Collector", func() {
+		var (
+			sut     *seccompaudit.Collector
+			dir     string
+			source  string
+			running int32
+		)
+
+		appendRecords := func(records ...string) {
+			f, err := os.OpenFile(source, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+			Expect(err).To(BeNil())
+			defer f.Close()
+			for _, record := range records {
+				_, err := f.WriteString(record)
+				Expect(err).To(BeNil())
+			}
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "seccompaudit")
+			Expect(err).To(BeNil())
+			source = filepath.Join(dir, "audit.log")
+			appendRecords(auditRecord(42, 0))
+
+			running = 1
+			sut = seccompaudit.New(source, dir, func(pid int, id string) bool {
+				return atomic.LoadInt32(&running) == 1 && pid == 42 && id == "id"
+			})
+		})
+
+		AfterEach(func() {
+			sut.Close()
+			Expect(os.RemoveAll(dir)).To(BeNil())
+		})
+
+		It("should generate a profile of the observed syscalls", func() {
+			// Given
+			skipUnlessSeccomp()
+			Expect(sut.Add("id")).To(BeNil())
+
+			// When
+			appendRecords(auditRecord(42, 59), auditRecord(1, 2), auditRecord(42, 1))
+			profile, err := sut.Finish("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(profile).NotTo(BeNil())
+			Expect(profile.DefaultAction).To(Equal(seccomp.ActErrno))
+			Expect(profile.Architectures).To(Equal([]seccomp.Arch{seccomp.ArchX86_64}))
+			Expect(profile.Syscalls).To(HaveLen(1))
+			Expect(profile.Syscalls[0].Action).To(Equal(seccomp.ActAllow))
+			Expect(profile.Syscalls[0].Names).To(Equal([]string{"execve", "write"}))
+		})
+
+		It("should follow a rotated audit source", func() {
+			// Given
+			skipUnlessSeccomp()
+			Expect(sut.Add("id")).To(BeNil())
+			appendRecords(auditRecord(42, 59))
+
+			// When
+			Expect(os.Rename(source, source+".1")).To(BeNil())
+			appendRecords(auditRecord(42, 1))
+			profile, err := sut.Finish("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(profile).NotTo(BeNil())
+			Expect(profile.Syscalls).To(HaveLen(1))
+			Expect(profile.Syscalls[0].Names).To(Equal([]string{"execve", "write"}))
+		})
+
+		It("should return the profile observed so far", func() {
+			// Given
+			Expect(sut.Add("id")).To(BeNil())
+
+			// When
+			profile := sut.Profile("id")
+
+			// Then
+			Expect(profile).NotTo(BeNil())
+			Expect(profile.Syscalls).To(BeEmpty())
+		})
+
+		It("should assign the records of exited processes", func() {
+			// Given
+			skipUnlessSeccomp()
+			Expect(sut.Add("id")).To(BeNil())
+			appendRecords(auditRecord(42, 59))
+			Eventually(func() []*seccomp.Syscall {
+				return sut.Profile("id").Syscalls
+			}).Should(HaveLen(1))
+
+			// When
+			atomic.StoreInt32(&running, 0)
+			appendRecords(auditRecord(42, 1))
+			profile, err := sut.Finish("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(profile.Syscalls[0].Names).To(Equal([]string{"execve", "write"}))
+		})
+
+		It("should continue with the persisted profile", func() {
+			// Given
+			skipUnlessSeccomp()
+			Expect(sut.Add("id")).To(BeNil())
+			appendRecords(auditRecord(42, 59))
+			Eventually(func() error {
+				_, err := os.Stat(sut.ProfilePath("id"))
+				return err
+			}).Should(BeNil())
+			sut.Close()
+
+			// When
+			Expect(sut.Add("id")).To(BeNil())
+			appendRecords(auditRecord(42, 1))
+			profile, err := sut.Finish("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(profile.Syscalls[0].Names).To(Equal([]string{"execve", "write"}))
+		})
+
+		It("should write the profile when finished", func() {
+			// Given
+			Expect(sut.Add("id")).To(BeNil())
+
+			// When
+			_, err := sut.Finish("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.ProfilePath("id")).To(BeARegularFile())
+		})
+
+		It("should remove the profile of removed containers", func() {
+			// Given
+			Expect(sut.Add("id")).To(BeNil())
+			_, err := sut.Finish("id")
+			Expect(err).To(BeNil())
+			Expect(sut.Add("id")).To(BeNil())
+
+			// When
+			err = sut.Remove("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut.ProfilePath("id")).NotTo(BeAnExistingFile())
+		})
+
+		It("should return no profile for unknown containers", func() {
+			// Given
+			// When
+			profile, err := sut.Finish("id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(profile).To(BeNil())
+		})
+
+		It("should fail to add a container without audit source", func() {
+			// Given
+			sut = seccompaudit.New(filepath.Join(dir, "missing"), dir, seccompaudit.ProcessInContainer)
+
+			// When
+			err := sut.Add("id")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,26 @@
+package seccompaudit_test
+
+import (
+	"testing"
+
+	. "github.com/cri-o/cri-o/test/framework"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// TestSeccompAudit runs the created specs
+func TestSeccompAudit(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunFrameworkSpecs(t, "SeccompAudit")
+}
+
+var t *TestFramework
+
+var _ = BeforeSuite(func() {
+	t = NewTestFramework(NilFunc, NilFunc)
+	t.Setup()
+})
+
+var _ = AfterSuite(func() {
+	t.Teardown()
+})
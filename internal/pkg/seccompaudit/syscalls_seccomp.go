@@ -0,0 +1,82 @@
+// +build seccomp
+
+package seccompaudit
+
+import (
+	"fmt"
+	"sync"
+
+	seccomp "github.com/seccomp/containers-golang"
+	libseccomp "github.com/seccomp/libseccomp-golang"
+)
+
+// maxSyscall exceeds the syscall numbers of all supported architectures.
+const maxSyscall = 1024
+
+var (
+	archSyscallsLock sync.Mutex
+	archSyscalls     = make(map[seccomp.Arch][]string)
+)
+
+// syscallName returns the name of the syscall `nr` of the architecture
+// `arch`.
+func syscallName(arch seccomp.Arch, nr int) (string, error) {
+	scmpArch, err := libseccomp.GetArchFromString(scmpArches[arch])
+	if err != nil {
+		return "", err
+	}
+	return libseccomp.ScmpSyscall(nr).GetNameByArch(scmpArch)
+}
+
+// scmpArches maps the seccomp architectures to the names of libseccomp.
+var scmpArches = map[seccomp.Arch]string{
+	seccomp.ArchX86:     "x86",
+	seccomp.ArchX86_64:  "amd64",
+	seccomp.ArchARM:     "arm",
+	seccomp.ArchAARCH64: "arm64",
+	seccomp.ArchPPC64:   "ppc64",
+	seccomp.ArchPPC64LE: "ppc64le",
+	seccomp.ArchS390:    "s390",
+	seccomp.ArchS390X:   "s390x",
+}
+
+// syscallNames returns the names of all syscalls of the architecture `arch`,
+// or of the native architecture if `arch` is empty. Architectures without
+// audit support, like x32, have no names.
+func syscallNames(arch seccomp.Arch) ([]string, error) {
+	if arch == "" {
+		native, err := libseccomp.GetNativeArch()
+		if err != nil {
+			return nil, err
+		}
+		for a, name := range scmpArches {
+			if name == native.String() {
+				arch = a
+			}
+		}
+		if arch == "" {
+			return nil, fmt.Errorf("unsupported native architecture %s", native)
+		}
+	}
+
+	archSyscallsLock.Lock()
+	defer archSyscallsLock.Unlock()
+	if names, ok := archSyscalls[arch]; ok {
+		return names, nil
+	}
+	if _, ok := scmpArches[arch]; !ok {
+		return nil, nil
+	}
+	scmpArch, err := libseccomp.GetArchFromString(scmpArches[arch])
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for nr := 0; nr < maxSyscall; nr++ {
+		if name, err := libseccomp.ScmpSyscall(nr).GetNameByArch(scmpArch); err == nil && name != "" {
+			names = append(names, name)
+		}
+	}
+	archSyscalls[arch] = names
+	return names, nil
+}
@@ -0,0 +1,17 @@
+// +build !seccomp
+
+package seccompaudit
+
+import (
+	"fmt"
+
+	seccomp "github.com/seccomp/containers-golang"
+)
+
+func syscallName(arch seccomp.Arch, nr int) (string, error) {
+	return "", fmt.Errorf("seccomp is not supported in this build")
+}
+
+func syscallNames(arch seccomp.Arch) ([]string, error) {
+	return nil, fmt.Errorf("seccomp is not supported in this build")
+}
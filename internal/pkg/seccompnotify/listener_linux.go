@@ -0,0 +1,222 @@
+// +build linux
+
+package seccompnotify
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"github.com/cri-o/cri-o/internal/pkg/seccompaudit"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ioctls of the seccomp user notification file descriptor
+	seccompIoctlNotifRecv = 0xc0502100
+	seccompIoctlNotifSend = 0xc0182101
+
+	// seccompFdName is the name of the seccomp notification file descriptor
+	// in the container process state
+	seccompFdName = "seccompFd"
+
+	// maxFds is the maximum number of file descriptors accepted from the
+	// runtime
+	maxFds = 16
+)
+
+// seccompNotif is the struct seccomp_notif of the kernel.
+type seccompNotif struct {
+	ID    uint64
+	Pid   uint32
+	Flags uint32
+	Data  seccompData
+}
+
+// seccompData is the struct seccomp_data of the kernel.
+type seccompData struct {
+	Nr                 int32
+	Arch               uint32
+	InstructionPointer uint64
+	Args               [6]uint64
+}
+
+// seccompNotifResp is the struct seccomp_notif_resp of the kernel.
+type seccompNotifResp struct {
+	ID    uint64
+	Val   int64
+	Error int32
+	Flags uint32
+}
+
+// containerProcessState is the state the runtime sends along with the
+// seccomp notification file descriptor to the listenerPath.
+type containerProcessState struct {
+	Fds   []string    `json:"fds"`
+	State specs.State `json:"state"`
+}
+
+// Listen starts listening for the seccomp notification file descriptors of
+// containers on the socket, unless it already does.
+func (n *Notifier) Listen() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.listener != nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(n.path), 0700); err != nil {
+		return err
+	}
+	if err := os.Remove(n.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", n.path)
+	if err != nil {
+		return errors.Wrapf(err, "unable to listen on %s", n.path)
+	}
+	n.listener = listener
+	go n.accept(listener)
+	return nil
+}
+
+// accept serves the connections of the runtime until `listener` gets closed.
+func (n *Notifier) accept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go n.serve(conn.(*net.UnixConn))
+	}
+}
+
+// serve receives the seccomp notification file descriptor of a container
+// from `conn` and answers its notifications until the container exits.
+func (n *Notifier) serve(conn *net.UnixConn) {
+	id, fd, err := receive(conn)
+	conn.Close()
+	if err != nil {
+		logrus.Warnf("unable to receive seccomp notification file descriptor: %v", err)
+		return
+	}
+	defer unix.Close(fd)
+	logrus.Debugf("recording blocked syscalls of container %s", id)
+	n.setServing(id, true)
+	defer n.setServing(id, false)
+
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+	for {
+		fds[0].Revents = 0
+		if _, err := unix.Poll(fds, -1); err != nil {
+			if err == unix.EINTR {
+				continue
+			}
+			logrus.Warnf("unable to poll seccomp notifications of container %s: %v", id, err)
+			return
+		}
+		if fds[0].Revents&unix.POLLIN != 0 {
+			if err := n.handle(id, fd); err != nil {
+				logrus.Debugf("unable to handle seccomp notification of container %s: %v", id, err)
+			}
+			continue
+		}
+		if fds[0].Revents&(unix.POLLHUP|unix.POLLERR|unix.POLLNVAL) != 0 {
+			// All processes of the container exited
+			return
+		}
+	}
+}
+
+// receive reads the container process state and the seccomp notification
+// file descriptor sent by the runtime, and returns the container ID and the
+// file descriptor.
+func receive(conn *net.UnixConn) (string, int, error) {
+	buf := make([]byte, 4096)
+	oob := make([]byte, unix.CmsgSpace(maxFds*4))
+	nbytes, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+	if err != nil {
+		return "", -1, err
+	}
+	var fds []int
+	msgs, err := unix.ParseSocketControlMessage(oob[:oobn])
+	if err != nil {
+		return "", -1, err
+	}
+	for i := range msgs {
+		rights, err := unix.ParseUnixRights(&msgs[i])
+		if err != nil {
+			continue
+		}
+		fds = append(fds, rights...)
+	}
+	closeFds := func(keep int) {
+		for _, fd := range fds {
+			if fd != keep {
+				unix.Close(fd)
+			}
+		}
+	}
+
+	// The runtime closes the connection after sending the state
+	rest, err := ioutil.ReadAll(conn)
+	if err != nil {
+		closeFds(-1)
+		return "", -1, err
+	}
+	var state containerProcessState
+	if err := json.Unmarshal(append(buf[:nbytes], rest...), &state); err != nil {
+		closeFds(-1)
+		return "", -1, errors.Wrap(err, "unable to parse container process state")
+	}
+	for i, name := range state.Fds {
+		if name != seccompFdName {
+			continue
+		}
+		if i >= len(fds) {
+			break
+		}
+		closeFds(fds[i])
+		return state.State.ID, fds[i], nil
+	}
+	closeFds(-1)
+	return "", -1, fmt.Errorf("no %s received for container %q", seccompFdName, state.State.ID)
+}
+
+// handle answers the pending seccomp notification of the container `id` on
+// `fd` with EPERM and records the syscall.
+func (n *Notifier) handle(id string, fd int) error {
+	var req seccompNotif
+	if err := ioctl(fd, seccompIoctlNotifRecv, unsafe.Pointer(&req)); err != nil {
+		return errors.Wrap(err, "unable to receive notification")
+	}
+	resp := seccompNotifResp{ID: req.ID, Error: -int32(unix.EPERM)}
+	if err := ioctl(fd, seccompIoctlNotifSend, unsafe.Pointer(&resp)); err != nil {
+		// The process got interrupted or killed meanwhile, the syscall
+		// got blocked anyway
+		logrus.Debugf("unable to respond to seccomp notification of container %s: %v", id, err)
+	}
+
+	syscall, err := seccompaudit.SyscallName(req.Data.Arch, int(req.Data.Nr))
+	if err != nil {
+		logrus.Debugf("unable to resolve syscall %d of container %s: %v", req.Data.Nr, id, err)
+		syscall = fmt.Sprintf("%d", req.Data.Nr)
+	}
+	n.record(id, syscall)
+	return nil
+}
+
+// ioctl calls the ioctl `req` with the argument `arg` on `fd`.
+func ioctl(fd int, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, uintptr(fd), req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+// +build !linux
+
+package seccompnotify
+
+import "fmt"
+
+// Listen is not supported on this platform.
+func (n *Notifier) Listen() error {
+	return fmt.Errorf("seccomp notifications are not supported on this platform")
+}
@@ -0,0 +1,129 @@
+// Package seccompnotify records the syscalls blocked by the seccomp profiles
+// of containers. The OCI runtime hands the seccomp user notification file
+// descriptor of a container over to the listener socket of the Notifier,
+// which answers every notification with EPERM, the error the syscall would
+// have failed with without the notification.
+package seccompnotify
+
+import (
+	"net"
+	"sync"
+
+	seccomp "github.com/seccomp/containers-golang"
+)
+
+// ActNotify is the seccomp action which hands the syscall over to the seccomp
+// listener of the container
+const ActNotify seccomp.Action = "SCMP_ACT_NOTIFY"
+
+// Handler gets called for every blocked syscall `syscall` of the container
+// `id` with the number of times this syscall and all syscalls of the
+// container have been blocked.
+type Handler func(id, syscall string, count, total uint64)
+
+// Notifier receives the seccomp notifications of containers on a unix socket
+// and records their blocked syscalls.
+type Notifier struct {
+	path    string
+	handler Handler
+
+	lock     sync.Mutex
+	listener net.Listener
+	blocked  map[string]map[string]uint64
+	serving  map[string]struct{}
+}
+
+// New creates a new Notifier listening on the unix socket `path`, which
+// calls `handler` for every blocked syscall.
+func New(path string, handler Handler) *Notifier {
+	return &Notifier{
+		path:    path,
+		handler: handler,
+		blocked: make(map[string]map[string]uint64),
+		serving: make(map[string]struct{}),
+	}
+}
+
+// Path returns the path of the listener socket.
+func (n *Notifier) Path() string {
+	return n.path
+}
+
+// Blocked returns the blocked syscalls of the container `id` with the number
+// of times they have been blocked.
+func (n *Notifier) Blocked(id string) map[string]uint64 {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	blocked := make(map[string]uint64, len(n.blocked[id]))
+	for syscall, count := range n.blocked[id] {
+		blocked[syscall] = count
+	}
+	return blocked
+}
+
+// Recording returns whether the blocked syscalls of the container `id` get
+// recorded. The recording stops when all processes of the container exited,
+// and on restarts, because the seccomp notification file descriptor is only
+// held by the Notifier.
+func (n *Notifier) Recording(id string) bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	_, ok := n.serving[id]
+	return ok
+}
+
+// setServing records whether the notifications of the container `id` are
+// served.
+func (n *Notifier) setServing(id string, serving bool) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if serving {
+		n.serving[id] = struct{}{}
+	} else {
+		delete(n.serving, id)
+	}
+}
+
+// Remove forgets the blocked syscalls of the container `id`.
+func (n *Notifier) Remove(id string) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	delete(n.blocked, id)
+}
+
+// Close stops listening for new containers. The containers already handed
+// over keep being served until they exit.
+func (n *Notifier) Close() {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.listener != nil {
+		n.listener.Close()
+		n.listener = nil
+	}
+}
+
+// record counts the blocked syscall `syscall` of the container `id`.
+func (n *Notifier) record(id, syscall string) {
+	n.lock.Lock()
+	blocked, ok := n.blocked[id]
+	if !ok {
+		blocked = make(map[string]uint64)
+		n.blocked[id] = blocked
+	}
+	blocked[syscall]++
+	count := blocked[syscall]
+	var total uint64
+	for _, c := range blocked {
+		total += c
+	}
+	n.lock.Unlock()
+
+	if n.handler != nil {
+		n.handler(id, syscall, count, total)
+	}
+}
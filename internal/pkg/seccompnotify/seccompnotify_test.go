@@ -0,0 +1,175 @@
+package seccompnotify_test
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+	"unsafe"
+
+	"github.com/cri-o/cri-o/internal/pkg/seccompnotify"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	seccomp "github.com/seccomp/containers-golang"
+	"golang.org/x/sys/unix"
+)
+
+// notifyFilter installs a seccomp filter on the current thread which hands
+// getppid over to the returned seccomp notification file descriptor.
+func notifyFilter() (int, error) {
+	const (
+		seccompSetModeFilter         = 1
+		seccompFilterFlagNewListener = 1 << 3
+		seccompRetUserNotif          = 0x7fc00000
+		seccompRetAllow              = 0x7fff0000
+	)
+	filter := []unix.SockFilter{
+		{Code: unix.BPF_LD | unix.BPF_W | unix.BPF_ABS, K: 0},
+		{Code: unix.BPF_JMP | unix.BPF_JEQ | unix.BPF_K, Jt: 0, Jf: 1, K: unix.SYS_GETPPID},
+		{Code: unix.BPF_RET | unix.BPF_K, K: seccompRetUserNotif},
+		{Code: unix.BPF_RET | unix.BPF_K, K: seccompRetAllow},
+	}
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return -1, err
+	}
+	fd, _, errno := unix.Syscall(unix.SYS_SECCOMP, seccompSetModeFilter,
+		seccompFilterFlagNewListener, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return -1, errno
+	}
+	return int(fd), nil
+}
+
+// handOver sends the seccomp notification file descriptor `fd` of the
+// container `id` to the listener socket `path` like the runtime does.
+func handOver(path, id string, fd int) {
+	conn, err := net.Dial("unix", path)
+	Expect(err).To(BeNil())
+	defer conn.Close()
+	state := `{"ociVersion":"1.0.2","fds":["seccompFd"],"pid":1,` +
+		`"state":{"ociVersion":"1.0.2","id":"` + id + `","status":"creating"}}`
+	_, _, err = conn.(*net.UnixConn).WriteMsgUnix([]byte(state), unix.UnixRights(fd), nil)
+	Expect(err).To(BeNil())
+}
+
+// The actual test suite
+var _ = t.Describe("SeccompNotify", func() {
+	var (
+		sut     *seccompnotify.Notifier
+		dir     string
+		handled chan []interface{}
+	)
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "seccompnotify")
+		Expect(err).To(BeNil())
+		handled = make(chan []interface{}, 10)
+		sut = seccompnotify.New(filepath.Join(dir, "seccomp.sock"),
+			func(id, syscall string, count, total uint64) {
+				handled <- []interface{}{id, syscall, count, total}
+			})
+	})
+
+	AfterEach(func() {
+		sut.Close()
+		Expect(os.RemoveAll(dir)).To(BeNil())
+	})
+
+	It("should listen on the socket", func() {
+		// Given
+		// When
+		err := sut.Listen()
+
+		// Then
+		Expect(err).To(BeNil())
+		Expect(sut.Listen()).To(BeNil())
+		info, err := os.Stat(sut.Path())
+		Expect(err).To(BeNil())
+		Expect(info.Mode() & os.ModeSocket).NotTo(BeZero())
+	})
+
+	It("should record blocked syscalls", func() {
+		// Given
+		Expect(sut.Listen()).To(BeNil())
+		fds := make(chan int, 1)
+		errnos := make(chan unix.Errno, 1)
+		proceed := make(chan struct{})
+		go func() {
+			// The thread carrying the filter exits with the goroutine
+			runtime.LockOSThread()
+			fd, err := notifyFilter()
+			if err != nil {
+				fds <- -1
+				return
+			}
+			fds <- fd
+			<-proceed
+			_, _, errno := unix.Syscall(unix.SYS_GETPPID, 0, 0, 0)
+			errnos <- errno
+		}()
+		var fd int
+		Eventually(fds, 5*time.Second).Should(Receive(&fd))
+		if fd < 0 {
+			Skip("seccomp notifications are not supported by the kernel")
+		}
+		// Closing the last copy of the file descriptor fails the pending
+		// getppid, so the thread does not hang if the hand over fails
+		defer unix.Close(fd)
+
+		// Syscall numbers are recorded if their names cannot be resolved
+		syscall := "getppid"
+		if !seccomp.IsEnabled() {
+			syscall = strconv.Itoa(unix.SYS_GETPPID)
+		}
+
+		// When
+		handOver(sut.Path(), "id", fd)
+		Eventually(func() bool { return sut.Recording("id") }).Should(BeTrue())
+		close(proceed)
+
+		// Then
+		Eventually(errnos, 5*time.Second).Should(Receive(Equal(unix.EPERM)))
+		Eventually(handled).Should(Receive(Equal(
+			[]interface{}{"id", syscall, uint64(1), uint64(1)},
+		)))
+		Expect(sut.Blocked("id")).To(Equal(map[string]uint64{syscall: 1}))
+		sut.Remove("id")
+		Expect(sut.Blocked("id")).To(BeEmpty())
+		// The recording stops once the thread carrying the filter exited
+		Eventually(func() bool { return sut.Recording("id") }).Should(BeFalse())
+	})
+
+	It("should reject a runtime without seccomp file descriptor", func() {
+		// Given
+		Expect(sut.Listen()).To(BeNil())
+		conn, err := net.Dial("unix", sut.Path())
+		Expect(err).To(BeNil())
+		defer conn.Close()
+
+		// When
+		_, err = conn.Write([]byte(`{"fds":[],"state":{"id":"id"}}`))
+		Expect(err).To(BeNil())
+		Expect(conn.(*net.UnixConn).CloseWrite()).To(BeNil())
+
+		// Then
+		_, err = ioutil.ReadAll(conn)
+		Expect(err).To(BeNil())
+		Expect(sut.Blocked("id")).To(BeEmpty())
+		Expect(sut.Recording("id")).To(BeFalse())
+		Consistently(handled).ShouldNot(Receive())
+	})
+
+	It("should return no blocked syscalls of unknown containers", func() {
+		// Given
+		// When
+		blocked := sut.Blocked("id")
+
+		// Then
+		Expect(blocked).To(BeEmpty())
+	})
+})
@@ -0,0 +1,26 @@
+package seccompnotify_test
+
+import (
+	"testing"
+
+	. "github.com/cri-o/cri-o/test/framework"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// TestSeccompNotify runs the created specs
+func TestSeccompNotify(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunFrameworkSpecs(t, "SeccompNotify")
+}
+
+var t *TestFramework
+
+var _ = BeforeSuite(func() {
+	t = NewTestFramework(NilFunc, NilFunc)
+	t.Setup()
+})
+
+var _ = AfterSuite(func() {
+	t.Teardown()
+})
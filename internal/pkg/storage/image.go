@@ -9,7 +9,9 @@ import (
 	"sync"
 
 	"github.com/containers/image/copy"
+	"github.com/containers/image/docker"
 	"github.com/containers/image/docker/reference"
+	ciimage "github.com/containers/image/image"
 	"github.com/containers/image/pkg/sysregistriesv2"
 	"github.com/containers/image/signature"
 	istorage "github.com/containers/image/storage"
@@ -18,6 +20,7 @@ import (
 	"github.com/containers/libpod/pkg/rootless"
 	"github.com/containers/storage"
 	digest "github.com/opencontainers/go-digest"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -31,6 +34,8 @@ var (
 	ErrImageMultiplyTagged = errors.New("image still has multiple names applied")
 	// ErrNoRegistriesConfigured is returned when there are no registries configured in /etc/crio.conf#additional_registries
 	ErrNoRegistriesConfigured = errors.New(`no registries configured while trying to pull an unqualified image, add at least one in either /etc/crio/crio.conf or /etc/containers/registries.conf`)
+	// ErrImagePinned is returned when we try to remove a name from a pinned image
+	ErrImagePinned = errors.New("image is pinned and cannot be removed")
 )
 
 // ImageResult wraps a subset of information about an image: its ID, its names,
@@ -44,6 +49,10 @@ type ImageResult struct {
 	Digest       digest.Digest
 	ConfigDigest digest.Digest
 	User         string
+	Pinned       bool
+	// PullSource is the mirror or registry which served the latest pull of
+	// the image, if it has been pulled since the server started.
+	PullSource string
 }
 
 type indexInfo struct {
@@ -68,6 +77,13 @@ type imageService struct {
 	insecureRegistryCIDRs       []*net.IPNet
 	indexConfigs                map[string]*indexInfo
 	unqualifiedSearchRegistries []string
+	registriesLock              sync.RWMutex
+	pinnedImages                []pinnedImage
+	pinnedImagesLock            sync.RWMutex
+	registryMirrors             map[string][]string
+	shortNameAliases            map[string]string
+	pullLocations               map[string]string
+	pullLocationsLock           sync.Mutex
 	imageCache                  imageCache
 	imageCacheLock              sync.Mutex
 	ctx                         context.Context
@@ -81,10 +97,17 @@ type ImageServer interface {
 	// ImageStatus returns status of an image which matches the filter.
 	ImageStatus(systemContext *types.SystemContext, filter string) (*ImageResult, error)
 	// PrepareImage returns an Image where the config digest can be grabbed
-	// for further analysis. Call Close() on the resulting image.
-	PrepareImage(systemContext *types.SystemContext, imageName string) (types.ImageCloser, error)
-	// PullImage imports an image from the specified location.
-	PullImage(systemContext *types.SystemContext, imageName string, options *copy.Options) (types.ImageReference, error)
+	// for further analysis. The lookup gets aborted if the context `ctx` is
+	// done. Call Close() on the resulting image.
+	PrepareImage(ctx context.Context, systemContext *types.SystemContext, imageName string) (types.ImageCloser, error)
+	// PullImage imports an image from the specified location. The pull
+	// gets aborted if the context `ctx` is done.
+	PullImage(ctx context.Context, systemContext *types.SystemContext, imageName string, options *copy.Options) (types.ImageReference, error)
+	// VerifyImage checks the image `id` in store against the signature
+	// policy of the `systemContext`. The policy requirements of the registry
+	// references of its names apply, where the image is allowed if any name
+	// satisfies them with the signatures stored on pull.
+	VerifyImage(systemContext *types.SystemContext, id string) error
 	// UntagImage removes a name from the specified image, and if it was
 	// the only name the image had, removes the image.
 	UntagImage(systemContext *types.SystemContext, imageName string) error
@@ -95,6 +118,15 @@ type ImageServer interface {
 	// ResolveNames takes an image reference and if it's unqualified (w/o hostname),
 	// it uses crio's default registries to qualify it.
 	ResolveNames(systemContext *types.SystemContext, imageName string) ([]string, error)
+	// UpdateRegistries replaces the unqualified search registries and the
+	// insecure registries of the image server.
+	UpdateRegistries(systemContext *types.SystemContext, insecureRegistries, registries []string) error
+	// UpdatePinnedImages replaces the pinned images of the image server.
+	// Pinned images are reported as such and cannot be untagged.
+	UpdatePinnedImages(pinnedImages []string)
+	// UpdateRegistryMirrors replaces the registry mirrors and the short name
+	// aliases of the image server.
+	UpdateRegistryMirrors(registryMirrors map[string][]string, shortNameAliases map[string]string)
 }
 
 func (svc *imageService) getRef(name string) (types.ImageReference, error) {
@@ -189,6 +221,16 @@ func (svc *imageService) buildImageCacheItem(systemContext *types.SystemContext,
 func (svc *imageService) buildImageResult(image *storage.Image, cacheItem imageCacheItem) ImageResult {
 	name, tags, digests := sortNamesByType(image.Names)
 	imageDigest, repoDigests := svc.makeRepoDigests(digests, tags, image)
+	pinned := svc.isPinned(image, imageDigest, repoDigests)
+	var pullSource string
+	svc.pullLocationsLock.Lock()
+	for _, name := range image.Names {
+		if location, ok := svc.pullLocations[name]; ok {
+			pullSource = location
+			break
+		}
+	}
+	svc.pullLocationsLock.Unlock()
 	return ImageResult{
 		ID:           image.ID,
 		Name:         name,
@@ -198,6 +240,8 @@ func (svc *imageService) buildImageResult(image *storage.Image, cacheItem imageC
 		Digest:       imageDigest,
 		ConfigDigest: cacheItem.configDigest,
 		User:         cacheItem.user,
+		Pinned:       pinned,
+		PullSource:   pullSource,
 	}
 }
 
@@ -283,6 +327,81 @@ func (svc *imageService) ImageStatus(systemContext *types.SystemContext, nameOrI
 	return &result, nil
 }
 
+func (svc *imageService) VerifyImage(systemContext *types.SystemContext, id string) error {
+	ref, err := istorage.Transport.ParseStoreReference(svc.store, "@"+id)
+	if err != nil {
+		return err
+	}
+	image, err := istorage.Transport.GetStoreImage(svc.store, ref)
+	if err != nil {
+		return err
+	}
+
+	policy, err := signature.DefaultPolicy(systemContext)
+	if err != nil {
+		return err
+	}
+	policyContext, err := signature.NewPolicyContext(policy)
+	if err != nil {
+		return err
+	}
+	defer policyContext.Destroy() // nolint: errcheck
+
+	err = fmt.Errorf("image %s has no name to verify", id)
+	for _, name := range image.Names {
+		if err = svc.verifyImageName(systemContext, policyContext, name, image.ID); err == nil {
+			return nil
+		}
+		logrus.Debugf("image %s rejected as %s: %v", id, name, err)
+	}
+	return err
+}
+
+// verifyImageName checks the image `id` in store against the policy of the
+// registry reference of its `name`.
+func (svc *imageService) verifyImageName(systemContext *types.SystemContext, policyContext *signature.PolicyContext, name, id string) error {
+	named, err := reference.ParseNormalizedNamed(name)
+	if err != nil {
+		return err
+	}
+	registryRef, err := docker.NewReference(named)
+	if err != nil {
+		return err
+	}
+	storeRef, err := istorage.Transport.ParseStoreReference(svc.store, name+"@"+id)
+	if err != nil {
+		return err
+	}
+	src, err := storeRef.NewImageSource(svc.ctx, systemContext)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	unparsed := ciimage.UnparsedInstance(&storedImageSource{ImageSource: src, ref: registryRef}, nil)
+	allowed, err := policyContext.IsRunningImageAllowed(svc.ctx, unparsed)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return fmt.Errorf("image %s is not allowed by the signature policy", name)
+	}
+	return nil
+}
+
+// storedImageSource is the source of an image in store, which reports the
+// registry reference of the image, so that the signature policy of that
+// reference applies to it.
+type storedImageSource struct {
+	types.ImageSource
+	ref types.ImageReference
+}
+
+// Reference returns the registry reference of the image.
+func (s *storedImageSource) Reference() types.ImageReference {
+	return s.ref
+}
+
 func imageSize(img types.Image) *uint64 {
 	if sum, err := img.Size(); err == nil {
 		usum := uint64(sum)
@@ -311,13 +430,9 @@ func (svc *imageService) remoteImageReference(imageName string) (types.ImageRefe
 	return srcRef, nil
 }
 
-// prepareReference creates an image reference from an image string and returns an updated types.SystemContext (never nil) for the image
-func (svc *imageService) prepareReference(inputSystemContext *types.SystemContext, imageName string) (*types.SystemContext, types.ImageReference, error) {
-	srcRef, err := svc.remoteImageReference(imageName)
-	if err != nil {
-		return nil, nil, err
-	}
-
+// prepareSystemContext returns an updated types.SystemContext (never nil) for
+// the image reference `srcRef`
+func (svc *imageService) prepareSystemContext(inputSystemContext *types.SystemContext, srcRef types.ImageReference) *types.SystemContext {
 	sc := types.SystemContext{}
 	if inputSystemContext != nil {
 		sc = *inputSystemContext // A shallow copy
@@ -328,19 +443,113 @@ func (svc *imageService) prepareReference(inputSystemContext *types.SystemContex
 			sc.DockerInsecureSkipTLSVerify = types.OptionalBoolTrue
 		}
 	}
-	return &sc, srcRef, nil
+	return &sc
+}
+
+// pullSource is a location to pull an image from, which is either a mirror
+// or the registry of the image.
+type pullSource struct {
+	location string
+	// ref is the reference to pull, which keeps the identity of the image
+	// for the signature policy
+	ref types.ImageReference
+	// endpoint is the reference of the image on the mirror or the registry
+	endpoint types.ImageReference
+}
+
+// pullSources returns the sources to pull the image `srcRef` from, which are
+// the mirrors of its registry in order, followed by the registry itself.
+func (svc *imageService) pullSources(srcRef types.ImageReference) ([]pullSource, error) {
+	named := srcRef.DockerReference()
+	if named == nil || srcRef.Transport().Name() != docker.Transport.Name() {
+		return []pullSource{{ref: srcRef, endpoint: srcRef}}, nil
+	}
+	domain := reference.Domain(named)
+
+	svc.registriesLock.RLock()
+	mirrors := svc.registryMirrors[domain]
+	svc.registriesLock.RUnlock()
+
+	sources := make([]pullSource, 0, len(mirrors)+1)
+	for _, mirror := range mirrors {
+		mirrorRef, err := docker.ParseReference(
+			"//" + mirror + strings.TrimPrefix(named.String(), domain))
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror %s: %v", mirror, err)
+		}
+		sources = append(sources, pullSource{
+			location: mirror,
+			ref:      &mirrorReference{ImageReference: srcRef, mirror: mirrorRef},
+			endpoint: mirrorRef,
+		})
+	}
+	return append(sources, pullSource{location: domain, ref: srcRef, endpoint: srcRef}), nil
 }
 
-func (svc *imageService) PrepareImage(inputSystemContext *types.SystemContext, imageName string) (types.ImageCloser, error) {
-	systemContext, srcRef, err := svc.prepareReference(inputSystemContext, imageName)
+// mirrorReference is the reference of an image pulled from the mirror
+// `mirror`. The image sources it creates keep the identity of the embedded
+// reference of the image, so that the signature policy of the image applies
+// to it, and not the one of the mirror.
+type mirrorReference struct {
+	types.ImageReference
+	mirror types.ImageReference
+}
+
+// NewImage returns the image pulled from the mirror.
+func (r *mirrorReference) NewImage(ctx context.Context, sys *types.SystemContext) (types.ImageCloser, error) {
+	src, err := r.NewImageSource(ctx, sys)
 	if err != nil {
 		return nil, err
 	}
+	return ciimage.FromSource(ctx, sys, src)
+}
 
-	return srcRef.NewImage(svc.ctx, systemContext)
+// NewImageSource returns the source of the image pulled from the mirror.
+func (r *mirrorReference) NewImageSource(ctx context.Context, sys *types.SystemContext) (types.ImageSource, error) {
+	src, err := r.mirror.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	return &mirrorImageSource{ImageSource: src, ref: r.ImageReference}, nil
+}
+
+// mirrorImageSource is the source of an image pulled from a mirror, which
+// reports the reference of the image instead of the one of the mirror.
+type mirrorImageSource struct {
+	types.ImageSource
+	ref types.ImageReference
 }
 
-func (svc *imageService) PullImage(systemContext *types.SystemContext, imageName string, inputOptions *copy.Options) (types.ImageReference, error) {
+// Reference returns the reference of the image.
+func (s *mirrorImageSource) Reference() types.ImageReference {
+	return s.ref
+}
+
+func (svc *imageService) PrepareImage(ctx context.Context, inputSystemContext *types.SystemContext, imageName string) (types.ImageCloser, error) {
+	srcRef, err := svc.remoteImageReference(imageName)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := svc.pullSources(srcRef)
+	if err != nil {
+		return nil, err
+	}
+
+	var img types.ImageCloser
+	for _, source := range sources {
+		img, err = source.ref.NewImage(ctx, svc.prepareSystemContext(inputSystemContext, source.endpoint))
+		if err == nil {
+			return img, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		logrus.Debugf("unable to prepare image %s from %s: %v", imageName, source.location, err)
+	}
+	return nil, err
+}
+
+func (svc *imageService) PullImage(ctx context.Context, systemContext *types.SystemContext, imageName string, inputOptions *copy.Options) (types.ImageReference, error) {
 	policy, err := signature.DefaultPolicy(systemContext)
 	if err != nil {
 		return nil, err
@@ -351,11 +560,14 @@ func (svc *imageService) PullImage(systemContext *types.SystemContext, imageName
 	}
 
 	options := *inputOptions // A shallow copy
-	srcSystemContext, srcRef, err := svc.prepareReference(options.SourceCtx, imageName)
+	srcRef, err := svc.remoteImageReference(imageName)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := svc.pullSources(srcRef)
 	if err != nil {
 		return nil, err
 	}
-	options.SourceCtx = srcSystemContext
 
 	dest := imageName
 	if srcRef.DockerReference() != nil {
@@ -365,11 +577,24 @@ func (svc *imageService) PullImage(systemContext *types.SystemContext, imageName
 	if err != nil {
 		return nil, err
 	}
-	_, err = copy.Image(svc.ctx, policyContext, destRef, srcRef, &options)
-	if err != nil {
-		return nil, err
+	for _, source := range sources {
+		options.SourceCtx = svc.prepareSystemContext(inputOptions.SourceCtx, source.endpoint)
+		if _, err = copy.Image(ctx, policyContext, destRef, source.ref, &options); err != nil {
+			logrus.Debugf("unable to pull image %s from %s: %v", imageName, source.location, err)
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			continue
+		}
+		if source.location != "" {
+			logrus.Infof("pulled image %s from %s", dest, source.location)
+			svc.pullLocationsLock.Lock()
+			svc.pullLocations[dest] = source.location
+			svc.pullLocationsLock.Unlock()
+		}
+		return destRef, nil
 	}
-	return destRef, nil
+	return nil, err
 }
 
 func (svc *imageService) UntagImage(systemContext *types.SystemContext, nameOrID string) error {
@@ -401,11 +626,43 @@ func (svc *imageService) UntagImage(systemContext *types.SystemContext, nameOrID
 		}
 
 		if len(prunedNames) > 0 {
-			return svc.store.SetNames(img.ID, prunedNames)
+			// The image is kept with its other names
+			if svc.isPinnedName(name) {
+				return ErrImagePinned
+			}
+			if err := svc.store.SetNames(img.ID, prunedNames); err != nil {
+				return err
+			}
+			svc.forgetPullSources(name, nameOrID)
+			return nil
 		}
 	}
 
-	return ref.DeleteImage(svc.ctx, systemContext)
+	// Avoid looking up the digest in the store, the pinned images are matched
+	// against the digests known by the image
+	_, tags, repoDigests := sortNamesByType(img.Names)
+	if img.Digest != "" {
+		_, repoDigests = svc.makeRepoDigests(repoDigests, tags, img)
+	}
+	if svc.isPinned(img, img.Digest, repoDigests) {
+		return ErrImagePinned
+	}
+
+	if err := ref.DeleteImage(svc.ctx, systemContext); err != nil {
+		return err
+	}
+	svc.forgetPullSources(img.Names...)
+	return nil
+}
+
+// forgetPullSources drops the recorded pull sources of the removed image
+// names `names`.
+func (svc *imageService) forgetPullSources(names ...string) {
+	svc.pullLocationsLock.Lock()
+	defer svc.pullLocationsLock.Unlock()
+	for _, name := range names {
+		delete(svc.pullLocations, name)
+	}
 }
 
 func (svc *imageService) GetStore() storage.Store {
@@ -413,6 +670,9 @@ func (svc *imageService) GetStore() storage.Store {
 }
 
 func (svc *imageService) isSecureIndex(indexName string) bool {
+	svc.registriesLock.RLock()
+	defer svc.registriesLock.RUnlock()
+
 	if index, ok := svc.indexConfigs[indexName]; ok {
 		return index.secure
 	}
@@ -458,6 +718,19 @@ func splitDockerDomain(name string) (domain, remainder string) {
 	return
 }
 
+// splitTagOrDigest splits the image `name` into its repository and its tag or
+// digest suffix, including the separator.
+func splitTagOrDigest(name string) (repository, tagOrDigest string) {
+	repository = name
+	if i := strings.IndexRune(repository, '@'); i != -1 {
+		repository, tagOrDigest = repository[:i], repository[i:]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, tagOrDigest = repository[:i], repository[i:]+tagOrDigest
+	}
+	return repository, tagOrDigest
+}
+
 // ResolveNames resolves an image name into a storage image ID or a fully-qualified image name (domain/repo/image:tag).
 // Will only return an empty slice if err != nil.
 func (svc *imageService) ResolveNames(systemContext *types.SystemContext, imageName string) ([]string, error) {
@@ -490,16 +763,34 @@ func (svc *imageService) ResolveNames(systemContext *types.SystemContext, imageN
 		}
 		return []string{imageName}, nil
 	}
+	// a short name with an alias is resolved to the alias only, never against
+	// the search registries
+	repository, tagOrDigest := splitTagOrDigest(remainder)
+	svc.registriesLock.RLock()
+	alias, hasAlias := svc.shortNameAliases[repository]
+	unqualifiedSearchRegistries := svc.unqualifiedSearchRegistries
+	svc.registriesLock.RUnlock()
+	if hasAlias {
+		image := alias + tagOrDigest
+		registry, err := sysregistriesv2.FindRegistry(systemContext, image)
+		if err != nil {
+			return nil, err
+		}
+		if registry != nil && registry.Blocked {
+			return nil, fmt.Errorf("cannot use alias %q of %q because it's blocked", image, imageName)
+		}
+		return []string{image}, nil
+	}
 	// we got an unqualified image here, we can't go ahead w/o registries configured
 	// properly.
-	if len(svc.unqualifiedSearchRegistries) == 0 {
+	if len(unqualifiedSearchRegistries) == 0 {
 		return nil, ErrNoRegistriesConfigured
 	}
 	// this means we got an image in the form of "busybox"
 	// we need to use additional registries...
 	// normalize the unqualified image to be domain/repo/image...
 	images := []string{}
-	for _, r := range svc.unqualifiedSearchRegistries {
+	for _, r := range unqualifiedSearchRegistries {
 		rem := remainder
 		if r == "docker.io" && !strings.ContainsRune(remainder, '/') {
 			rem = "library/" + rem
@@ -538,14 +829,26 @@ func GetImageService(ctx context.Context, sc *types.SystemContext, store storage
 	}
 
 	is := &imageService{
-		store:                 store,
-		defaultTransport:      defaultTransport,
-		indexConfigs:          make(map[string]*indexInfo),
-		insecureRegistryCIDRs: make([]*net.IPNet, 0),
-		imageCache:            make(map[string]imageCacheItem),
-		ctx:                   ctx,
+		store:            store,
+		defaultTransport: defaultTransport,
+		imageCache:       make(map[string]imageCacheItem),
+		pullLocations:    make(map[string]string),
+		ctx:              ctx,
 	}
 
+	if err := is.UpdateRegistries(sc, insecureRegistries, registries); err != nil {
+		return nil, err
+	}
+
+	return is, nil
+}
+
+// UpdateRegistries replaces the unqualified search registries and the insecure
+// registries of the image service. If no registries are provided, then the
+// unqualified search registries of the system registries configuration will
+// be used.
+func (svc *imageService) UpdateRegistries(sc *types.SystemContext, insecureRegistries, registries []string) error {
+	var unqualifiedSearchRegistries []string
 	if len(registries) != 0 {
 		seenRegistries := make(map[string]bool, len(registries))
 		cleanRegistries := []string{}
@@ -557,31 +860,164 @@ func GetImageService(ctx context.Context, sc *types.SystemContext, store storage
 			seenRegistries[r] = true
 		}
 
-		is.unqualifiedSearchRegistries = cleanRegistries
+		unqualifiedSearchRegistries = cleanRegistries
 	} else {
 		systemRegistries, err := sysregistriesv2.UnqualifiedSearchRegistries(sc)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		is.unqualifiedSearchRegistries = systemRegistries
+		unqualifiedSearchRegistries = systemRegistries
 	}
 
-	insecureRegistries = append(insecureRegistries, "127.0.0.0/8")
+	indexConfigs := make(map[string]*indexInfo)
+	insecureRegistryCIDRs := make([]*net.IPNet, 0)
+
+	// Copy the provided slice to not modify the backing array of the caller
+	insecureRegistries = append(append([]string{}, insecureRegistries...), "127.0.0.0/8")
 	// Split --insecure-registry into CIDR and registry-specific settings.
 	for _, r := range insecureRegistries {
 		// Check if CIDR was passed to --insecure-registry
 		_, ipnet, err := net.ParseCIDR(r)
 		if err == nil {
 			// Valid CIDR.
-			is.insecureRegistryCIDRs = append(is.insecureRegistryCIDRs, ipnet)
+			insecureRegistryCIDRs = append(insecureRegistryCIDRs, ipnet)
 		} else {
 			// Assume `host:port` if not CIDR.
-			is.indexConfigs[r] = &indexInfo{
+			indexConfigs[r] = &indexInfo{
 				name:   r,
 				secure: false,
 			}
 		}
 	}
 
-	return is, nil
+	svc.registriesLock.Lock()
+	defer svc.registriesLock.Unlock()
+	svc.unqualifiedSearchRegistries = unqualifiedSearchRegistries
+	svc.indexConfigs = indexConfigs
+	svc.insecureRegistryCIDRs = insecureRegistryCIDRs
+	return nil
+}
+
+// UpdateRegistryMirrors replaces the registry mirrors and the short name
+// aliases of the image service.
+func (svc *imageService) UpdateRegistryMirrors(registryMirrors map[string][]string, shortNameAliases map[string]string) {
+	svc.registriesLock.Lock()
+	defer svc.registriesLock.Unlock()
+	svc.registryMirrors = registryMirrors
+	svc.shortNameAliases = shortNameAliases
+}
+
+// pinnedImage is a normalized entry of the pinned images.
+type pinnedImage struct {
+	// pattern is the normalized image name, digest or name prefix
+	pattern string
+	// prefix indicates that the pattern is a name prefix
+	prefix bool
+}
+
+// matchesName returns true if the image name `name` matches the pinned image.
+func (p pinnedImage) matchesName(name string) bool {
+	if p.prefix {
+		return strings.HasPrefix(name, p.pattern)
+	}
+	return name == p.pattern
+}
+
+// normalizePinnedPrefix qualifies the name prefix `prefix` like image names
+// are stored, e.g. "busybox" becomes "docker.io/library/busybox".
+func normalizePinnedPrefix(prefix string) string {
+	if prefix == "" {
+		return prefix
+	}
+	i := strings.IndexRune(prefix, '/')
+	if i == -1 {
+		// A prefix of a repository name of the default registry
+		return "docker.io/library/" + prefix
+	}
+	domain, remainder := prefix[:i], prefix[i+1:]
+	if !strings.ContainsAny(domain, ".:") && domain != "localhost" {
+		domain, remainder = "docker.io", prefix
+	}
+	if domain == "docker.io" && remainder != "" && !strings.ContainsRune(remainder, '/') {
+		remainder = "library/" + remainder
+	}
+	return domain + "/" + remainder
+}
+
+// UpdatePinnedImages replaces the pinned images of the image service. Every
+// entry can be an exact image name, a name prefix ending with "*", an image ID
+// or a digest.
+func (svc *imageService) UpdatePinnedImages(pinnedImages []string) {
+	normalized := make([]pinnedImage, 0, len(pinnedImages))
+	for _, pattern := range pinnedImages {
+		if pattern == "" {
+			continue
+		}
+		if strings.HasSuffix(pattern, "*") {
+			normalized = append(normalized, pinnedImage{
+				pattern: normalizePinnedPrefix(strings.TrimSuffix(pattern, "*")),
+				prefix:  true,
+			})
+			continue
+		}
+		// Image names are stored fully qualified and tagged
+		if _, err := digest.Parse(pattern); err != nil {
+			if named, err := reference.ParseNormalizedNamed(pattern); err == nil {
+				pattern = reference.TagNameOnly(named).String()
+			}
+		}
+		normalized = append(normalized, pinnedImage{pattern: pattern})
+	}
+
+	svc.pinnedImagesLock.Lock()
+	defer svc.pinnedImagesLock.Unlock()
+	svc.pinnedImages = normalized
+}
+
+// isPinned returns true if any of the names, the ID or the digests of the
+// image matches a pinned image.
+func (svc *imageService) isPinned(image *storage.Image, imageDigest digest.Digest, repoDigests []string) bool {
+	svc.pinnedImagesLock.RLock()
+	defer svc.pinnedImagesLock.RUnlock()
+	if len(svc.pinnedImages) == 0 {
+		return false
+	}
+
+	names := append(append([]string{}, image.Names...), repoDigests...)
+	ids := []string{image.ID, "sha256:" + image.ID}
+	for _, d := range append([]digest.Digest{imageDigest, image.Digest}, image.Digests...) {
+		if d != "" {
+			ids = append(ids, d.String())
+		}
+	}
+	candidates := append(append([]string{}, names...), ids...)
+
+	for _, pinned := range svc.pinnedImages {
+		if pinned.prefix {
+			for _, name := range names {
+				if pinned.matchesName(name) {
+					return true
+				}
+			}
+			continue
+		}
+		for _, candidate := range candidates {
+			if candidate == pinned.pattern {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isPinnedName returns true if the image name `name` matches a pinned image.
+func (svc *imageService) isPinnedName(name string) bool {
+	svc.pinnedImagesLock.RLock()
+	defer svc.pinnedImagesLock.RUnlock()
+	for _, pinned := range svc.pinnedImages {
+		if pinned.matchesName(name) {
+			return true
+		}
+	}
+	return false
 }
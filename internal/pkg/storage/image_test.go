@@ -2,8 +2,13 @@ package storage_test
 
 import (
 	"context"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/containers/image/copy"
 	"github.com/containers/image/types"
@@ -171,6 +176,24 @@ var _ = t.Describe("Image", func() {
 			Expect(names[0]).To(Equal(imageName))
 		})
 
+		It("should succeed to resolve with short name alias", func() {
+			// Given
+			sut.UpdateRegistryMirrors(nil, map[string]string{
+				testImageName: "quay.io/crio/" + testImageName,
+			})
+			gomock.InOrder(
+				storeMock.EXPECT().Image(gomock.Any()).
+					Return(&cs.Image{ID: "id"}, nil),
+			)
+
+			// When
+			names, err := sut.ResolveNames(nil, testImageName+":1.0")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(names).To(Equal([]string{"quay.io/crio/" + testImageName + ":1.0"}))
+		})
+
 		It("should succeed to resolve with a local copy", func() {
 			// Given
 			gomock.InOrder(
@@ -246,6 +269,37 @@ var _ = t.Describe("Image", func() {
 		})
 	})
 
+	t.Describe("UpdateRegistries", func() {
+		It("should succeed to resolve with updated registries", func() {
+			// Given
+			gomock.InOrder(
+				storeMock.EXPECT().Image(gomock.Any()).
+					Return(&cs.Image{ID: "id"}, nil),
+			)
+			Expect(sut.UpdateRegistries(nil, []string{},
+				[]string{"quay.io", "quay.io"})).To(BeNil())
+
+			// When
+			names, err := sut.ResolveNames(nil, testImageName)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(names).To(Equal([]string{"quay.io/" + testImageName}))
+		})
+
+		It("should fail if unqualified search registries errors", func() {
+			// Given
+			// When
+			err := sut.UpdateRegistries(
+				&types.SystemContext{SystemRegistriesConfPath: "/invalid"},
+				[]string{}, []string{},
+			)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
 	t.Describe("UntagImage", func() {
 		It("should succeed to untag an image", func() {
 			// Given
@@ -264,6 +318,109 @@ var _ = t.Describe("Image", func() {
 			Expect(err).To(BeNil())
 		})
 
+		It("should fail to untag a pinned image", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{testImageName})
+			inOrder(
+				mockGetRef(),
+				mockGetStoreImage(storeMock, testNormalizedImageName, testSHA256),
+			)
+
+			// When
+			err := sut.UntagImage(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(Equal(storage.ErrImagePinned))
+		})
+
+		It("should fail to untag an image pinned by prefix", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{"docker.io/library/*"})
+			inOrder(
+				mockGetRef(),
+				mockGetStoreImage(storeMock, testNormalizedImageName, testSHA256),
+			)
+
+			// When
+			err := sut.UntagImage(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(Equal(storage.ErrImagePinned))
+		})
+
+		It("should fail to untag an image pinned by ID", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{"sha256:" + testSHA256})
+			inOrder(
+				mockGetRef(),
+				mockGetStoreImage(storeMock, testNormalizedImageName, testSHA256),
+			)
+
+			// When
+			err := sut.UntagImage(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(Equal(storage.ErrImagePinned))
+		})
+
+		It("should fail to untag an image pinned by an unqualified prefix", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{"ima*"})
+			inOrder(
+				mockGetRef(),
+				mockGetStoreImage(storeMock, testNormalizedImageName, testSHA256),
+			)
+
+			// When
+			err := sut.UntagImage(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(Equal(storage.ErrImagePinned))
+		})
+
+		It("should succeed to untag a name of an image pinned by another name", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{"localhost/b:latest"})
+			inOrder(
+				mockGetRef(),
+				// storage.Transport.GetStoreImage:
+				storeMock.EXPECT().Image(testNormalizedImageName).
+					Return(&cs.Image{
+						ID:    testSHA256,
+						Names: []string{testNormalizedImageName, "localhost/b:latest"},
+					}, nil),
+
+				storeMock.EXPECT().SetNames(testSHA256, []string{"localhost/b:latest"}).
+					Return(nil),
+			)
+
+			// When
+			err := sut.UntagImage(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should fail to untag a pinned name of an image with other names", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{testImageName})
+			inOrder(
+				mockGetRef(),
+				// storage.Transport.GetStoreImage:
+				storeMock.EXPECT().Image(testNormalizedImageName).
+					Return(&cs.Image{
+						ID:    testSHA256,
+						Names: []string{testNormalizedImageName, "localhost/b:latest"},
+					}, nil),
+			)
+
+			// When
+			err := sut.UntagImage(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(Equal(storage.ErrImagePinned))
+		})
+
 		It("should fail to untag an image with invalid name", func() {
 			// Given
 			// When
@@ -367,6 +524,34 @@ var _ = t.Describe("Image", func() {
 			Expect(res).NotTo(BeNil())
 		})
 
+		It("should succeed to get the status of a pinned image", func() {
+			// Given
+			sut.UpdatePinnedImages([]string{"localhost/a@sha256:" + testSHA256})
+			inOrder(
+				mockGetRef(),
+				// storage.Transport.GetStoreImage:
+				storeMock.EXPECT().Image(testNormalizedImageName).
+					Return(&cs.Image{
+						ID: testSHA256,
+						Names: []string{testNormalizedImageName,
+							"localhost/a@sha256:" + testSHA256},
+					}, nil),
+				// buildImageCacheItem
+				mockNewImage(storeMock, testNormalizedImageName, testSHA256),
+				// makeRepoDigests
+				storeMock.EXPECT().ImageBigDataDigest(testSHA256, gomock.Any()).
+					Return(digest.Digest("a:"+testSHA256), nil),
+			)
+
+			// When
+			res, err := sut.ImageStatus(&types.SystemContext{}, testImageName)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(res).NotTo(BeNil())
+			Expect(res.Pinned).To(BeTrue())
+		})
+
 		It("should fail to get on wrong reference", func() {
 			// Given
 			// When
@@ -582,7 +767,7 @@ var _ = t.Describe("Image", func() {
 			const imageName = "tarball:../../../test/testdata/image.tar"
 
 			// When
-			res, err := sut.PrepareImage(&types.SystemContext{}, imageName)
+			res, err := sut.PrepareImage(context.Background(), &types.SystemContext{}, imageName)
 
 			// Then
 			Expect(err).To(BeNil())
@@ -592,7 +777,7 @@ var _ = t.Describe("Image", func() {
 		It("should fail on invalid image name", func() {
 			// Given
 			// When
-			res, err := sut.PrepareImage(&types.SystemContext{}, "")
+			res, err := sut.PrepareImage(context.Background(), &types.SystemContext{}, "")
 
 			// Then
 			Expect(err).NotTo(BeNil())
@@ -604,7 +789,7 @@ var _ = t.Describe("Image", func() {
 		It("should fail on invalid image name", func() {
 			// Given
 			// When
-			res, err := sut.PullImage(&types.SystemContext{}, "",
+			res, err := sut.PullImage(context.Background(), &types.SystemContext{}, "",
 				&copy.Options{})
 
 			// Then
@@ -615,7 +800,7 @@ var _ = t.Describe("Image", func() {
 		It("should fail on invalid policy path", func() {
 			// Given
 			// When
-			res, err := sut.PullImage(&types.SystemContext{
+			res, err := sut.PullImage(context.Background(), &types.SystemContext{
 				SignaturePolicyPath: "/not-existing",
 			}, "", &copy.Options{})
 
@@ -630,7 +815,7 @@ var _ = t.Describe("Image", func() {
 			mockParseStoreReference(storeMock, "localhost/busybox:latest")
 
 			// When
-			res, err := sut.PullImage(&types.SystemContext{
+			res, err := sut.PullImage(context.Background(), &types.SystemContext{
 				SignaturePolicyPath: "../../../test/policy.json",
 			}, imageName, &copy.Options{})
 
@@ -639,13 +824,60 @@ var _ = t.Describe("Image", func() {
 			Expect(res).To(BeNil())
 		})
 
+		It("should apply the policy of the image pulled from a mirror", func() {
+			// Given
+			const imageName = "docker://localhost/busybox:latest"
+			mockParseStoreReference(storeMock, "localhost/busybox:latest")
+			// The mirror serves the manifest, so that the policy gets
+			// evaluated, and records the requested blobs
+			blobs := make(chan string, 10)
+			mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasPrefix(r.URL.Path, "/v2/busybox/blobs/") {
+					blobs <- r.URL.Path
+				}
+				switch r.URL.Path {
+				case "/v2/":
+				case "/v2/busybox/manifests/latest":
+					w.Header().Set("Content-Type", "application/vnd.docker.distribution.manifest.v2+json")
+					fmt.Fprint(w, `{"schemaVersion": 2,`+
+						`"mediaType": "application/vnd.docker.distribution.manifest.v2+json",`+
+						`"config": {"mediaType": "application/vnd.docker.container.image.v1+json",`+
+						`"size": 2, "digest": "sha256:`+testSHA256+`"}, "layers": []}`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer mirror.Close()
+			sut.UpdateRegistryMirrors(map[string][]string{
+				"localhost": {strings.TrimPrefix(mirror.URL, "http://")},
+			}, nil)
+			dir, err := ioutil.TempDir("", "policy")
+			Expect(err).To(BeNil())
+			defer os.RemoveAll(dir)
+			policyPath := filepath.Join(dir, "policy.json")
+			Expect(ioutil.WriteFile(policyPath, []byte(`{
+				"default": [{"type": "reject"}],
+				"transports": {"docker": {"localhost/busybox": [{"type": "insecureAcceptAnything"}]}}
+			}`), 0600)).To(BeNil())
+
+			// When
+			res, err := sut.PullImage(context.Background(), &types.SystemContext{
+				SignaturePolicyPath: policyPath,
+			}, imageName, &copy.Options{})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(res).To(BeNil())
+			Expect(blobs).To(Receive(Equal("/v2/busybox/blobs/sha256:" + testSHA256)))
+		})
+
 		It("should fail on canonical copy image", func() {
 			// Given
 			const imageName = "docker://localhost/busybox@sha256:" + testSHA256
 			mockParseStoreReference(storeMock, "localhost/busybox@sha256:"+testSHA256)
 
 			// When
-			res, err := sut.PullImage(&types.SystemContext{
+			res, err := sut.PullImage(context.Background(), &types.SystemContext{
 				SignaturePolicyPath: "../../../test/policy.json",
 			}, imageName, &copy.Options{})
 
@@ -654,4 +886,65 @@ var _ = t.Describe("Image", func() {
 			Expect(res).To(BeNil())
 		})
 	})
+
+	t.Describe("VerifyImage", func() {
+		var systemContext *types.SystemContext
+
+		writePolicy := func(policy string) {
+			policyPath := filepath.Join(t.MustTempDir("policy"), "policy.json")
+			Expect(ioutil.WriteFile(policyPath, []byte(policy), 0644)).To(BeNil())
+			systemContext = &types.SystemContext{SignaturePolicyPath: policyPath}
+		}
+
+		mockStoreReference := func() {
+			storeMock.EXPECT().GraphOptions().Return([]string{}).AnyTimes()
+			storeMock.EXPECT().GraphDriverName().Return("").AnyTimes()
+			storeMock.EXPECT().GraphRoot().Return("").AnyTimes()
+			storeMock.EXPECT().RunRoot().Return("").AnyTimes()
+		}
+
+		It("should succeed if the policy accepts the image", func() {
+			// Given
+			writePolicy(`{"default":[{"type":"insecureAcceptAnything"}]}`)
+			mockStoreReference()
+			storeMock.EXPECT().Image(testSHA256).
+				Return(&cs.Image{ID: testSHA256, Names: []string{testNormalizedImageName}}, nil).
+				Times(2)
+
+			// When
+			err := sut.VerifyImage(systemContext, testSHA256)
+
+			// Then
+			Expect(err).To(BeNil())
+		})
+
+		It("should fail if the policy rejects the image", func() {
+			// Given
+			writePolicy(`{"default":[{"type":"reject"}]}`)
+			mockStoreReference()
+			storeMock.EXPECT().Image(testSHA256).
+				Return(&cs.Image{ID: testSHA256, Names: []string{testNormalizedImageName}}, nil).
+				Times(2)
+
+			// When
+			err := sut.VerifyImage(systemContext, testSHA256)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail if the image has no names", func() {
+			// Given
+			writePolicy(`{"default":[{"type":"insecureAcceptAnything"}]}`)
+			mockStoreReference()
+			storeMock.EXPECT().Image(testSHA256).
+				Return(&cs.Image{ID: testSHA256}, nil)
+
+			// When
+			err := sut.VerifyImage(systemContext, testSHA256)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
 })
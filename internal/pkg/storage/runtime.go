@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/containers/image/copy"
@@ -12,6 +13,7 @@ import (
 	"github.com/containers/image/types"
 	"github.com/containers/storage"
 	cstorage "github.com/containers/storage"
+	"github.com/containers/storage/pkg/archive"
 	"github.com/containers/storage/pkg/idtools"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/pkg/errors"
@@ -72,7 +74,9 @@ type RuntimeServer interface {
 	// with the pod's infrastructure container having the same value for
 	// both its pod's ID and its container ID.
 	// Pointer arguments can be nil.  Either the image name or ID can be
-	// omitted, but not both.  All other arguments are required.
+	// omitted.  If both are omitted, the infrastructure container is
+	// created without an image, which is used by pod sandboxes without an
+	// infrastructure container process.  All other arguments are required.
 	CreatePodSandbox(systemContext *types.SystemContext, podName, podID, imageName, imageAuthFile, imageID, containerName, metadataName, uid, namespace string, attempt uint32, idMappings *idtools.IDMappings, labelOptions []string) (ContainerInfo, error)
 	// RemovePodSandbox deletes a pod sandbox's infrastructure container.
 	// The CRI expects that a sandbox can't be removed unless its only
@@ -111,6 +115,14 @@ type RuntimeServer interface {
 	// specific to the container.  It will be removed automatically when
 	// the container is deleted.
 	GetRunDir(id string) (string, error)
+
+	// GetContainerRootfsDiff returns an uncompressed tar stream of the
+	// changes which have been made to the container's root filesystem on
+	// top of its image.
+	GetContainerRootfsDiff(idOrName string) (io.ReadCloser, error)
+	// ApplyContainerRootfsDiff applies a tar stream, as returned by
+	// GetContainerRootfsDiff, to the container's root filesystem.
+	ApplyContainerRootfsDiff(idOrName string, diff io.Reader) error
 }
 
 // RuntimeContainerMetadata is the structure that we encode as JSON and store
@@ -140,6 +152,10 @@ type RuntimeContainerMetadata struct {
 	Attempt      uint32 `json:"attempt,omitempty"`    // Applicable to both PodSandboxes and Containers
 	// Pod is true if this is the pod's infrastructure container.
 	Pod bool `json:"pod,omitempty"` // Applicable to both PodSandboxes and Containers
+	// The host ID range allocated for the pod's own user namespace, kept to
+	// reserve it again after a restart.
+	UsernsHostID uint32 `json:"userns-host-id,omitempty"` // Only applicable to pods
+	UsernsSize   uint32 `json:"userns-size,omitempty"`    // Only applicable to pods
 }
 
 // SetMountLabel updates the mount label held by a RuntimeContainerMetadata
@@ -149,11 +165,12 @@ func (metadata *RuntimeContainerMetadata) SetMountLabel(mountLabel string) {
 }
 
 func (r *runtimeService) createContainerOrPodSandbox(systemContext *types.SystemContext, podName, podID, imageName, imageAuthFile, imageID, containerName, containerID, metadataName, uid, namespace string, attempt uint32, idMappings *idtools.IDMappings, labelOptions []string, isPauseImage bool) (ContainerInfo, error) {
-	var ref types.ImageReference
 	if podName == "" || podID == "" {
 		return ContainerInfo{}, ErrInvalidPodName
 	}
-	if imageName == "" && imageID == "" {
+	// Pod sandboxes without an infra container process do not need an image
+	withoutImage := isPauseImage && imageName == "" && imageID == ""
+	if imageName == "" && imageID == "" && !withoutImage {
 		return ContainerInfo{}, ErrInvalidImageName
 	}
 	if containerName == "" {
@@ -163,79 +180,23 @@ func (r *runtimeService) createContainerOrPodSandbox(systemContext *types.System
 		metadataName = containerName
 	}
 
-	// Check if we have the specified image.
-	ref, err := istorage.Transport.ParseStoreReference(r.storageImageServer.GetStore(), imageName)
-	if err != nil {
-		// Maybe it's some other transport's copy of the image?
-		otherRef, err2 := alltransports.ParseImageName(imageName)
-		if err2 == nil && otherRef.DockerReference() != nil {
-			ref, err = istorage.Transport.ParseStoreReference(r.storageImageServer.GetStore(), otherRef.DockerReference().String())
-		}
-		if err != nil {
-			// Maybe the image ID is sufficient?
-			ref, err = istorage.Transport.ParseStoreReference(r.storageImageServer.GetStore(), "@"+imageID)
-			if err != nil {
-				return ContainerInfo{}, err
-			}
-		}
-	}
-	img, err := istorage.Transport.GetStoreImage(r.storageImageServer.GetStore(), ref)
-	if img == nil && errors.Cause(err) == storage.ErrImageUnknown && isPauseImage {
-		image := imageID
-		if imageName != "" {
-			image = imageName
-		}
-		if image == "" {
-			return ContainerInfo{}, ErrInvalidImageName
-		}
-		logrus.Debugf("couldn't find image %q, retrieving it", image)
-		sourceCtx := types.SystemContext{}
-		if systemContext != nil {
-			sourceCtx = *systemContext // A shallow copy
-		}
-		if imageAuthFile != "" {
-			sourceCtx.AuthFilePath = imageAuthFile
-		}
-		ref, err = r.storageImageServer.PullImage(systemContext, image, &copy.Options{
-			SourceCtx:      &sourceCtx,
-			DestinationCtx: systemContext,
-		})
-		if err != nil {
-			return ContainerInfo{}, err
-		}
-		img, err = istorage.Transport.GetStoreImage(r.storageImageServer.GetStore(), ref)
+	var (
+		img         *storage.Image
+		imageConfig *v1.Image
+	)
+	if !withoutImage {
+		var err error
+		img, imageConfig, err = r.getImage(systemContext, imageName, imageAuthFile, imageID, isPauseImage)
 		if err != nil {
 			return ContainerInfo{}, err
 		}
-		logrus.Debugf("successfully pulled image %q", image)
-	}
-	if img == nil && errors.Cause(err) == storage.ErrImageUnknown {
-		if imageID == "" {
-			return ContainerInfo{}, fmt.Errorf("image %q not present in image store", imageName)
-		}
-		if imageName == "" {
-			return ContainerInfo{}, fmt.Errorf("image with ID %q not present in image store", imageID)
-		}
-		return ContainerInfo{}, fmt.Errorf("image %q with ID %q not present in image store", imageName, imageID)
-	}
 
-	// Pull out a copy of the image's configuration.
-	image, err := ref.NewImage(r.ctx, systemContext)
-	if err != nil {
-		return ContainerInfo{}, err
-	}
-	defer image.Close()
-
-	imageConfig, err := image.OCIConfig(r.ctx)
-	if err != nil {
-		return ContainerInfo{}, err
-	}
-
-	// Update the image name and ID.
-	if imageName == "" && len(img.Names) > 0 {
-		imageName = img.Names[0]
+		// Update the image name and ID.
+		if imageName == "" && len(img.Names) > 0 {
+			imageName = img.Names[0]
+		}
+		imageID = img.ID
 	}
-	imageID = img.ID
 
 	// Build metadata to store with the container.
 	metadata := RuntimeContainerMetadata{
@@ -268,7 +229,7 @@ func (r *runtimeService) createContainerOrPodSandbox(systemContext *types.System
 	if idMappings != nil {
 		coptions.IDMappingOptions = cstorage.IDMappingOptions{UIDMap: idMappings.UIDs(), GIDMap: idMappings.GIDs()}
 	}
-	container, err := r.storageImageServer.GetStore().CreateContainer(containerID, names, img.ID, "", string(mdata), &coptions)
+	container, err := r.storageImageServer.GetStore().CreateContainer(containerID, names, imageID, "", string(mdata), &coptions)
 	if err != nil {
 		if metadata.Pod {
 			logrus.Debugf("failed to create pod sandbox %s(%s): %v", metadata.PodName, metadata.PodID, err)
@@ -345,6 +306,81 @@ func (r *runtimeService) createContainerOrPodSandbox(systemContext *types.System
 	}, nil
 }
 
+// getImage returns the image `imageName` or `imageID` together with its
+// configuration. The image is pulled if it is a missing pause image.
+func (r *runtimeService) getImage(systemContext *types.SystemContext, imageName, imageAuthFile, imageID string, isPauseImage bool) (*storage.Image, *v1.Image, error) {
+	var ref types.ImageReference
+	// Check if we have the specified image.
+	ref, err := istorage.Transport.ParseStoreReference(r.storageImageServer.GetStore(), imageName)
+	if err != nil {
+		// Maybe it's some other transport's copy of the image?
+		otherRef, err2 := alltransports.ParseImageName(imageName)
+		if err2 == nil && otherRef.DockerReference() != nil {
+			ref, err = istorage.Transport.ParseStoreReference(r.storageImageServer.GetStore(), otherRef.DockerReference().String())
+		}
+		if err != nil {
+			// Maybe the image ID is sufficient?
+			ref, err = istorage.Transport.ParseStoreReference(r.storageImageServer.GetStore(), "@"+imageID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	img, err := istorage.Transport.GetStoreImage(r.storageImageServer.GetStore(), ref)
+	if img == nil && errors.Cause(err) == storage.ErrImageUnknown && isPauseImage {
+		image := imageID
+		if imageName != "" {
+			image = imageName
+		}
+		if image == "" {
+			return nil, nil, ErrInvalidImageName
+		}
+		logrus.Debugf("couldn't find image %q, retrieving it", image)
+		sourceCtx := types.SystemContext{}
+		if systemContext != nil {
+			sourceCtx = *systemContext // A shallow copy
+		}
+		if imageAuthFile != "" {
+			sourceCtx.AuthFilePath = imageAuthFile
+		}
+		ref, err = r.storageImageServer.PullImage(r.ctx, systemContext, image, &copy.Options{
+			SourceCtx:      &sourceCtx,
+			DestinationCtx: systemContext,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		img, err = istorage.Transport.GetStoreImage(r.storageImageServer.GetStore(), ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		logrus.Debugf("successfully pulled image %q", image)
+	}
+	if img == nil && errors.Cause(err) == storage.ErrImageUnknown {
+		if imageID == "" {
+			return nil, nil, fmt.Errorf("image %q not present in image store", imageName)
+		}
+		if imageName == "" {
+			return nil, nil, fmt.Errorf("image with ID %q not present in image store", imageID)
+		}
+		return nil, nil, fmt.Errorf("image %q with ID %q not present in image store", imageName, imageID)
+	}
+
+	// Pull out a copy of the image's configuration.
+	image, err := ref.NewImage(r.ctx, systemContext)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer image.Close()
+
+	imageConfig, err := image.OCIConfig(r.ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return img, imageConfig, nil
+}
+
 func (r *runtimeService) CreatePodSandbox(systemContext *types.SystemContext, podName, podID, imageName, imageAuthFile, imageID, containerName, metadataName, uid, namespace string, attempt uint32, idMappings *idtools.IDMappings, labelOptions []string) (ContainerInfo, error) {
 	return r.createContainerOrPodSandbox(systemContext, podName, podID, imageName, imageAuthFile, imageID, containerName, podID, metadataName, uid, namespace, attempt, idMappings, labelOptions, true)
 }
@@ -466,6 +502,35 @@ func (r *runtimeService) GetRunDir(id string) (string, error) {
 	return r.storageImageServer.GetStore().ContainerRunDirectory(container.ID)
 }
 
+func (r *runtimeService) GetContainerRootfsDiff(idOrName string) (io.ReadCloser, error) {
+	container, err := r.storageImageServer.GetStore().Container(idOrName)
+	if err != nil {
+		if errors.Cause(err) == storage.ErrContainerUnknown {
+			return nil, ErrInvalidContainerID
+		}
+		return nil, err
+	}
+	compression := archive.Uncompressed
+	return r.storageImageServer.GetStore().Diff("", container.LayerID, &storage.DiffOptions{
+		Compression: &compression,
+	})
+}
+
+func (r *runtimeService) ApplyContainerRootfsDiff(idOrName string, diff io.Reader) error {
+	container, err := r.storageImageServer.GetStore().Container(idOrName)
+	if err != nil {
+		if errors.Cause(err) == storage.ErrContainerUnknown {
+			return ErrInvalidContainerID
+		}
+		return err
+	}
+	if _, err := r.storageImageServer.GetStore().ApplyDiff(container.LayerID, diff); err != nil {
+		logrus.Debugf("failed to apply rootfs diff to container %q: %v", container.ID, err)
+		return err
+	}
+	return nil
+}
+
 // GetRuntimeService returns a RuntimeServer that uses the passed-in image
 // service to pull and manage images, and its store to manage containers based
 // on those images.
@@ -732,6 +732,37 @@ var _ = t.Describe("Runtime", func() {
 			Expect(err).NotTo(BeNil())
 		})
 
+		It("should succeed to create a pod sandbox without image", func() {
+			// Given
+			inOrder(
+				imageServerMock.EXPECT().GetStore().Return(storeMock),
+				storeMock.EXPECT().CreateContainer("podID", gomock.Any(),
+					"", "", gomock.Any(), gomock.Any()).
+					Return(&cs.Container{ID: "id"}, nil),
+				imageServerMock.EXPECT().GetStore().Return(storeMock),
+				storeMock.EXPECT().Names(gomock.Any()).Return([]string{}, nil),
+				imageServerMock.EXPECT().GetStore().Return(storeMock),
+				storeMock.EXPECT().SetNames(gomock.Any(), gomock.Any()).Return(nil),
+				imageServerMock.EXPECT().GetStore().Return(storeMock),
+				storeMock.EXPECT().ContainerDirectory(gomock.Any()).
+					Return("dir", nil),
+				imageServerMock.EXPECT().GetStore().Return(storeMock),
+				storeMock.EXPECT().ContainerRunDirectory(gomock.Any()).
+					Return("runDir", nil),
+			)
+
+			// When
+			info, err := sut.CreatePodSandbox(&types.SystemContext{},
+				"podName", "podID", "", "", "",
+				"containerName", "metadataName",
+				"uid", "namespace", 0, &idtools.IDMappings{}, []string{"mountLabel"})
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(info.ID).To(Equal("id"))
+			Expect(info.Config).To(BeNil())
+		})
+
 		It("should fail to create a pod sandbox on names retrieval error", func() {
 			// Given
 			inOrder(
@@ -839,7 +870,7 @@ var _ = t.Describe("Runtime", func() {
 				mockParseStoreReference(storeMock, "pauseimagename"),
 				imageServerMock.EXPECT().GetStore().Return(storeMock),
 				mockGetStoreImage(storeMock, "docker.io/library/pauseimagename:latest", ""),
-				imageServerMock.EXPECT().PullImage(gomock.Any(), "pauseimagename", expectedCopyOptions).Return(pulledRef, nil),
+				imageServerMock.EXPECT().PullImage(gomock.Any(), gomock.Any(), "pauseimagename", expectedCopyOptions).Return(pulledRef, nil),
 				imageServerMock.EXPECT().GetStore().Return(storeMock),
 				mockGetStoreImage(storeMock, "docker.io/library/pauseimagename:latest", "123"),
 				mockNewImage(storeMock, "docker.io/library/pauseimagename:latest", "nonempty"),
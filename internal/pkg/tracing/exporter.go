@@ -0,0 +1,279 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// ServiceName is the name of the service reported to the collector
+	ServiceName = "crio"
+
+	// scopeName is the name of the instrumentation scope of all spans
+	scopeName = "github.com/cri-o/cri-o"
+
+	// tracesPath is the OTLP HTTP path to which the spans are exported
+	tracesPath = "/v1/traces"
+
+	// queueSize is the maximum number of spans waiting to be exported.
+	// Further spans are dropped.
+	queueSize = 2048
+
+	// batchSize is the maximum number of spans exported at once
+	batchSize = 512
+
+	// exportInterval is the maximum delay before a span gets exported
+	exportInterval = 5 * time.Second
+
+	// exportTimeout is the timeout of a single export request
+	exportTimeout = 10 * time.Second
+)
+
+// Tracer creates spans and exports the sampled ones to an OTLP collector.
+type Tracer struct {
+	url          string
+	samplingRate int
+	client       *http.Client
+	queue        chan *Span
+	flush        chan chan struct{}
+	done         chan struct{}
+}
+
+// New creates a new tracer which exports its spans to the OTLP HTTP
+// collector listening at `endpoint` ("host:port"). New root spans are sampled
+// with the rate of `samplingRatePerMillion`, whereas child spans follow the
+// decision of their parent.
+func New(endpoint string, samplingRatePerMillion int) *Tracer {
+	url := endpoint
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		url = "http://" + url
+	}
+	t := &Tracer{
+		url:          strings.TrimSuffix(url, "/") + tracesPath,
+		samplingRate: samplingRatePerMillion,
+		client:       &http.Client{Timeout: exportTimeout},
+		queue:        make(chan *Span, queueSize),
+		flush:        make(chan chan struct{}),
+		done:         make(chan struct{}),
+	}
+	go t.run()
+	return t
+}
+
+// Shutdown exports all pending spans and stops the tracer. No spans are
+// exported afterwards.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	flushed := make(chan struct{})
+	select {
+	case t.flush <- flushed:
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// export queues an ended span for export.
+func (t *Tracer) export(s *Span) {
+	select {
+	case <-t.done:
+	case t.queue <- s:
+	default:
+		logrus.Debugf("dropping span %s, export queue is full", s.name)
+	}
+}
+
+// run batches the queued spans and exports them periodically, until the
+// tracer gets shut down.
+func (t *Tracer) run() {
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, batchSize)
+	send := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.send(batch); err != nil {
+			logrus.Warnf("unable to export %d spans: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case s := <-t.queue:
+			batch = append(batch, s)
+			if len(batch) >= batchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case flushed := <-t.flush:
+			close(t.done)
+			for len(t.queue) > 0 {
+				batch = append(batch, <-t.queue)
+				if len(batch) >= batchSize {
+					send()
+				}
+			}
+			send()
+			close(flushed)
+			return
+		}
+	}
+}
+
+// send exports `spans` within a single OTLP request.
+func (t *Tracer) send(spans []*Span) error {
+	body, err := json.Marshal(newExportRequest(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector %s responded with %s", t.url, resp.Status)
+	}
+	return nil
+}
+
+// The following types are the JSON encoding of the OTLP
+// ExportTraceServiceRequest. IDs are hex encoded and 64 bit integers are
+// encoded as strings.
+
+type exportRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []spanData `json:"spans"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type spanData struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	ParentSpanID      string     `json:"parentSpanId,omitempty"`
+	Name              string     `json:"name"`
+	Kind              SpanKind   `json:"kind"`
+	StartTimeUnixNano string     `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string     `json:"endTimeUnixNano"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            spanStatus `json:"status"`
+}
+
+type spanStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func newExportRequest(spans []*Span) *exportRequest {
+	data := make([]spanData, 0, len(spans))
+	for _, s := range spans {
+		data = append(data, newSpanData(s))
+	}
+	return &exportRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource: resource{
+				Attributes: []keyValue{newKeyValue("service.name", ServiceName)},
+			},
+			ScopeSpans: []scopeSpans{{
+				Scope: scope{Name: scopeName},
+				Spans: data,
+			}},
+		}},
+	}
+}
+
+func newSpanData(s *Span) spanData {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data := spanData{
+		TraceID:           hex.EncodeToString(s.context.TraceID[:]),
+		SpanID:            hex.EncodeToString(s.context.SpanID[:]),
+		Name:              s.name,
+		Kind:              s.kind,
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Status:            spanStatus{Code: s.statusCode, Message: s.statusMsg},
+	}
+	if s.parentID != (SpanID{}) {
+		data.ParentSpanID = hex.EncodeToString(s.parentID[:])
+	}
+	for key, value := range s.attributes {
+		data.Attributes = append(data.Attributes, newKeyValue(key, value))
+	}
+	return data
+}
+
+func newKeyValue(key string, value interface{}) keyValue {
+	kv := keyValue{Key: key}
+	switch v := value.(type) {
+	case string:
+		kv.Value.StringValue = &v
+	case bool:
+		kv.Value.BoolValue = &v
+	case int:
+		i := strconv.FormatInt(int64(v), 10)
+		kv.Value.IntValue = &i
+	case int32:
+		i := strconv.FormatInt(int64(v), 10)
+		kv.Value.IntValue = &i
+	case int64:
+		i := strconv.FormatInt(v, 10)
+		kv.Value.IntValue = &i
+	case uint32:
+		i := strconv.FormatUint(uint64(v), 10)
+		kv.Value.IntValue = &i
+	case float64:
+		kv.Value.DoubleValue = &v
+	default:
+		str := fmt.Sprint(v)
+		kv.Value.StringValue = &str
+	}
+	return kv
+}
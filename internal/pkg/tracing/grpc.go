@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a gRPC interceptor which wraps each call
+// into a server span of `t`. The trace context sent by the client within the
+// traceparent metadata is used as parent of the span.
+func UnaryServerInterceptor(t *Tracer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = extract(ctx)
+		ctx, span := t.Start(ctx, info.FullMethod, SpanKindServer)
+		defer span.End()
+
+		service, method := splitMethod(info.FullMethod)
+		span.SetAttribute("rpc.system", "grpc")
+		span.SetAttribute("rpc.service", service)
+		span.SetAttribute("rpc.method", method)
+
+		resp, err := handler(ctx, req)
+		span.SetAttribute("rpc.grpc.status_code", int(status.Code(err)))
+		span.RecordError(err)
+		return resp, err
+	}
+}
+
+// extract returns a copy of `ctx` carrying the remote span context of the
+// incoming gRPC metadata, if any.
+func extract(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	values := md.Get(TraceParentHeader)
+	if len(values) == 0 {
+		return ctx
+	}
+	sc, err := ParseTraceParent(values[0])
+	if err != nil {
+		logrus.Debugf("ignoring trace context: %v", err)
+		return ctx
+	}
+	return ContextWithRemoteSpanContext(ctx, sc)
+}
+
+// splitMethod splits a full gRPC method name ("/service/method") into its
+// service and method parts.
+func splitMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "", fullMethod
+}
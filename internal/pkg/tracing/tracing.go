@@ -0,0 +1,271 @@
+// Package tracing provides a lightweight tracer for CRI-O, whose spans are
+// compatible with OpenTelemetry and get exported to a collector via the OTLP
+// HTTP/JSON protocol.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SpanKind describes the relationship between a span and its parent.
+type SpanKind int
+
+const (
+	// SpanKindInternal is an internal operation of CRI-O
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is a request handled by CRI-O, like a CRI call
+	SpanKindServer SpanKind = 2
+)
+
+// statusCodeError is the status code of failed spans
+const statusCodeError = 2
+
+// maxSamplingRate is the sampling rate per million which samples all traces
+const maxSamplingRate = 1000000
+
+// TraceID identifies a trace.
+type TraceID [16]byte
+
+// SpanID identifies a span within a trace.
+type SpanID [8]byte
+
+// SpanContext is the part of a span which gets propagated to its children,
+// including remote ones.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+// IsValid returns whether both the trace and span IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Span is a single timed operation within a trace. Spans which are not
+// sampled only propagate their context and are never exported. All methods
+// are safe to call on a nil span.
+type Span struct {
+	tracer     *Tracer
+	context    SpanContext
+	parentID   SpanID
+	name       string
+	kind       SpanKind
+	start      time.Time
+	mutex      sync.Mutex
+	end        time.Time
+	attributes map[string]interface{}
+	statusCode int
+	statusMsg  string
+	ended      bool
+}
+
+// SpanContext returns the propagated context of the span.
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttribute sets the attribute `key` of the span. Supported values are
+// strings, booleans, integers and floats, all other ones are formatted as
+// strings.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if !s.recording() {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed if `err` is not nil.
+func (s *Span) RecordError(err error) {
+	if err == nil || !s.recording() {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.statusCode = statusCodeError
+	s.statusMsg = err.Error()
+}
+
+// End finishes the span and hands it over to the exporter. Calling End more
+// than once has no effect.
+func (s *Span) End() {
+	if !s.recording() {
+		return
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mutex.Unlock()
+	s.tracer.export(s)
+}
+
+// recording returns whether the span gets exported.
+func (s *Span) recording() bool {
+	return s != nil && s.tracer != nil && s.context.Sampled
+}
+
+type spanKey struct{}
+
+type remoteSpanContextKey struct{}
+
+// ContextWithSpan returns a copy of `ctx` which carries `span`.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span carried by `ctx`, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span) // nolint: errcheck
+	return span
+}
+
+// ContextWithRemoteSpanContext returns a copy of `ctx` which carries the
+// span context of a remote parent, for example one received from the kubelet.
+func ContextWithRemoteSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteSpanContextKey{}, sc)
+}
+
+// parentSpanContext returns the span context of the parent of a new span
+// created for `ctx`.
+func parentSpanContext(ctx context.Context) SpanContext {
+	if span := SpanFromContext(ctx); span != nil {
+		return span.SpanContext()
+	}
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(remoteSpanContextKey{}).(SpanContext) // nolint: errcheck
+	return sc
+}
+
+var (
+	globalTracer      *Tracer
+	globalTracerMutex sync.RWMutex
+)
+
+// SetTracer sets the tracer used by StartSpan. Setting it to nil disables
+// tracing.
+func SetTracer(t *Tracer) {
+	globalTracerMutex.Lock()
+	defer globalTracerMutex.Unlock()
+	globalTracer = t
+}
+
+// StartSpan starts a new internal span with the global tracer. It returns a
+// nil span if tracing is disabled.
+func StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	globalTracerMutex.RLock()
+	t := globalTracer
+	globalTracerMutex.RUnlock()
+	return t.Start(ctx, name, SpanKindInternal)
+}
+
+// Start starts a new span as child of the span carried by `ctx`. The span
+// gets sampled if its parent is sampled. Root spans are sampled according to
+// the sampling rate of the tracer.
+func (t *Tracer) Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{
+		tracer:     t,
+		name:       name,
+		kind:       kind,
+		start:      time.Now(),
+		attributes: make(map[string]interface{}),
+	}
+	parent := parentSpanContext(ctx)
+	if parent.IsValid() {
+		span.context.TraceID = parent.TraceID
+		span.context.Sampled = parent.Sampled
+		span.parentID = parent.SpanID
+	} else {
+		span.context.TraceID = newTraceID()
+		span.context.Sampled = t.sample(span.context.TraceID)
+	}
+	span.context.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+// sample decides whether a new trace gets sampled, based on the lower half of
+// its random trace ID.
+func (t *Tracer) sample(id TraceID) bool {
+	if t.samplingRate >= maxSamplingRate {
+		return true
+	}
+	return binary.BigEndian.Uint64(id[8:])%maxSamplingRate < uint64(t.samplingRate)
+}
+
+func newTraceID() (id TraceID) {
+	rand.Read(id[:]) // nolint: errcheck
+	return id
+}
+
+func newSpanID() (id SpanID) {
+	rand.Read(id[:]) // nolint: errcheck
+	return id
+}
+
+// TraceParentHeader is the key of the W3C trace context header, which is also
+// used as gRPC metadata key.
+const TraceParentHeader = "traceparent"
+
+// FormatTraceParent formats `sc` as W3C traceparent header value.
+func FormatTraceParent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%s-%s-%s",
+		hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]), flags)
+}
+
+// ParseTraceParent parses a W3C traceparent header value.
+func ParseTraceParent(value string) (SpanContext, error) {
+	sc := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" ||
+		len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, fmt.Errorf("invalid traceparent %q", value)
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, fmt.Errorf("invalid trace ID in traceparent %q: %v", value, err)
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, fmt.Errorf("invalid span ID in traceparent %q: %v", value, err)
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, fmt.Errorf("invalid flags in traceparent %q: %v", value, err)
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid zero ID in traceparent %q", value)
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, nil
+}
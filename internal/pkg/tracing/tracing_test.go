@@ -0,0 +1,295 @@
+package tracing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// exportedSpan is the part of an OTLP JSON span checked by the tests
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+	Attributes   []struct {
+		Key   string                 `json:"key"`
+		Value map[string]interface{} `json:"value"`
+	} `json:"attributes"`
+	Status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+// collector is an in-process stand-in for an OTLP HTTP collector
+type collector struct {
+	server *httptest.Server
+	mutex  sync.Mutex
+	spans  []exportedSpan
+}
+
+func newCollector() *collector {
+	c := &collector{}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Expect(r.URL.Path).To(Equal("/v1/traces"))
+		Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+		req := struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []exportedSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}{}
+		Expect(json.NewDecoder(r.Body).Decode(&req)).To(BeNil())
+		c.mutex.Lock()
+		defer c.mutex.Unlock()
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				c.spans = append(c.spans, ss.Spans...)
+			}
+		}
+	}))
+	return c
+}
+
+func (c *collector) endpoint() string {
+	return c.server.Listener.Addr().String()
+}
+
+func (c *collector) exported() []exportedSpan {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.spans
+}
+
+// The actual test suite
+var _ = t.Describe("Tracing", func() {
+	var col *collector
+
+	BeforeEach(func() {
+		col = newCollector()
+	})
+
+	AfterEach(func() {
+		tracing.SetTracer(nil)
+		col.server.Close()
+	})
+
+	t.Describe("StartSpan", func() {
+		It("should succeed without tracer", func() {
+			// Given
+			ctx := context.Background()
+
+			// When
+			newCtx, span := tracing.StartSpan(ctx, "span")
+			span.SetAttribute("key", "value")
+			span.RecordError(errors.New("error"))
+			span.End()
+
+			// Then
+			Expect(span).To(BeNil())
+			Expect(newCtx).To(Equal(ctx))
+		})
+
+		It("should export span hierarchy", func() {
+			// Given
+			tracer := tracing.New(col.endpoint(), 1000000)
+			tracing.SetTracer(tracer)
+
+			// When
+			ctx, parent := tracing.StartSpan(context.Background(), "parent")
+			_, child := tracing.StartSpan(ctx, "child")
+			child.SetAttribute("string", "value")
+			child.SetAttribute("int", 42)
+			child.RecordError(errors.New("failed"))
+			child.End()
+			parent.End()
+			parent.End()
+			Expect(tracer.Shutdown(context.Background())).To(BeNil())
+
+			// Then
+			spans := col.exported()
+			Expect(spans).To(HaveLen(2))
+			Expect(spans[0].Name).To(Equal("child"))
+			Expect(spans[0].TraceID).To(Equal(spans[1].TraceID))
+			Expect(spans[0].ParentSpanID).To(Equal(spans[1].SpanID))
+			Expect(spans[0].Kind).To(Equal(int(tracing.SpanKindInternal)))
+			Expect(spans[0].Status.Code).To(Equal(2))
+			Expect(spans[0].Status.Message).To(Equal("failed"))
+			Expect(spans[0].Attributes).To(HaveLen(2))
+			Expect(spans[1].Name).To(Equal("parent"))
+			Expect(spans[1].ParentSpanID).To(BeEmpty())
+			Expect(spans[1].Status.Code).To(BeZero())
+		})
+
+		It("should not export unsampled spans", func() {
+			// Given
+			tracer := tracing.New(col.endpoint(), 0)
+			tracing.SetTracer(tracer)
+
+			// When
+			ctx, parent := tracing.StartSpan(context.Background(), "parent")
+			_, child := tracing.StartSpan(ctx, "child")
+			child.End()
+			parent.End()
+			Expect(tracer.Shutdown(context.Background())).To(BeNil())
+
+			// Then
+			Expect(parent.SpanContext().IsValid()).To(BeTrue())
+			Expect(child.SpanContext().TraceID).
+				To(Equal(parent.SpanContext().TraceID))
+			Expect(col.exported()).To(BeEmpty())
+		})
+
+		It("should follow sampled remote parent", func() {
+			// Given
+			tracer := tracing.New(col.endpoint(), 0)
+			tracing.SetTracer(tracer)
+			remote, err := tracing.ParseTraceParent(
+				"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+			Expect(err).To(BeNil())
+			ctx := tracing.ContextWithRemoteSpanContext(
+				context.Background(), remote)
+
+			// When
+			_, span := tracing.StartSpan(ctx, "span")
+			span.End()
+			Expect(tracer.Shutdown(context.Background())).To(BeNil())
+
+			// Then
+			spans := col.exported()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].TraceID).
+				To(Equal("0af7651916cd43dd8448eb211c80319c"))
+			Expect(spans[0].ParentSpanID).To(Equal("b7ad6b7169203331"))
+		})
+	})
+
+	t.Describe("Shutdown", func() {
+		It("should succeed twice", func() {
+			// Given
+			tracer := tracing.New(col.endpoint(), 1000000)
+
+			// When
+			err1 := tracer.Shutdown(context.Background())
+			err2 := tracer.Shutdown(context.Background())
+
+			// Then
+			Expect(err1).To(BeNil())
+			Expect(err2).To(BeNil())
+		})
+	})
+
+	t.Describe("ParseTraceParent", func() {
+		It("should succeed", func() {
+			// Given
+			const value = "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"
+
+			// When
+			sc, err := tracing.ParseTraceParent(value)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sc.IsValid()).To(BeTrue())
+			Expect(sc.Sampled).To(BeTrue())
+			Expect(tracing.FormatTraceParent(sc)).To(Equal(value))
+		})
+
+		It("should succeed with unsampled parent", func() {
+			// Given
+			// When
+			sc, err := tracing.ParseTraceParent(
+				"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-00")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sc.Sampled).To(BeFalse())
+		})
+
+		It("should fail with invalid values", func() {
+			for _, value := range []string{
+				"",
+				"invalid",
+				"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331",
+				"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01-00",
+				"ff-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+				"00-00000000000000000000000000000000-b7ad6b7169203331-01",
+				"00-0af7651916cd43dd8448eb211c80319c-zzzzzzzzzzzzzzzz-01",
+			} {
+				// Given
+				// When
+				_, err := tracing.ParseTraceParent(value)
+
+				// Then
+				Expect(err).NotTo(BeNil())
+			}
+		})
+	})
+
+	t.Describe("UnaryServerInterceptor", func() {
+		var info = &grpc.UnaryServerInfo{
+			FullMethod: "/runtime.v1alpha2.RuntimeService/RunPodSandbox",
+		}
+
+		It("should propagate the trace context of the client", func() {
+			// Given
+			tracer := tracing.New(col.endpoint(), 0)
+			ctx := metadata.NewIncomingContext(context.Background(),
+				metadata.Pairs(tracing.TraceParentHeader,
+					"00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"))
+			var handlerSpan *tracing.Span
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				handlerSpan = tracing.SpanFromContext(ctx)
+				return "response", nil
+			}
+
+			// When
+			resp, err := tracing.UnaryServerInterceptor(tracer)(
+				ctx, "request", info, handler)
+			Expect(tracer.Shutdown(context.Background())).To(BeNil())
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(resp).To(Equal("response"))
+			Expect(handlerSpan).NotTo(BeNil())
+			spans := col.exported()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].Name).To(Equal(info.FullMethod))
+			Expect(spans[0].Kind).To(Equal(int(tracing.SpanKindServer)))
+			Expect(spans[0].TraceID).
+				To(Equal("0af7651916cd43dd8448eb211c80319c"))
+			Expect(spans[0].ParentSpanID).To(Equal("b7ad6b7169203331"))
+		})
+
+		It("should record handler errors", func() {
+			// Given
+			tracer := tracing.New(col.endpoint(), 1000000)
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, errors.New("error")
+			}
+
+			// When
+			_, err := tracing.UnaryServerInterceptor(tracer)(
+				context.Background(), "request", info, handler)
+			Expect(tracer.Shutdown(context.Background())).To(BeNil())
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			spans := col.exported()
+			Expect(spans).To(HaveLen(1))
+			Expect(spans[0].ParentSpanID).To(BeEmpty())
+			Expect(spans[0].Status.Code).To(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,162 @@
+// Package userns allocates the host ID ranges of pods which run within their
+// own user namespace.
+package userns
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/containers/storage/pkg/idtools"
+)
+
+const (
+	// ModeAuto is the user namespace mode which allocates a dedicated host
+	// ID range for the pod
+	ModeAuto = "auto"
+
+	// DefaultSize is the number of IDs allocated for a pod if the mode does
+	// not specify the size
+	DefaultSize = 65536
+)
+
+// Range is a contiguous range of host IDs, which gets mapped to the IDs
+// starting at 0 within the user namespace.
+type Range struct {
+	HostID uint32
+	Size   uint32
+}
+
+// end returns the first host ID after the range.
+func (r Range) end() uint64 {
+	return uint64(r.HostID) + uint64(r.Size)
+}
+
+// overlaps returns whether `r` and `o` share at least one host ID.
+func (r Range) overlaps(o Range) bool {
+	return uint64(r.HostID) < o.end() && uint64(o.HostID) < r.end()
+}
+
+// IDMappings returns the mappings of the range for both UIDs and GIDs.
+func (r Range) IDMappings() *idtools.IDMappings {
+	idMap := []idtools.IDMap{{
+		ContainerID: 0,
+		HostID:      int(r.HostID),
+		Size:        int(r.Size),
+	}}
+	return idtools.NewIDMappingsFromMaps(idMap, idMap)
+}
+
+// ParseRange parses a range in the form "HostID:Size".
+func ParseRange(value string) (Range, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 {
+		return Range{}, fmt.Errorf("invalid range %q, expected HostID:Size", value)
+	}
+	hostID, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid host ID in range %q: %v", value, err)
+	}
+	size, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil || size == 0 {
+		return Range{}, fmt.Errorf("invalid size in range %q", value)
+	}
+	r := Range{HostID: uint32(hostID), Size: uint32(size)}
+	if r.end() > 1<<32 {
+		return Range{}, fmt.Errorf("range %q exceeds the maximum ID", value)
+	}
+	return r, nil
+}
+
+// ParseMode parses the value of the user namespace mode annotation, which is
+// either "auto" or "auto:size=<size>". It returns the number of IDs which
+// should be allocated for the pod.
+func ParseMode(mode string) (uint32, error) {
+	if mode == ModeAuto {
+		return DefaultSize, nil
+	}
+	option := strings.TrimPrefix(mode, ModeAuto+":")
+	if option == mode || !strings.HasPrefix(option, "size=") {
+		return 0, fmt.Errorf("invalid user namespace mode %q", mode)
+	}
+	size, err := strconv.ParseUint(strings.TrimPrefix(option, "size="), 10, 32)
+	if err != nil || size == 0 {
+		return 0, fmt.Errorf("invalid size in user namespace mode %q", mode)
+	}
+	return uint32(size), nil
+}
+
+// Pool hands out non-overlapping ranges of its host IDs.
+type Pool struct {
+	pool      Range
+	mutex     sync.Mutex
+	allocated map[string]Range
+}
+
+// NewPool creates a new pool for the host IDs of `pool`.
+func NewPool(pool Range) *Pool {
+	return &Pool{
+		pool:      pool,
+		allocated: make(map[string]Range),
+	}
+}
+
+// Allocate allocates the first free range of `size` IDs for `id`.
+func (p *Pool) Allocate(id string, size uint32) (Range, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.allocated[id]; ok {
+		return Range{}, fmt.Errorf("user namespace range already allocated for %s", id)
+	}
+	ranges := make([]Range, 0, len(p.allocated))
+	for _, r := range p.allocated {
+		ranges = append(ranges, r)
+	}
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].HostID < ranges[j].HostID
+	})
+
+	start := uint64(p.pool.HostID)
+	for _, r := range ranges {
+		if start+uint64(size) <= uint64(r.HostID) {
+			break
+		}
+		if r.end() > start {
+			start = r.end()
+		}
+	}
+	if start+uint64(size) > p.pool.end() {
+		return Range{}, fmt.Errorf("no free user namespace range of size %d left", size)
+	}
+	r := Range{HostID: uint32(start), Size: size}
+	p.allocated[id] = r
+	return r, nil
+}
+
+// Reserve marks the previously allocated range `r` as used by `id`, for
+// example after a restart.
+func (p *Pool) Reserve(id string, r Range) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if r.HostID < p.pool.HostID || r.end() > p.pool.end() {
+		return fmt.Errorf("user namespace range %d:%d of %s is not part of the pool", r.HostID, r.Size, id)
+	}
+	for other, o := range p.allocated {
+		if other != id && r.overlaps(o) {
+			return fmt.Errorf("user namespace range %d:%d of %s overlaps with %s", r.HostID, r.Size, id, other)
+		}
+	}
+	p.allocated[id] = r
+	return nil
+}
+
+// Release frees the range allocated for `id`, if any.
+func (p *Pool) Release(id string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	delete(p.allocated, id)
+}
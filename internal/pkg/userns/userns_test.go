@@ -0,0 +1,182 @@
+package userns_test
+
+import (
+	"github.com/cri-o/cri-o/internal/pkg/userns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// The actual test suite
+var _ = t.Describe("Userns", func() {
+	t.Describe("ParseMode", func() {
+		It("should succeed with default size", func() {
+			// Given
+			// When
+			size, err := userns.ParseMode("auto")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(size).To(BeEquivalentTo(userns.DefaultSize))
+		})
+
+		It("should succeed with size", func() {
+			// Given
+			// When
+			size, err := userns.ParseMode("auto:size=1024")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(size).To(BeEquivalentTo(1024))
+		})
+
+		It("should fail with invalid modes", func() {
+			for _, mode := range []string{
+				"", "host", "auto:", "auto:size", "auto:size=0",
+				"auto:size=-1", "auto:other=1",
+			} {
+				// Given
+				// When
+				_, err := userns.ParseMode(mode)
+
+				// Then
+				Expect(err).NotTo(BeNil())
+			}
+		})
+	})
+
+	t.Describe("ParseRange", func() {
+		It("should succeed", func() {
+			// Given
+			// When
+			r, err := userns.ParseRange("100000:65536")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(r).To(Equal(userns.Range{HostID: 100000, Size: 65536}))
+		})
+
+		It("should fail with invalid ranges", func() {
+			for _, value := range []string{
+				"", "100000", "a:1", "1:a", "1:0", "1:2:3", "4294967295:2",
+			} {
+				// Given
+				// When
+				_, err := userns.ParseRange(value)
+
+				// Then
+				Expect(err).NotTo(BeNil())
+			}
+		})
+	})
+
+	t.Describe("Range", func() {
+		It("should convert to ID mappings", func() {
+			// Given
+			r := userns.Range{HostID: 100000, Size: 65536}
+
+			// When
+			mappings := r.IDMappings()
+
+			// Then
+			Expect(mappings.UIDs()).To(HaveLen(1))
+			Expect(mappings.UIDs()[0].HostID).To(Equal(100000))
+			Expect(mappings.UIDs()[0].ContainerID).To(BeZero())
+			Expect(mappings.UIDs()[0].Size).To(Equal(65536))
+			Expect(mappings.GIDs()).To(Equal(mappings.UIDs()))
+			Expect(mappings.RootPair().UID).To(Equal(100000))
+		})
+	})
+
+	t.Describe("Pool", func() {
+		var sut *userns.Pool
+
+		BeforeEach(func() {
+			sut = userns.NewPool(userns.Range{HostID: 1000, Size: 300})
+		})
+
+		It("should allocate non overlapping ranges", func() {
+			// Given
+			// When
+			r1, err1 := sut.Allocate("1", 100)
+			r2, err2 := sut.Allocate("2", 100)
+
+			// Then
+			Expect(err1).To(BeNil())
+			Expect(err2).To(BeNil())
+			Expect(r1).To(Equal(userns.Range{HostID: 1000, Size: 100}))
+			Expect(r2).To(Equal(userns.Range{HostID: 1100, Size: 100}))
+		})
+
+		It("should reuse released ranges", func() {
+			// Given
+			_, err := sut.Allocate("1", 100)
+			Expect(err).To(BeNil())
+			_, err = sut.Allocate("2", 100)
+			Expect(err).To(BeNil())
+			sut.Release("1")
+
+			// When
+			r, err := sut.Allocate("3", 50)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(r).To(Equal(userns.Range{HostID: 1000, Size: 50}))
+		})
+
+		It("should fail if the pool is exhausted", func() {
+			// Given
+			_, err := sut.Allocate("1", 200)
+			Expect(err).To(BeNil())
+
+			// When
+			_, err = sut.Allocate("2", 101)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail to allocate twice for the same ID", func() {
+			// Given
+			_, err := sut.Allocate("1", 100)
+			Expect(err).To(BeNil())
+
+			// When
+			_, err = sut.Allocate("1", 100)
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should allocate around reserved ranges", func() {
+			// Given
+			Expect(sut.Reserve("1", userns.Range{HostID: 1050, Size: 100})).To(BeNil())
+
+			// When
+			r, err := sut.Allocate("2", 100)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(r).To(Equal(userns.Range{HostID: 1150, Size: 100}))
+		})
+
+		It("should fail to reserve overlapping ranges", func() {
+			// Given
+			Expect(sut.Reserve("1", userns.Range{HostID: 1000, Size: 100})).To(BeNil())
+
+			// When
+			err := sut.Reserve("2", userns.Range{HostID: 1099, Size: 100})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should fail to reserve ranges outside of the pool", func() {
+			// Given
+			// When
+			err := sut.Reserve("1", userns.Range{HostID: 1250, Size: 100})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+		})
+	})
+})
@@ -0,0 +1,65 @@
+// Package annotations contains the annotations which are set by CRI-O in
+// addition to the ones defined by libpod.
+package annotations
+
+const (
+	// IPs is the annotation containing all IP addresses of the pod sandbox
+	// as JSON list
+	IPs = "io.kubernetes.cri-o.IPs"
+
+	// SpoofedContainer is the annotation marking the infra container of a pod
+	// sandbox as placeholder without any process
+	SpoofedContainer = "io.kubernetes.cri-o.Spoofed"
+
+	// UsernsMode is the pod sandbox annotation requesting a dedicated user
+	// namespace for the pod, for example "auto:size=65536"
+	UsernsMode = "io.kubernetes.cri-o.userns-mode"
+
+	// SeccompAudit is the pod sandbox annotation which runs its containers
+	// with a seccomp profile logging every syscall if set to "true", to
+	// generate a minimal profile from the observed syscalls
+	SeccompAudit = "io.kubernetes.cri-o.seccomp-audit"
+
+	// SeccompNotifier is the pod sandbox annotation which records the
+	// syscalls blocked by the seccomp profiles of its containers if set to
+	// "true"
+	SeccompNotifier = "io.kubernetes.cri-o.seccomp-notifier"
+
+	// SeccompBlockedSyscalls is the container status annotation listing the
+	// syscalls blocked for a container in seccomp notifier mode with their
+	// counts, for example "mkdir=3,unshare=1"
+	SeccompBlockedSyscalls = "io.kubernetes.cri-o.SeccompBlockedSyscalls"
+
+	// LogSizeMax is the pod sandbox annotation overriding the log_size_max
+	// of its containers
+	LogSizeMax = "io.kubernetes.cri-o.LogSizeMax"
+
+	// LogMaxFiles is the pod sandbox annotation overriding the log_max_files
+	// of its containers
+	LogMaxFiles = "io.kubernetes.cri-o.LogMaxFiles"
+
+	// LogCompress is the pod sandbox annotation overriding the log_compress
+	// of its containers
+	LogCompress = "io.kubernetes.cri-o.LogCompress"
+)
+
+// AllAllowedAnnotations contains the experimental annotations, which are only
+// honored for runtime handlers listing them in their allowed_annotations.
+var AllAllowedAnnotations = []string{
+	UsernsMode,
+	SeccompAudit,
+	SeccompNotifier,
+	LogSizeMax,
+	LogMaxFiles,
+	LogCompress,
+}
+
+// IsExperimental returns whether `key` is one of the experimental annotations.
+func IsExperimental(key string) bool {
+	for _, a := range AllAllowedAnnotations {
+		if a == key {
+			return true
+		}
+	}
+	return false
+}
@@ -2,10 +2,12 @@ package types
 
 import (
 	"github.com/containers/storage/pkg/idtools"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 )
 
 // ContainerInfo stores information about containers
 type ContainerInfo struct {
+	ID              string            `json:"id"`
 	Name            string            `json:"name"`
 	Pid             int               `json:"pid"`
 	Image           string            `json:"image"`
@@ -18,6 +20,47 @@ type ContainerInfo struct {
 	Root            string            `json:"root"`
 	Sandbox         string            `json:"sandbox"`
 	IP              string            `json:"ip_address"`
+	IPs             []string          `json:"ip_addresses"`
+}
+
+// ContainerStats stores the resource usage statistics of a container. The
+// memory.stat and io.stat values and the pressure stall information are only
+// available on hosts using the cgroup v2 unified hierarchy.
+type ContainerStats struct {
+	ID             string                       `json:"id"`
+	CPUNano        uint64                       `json:"cpu_nano"`
+	MemoryUsage    uint64                       `json:"memory_usage"`
+	MemoryLimit    uint64                       `json:"memory_limit"`
+	BlockInput     uint64                       `json:"block_input"`
+	BlockOutput    uint64                       `json:"block_output"`
+	PIDs           uint64                       `json:"pids"`
+	MemoryStat     map[string]uint64            `json:"memory_stat,omitempty"`
+	IOStat         map[string]map[string]uint64 `json:"io_stat,omitempty"`
+	CPUPressure    *cgroupv2.Pressure           `json:"cpu_pressure,omitempty"`
+	MemoryPressure *cgroupv2.Pressure           `json:"memory_pressure,omitempty"`
+	IOPressure     *cgroupv2.Pressure           `json:"io_pressure,omitempty"`
+}
+
+// SandboxInfo stores information about sandboxes
+type SandboxInfo struct {
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Namespace      string            `json:"namespace"`
+	RuntimeHandler string            `json:"runtime_handler"`
+	Stopped        bool              `json:"stopped"`
+	Labels         map[string]string `json:"labels"`
+	Annotations    map[string]string `json:"annotations"`
+	IPs            []string          `json:"ip_addresses"`
+	Containers     []string          `json:"containers"`
+}
+
+// ImageInfo stores information about images
+type ImageInfo struct {
+	ID          string   `json:"id"`
+	RepoTags    []string `json:"repo_tags"`
+	RepoDigests []string `json:"repo_digests"`
+	Size        uint64   `json:"size"`
+	Pinned      bool     `json:"pinned"`
 }
 
 // IDMappings specifies the ID mappings used for containers.
@@ -31,5 +74,86 @@ type CrioInfo struct {
 	StorageDriver     string     `json:"storage_driver"`
 	StorageRoot       string     `json:"storage_root"`
 	CgroupDriver      string     `json:"cgroup_driver"`
+	CgroupVersion     string     `json:"cgroup_version"`
 	DefaultIDMappings IDMappings `json:"default_id_mappings"`
+
+	// Quarantined are the containers and sandboxes which could not be
+	// restored on startup.
+	Quarantined []QuarantineInfo `json:"quarantined"`
+}
+
+// QuarantineInfo stores information about a container or sandbox, which
+// could not be restored and whose storage is kept for inspection
+type QuarantineInfo struct {
+	ID      string   `json:"id"`
+	Names   []string `json:"names"`
+	Sandbox string   `json:"sandbox"`
+	Pod     bool     `json:"pod"`
+	Reason  string   `json:"reason"`
+}
+
+// ImagePullInfo stores information about an image pull in progress
+type ImagePullInfo struct {
+	Image           string          `json:"image"`
+	StartedTime     int64           `json:"started_time"`
+	Waiters         int             `json:"waiters"`
+	BytesDownloaded uint64          `json:"bytes_downloaded"`
+	BytesTotal      uint64          `json:"bytes_total"`
+	LayersCompleted int             `json:"layers_completed"`
+	LayersTotal     int             `json:"layers_total"`
+	Layers          []LayerPullInfo `json:"layers"`
+}
+
+// LayerPullInfo stores the transfer progress of a single image blob
+type LayerPullInfo struct {
+	Digest          string `json:"digest"`
+	Size            int64  `json:"size"`
+	BytesDownloaded uint64 `json:"bytes_downloaded"`
+	Completed       bool   `json:"completed"`
+}
+
+// EventType is the type of a container or sandbox lifecycle event
+type EventType string
+
+const (
+	// EventContainerCreated is published after a container got created
+	EventContainerCreated EventType = "container_created"
+	// EventContainerStarted is published after a container got started
+	EventContainerStarted EventType = "container_started"
+	// EventContainerExited is published after the process of a container
+	// exited, together with its exit code
+	EventContainerExited EventType = "container_exited"
+	// EventContainerOOMKilled is published after a process of a container
+	// got OOM killed, which does not have to be its init process
+	EventContainerOOMKilled EventType = "container_oom_killed"
+	// EventContainerStopped is published after a container got stopped
+	EventContainerStopped EventType = "container_stopped"
+	// EventContainerRemoved is published after a container got removed
+	EventContainerRemoved EventType = "container_removed"
+	// EventSandboxNetworkSetUp is published after the network of a
+	// sandbox got set up
+	EventSandboxNetworkSetUp EventType = "sandbox_network_setup"
+	// EventSandboxNetworkTornDown is published after the network of a
+	// sandbox got torn down
+	EventSandboxNetworkTornDown EventType = "sandbox_network_teardown"
+)
+
+// Event stores a lifecycle change of a container or sandbox
+type Event struct {
+	ID          uint64            `json:"id"`
+	Type        EventType         `json:"type"`
+	Timestamp   int64             `json:"timestamp"`
+	ContainerID string            `json:"container_id,omitempty"`
+	SandboxID   string            `json:"sandbox_id"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	ExitCode    *int32            `json:"exit_code,omitempty"`
+	OOMKilled   bool              `json:"oom_killed,omitempty"`
+}
+
+// EventFilter selects the events of a subscription. Empty fields match all
+// events.
+type EventFilter struct {
+	SandboxID string            `json:"sandbox_id"`
+	Labels    map[string]string `json:"labels"`
+	Types     []EventType       `json:"types"`
 }
@@ -15,8 +15,9 @@ import (
 // Attach prepares a streaming endpoint to attach to a running container.
 func (s *Server) Attach(ctx context.Context, req *pb.AttachRequest) (resp *pb.AttachResponse, err error) {
 	const operation = "attach"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 	logrus.Debugf("AttachRequest %+v", req)
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"github.com/cri-o/cri-o/pkg/types"
+)
+
+// restoreCheckpoint restores a container from the checkpoint archive at
+// `importPath` inside the sandbox `sandboxID` and returns the ID of the new
+// container.
+func (s *Server) restoreCheckpoint(sandboxID, importPath string) (string, error) {
+	// The restored container gets created like on CreateContainer, so the
+	// configuration must not be reloaded meanwhile
+	s.updateLock.RLock()
+	defer s.updateLock.RUnlock()
+
+	sb, err := s.getPodSandboxFromRequest(sandboxID)
+	if err != nil {
+		return "", err
+	}
+	systemContext := *s.systemContext
+	id, err := s.ContainerRestore(&systemContext, sb.ID(), importPath)
+	if err != nil {
+		return "", err
+	}
+
+	c := s.GetContainer(id)
+	s.watchOOMKills(c)
+	s.publishContainerEvent(types.EventContainerCreated, c)
+	s.publishContainerEvent(types.EventContainerStarted, c)
+	return id, nil
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
+	"github.com/cri-o/cri-o/pkg/types"
 	"github.com/cri-o/cri-o/utils"
 	dockermounts "github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/stringid"
@@ -214,8 +215,12 @@ func resolveSymbolicLink(path, scope string) (string, error) {
 	return symlink.FollowSymlinkInScope(path, scope)
 }
 
-func addDevices(sb *sandbox.Sandbox, containerConfig *pb.ContainerConfig, specgen *generate.Generator) error {
-	return addDevicesPlatform(sb, containerConfig, specgen)
+func (s *Server) addDevices(sb *sandbox.Sandbox, containerConfig *pb.ContainerConfig, specgen *generate.Generator) error {
+	withoutHostDevices := false
+	if runtimeHandler := s.runtimeHandlerConfig(sb.RuntimeHandler()); runtimeHandler != nil {
+		withoutHostDevices = runtimeHandler.PrivilegedWithoutHostDevices
+	}
+	return addDevicesPlatform(sb, containerConfig, withoutHostDevices, specgen)
 }
 
 // buildOCIProcessArgs build an OCI compatible process arguments slice.
@@ -515,8 +520,9 @@ func addSecretsBindMounts(mountLabel, ctrRunDir string, defaultMounts []string,
 // CreateContainer creates a new container in specified PodSandbox
 func (s *Server) CreateContainer(ctx context.Context, req *pb.CreateContainerRequest) (res *pb.CreateContainerResponse, err error) {
 	const operation = "create_container"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 	logrus.Debugf("CreateContainerRequest %+v", req)
@@ -603,7 +609,17 @@ func (s *Server) CreateContainer(ctx context.Context, req *pb.CreateContainerReq
 		}
 	}()
 
-	if err := s.createContainerPlatform(container, sb.InfraContainer(), sb.CgroupParent()); err != nil {
+	s.auditSeccomp(container)
+	defer func() {
+		if err != nil {
+			// Drop the syscalls of the container which never ran
+			if err := s.seccompAudit.Remove(containerID); err != nil {
+				logrus.Warnf("unable to remove seccomp profile of container %s: %v", containerID, err)
+			}
+		}
+	}()
+
+	if err := s.createContainerPlatform(ctx, container, sb.InfraContainer(), sb.CgroupParent()); err != nil {
 		return nil, err
 	}
 
@@ -614,6 +630,7 @@ func (s *Server) CreateContainer(ctx context.Context, req *pb.CreateContainerReq
 	container.SetCreated()
 
 	logrus.Infof("Created container: %s", container.Description())
+	s.publishContainerEvent(types.EventContainerCreated, container)
 	resp := &pb.CreateContainerResponse{
 		ContainerId: containerID,
 	}
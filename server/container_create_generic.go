@@ -2,9 +2,12 @@
 
 package server
 
-import "github.com/cri-o/cri-o/internal/oci"
+import (
+	"github.com/cri-o/cri-o/internal/oci"
+	"golang.org/x/net/context"
+)
 
 // createContainerPlatform performs platform dependent intermediate steps before calling the container's oci.Runtime().CreateContainer()
-func (s *Server) createContainerPlatform(container *oci.Container, infraContainer *oci.Container, cgroupParent string) error {
-	return s.Runtime().CreateContainer(container, cgroupParent)
+func (s *Server) createContainerPlatform(ctx context.Context, container *oci.Container, infraContainer *oci.Container, cgroupParent string) error {
+	return s.Runtime().CreateContainer(ctx, container, cgroupParent)
 }
@@ -20,7 +20,10 @@ import (
 	libconfig "github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
+	"github.com/cri-o/cri-o/server/metrics"
 	"github.com/cri-o/cri-o/utils"
 	dockermounts "github.com/docker/docker/pkg/mount"
 	"github.com/docker/docker/pkg/symlink"
@@ -46,13 +49,19 @@ type configDevice struct {
 
 func findCgroupMountpoint(name string) error {
 	// Set up pids limit if pids cgroup is mounted
+	if cgroupv2.Enabled() {
+		if !cgroupv2.HasController(name) {
+			return fmt.Errorf("cgroup controller %q is not available", name)
+		}
+		return nil
+	}
 	_, err := cgroups.FindCgroupMountpoint("", name)
 	return err
 }
 
-func addDevicesPlatform(sb *sandbox.Sandbox, containerConfig *pb.ContainerConfig, specgen *generate.Generator) error {
+func addDevicesPlatform(sb *sandbox.Sandbox, containerConfig *pb.ContainerConfig, privilegedWithoutHostDevices bool, specgen *generate.Generator) error {
 	sp := specgen.Config
-	if containerConfig.GetLinux().GetSecurityContext().GetPrivileged() {
+	if containerConfig.GetLinux().GetSecurityContext().GetPrivileged() && !privilegedWithoutHostDevices {
 		hostDevices, err := devices.HostDevices()
 		if err != nil {
 			return err
@@ -89,7 +98,7 @@ func addDevicesPlatform(sb *sandbox.Sandbox, containerConfig *pb.ContainerConfig
 		// If the requested container path already exists on the host, the container won't see the expected host path.
 		// Therefore, we must error out if the container path already exists
 		privileged := containerConfig.GetLinux().GetSecurityContext() != nil && containerConfig.GetLinux().GetSecurityContext().GetPrivileged()
-		if privileged && device.ContainerPath != device.HostPath {
+		if privileged && !privilegedWithoutHostDevices && device.ContainerPath != device.HostPath {
 			// we expect this to not exist
 			_, err := os.Stat(device.ContainerPath)
 			if err == nil {
@@ -170,9 +179,9 @@ func addDevicesPlatform(sb *sandbox.Sandbox, containerConfig *pb.ContainerConfig
 }
 
 // createContainerPlatform performs platform dependent intermediate steps before calling the container's oci.Runtime().CreateContainer()
-func (s *Server) createContainerPlatform(container, infraContainer *oci.Container, cgroupParent string) error {
-	if s.defaultIDMappings != nil && !s.defaultIDMappings.Empty() {
-		rootPair := s.defaultIDMappings.RootPair()
+func (s *Server) createContainerPlatform(ctx context.Context, container, infraContainer *oci.Container, cgroupParent string) error {
+	if idMappings := container.IDMappings(); idMappings != nil && !idMappings.Empty() {
+		rootPair := idMappings.RootPair()
 
 		for _, path := range []string{container.BundlePath(), container.MountPoint()} {
 			if err := os.Chown(path, rootPair.UID, rootPair.GID); err != nil {
@@ -183,7 +192,16 @@ func (s *Server) createContainerPlatform(container, infraContainer *oci.Containe
 			}
 		}
 	}
-	return s.Runtime().CreateContainer(container, cgroupParent)
+
+	start := time.Now()
+	err := s.Runtime().CreateContainer(ctx, container, cgroupParent)
+	runtimeHandler := container.RuntimeHandler()
+	if runtimeHandler == "" {
+		runtimeHandler = s.config.DefaultRuntime
+	}
+	metrics.CRIOContainersCreateLatency.WithLabelValues(metrics.RuntimeHandlerLabels.Value(runtimeHandler)).
+		Observe(time.Since(start).Seconds())
+	return err
 }
 
 // makeAccessible changes the path permission and each parent directory to have --x--x--x
@@ -319,6 +337,9 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 	if imgResultErr != nil {
 		return nil, imgResultErr
 	}
+	if err := s.verifyStoredImage(sb.Namespace(), imgResult.ID); err != nil {
+		return nil, err
+	}
 
 	imageName := imgResult.Name
 	imageRef := imgResult.ID
@@ -341,9 +362,11 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 		labelOptions = getLabelOptions(selinuxConfig)
 	}
 
-	containerIDMappings := s.defaultIDMappings
+	containerIDMappings := s.sandboxIDMappings(sb)
 	metadata := containerConfig.GetMetadata()
 
+	_, storageSpan := tracing.StartSpan(ctx, "storage.CreateContainer")
+	storageSpan.SetAttribute("container.id", containerID)
 	containerInfo, err := s.StorageRuntimeServer().CreateContainer(s.systemContext,
 		sb.Name(), sb.ID(),
 		image, imgResult.ID,
@@ -352,6 +375,8 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 		metadata.Attempt,
 		containerIDMappings,
 		labelOptions)
+	storageSpan.RecordError(err)
+	storageSpan.End()
 	if err != nil {
 		return nil, err
 	}
@@ -401,7 +426,7 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 		specgen.AddLinuxResourcesDevice(d.Resource.Allow, d.Resource.Type, d.Resource.Major, d.Resource.Minor, d.Resource.Access)
 	}
 
-	if err := addDevices(sb, containerConfig, &specgen); err != nil {
+	if err := s.addDevices(sb, containerConfig, &specgen); err != nil {
 		return nil, err
 	}
 
@@ -575,13 +600,17 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 
 	logrus.Debugf("pod container state %+v", podInfraState)
 
-	ipcNsPath := fmt.Sprintf("/proc/%d/ns/ipc", podInfraState.Pid)
-	if err := specgen.AddOrReplaceLinuxNamespace(string(rspec.IPCNamespace), ipcNsPath); err != nil {
+	// Sandboxes without infra container process have no IPC namespace if
+	// they use the one of the host
+	if ipcNsPath := sb.IpcNsPath(); ipcNsPath != "" {
+		if err := specgen.AddOrReplaceLinuxNamespace(string(rspec.IPCNamespace), ipcNsPath); err != nil {
+			return nil, err
+		}
+	} else if err := specgen.RemoveLinuxNamespace(string(rspec.IPCNamespace)); err != nil {
 		return nil, err
 	}
 
-	utsNsPath := fmt.Sprintf("/proc/%d/ns/uts", podInfraState.Pid)
-	if err := specgen.AddOrReplaceLinuxNamespace(string(rspec.UTSNamespace), utsNsPath); err != nil {
+	if err := specgen.AddOrReplaceLinuxNamespace(string(rspec.UTSNamespace), sb.UtsNsPath()); err != nil {
 		return nil, err
 	}
 
@@ -592,7 +621,10 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 		}
 	} else if containerConfig.GetLinux().GetSecurityContext().GetNamespaceOptions().GetPid() == pb.NamespaceMode_POD {
 		// share Pod PID namespace
-		pidNsPath := fmt.Sprintf("/proc/%d/ns/pid", podInfraState.Pid)
+		pidNsPath := sb.PidNsPath()
+		if pidNsPath == "" {
+			return nil, fmt.Errorf("pod sandbox %s has no infra container to share its PID namespace", sb.ID())
+		}
 		if err := specgen.AddOrReplaceLinuxNamespace(string(rspec.PIDNamespace), pidNsPath); err != nil {
 			return nil, err
 		}
@@ -723,10 +755,21 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 	specgen.AddAnnotation(annotations.Annotations, string(kubeAnnotationsJSON))
 
 	spp := containerConfig.GetLinux().GetSecurityContext().GetSeccompProfilePath()
+	seccompNotifier := false
 	if !privileged {
 		if err := s.setupSeccomp(&specgen, spp); err != nil {
 			return nil, err
 		}
+		if s.seccompAuditRequested(sb) {
+			if err := s.setupSeccompAudit(&specgen); err != nil {
+				return nil, err
+			}
+		}
+		if s.seccompNotifierRequested(sb) {
+			if seccompNotifier, err = s.setupSeccompNotifier(&specgen); err != nil {
+				return nil, err
+			}
+		}
 	}
 	specgen.AddAnnotation(annotations.SeccompProfilePath, spp)
 
@@ -822,7 +865,13 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 		newAnnotations[key] = value
 	}
 	if s.ContainerServer.Hooks != nil {
-		if _, err := s.ContainerServer.Hooks.Hooks(specgen.Config, newAnnotations, len(containerConfig.GetMounts()) > 0); err != nil {
+		// The hooks get executed by the OCI runtime, which is traced as part
+		// of the container creation
+		_, hooksSpan := tracing.StartSpan(ctx, "hooks.Hooks")
+		_, err := s.ContainerServer.Hooks.Hooks(specgen.Config, newAnnotations, len(containerConfig.GetMounts()) > 0)
+		hooksSpan.RecordError(err)
+		hooksSpan.End()
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -849,16 +898,22 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 		return nil, err
 	}
 
+	logRotation, err := s.containerLogRotation(sb)
+	if err != nil {
+		return nil, err
+	}
+	container.SetLogRotation(logRotation)
+
 	container.SetIDMappings(containerIDMappings)
-	if s.defaultIDMappings != nil && !s.defaultIDMappings.Empty() {
+	if containerIDMappings != nil && !containerIDMappings.Empty() {
 		userNsPath := sb.UserNsPath()
 		if err := specgen.AddOrReplaceLinuxNamespace(string(rspec.UserNamespace), userNsPath); err != nil {
 			return nil, err
 		}
-		for _, uidmap := range s.defaultIDMappings.UIDs() {
+		for _, uidmap := range containerIDMappings.UIDs() {
 			specgen.AddLinuxUIDMapping(uint32(uidmap.HostID), uint32(uidmap.ContainerID), uint32(uidmap.Size))
 		}
-		for _, gidmap := range s.defaultIDMappings.GIDs() {
+		for _, gidmap := range containerIDMappings.GIDs() {
 			specgen.AddLinuxGIDMapping(uint32(gidmap.HostID), uint32(gidmap.ContainerID), uint32(gidmap.Size))
 		}
 	}
@@ -874,6 +929,13 @@ func (s *Server) createSandboxContainer(ctx context.Context, containerID, contai
 	if err := specgen.SaveToFile(filepath.Join(containerInfo.RunDir, "config.json"), saveOptions); err != nil {
 		return nil, err
 	}
+	if seccompNotifier {
+		for _, dir := range []string{containerInfo.Dir, containerInfo.RunDir} {
+			if err := addSeccompListenerPath(filepath.Join(dir, "config.json"), s.seccompNotifier.Path()); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	container.SetSpec(specgen.Config)
 	container.SetMountPoint(mountPoint)
@@ -2,14 +2,18 @@ package server_test
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	v1 "github.com/opencontainers/image-spec/specs-go/v1"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	"k8s.io/kubernetes/pkg/kubelet/dockershim/network/hostport"
 )
 
 // The actual test suite
@@ -44,7 +48,7 @@ var _ = t.Describe("ContainerCreate", func() {
 				runtimeServerMock.EXPECT().StartContainer(gomock.Any()).
 					Return("testfolder", nil),
 				ociRuntimeMock.EXPECT().CreateContainer(gomock.Any(),
-					gomock.Any()).Return(nil),
+					gomock.Any(), gomock.Any()).Return(nil),
 				ociRuntimeMock.EXPECT().UpdateContainerStatus(gomock.Any()).
 					Return(nil),
 			)
@@ -67,6 +71,49 @@ var _ = t.Describe("ContainerCreate", func() {
 			Expect(response).NotTo(BeNil())
 		})
 
+		It("should fail when the image is rejected by the namespace signature policy", func() {
+			// Given
+			policyDir := t.MustTempDir("policies")
+			Expect(ioutil.WriteFile(filepath.Join(policyDir, "namespace.json"),
+				[]byte(`{"default":[{"type":"reject"}]}`), 0644)).To(BeNil())
+			serverConfig.SignaturePolicyDir = policyDir
+			setupSUT()
+			var err error
+			testSandbox, err = sandbox.New(sandboxID, "namespace", "", "", "",
+				make(map[string]string), make(map[string]string), "", "",
+				&pb.PodSandboxMetadata{}, "", "", false, "", "", "",
+				[]*hostport.PortMapping{}, false)
+			Expect(err).To(BeNil())
+			addContainerAndSandbox()
+			gomock.InOrder(
+				imageServerMock.EXPECT().ResolveNames(
+					gomock.Any(), gomock.Any()).
+					Return([]string{"image"}, nil),
+				imageServerMock.EXPECT().ImageStatus(gomock.Any(),
+					gomock.Any()).Return(&storage.ImageResult{ID: "image"}, nil),
+				imageServerMock.EXPECT().VerifyImage(gomock.Any(), "image").
+					Return(t.TestError),
+			)
+
+			// When
+			response, err := sut.CreateContainer(context.Background(),
+				&pb.CreateContainerRequest{PodSandboxId: testSandbox.ID(),
+					Config: &pb.ContainerConfig{
+						Metadata: &pb.ContainerMetadata{
+							Name: "name",
+						},
+						Image:   &pb.ImageSpec{Image: "{}"},
+						Command: []string{"cmd"},
+						Args:    []string{"arg"},
+					}})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring(
+				`rejected by the ` + policyDir + `/namespace.json signature policy for namespace "namespace"`))
+			Expect(response).To(BeNil())
+		})
+
 		It("should fail when container config image is nil", func() {
 			// Given
 			addContainerAndSandbox()
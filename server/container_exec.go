@@ -15,8 +15,9 @@ import (
 // Exec prepares a streaming endpoint to execute a command in the container.
 func (s *Server) Exec(ctx context.Context, req *pb.ExecRequest) (resp *pb.ExecResponse, err error) {
 	const operation = "exec"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
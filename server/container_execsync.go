@@ -13,8 +13,9 @@ import (
 // ExecSync runs a command in a container synchronously.
 func (s *Server) ExecSync(ctx context.Context, req *pb.ExecSyncRequest) (resp *pb.ExecSyncResponse, err error) {
 	const operation = "exec_sync"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 	logrus.Debugf("ExecSyncRequest %+v", req)
@@ -65,8 +65,9 @@ func (s *Server) filterContainerList(filter *pb.ContainerFilter, origCtrList []*
 // ListContainers lists all containers by filters.
 func (s *Server) ListContainers(ctx context.Context, req *pb.ListContainersRequest) (resp *pb.ListContainersResponse, err error) {
 	const operation = "list_containers"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 	logrus.Debugf("ListContainersRequest %+v", req)
@@ -0,0 +1,68 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	libconfig "github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/oci"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	"github.com/pkg/errors"
+)
+
+// containerLogRotation returns the log rotation settings of a container
+// within the pod sandbox `sb`, whose annotations have to be allowed for its
+// runtime handler. It returns nil if the pod does not override any of the
+// configured log rotation settings. The overrides never exceed the configured
+// log_size_max and log_max_files.
+func (s *Server) containerLogRotation(sb *sandbox.Sandbox) (*oci.LogRotation, error) {
+	annotations := s.filterDisallowedAnnotations(sb.RuntimeHandler(), sb.Annotations())
+	sizeMax, hasSizeMax := annotations[crioannotations.LogSizeMax]
+	maxFiles, hasMaxFiles := annotations[crioannotations.LogMaxFiles]
+	compress, hasCompress := annotations[crioannotations.LogCompress]
+	if !hasSizeMax && !hasMaxFiles && !hasCompress {
+		return nil, nil
+	}
+
+	rotation := &oci.LogRotation{
+		SizeMax:  s.config.LogSizeMax,
+		MaxFiles: s.config.LogMaxFiles,
+		Compress: s.config.LogCompress,
+	}
+	if hasSizeMax {
+		value, err := strconv.ParseInt(sizeMax, 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s annotation", crioannotations.LogSizeMax)
+		}
+		if value >= 0 && value < libconfig.OCIBufSize {
+			return nil, fmt.Errorf("invalid %s annotation: log size max should be negative or >= %d",
+				crioannotations.LogSizeMax, libconfig.OCIBufSize)
+		}
+		// Negative values would lift the size limit
+		if s.config.LogSizeMax < 0 || (value >= 0 && value < s.config.LogSizeMax) {
+			rotation.SizeMax = value
+		}
+	}
+	if hasMaxFiles {
+		value, err := strconv.Atoi(maxFiles)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s annotation", crioannotations.LogMaxFiles)
+		}
+		if value < 0 {
+			return nil, fmt.Errorf("invalid %s annotation: log max files should not be negative",
+				crioannotations.LogMaxFiles)
+		}
+		if value < s.config.LogMaxFiles {
+			rotation.MaxFiles = value
+		}
+	}
+	if hasCompress {
+		value, err := strconv.ParseBool(compress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid %s annotation", crioannotations.LogCompress)
+		}
+		rotation.Compress = value
+	}
+	return rotation, nil
+}
@@ -0,0 +1,99 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/oci"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestContainerLogRotation(t *testing.T) {
+	c, err := config.DefaultConfig()
+	if err != nil {
+		t.Fatal("error loading default config")
+	}
+	c.LogSizeMax = 16384
+	c.LogMaxFiles = 5
+	c.Runtimes["logs"] = &config.RuntimeHandler{
+		AllowedAnnotations: []string{
+			crioannotations.LogSizeMax,
+			crioannotations.LogMaxFiles,
+			crioannotations.LogCompress,
+		},
+	}
+	s := &Server{config: *c}
+	newSandbox := func(handler string, annotations map[string]string) *sandbox.Sandbox {
+		sb, err := sandbox.New("testsandboxid", "", "", "", "", map[string]string{},
+			annotations, "", "", &runtime.PodSandboxMetadata{}, "", "", false, handler, "", "", nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sb
+	}
+
+	rotation, err := s.containerLogRotation(newSandbox("logs", map[string]string{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotation != nil {
+		t.Fatalf("expected no rotation override, got %+v", rotation)
+	}
+
+	rotation, err = s.containerLogRotation(newSandbox("", map[string]string{
+		crioannotations.LogSizeMax: "-1",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotation != nil {
+		t.Fatalf("expected the annotations to be ignored for the default handler, got %+v", rotation)
+	}
+
+	for _, tc := range []struct {
+		annotations map[string]string
+		expected    oci.LogRotation
+	}{
+		{
+			annotations: map[string]string{
+				crioannotations.LogSizeMax:  "8192",
+				crioannotations.LogMaxFiles: "2",
+				crioannotations.LogCompress: "true",
+			},
+			expected: oci.LogRotation{SizeMax: 8192, MaxFiles: 2, Compress: true},
+		},
+		{
+			// The configured limits are never exceeded
+			annotations: map[string]string{
+				crioannotations.LogSizeMax:  "-1",
+				crioannotations.LogMaxFiles: "100",
+			},
+			expected: oci.LogRotation{SizeMax: 16384, MaxFiles: 5},
+		},
+		{
+			annotations: map[string]string{crioannotations.LogSizeMax: "1048576"},
+			expected:    oci.LogRotation{SizeMax: 16384, MaxFiles: 5},
+		},
+	} {
+		rotation, err := s.containerLogRotation(newSandbox("logs", tc.annotations))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *rotation != tc.expected {
+			t.Fatalf("expected %+v for %v, got %+v", tc.expected, tc.annotations, *rotation)
+		}
+	}
+
+	for _, annotations := range []map[string]string{
+		{crioannotations.LogSizeMax: "1024"},
+		{crioannotations.LogSizeMax: "foo"},
+		{crioannotations.LogMaxFiles: "-1"},
+		{crioannotations.LogCompress: "maybe"},
+	} {
+		if _, err := s.containerLogRotation(newSandbox("logs", annotations)); err == nil {
+			t.Fatalf("expected an error for annotations %v", annotations)
+		}
+	}
+}
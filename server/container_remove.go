@@ -3,6 +3,7 @@ package server
 import (
 	"time"
 
+	"github.com/cri-o/cri-o/pkg/types"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -12,8 +13,9 @@ import (
 // should be force removed.
 func (s *Server) RemoveContainer(ctx context.Context, req *pb.RemoveContainerRequest) (resp *pb.RemoveContainerResponse, err error) {
 	const operation = "remove_container"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 	logrus.Debugf("RemoveContainerRequest: %+v", req)
@@ -28,8 +30,12 @@ func (s *Server) RemoveContainer(ctx context.Context, req *pb.RemoveContainerReq
 	if err != nil {
 		return nil, err
 	}
+	s.oomWatcher.Remove(c.ID())
+	s.finishSeccompAudit(c)
+	s.seccompNotifier.Remove(c.ID())
 
 	logrus.Infof("Removed container %s", c.Description())
+	s.publishContainerEvent(types.EventContainerRemoved, c)
 	resp = &pb.RemoveContainerResponse{}
 	logrus.Debugf("RemoveContainerResponse: %+v", resp)
 	return resp, nil
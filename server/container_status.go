@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/cri-o/cri-o/internal/oci"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -19,8 +20,9 @@ const (
 // ContainerStatus returns status of the container.
 func (s *Server) ContainerStatus(ctx context.Context, req *pb.ContainerStatusRequest) (resp *pb.ContainerStatusResponse, err error) {
 	const operation = "container_status"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 	logrus.Debugf("ContainerStatusRequest %+v", req)
@@ -35,7 +37,7 @@ func (s *Server) ContainerStatus(ctx context.Context, req *pb.ContainerStatusReq
 			Id:          containerID,
 			Metadata:    c.Metadata(),
 			Labels:      c.Labels(),
-			Annotations: c.Annotations(),
+			Annotations: withBlockedSyscalls(c.Annotations(), s.seccompNotifier.Blocked(c.ID())),
 			ImageRef:    c.ImageRef(),
 		},
 	}
@@ -100,9 +102,18 @@ func (s *Server) ContainerStatus(ctx context.Context, req *pb.ContainerStatusReq
 	resp.Status.LogPath = c.LogPath()
 
 	if req.Verbose {
+		cState = c.State()
 		resp.Info = map[string]string{
-			"pid":       strconv.Itoa(c.State().Pid),
-			"sandboxId": c.Sandbox(),
+			"pid":          strconv.Itoa(cState.Pid),
+			"sandboxId":    c.Sandbox(),
+			"oomKillCount": strconv.FormatUint(cState.OOMKillCount, 10),
+		}
+		if !cState.LastOOMKill.IsZero() {
+			resp.Info["lastOOMKill"] = cState.LastOOMKill.Format(time.RFC3339Nano)
+		}
+		if c.Annotations()[crioannotations.SeccompNotifier] == "true" {
+			// The recording stops for good when CRI-O restarts
+			resp.Info["seccompNotifierRecording"] = strconv.FormatBool(s.seccompNotifier.Recording(containerID))
 		}
 	}
 
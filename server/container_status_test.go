@@ -2,6 +2,8 @@ package server_test
 
 import (
 	"context"
+	"strconv"
+	"time"
 
 	"github.com/cri-o/cri-o/internal/oci"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
@@ -50,6 +52,8 @@ var _ = t.Describe("ContainerStatus", func() {
 			Expect(response).NotTo(BeNil())
 			Expect(len(response.Status.Mounts)).To(BeEquivalentTo(1))
 			Expect(response.Status.State).To(Equal(expectedState))
+			Expect(response.Info["oomKillCount"]).
+				To(Equal(strconv.FormatUint(givenState.OOMKillCount, 10)))
 		},
 			Entry("Created", &oci.ContainerState{
 				State: specs.State{Status: oci.ContainerStateCreated},
@@ -69,6 +73,11 @@ var _ = t.Describe("ContainerStatus", func() {
 				OOMKilled: true,
 				State:     specs.State{Status: oci.ContainerStateStopped},
 			}, pb.ContainerState_CONTAINER_EXITED),
+			Entry("Running: OOM killed processes", &oci.ContainerState{
+				OOMKillCount: 2,
+				LastOOMKill:  time.Now(),
+				State:        specs.State{Status: oci.ContainerStateRunning},
+			}, pb.ContainerState_CONTAINER_RUNNING),
 		)
 
 		It("should fail with invalid container ID", func() {
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/pkg/types"
+)
+
+// publishContainerEvent publishes the lifecycle event `eventType` of the
+// container `c`.
+func (s *Server) publishContainerEvent(eventType types.EventType, c *oci.Container) {
+	s.events.Publish(newContainerEvent(eventType, c))
+}
+
+// publishContainerExit publishes the exit of the container `c`, including the
+// exit code and the OOM flag of its current state.
+func (s *Server) publishContainerExit(c *oci.Container) {
+	event := newContainerEvent(types.EventContainerExited, c)
+	if state := c.State(); state != nil {
+		exitCode := state.ExitCode
+		event.ExitCode = &exitCode
+		event.OOMKilled = state.OOMKilled
+	}
+	s.events.Publish(event)
+}
+
+func newContainerEvent(eventType types.EventType, c *oci.Container) types.Event {
+	return types.Event{
+		Type:        eventType,
+		ContainerID: c.ID(),
+		SandboxID:   c.Sandbox(),
+		Labels:      c.Labels(),
+	}
+}
+
+// publishSandboxEvent publishes the lifecycle event `eventType` of the
+// sandbox `sb`.
+func (s *Server) publishSandboxEvent(eventType types.EventType, sb *sandbox.Sandbox) {
+	s.events.Publish(types.Event{
+		Type:      eventType,
+		SandboxID: sb.ID(),
+		Labels:    sb.Labels(),
+	})
+}
+
+// CloseEventSubscriptions ends all event subscriptions, which allows the
+// long-lived /events requests to finish on shutdown.
+func (s *Server) CloseEventSubscriptions() {
+	s.events.Close()
+}
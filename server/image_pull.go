@@ -2,22 +2,31 @@ package server
 
 import (
 	"encoding/base64"
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/containers/image/copy"
+	"github.com/containers/image/signature"
 	"github.com/containers/image/types"
-	"github.com/cri-o/cri-o/internal/pkg/storage"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
+	"github.com/cri-o/cri-o/server/metrics"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
+// ErrPullStalled is returned for image pulls which got aborted because they
+// made no progress within the pull_progress_timeout.
+var ErrPullStalled = errors.New("image pull made no progress")
+
 // PullImage pulls a image with authentication config.
 func (s *Server) PullImage(ctx context.Context, req *pb.PullImageRequest) (resp *pb.PullImageResponse, err error) {
 	const operation = "pull_image"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
@@ -29,7 +38,17 @@ func (s *Server) PullImage(ctx context.Context, req *pb.PullImageRequest) (resp
 		image = img.Image
 	}
 
+	// The signature policy is selected by the namespace of the requesting pod
+	namespace := req.GetSandboxConfig().GetMetadata().GetNamespace()
+	// The signature policies change on configuration reloads
+	s.updateLock.RLock()
 	sourceCtx := *s.systemContext // A shallow copy we can modify
+	sourceCtx.SignaturePolicyPath, err = s.config.SignaturePolicy(namespace)
+	namespacePolicy := sourceCtx.SignaturePolicyPath != s.systemContext.SignaturePolicyPath
+	s.updateLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
 	if req.GetAuth() != nil {
 		username := req.GetAuth().Username
 		password := req.GetAuth().Password
@@ -50,8 +69,9 @@ func (s *Server) PullImage(ctx context.Context, req *pb.PullImageRequest) (resp
 	}
 
 	var (
-		images []string
-		pulled string
+		images    []string
+		pulled    string
+		rejectErr error
 	)
 	images, err = s.StorageImageServer().ResolveNames(s.systemContext, image)
 	if err != nil {
@@ -59,40 +79,32 @@ func (s *Server) PullImage(ctx context.Context, req *pb.PullImageRequest) (resp
 	}
 	for _, img := range images {
 		var tmpImg types.ImageCloser
-		tmpImg, err = s.StorageImageServer().PrepareImage(&sourceCtx, img)
-		if err != nil {
-			logrus.Debugf("error preparing image %s: %v", img, err)
-			continue
-		}
-		defer tmpImg.Close()
-
-		var storedImage *storage.ImageResult
-		storedImage, err = s.StorageImageServer().ImageStatus(s.systemContext, img)
-		if err == nil {
-			tmpImgConfigDigest := tmpImg.ConfigInfo().Digest
-			if tmpImgConfigDigest.String() == "" {
-				// this means we are playing with a schema1 image, in which
-				// case, we're going to repull the image in any case
-				logrus.Debugf("image config digest is empty, re-pulling image")
-			} else if tmpImgConfigDigest.String() == storedImage.ConfigDigest.String() {
-				logrus.Debugf("image %s already in store, skipping pull", img)
-				pulled = img
-				break
-			}
-			logrus.Debugf("image in store has different ID, re-pulling %s", img)
+		tmpImg, err = s.pullImageAttempt(ctx, &sourceCtx, img, namespacePolicy)
+		if tmpImg != nil {
+			defer tmpImg.Close()
 		}
-
-		_, err = s.StorageImageServer().PullImage(s.systemContext, img, &copy.Options{
-			SourceCtx:      &sourceCtx,
-			DestinationCtx: s.systemContext,
-		})
 		if err != nil {
-			logrus.Debugf("error pulling image %s: %v", img, err)
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			if isSignatureRejection(err) {
+				rejectErr = err
+			}
 			continue
 		}
 		pulled = img
 		break
 	}
+	if pulled == "" && rejectErr != nil {
+		metrics.CRIOImagePullsSignatureFailures.WithLabelValues(
+			metrics.NamespaceLabels.Value(namespace)).Inc()
+		policy := sourceCtx.SignaturePolicyPath
+		if policy == "" {
+			policy = "system-wide default"
+		}
+		return nil, fmt.Errorf("image %s rejected by the %s signature policy for namespace %q: %v",
+			image, policy, namespace, rejectErr)
+	}
 	if pulled == "" && err != nil {
 		return nil, err
 	}
@@ -111,6 +123,218 @@ func (s *Server) PullImage(ctx context.Context, req *pb.PullImageRequest) (resp
 	return resp, nil
 }
 
+// pullImageAttempt pulls the resolved image name `img`, unless the image is
+// already in store with the same config digest and `namespacePolicy` is not
+// set, which means that the source context carries the signature policy of a
+// namespace. The returned prepared image has to be closed by the caller.
+func (s *Server) pullImageAttempt(ctx context.Context, sourceCtx *types.SystemContext, img string, namespacePolicy bool) (tmpImg types.ImageCloser, err error) {
+	ctx, span := tracing.StartSpan(ctx, "image.PullImageAttempt")
+	span.SetAttribute("image", img)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	tmpImg, err = s.StorageImageServer().PrepareImage(ctx, sourceCtx, img)
+	if err != nil {
+		logrus.Debugf("error preparing image %s: %v", img, err)
+		return nil, err
+	}
+
+	// An image in store has been verified against the default signature policy
+	// only, so it has to be pulled again to verify it against the namespace
+	// policy. The layers in store are reused by the pull.
+	storedImage, err := s.StorageImageServer().ImageStatus(s.systemContext, img)
+	if err == nil && !namespacePolicy {
+		tmpImgConfigDigest := tmpImg.ConfigInfo().Digest
+		if tmpImgConfigDigest.String() == "" {
+			// this means we are playing with a schema1 image, in which
+			// case, we're going to repull the image in any case
+			logrus.Debugf("image config digest is empty, re-pulling image")
+		} else if tmpImgConfigDigest.String() == storedImage.ConfigDigest.String() {
+			logrus.Debugf("image %s already in store, skipping pull", img)
+			span.SetAttribute("image.cached", true)
+			return tmpImg, nil
+		}
+		logrus.Debugf("image in store has different ID, re-pulling %s", img)
+	}
+
+	if err := s.pullImageCandidate(ctx, sourceCtx, img); err != nil {
+		logrus.Debugf("error pulling image %s: %v", img, err)
+		return tmpImg, err
+	}
+	return tmpImg, nil
+}
+
+// verifyStoredImage checks the image `id` in store against the signature
+// policy of the Kubernetes `namespace`, if the namespace has a policy of its
+// own. Images in store have only been verified against the policy of the
+// namespace which pulled them. The caller has to hold the updateLock.
+func (s *Server) verifyStoredImage(namespace, id string) error {
+	sourceCtx := *s.systemContext
+	policy, err := s.config.SignaturePolicy(namespace)
+	if err != nil {
+		return err
+	}
+	if policy == sourceCtx.SignaturePolicyPath {
+		return nil
+	}
+	sourceCtx.SignaturePolicyPath = policy
+	if err := s.StorageImageServer().VerifyImage(&sourceCtx, id); err != nil {
+		return fmt.Errorf("image %s rejected by the %s signature policy for namespace %q: %v",
+			id, policy, namespace, err)
+	}
+	return nil
+}
+
+// pullArguments identifies a single pull of a resolved image name. Pulls with
+// equal arguments are coalesced into one operation.
+type pullArguments struct {
+	image           string
+	credentials     types.DockerAuthConfig
+	signaturePolicy string
+}
+
+// pullOperation is a pull which is currently in flight. Callers requesting
+// the same pullArguments wait for it to finish and share its result. The pull
+// gets canceled once no caller waits for it anymore.
+type pullOperation struct {
+	done     chan struct{}
+	err      error
+	progress *pullProgress
+	cancel   context.CancelFunc
+}
+
+// pullImageCandidate pulls the resolved image name `img` into the local
+// storage. If an identical pull is already in progress, it waits for that
+// pull to finish instead of starting another one. It stops waiting if the
+// context `ctx` is done, which cancels the pull if nobody else waits for it.
+func (s *Server) pullImageCandidate(ctx context.Context, sourceCtx *types.SystemContext, img string) error {
+	pullArgs := pullArguments{image: img, signaturePolicy: sourceCtx.SignaturePolicyPath}
+	if sourceCtx.DockerAuthConfig != nil {
+		pullArgs.credentials = *sourceCtx.DockerAuthConfig
+	}
+
+	s.pullOperationsLock.Lock()
+	pullOp, pullInProgress := s.pullOperationsInProgress[pullArgs]
+	if pullInProgress {
+		logrus.Debugf("pull of image %s already in progress, waiting for it", img)
+	} else {
+		pullCtx, cancel := context.WithCancel(context.Background())
+		pullOp = &pullOperation{
+			done:     make(chan struct{}),
+			progress: newPullProgress(img),
+			cancel:   cancel,
+		}
+		s.pullOperationsInProgress[pullArgs] = pullOp
+		go s.runPullOperation(pullCtx, pullOp, pullArgs, *sourceCtx)
+	}
+	pullOp.progress.addWaiter()
+	s.pullOperationsLock.Unlock()
+
+	select {
+	case <-pullOp.done:
+		return pullOp.err
+	case <-ctx.Done():
+	}
+
+	s.pullOperationsLock.Lock()
+	if pullOp.progress.removeWaiter() == 0 {
+		// New requests for the same image start a new pull
+		if s.pullOperationsInProgress[pullArgs] == pullOp {
+			delete(s.pullOperationsInProgress, pullArgs)
+		}
+		pullOp.cancel()
+	}
+	s.pullOperationsLock.Unlock()
+	return errors.Wrapf(ctx.Err(), "pull of image %s canceled", img)
+}
+
+// runPullOperation runs the pull `pullOp` until it finishes or its context
+// `ctx` gets canceled. A canceled pull removes the blobs it transferred so
+// far. The pull is canceled as well if it makes no progress within the
+// pull_progress_timeout before it commits the image.
+func (s *Server) runPullOperation(ctx context.Context, pullOp *pullOperation, pullArgs pullArguments, sourceCtx types.SystemContext) {
+	defer pullOp.cancel()
+
+	progress := make(chan types.ProgressProperties)
+	progressDone := make(chan struct{})
+	go func() {
+		defer close(progressDone)
+		for p := range progress {
+			pullOp.progress.update(p.Artifact.Digest, p.Artifact.Size, p.Offset)
+		}
+	}()
+
+	timeout := time.Duration(s.config.PullProgressTimeout) * time.Second
+	stalled := watchPullProgress(ctx, pullOp.progress, timeout, pullOp.cancel)
+
+	// The signature policy is taken from the source context
+	_, err := s.StorageImageServer().PullImage(ctx, &sourceCtx, pullArgs.image, &copy.Options{
+		SourceCtx:        &sourceCtx,
+		DestinationCtx:   s.systemContext,
+		Progress:         progress,
+		ProgressInterval: pullProgressInterval,
+		ReportWriter:     pullOp.progress,
+	})
+	close(progress)
+	<-progressDone
+
+	select {
+	case <-stalled:
+		if err != nil {
+			metrics.CRIOImagePullsTimeouts.WithLabelValues(
+				metrics.RegistryLabels.Value(imageRegistry(pullArgs.image))).Inc()
+			err = errors.Wrapf(ErrPullStalled, "pulling image %s aborted after %v", pullArgs.image, timeout)
+		}
+	default:
+	}
+
+	s.pullOperationsLock.Lock()
+	if s.pullOperationsInProgress[pullArgs] == pullOp {
+		delete(s.pullOperationsInProgress, pullArgs)
+	}
+	s.pullOperationsLock.Unlock()
+	pullOp.progress.finish(err == nil)
+	pullOp.err = err
+	close(pullOp.done)
+}
+
+// watchPullProgress calls `cancel` if the pull `progress` does not advance
+// within the `timeout`, until the context `ctx` is done. The returned channel
+// gets closed when the pull got canceled. A zero timeout disables the watch.
+func watchPullProgress(ctx context.Context, progress *pullProgress, timeout time.Duration, cancel func()) <-chan struct{} {
+	stalled := make(chan struct{})
+	if timeout <= 0 {
+		return stalled
+	}
+	go func() {
+		ticker := time.NewTicker(pullProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if idle := progress.idle(); idle >= timeout {
+					logrus.Warnf("pull of image %s made no progress for %v, aborting it", progress.image, idle)
+					close(stalled)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return stalled
+}
+
+// isSignatureRejection returns whether the pull error `err` is caused by the
+// rejection of the image by the signature policy.
+func isSignatureRejection(err error) bool {
+	_, ok := errors.Cause(err).(signature.PolicyRequirementError)
+	return ok
+}
+
 func decodeDockerAuth(s string) (user, password string, err error) {
 	decoded, err := base64.StdEncoding.DecodeString(s)
 	if err != nil {
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/containers/image/docker/reference"
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/cri-o/cri-o/server/metrics"
+	digest "github.com/opencontainers/go-digest"
+)
+
+// pullProgressInterval is the interval in which the image copy reports the
+// progress of each blob it transfers.
+const pullProgressInterval = time.Second
+
+// layerProgress is the transfer state of a single blob of an image pull.
+type layerProgress struct {
+	size   int64
+	offset uint64
+}
+
+// pullProgress tracks the byte and layer progress of a single image pull.
+type pullProgress struct {
+	lock         sync.Mutex
+	image        string
+	started      time.Time
+	lastProgress time.Time
+	committing   bool
+	waiters      int
+	layers       map[digest.Digest]*layerProgress
+	order        []digest.Digest
+}
+
+func newPullProgress(image string) *pullProgress {
+	metrics.CRIOImagePullsInProgress.Inc()
+	now := time.Now()
+	return &pullProgress{
+		image:        image,
+		started:      now,
+		lastProgress: now,
+		layers:       make(map[digest.Digest]*layerProgress),
+	}
+}
+
+// addWaiter records that another caller is waiting for the pull.
+func (p *pullProgress) addWaiter() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.waiters++
+}
+
+// removeWaiter records that a caller stopped waiting for the pull and returns
+// the number of remaining waiters.
+func (p *pullProgress) removeWaiter() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.waiters--
+	return p.waiters
+}
+
+// idle returns the time since the pull made any progress, or since it
+// started if it did not make any yet. A pull which commits the image is never
+// idle.
+func (p *pullProgress) idle() time.Duration {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.committing {
+		return 0
+	}
+	return time.Since(p.lastProgress)
+}
+
+// Write records a step reported by the image copy, like a blob which is
+// skipped because it is already in store, as progress. Once the copy stores
+// the signatures, the pull is committing: the layers get applied without any
+// further progress reports.
+func (p *pullProgress) Write(report []byte) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lastProgress = time.Now()
+	if bytes.HasPrefix(report, []byte("Storing signatures")) {
+		p.committing = true
+	}
+	return len(report), nil
+}
+
+// update records that `offset` bytes of the blob `artifact` have been
+// transferred.
+func (p *pullProgress) update(artifact digest.Digest, size int64, offset uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	layer, ok := p.layers[artifact]
+	if !ok {
+		layer = &layerProgress{size: size}
+		p.layers[artifact] = layer
+		p.order = append(p.order, artifact)
+	}
+	if !ok || offset != layer.offset {
+		p.lastProgress = time.Now()
+	}
+	layer.offset = offset
+	p.recordMetrics()
+}
+
+// finish marks the pull as done. On success every blob seen so far is
+// considered complete, because the copy does not report the final offset of
+// a blob.
+func (p *pullProgress) finish(success bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if success {
+		for _, layer := range p.layers {
+			if layer.size > 0 {
+				layer.offset = uint64(layer.size)
+			}
+		}
+	}
+	metrics.CRIOImagePullsInProgress.Dec()
+	metrics.CRIOImagePullsBytes.DeleteLabelValues(p.image)
+	metrics.CRIOImagePullsLayers.DeleteLabelValues(p.image)
+
+	registry := metrics.RegistryLabels.Value(imageRegistry(p.image))
+	metrics.CRIOImagePullsDuration.WithLabelValues(registry).Observe(time.Since(p.started).Seconds())
+	metrics.CRIOImagePullsBytesTotal.WithLabelValues(registry).Add(float64(p.infoLocked().BytesDownloaded))
+	if !success {
+		metrics.CRIOImagePullsFailures.WithLabelValues(registry).Inc()
+	}
+}
+
+// imageRegistry returns the registry of the resolved image name `image`.
+func imageRegistry(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return "unknown"
+	}
+	return reference.Domain(named)
+}
+
+// recordMetrics updates the per image pull gauges. The caller must hold the
+// lock.
+func (p *pullProgress) recordMetrics() {
+	info := p.infoLocked()
+	metrics.CRIOImagePullsBytes.WithLabelValues(p.image).Set(float64(info.BytesDownloaded))
+	metrics.CRIOImagePullsLayers.WithLabelValues(p.image).Set(float64(info.LayersCompleted))
+}
+
+// info returns a snapshot of the pull progress.
+func (p *pullProgress) info() types.ImagePullInfo {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.infoLocked()
+}
+
+func (p *pullProgress) infoLocked() types.ImagePullInfo {
+	info := types.ImagePullInfo{
+		Image:       p.image,
+		StartedTime: p.started.UnixNano(),
+		Waiters:     p.waiters,
+		LayersTotal: len(p.order),
+		Layers:      make([]types.LayerPullInfo, 0, len(p.order)),
+	}
+	for _, d := range p.order {
+		layer := p.layers[d]
+		completed := layer.size > 0 && layer.offset >= uint64(layer.size)
+		info.BytesDownloaded += layer.offset
+		if layer.size > 0 {
+			info.BytesTotal += uint64(layer.size)
+		}
+		if completed {
+			info.LayersCompleted++
+		}
+		info.Layers = append(info.Layers, types.LayerPullInfo{
+			Digest:          d.String(),
+			Size:            layer.size,
+			BytesDownloaded: layer.offset,
+			Completed:       completed,
+		})
+	}
+	return info
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	digest "github.com/opencontainers/go-digest"
+)
+
+func TestWatchPullProgress(t *testing.T) {
+	progress := newPullProgress("image")
+	defer progress.finish(false)
+
+	// A disabled watch never cancels the pull
+	ctx, cancel := context.WithCancel(context.Background())
+	stalled := watchPullProgress(ctx, progress, 0, func() {
+		t.Error("pull canceled without timeout")
+	})
+	cancel()
+
+	// A pull without progress gets canceled
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	canceled := make(chan struct{})
+	progress.update(digest.Digest("sha256:layer"), 2048, 1024)
+	stalled = watchPullProgress(ctx, progress, time.Millisecond, func() {
+		close(canceled)
+	})
+	select {
+	case <-stalled:
+	case <-time.After(10 * pullProgressInterval):
+		t.Fatal("stalled pull not detected")
+	}
+	<-canceled
+	if idle := progress.idle(); idle < time.Millisecond {
+		t.Errorf("unexpected idle time %v", idle)
+	}
+}
+
+func TestPullProgressWrite(t *testing.T) {
+	progress := newPullProgress("image")
+	defer progress.finish(false)
+
+	// Skipped blobs count as progress
+	time.Sleep(10 * time.Millisecond)
+	if _, err := progress.Write([]byte("Copying blob sha256:layer\n")); err != nil {
+		t.Fatal(err)
+	}
+	if idle := progress.idle(); idle >= 10*time.Millisecond {
+		t.Errorf("unexpected idle time %v after a reported blob", idle)
+	}
+
+	// A committing pull is never idle
+	if _, err := progress.Write([]byte("Storing signatures\n")); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if idle := progress.idle(); idle != 0 {
+		t.Errorf("unexpected idle time %v while committing", idle)
+	}
+}
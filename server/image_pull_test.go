@@ -2,13 +2,20 @@ package server_test
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 
+	"github.com/containers/image/copy"
+	"github.com/containers/image/signature"
 	"github.com/containers/image/types"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
+	crioTypes "github.com/cri-o/cri-o/pkg/types"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	digest "github.com/opencontainers/go-digest"
+	"github.com/pkg/errors"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
 )
 
@@ -28,7 +35,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageServerMock.EXPECT().ResolveNames(
 					gomock.Any(), gomock.Any()).
 					Return([]string{"image"}, nil),
-				imageServerMock.EXPECT().PrepareImage(gomock.Any(),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
 					gomock.Any()).Return(imageCloserMock, nil),
 				imageServerMock.EXPECT().ImageStatus(
 					gomock.Any(), gomock.Any()).
@@ -36,7 +43,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageCloserMock.EXPECT().ConfigInfo().
 					Return(types.BlobInfo{Digest: digest.Digest("")}),
 				imageServerMock.EXPECT().PullImage(
-					gomock.Any(), gomock.Any(), gomock.Any()).
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, nil),
 				imageServerMock.EXPECT().ImageStatus(
 					gomock.Any(), gomock.Any()).
@@ -61,7 +68,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageServerMock.EXPECT().ResolveNames(
 					gomock.Any(), gomock.Any()).
 					Return([]string{"image"}, nil),
-				imageServerMock.EXPECT().PrepareImage(gomock.Any(),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
 					gomock.Any()).Return(imageCloserMock, nil),
 				imageServerMock.EXPECT().ImageStatus(
 					gomock.Any(), gomock.Any()).
@@ -98,7 +105,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageServerMock.EXPECT().ResolveNames(
 					gomock.Any(), gomock.Any()).
 					Return([]string{"image"}, nil),
-				imageServerMock.EXPECT().PrepareImage(gomock.Any(),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
 					gomock.Any()).Return(imageCloserMock, nil),
 				imageServerMock.EXPECT().ImageStatus(
 					gomock.Any(), gomock.Any()).
@@ -106,7 +113,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageCloserMock.EXPECT().ConfigInfo().
 					Return(types.BlobInfo{Digest: digest.Digest("")}),
 				imageServerMock.EXPECT().PullImage(
-					gomock.Any(), gomock.Any(), gomock.Any()).
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, nil),
 				imageServerMock.EXPECT().ImageStatus(
 					gomock.Any(), gomock.Any()).
@@ -152,7 +159,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageServerMock.EXPECT().ResolveNames(
 					gomock.Any(), gomock.Any()).
 					Return([]string{"image"}, nil),
-				imageServerMock.EXPECT().PrepareImage(gomock.Any(),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
 					gomock.Any()).Return(imageCloserMock, nil),
 				imageServerMock.EXPECT().ImageStatus(
 					gomock.Any(), gomock.Any()).
@@ -160,7 +167,7 @@ var _ = t.Describe("ImagePull", func() {
 				imageCloserMock.EXPECT().ConfigInfo().
 					Return(types.BlobInfo{Digest: digest.Digest("")}),
 				imageServerMock.EXPECT().PullImage(
-					gomock.Any(), gomock.Any(), gomock.Any()).
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 					Return(nil, t.TestError),
 				imageCloserMock.EXPECT().Close().Return(nil),
 			)
@@ -175,13 +182,47 @@ var _ = t.Describe("ImagePull", func() {
 			Expect(response).To(BeNil())
 		})
 
+		It("should fail when image is rejected by the signature policy", func() {
+			// Given
+			gomock.InOrder(
+				imageServerMock.EXPECT().ResolveNames(
+					gomock.Any(), gomock.Any()).
+					Return([]string{"image"}, nil),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
+					gomock.Any()).Return(imageCloserMock, nil),
+				imageServerMock.EXPECT().ImageStatus(
+					gomock.Any(), gomock.Any()).
+					Return(nil, t.TestError),
+				imageServerMock.EXPECT().PullImage(
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(nil, errors.Wrap(signature.PolicyRequirementError(
+						"rejected"), "Source image rejected")),
+				imageCloserMock.EXPECT().Close().Return(nil),
+			)
+
+			// When
+			response, err := sut.PullImage(context.Background(),
+				&pb.PullImageRequest{
+					Image: &pb.ImageSpec{Image: "id"},
+					SandboxConfig: &pb.PodSandboxConfig{
+						Metadata: &pb.PodSandboxMetadata{Namespace: "namespace"},
+					},
+				})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring(
+				`rejected by the system-wide default signature policy for namespace "namespace"`))
+			Expect(response).To(BeNil())
+		})
+
 		It("should fail when prepare image errors", func() {
 			// Given
 			gomock.InOrder(
 				imageServerMock.EXPECT().ResolveNames(
 					gomock.Any(), gomock.Any()).
 					Return([]string{"image"}, nil),
-				imageServerMock.EXPECT().PrepareImage(gomock.Any(),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
 					gomock.Any()).Return(nil, t.TestError),
 			)
 
@@ -195,6 +236,120 @@ var _ = t.Describe("ImagePull", func() {
 			Expect(response).To(BeNil())
 		})
 
+		It("should coalesce concurrent pulls of the same image", func() {
+			// Given
+			pullStarted := make(chan struct{})
+			pullRelease := make(chan struct{})
+			imageServerMock.EXPECT().ResolveNames(
+				gomock.Any(), gomock.Any()).
+				Return([]string{"image"}, nil).Times(2)
+			imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
+				gomock.Any()).Return(imageCloserMock, nil).Times(2)
+			imageServerMock.EXPECT().ImageStatus(
+				gomock.Any(), gomock.Any()).
+				Return(nil, t.TestError).Times(2)
+			imageServerMock.EXPECT().PullImage(
+				gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, _ *types.SystemContext,
+					_ string, options *copy.Options) (types.ImageReference, error) {
+					options.Progress <- types.ProgressProperties{
+						Artifact: types.BlobInfo{
+							Digest: digest.Digest("sha256:layer"),
+							Size:   2048,
+						},
+						Offset: 1024,
+					}
+					close(pullStarted)
+					<-pullRelease
+					return nil, nil
+				}).Times(1)
+			imageServerMock.EXPECT().ImageStatus(
+				gomock.Any(), gomock.Any()).
+				Return(&storage.ImageResult{ID: "image",
+					RepoDigests: []string{"digest"}}, nil).Times(2)
+			imageCloserMock.EXPECT().Close().Return(nil).Times(2)
+
+			pull := func(done chan<- error) {
+				defer GinkgoRecover()
+				_, err := sut.PullImage(context.Background(),
+					&pb.PullImageRequest{Image: &pb.ImageSpec{
+						Image: "id"}})
+				done <- err
+			}
+			pulls := func() []crioTypes.ImagePullInfo {
+				recorder := httptest.NewRecorder()
+				request, err := http.NewRequest("GET", "/pulls", nil)
+				Expect(err).To(BeNil())
+				sut.GetInfoMux().ServeHTTP(recorder, request)
+				Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+				info := []crioTypes.ImagePullInfo{}
+				Expect(json.Unmarshal(recorder.Body.Bytes(), &info)).To(BeNil())
+				return info
+			}
+
+			// When
+			firstDone := make(chan error, 1)
+			secondDone := make(chan error, 1)
+			go pull(firstDone)
+			<-pullStarted
+			go pull(secondDone)
+			Eventually(func() int {
+				info := pulls()
+				if len(info) != 1 {
+					return 0
+				}
+				return info[0].Waiters
+			}).Should(Equal(2))
+			info := pulls()
+			close(pullRelease)
+
+			// Then
+			Expect(<-firstDone).To(BeNil())
+			Expect(<-secondDone).To(BeNil())
+			Expect(info[0].Image).To(Equal("image"))
+			Expect(info[0].BytesDownloaded).To(BeEquivalentTo(1024))
+			Expect(info[0].BytesTotal).To(BeEquivalentTo(2048))
+			Expect(info[0].LayersTotal).To(Equal(1))
+			Expect(info[0].LayersCompleted).To(BeZero())
+			Expect(pulls()).To(BeEmpty())
+		})
+
+		It("should cancel the pull when the request gets canceled", func() {
+			// Given
+			ctx, cancel := context.WithCancel(context.Background())
+			pullCanceled := make(chan struct{})
+			gomock.InOrder(
+				imageServerMock.EXPECT().ResolveNames(
+					gomock.Any(), gomock.Any()).
+					Return([]string{"image"}, nil),
+				imageServerMock.EXPECT().PrepareImage(gomock.Any(), gomock.Any(),
+					gomock.Any()).Return(imageCloserMock, nil),
+				imageServerMock.EXPECT().ImageStatus(
+					gomock.Any(), gomock.Any()).
+					Return(nil, t.TestError),
+				imageServerMock.EXPECT().PullImage(
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					DoAndReturn(func(pullCtx context.Context, _ *types.SystemContext,
+						_ string, _ *copy.Options) (types.ImageReference, error) {
+						cancel()
+						<-pullCtx.Done()
+						close(pullCanceled)
+						return nil, pullCtx.Err()
+					}),
+				imageCloserMock.EXPECT().Close().Return(nil),
+			)
+
+			// When
+			response, err := sut.PullImage(ctx,
+				&pb.PullImageRequest{Image: &pb.ImageSpec{
+					Image: "id"}})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(response).To(BeNil())
+			Eventually(pullCanceled).Should(BeClosed())
+		})
+
 		It("should fail when resolve names errors", func() {
 			// Given
 			gomock.InOrder(
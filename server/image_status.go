@@ -17,8 +17,9 @@ import (
 // ImageStatus returns the status of the image.
 func (s *Server) ImageStatus(ctx context.Context, req *pb.ImageStatusRequest) (resp *pb.ImageStatusResponse, err error) {
 	const operation = "image_status"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
@@ -77,6 +78,14 @@ func (s *Server) ImageStatus(ctx context.Context, req *pb.ImageStatusRequest) (r
 			resp.Image.Uid = &pb.Int64Value{Value: *uid}
 		}
 		resp.Image.Username = username
+		if req.GetVerbose() {
+			resp.Info = map[string]string{
+				"pinned": strconv.FormatBool(status.Pinned),
+			}
+			if status.PullSource != "" {
+				resp.Info["pullSource"] = status.PullSource
+			}
+		}
 		break
 	}
 	if lastErr != nil && resp == nil {
@@ -45,6 +45,31 @@ var _ = t.Describe("ImageStatus", func() {
 			Expect(response).NotTo(BeNil())
 		})
 
+		It("should succeed with pinned info on verbose", func() {
+			// Given
+			gomock.InOrder(
+				imageServerMock.EXPECT().ResolveNames(
+					gomock.Any(), gomock.Any()).
+					Return([]string{"image"}, nil),
+				imageServerMock.EXPECT().ImageStatus(
+					gomock.Any(), gomock.Any()).
+					Return(&storage.ImageResult{ID: "image",
+						Pinned: true}, nil),
+			)
+
+			// When
+			response, err := sut.ImageStatus(context.Background(),
+				&pb.ImageStatusRequest{
+					Image:   &pb.ImageSpec{Image: "image"},
+					Verbose: true,
+				})
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(response).NotTo(BeNil())
+			Expect(response.Info).To(HaveKeyWithValue("pinned", "true"))
+		})
+
 		It("should succeed with wrong image id", func() {
 			// Given
 			gomock.InOrder(
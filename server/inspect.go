@@ -6,11 +6,19 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/containers/storage/pkg/idtools"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
 	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/cri-o/cri-o/utils"
 	"github.com/go-zoo/bone"
 	"github.com/sirupsen/logrus"
 )
@@ -43,7 +51,9 @@ func (s *Server) getInfo() types.CrioInfo {
 		StorageDriver:     s.config.Storage,
 		StorageRoot:       s.config.Root,
 		CgroupDriver:      s.config.CgroupManager,
+		CgroupVersion:     cgroupv2.Version(),
 		DefaultIDMappings: s.getIDMappingsInfo(),
+		Quarantined:       s.quarantine.list(),
 	}
 }
 
@@ -51,6 +61,7 @@ var (
 	errCtrNotFound     = errors.New("container not found")
 	errCtrStateNil     = errors.New("container state is nil")
 	errSandboxNotFound = errors.New("sandbox for container not found")
+	errSbNotFound      = errors.New("sandbox not found")
 )
 
 func (s *Server) getContainerInfo(id string, getContainerFunc, getInfraContainerFunc func(id string) *oci.Container, getSandboxFunc func(id string) *sandbox.Sandbox) (types.ContainerInfo, error) {
@@ -78,6 +89,7 @@ func (s *Server) getContainerInfo(id string, getContainerFunc, getInfraContainer
 		}
 	}
 	return types.ContainerInfo{
+		ID:              ctr.ID(),
 		Name:            ctr.Name(),
 		Pid:             ctrState.Pid,
 		Image:           image,
@@ -90,10 +102,158 @@ func (s *Server) getContainerInfo(id string, getContainerFunc, getInfraContainer
 		LogPath:         ctr.LogPath(),
 		Sandbox:         ctr.Sandbox(),
 		IP:              sb.IP(),
+		IPs:             sb.IPs(),
 	}, nil
 
 }
 
+// getSandboxInfo returns the information about the sandbox `sb`.
+func getSandboxInfo(sb *sandbox.Sandbox) types.SandboxInfo {
+	containers := []string{}
+	for _, ctr := range sb.Containers().List() {
+		containers = append(containers, ctr.ID())
+	}
+	sort.Strings(containers)
+	return types.SandboxInfo{
+		ID:             sb.ID(),
+		Name:           sb.Metadata().GetName(),
+		Namespace:      sb.Namespace(),
+		RuntimeHandler: sb.RuntimeHandler(),
+		Stopped:        sb.Stopped(),
+		Labels:         sb.Labels(),
+		Annotations:    sb.Annotations(),
+		IPs:            sb.IPs(),
+		Containers:     containers,
+	}
+}
+
+// sandboxesInfo returns the information about all sandboxes, ordered by
+// their ID.
+func (s *Server) sandboxesInfo() []types.SandboxInfo {
+	sandboxes := s.ContainerServer.ListSandboxes()
+	infos := make([]types.SandboxInfo, 0, len(sandboxes))
+	for _, sb := range sandboxes {
+		infos = append(infos, getSandboxInfo(sb))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// getSandboxContainersInfo returns the information about all containers of
+// the sandbox `id`, ordered by their creation time.
+func (s *Server) getSandboxContainersInfo(id string, getSandboxFunc func(id string) *sandbox.Sandbox) ([]types.ContainerInfo, error) {
+	sb := getSandboxFunc(id)
+	if sb == nil {
+		return nil, errSbNotFound
+	}
+	ctrs := sb.Containers().List()
+	sort.Slice(ctrs, func(i, j int) bool {
+		return ctrs[i].CreatedAt().Before(ctrs[j].CreatedAt())
+	})
+	infos := make([]types.ContainerInfo, 0, len(ctrs))
+	for _, ctr := range ctrs {
+		getContainerFunc := func(string) *oci.Container { return ctr }
+		getSandboxFunc := func(string) *sandbox.Sandbox { return sb }
+		ci, err := s.getContainerInfo(ctr.ID(), getContainerFunc, getContainerFunc, getSandboxFunc)
+		if err == errCtrStateNil {
+			logrus.Debugf("skipping container %s without state", ctr.ID())
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, ci)
+	}
+	return infos, nil
+}
+
+// imagePullsInfo returns the progress of all image pulls currently in
+// flight, ordered by their start time.
+func (s *Server) imagePullsInfo() []types.ImagePullInfo {
+	s.pullOperationsLock.Lock()
+	defer s.pullOperationsLock.Unlock()
+	pulls := make([]types.ImagePullInfo, 0, len(s.pullOperationsInProgress))
+	for _, pullOp := range s.pullOperationsInProgress {
+		pulls = append(pulls, pullOp.progress.info())
+	}
+	sort.Slice(pulls, func(i, j int) bool {
+		return pulls[i].StartedTime < pulls[j].StartedTime
+	})
+	return pulls
+}
+
+// imagesInfo returns the information about all images in the storage.
+func (s *Server) imagesInfo() ([]types.ImageInfo, error) {
+	results, err := s.StorageImageServer().ListImages(s.systemContext, "")
+	if err != nil {
+		return nil, err
+	}
+	images := make([]types.ImageInfo, 0, len(results))
+	for i := range results {
+		image := types.ImageInfo{
+			ID:          results[i].ID,
+			RepoTags:    results[i].RepoTags,
+			RepoDigests: results[i].RepoDigests,
+			Pinned:      results[i].Pinned,
+		}
+		if results[i].Size != nil {
+			image.Size = *results[i].Size
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// parseEventFilter parses the event filter of the /events route from the
+// `query` parameters `sandbox`, `label` (as `key=value`) and `type`, where
+// the latter two can be repeated.
+func parseEventFilter(query url.Values) (types.EventFilter, error) {
+	filter := types.EventFilter{SandboxID: query.Get("sandbox")}
+	for _, label := range query["label"] {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return filter, fmt.Errorf("invalid label filter %q, expected key=value", label)
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(map[string]string)
+		}
+		filter.Labels[kv[0]] = kv[1]
+	}
+	for _, t := range query["type"] {
+		switch eventType := types.EventType(t); eventType {
+		case types.EventContainerCreated, types.EventContainerStarted,
+			types.EventContainerExited, types.EventContainerOOMKilled,
+			types.EventContainerStopped, types.EventContainerRemoved,
+			types.EventSandboxNetworkSetUp, types.EventSandboxNetworkTornDown:
+			filter.Types = append(filter.Types, eventType)
+		default:
+			return filter, fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	return filter, nil
+}
+
+// containerStatsInfo converts the runtime statistics of a container into the
+// format of the inspect endpoint.
+func containerStatsInfo(stats *oci.ContainerStats) types.ContainerStats {
+	return types.ContainerStats{
+		ID:             stats.Container,
+		CPUNano:        stats.CPUNano,
+		MemoryUsage:    stats.MemUsage,
+		MemoryLimit:    stats.MemLimit,
+		BlockInput:     stats.BlockInput,
+		BlockOutput:    stats.BlockOutput,
+		PIDs:           stats.PIDs,
+		MemoryStat:     stats.MemStat,
+		IOStat:         stats.IOStat,
+		CPUPressure:    stats.CPUPressure,
+		MemoryPressure: stats.MemoryPressure,
+		IOPressure:     stats.IOPressure,
+	}
+}
+
 // GetInfoMux returns the mux used to serve info requests
 func (s *Server) GetInfoMux() *bone.Mux {
 	mux := bone.New()
@@ -125,6 +285,204 @@ func (s *Server) GetInfoMux() *bone.Mux {
 		}
 	}))
 
+	mux.Get("/pulls", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		js, err := json.Marshal(s.imagePullsInfo())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(js); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write JSON: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
+	mux.Get("/images", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		images, err := s.imagesInfo()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		js, err := json.Marshal(images)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(js); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write JSON: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
+	mux.Get("/sandboxes", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		js, err := json.Marshal(s.sandboxesInfo())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(js); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write JSON: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
+	mux.Get("/sandboxes/:id/containers", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sandboxID := bone.GetValue(req, "id")
+		infos, err := s.getSandboxContainersInfo(sandboxID, s.getSandbox)
+		if err != nil {
+			if err == errSbNotFound {
+				http.Error(w, fmt.Sprintf("can't find the sandbox with id %s", sandboxID), http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		js, err := json.Marshal(infos)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(js); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write JSON: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
+	mux.Get("/debug/goroutines", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		if err := utils.WriteGoroutineStacks(w); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write goroutine stacks: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
+	mux.Get("/events", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		filter, err := parseEventFilter(req.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+		events, cancel := s.events.Subscribe(filter, req.URL.Query().Get("replay") == "true")
+		defer cancel()
+
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+		encoder := json.NewEncoder(w)
+		for {
+			select {
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := encoder.Encode(&event); err != nil {
+					logrus.Debugf("unable to write event: %v", err)
+					return
+				}
+				flusher.Flush()
+			case <-req.Context().Done():
+				return
+			}
+		}
+	}))
+
+	mux.Post("/shutdown", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("drain") == "true" {
+			timeout := DefaultDrainTimeout
+			if value := req.URL.Query().Get("timeout"); value != "" {
+				var err error
+				timeout, err = time.ParseDuration(value)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("invalid timeout %q: %v", value, err), http.StatusBadRequest)
+					return
+				}
+			}
+			if err := s.DrainPodSandboxes(req.Context(), timeout); err != nil {
+				http.Error(w, fmt.Sprintf("unable to drain pod sandboxes: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+		logrus.Infof("shutdown requested")
+		s.RequestShutdown()
+	}))
+
+	mux.Post("/containers/:id/checkpoint", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		containerID := bone.GetValue(req, "id")
+		exportPath := req.URL.Query().Get("path")
+		if !filepath.IsAbs(exportPath) {
+			http.Error(w, fmt.Sprintf("invalid export path %q: must be absolute", exportPath), http.StatusBadRequest)
+			return
+		}
+		leaveRunning := req.URL.Query().Get("leave-running") == "true"
+		if _, err := s.ContainerCheckpoint(containerID, exportPath, leaveRunning); err != nil {
+			http.Error(w, fmt.Sprintf("unable to checkpoint container %s: %v", containerID, err), http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("checkpointed container %s to %s", containerID, exportPath)
+	}))
+
+	mux.Post("/sandboxes/:id/restore", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		sandboxID := bone.GetValue(req, "id")
+		importPath := req.URL.Query().Get("path")
+		if !filepath.IsAbs(importPath) {
+			http.Error(w, fmt.Sprintf("invalid import path %q: must be absolute", importPath), http.StatusBadRequest)
+			return
+		}
+		containerID, err := s.restoreCheckpoint(sandboxID, importPath)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to restore container in sandbox %s: %v", sandboxID, err), http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("restored container %s from %s", containerID, importPath)
+		if _, err := fmt.Fprintln(w, containerID); err != nil {
+			logrus.Debugf("unable to write restored container ID: %v", err)
+		}
+	}))
+
+	mux.Get("/containers/:id/seccomp", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		containerID := bone.GetValue(req, "id")
+		profile, err := s.seccompAuditProfile(containerID)
+		if err != nil {
+			if os.IsNotExist(err) {
+				http.Error(w, fmt.Sprintf("can't find a seccomp profile for container id %s", containerID), http.StatusNotFound)
+			} else {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(profile); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write JSON: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
+	mux.Get("/containers/:id/stats", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		containerID := bone.GetValue(req, "id")
+		c := s.GetContainer(containerID)
+		if c == nil {
+			http.Error(w, fmt.Sprintf("can't find the container with id %s", containerID), http.StatusNotFound)
+			return
+		}
+		stats, err := s.Runtime().ContainerStats(c)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("unable to get stats of container %s: %v", containerID, err), http.StatusInternalServerError)
+			return
+		}
+		js, err := json.Marshal(containerStatsInfo(stats))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write(js); err != nil {
+			http.Error(w, fmt.Sprintf("unable to write JSON: %v", err), http.StatusInternalServerError)
+		}
+	}))
+
 	mux.Get("/containers/:id", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
 		containerID := bone.GetValue(req, "id")
 		ci, err := s.getContainerInfo(containerID, s.GetContainer, s.getInfraContainer, s.getSandbox)
@@ -1,8 +1,12 @@
 package server_test
 
 import (
+	"context"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 
 	"github.com/cri-o/cri-o/internal/pkg/storage"
 	"github.com/go-zoo/bone"
@@ -43,6 +47,57 @@ var _ = t.Describe("Inspect", func() {
 			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
 		})
 
+		It("should succeed with /pulls route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest("GET", "/pulls", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(request).NotTo(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+			Expect(recorder.Body.String()).To(Equal("[]"))
+		})
+
+		It("should succeed with /images route", func() {
+			// Given
+			size := uint64(100)
+			gomock.InOrder(
+				imageServerMock.EXPECT().ListImages(gomock.Any(), "").
+					Return([]storage.ImageResult{
+						{ID: "image", Size: &size, Pinned: true},
+					}, nil),
+			)
+
+			// When
+			request, err := http.NewRequest("GET", "/images", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(request).NotTo(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring(`"pinned":true`))
+		})
+
+		It("should fail with /images route if listing errors", func() {
+			// Given
+			gomock.InOrder(
+				imageServerMock.EXPECT().ListImages(gomock.Any(), "").
+					Return(nil, t.TestError),
+			)
+
+			// When
+			request, err := http.NewRequest("GET", "/images", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(request).NotTo(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusInternalServerError))
+		})
+
 		It("should succeed with valid /containers route", func() {
 			// Given
 			Expect(sut.AddSandbox(testSandbox)).To(BeNil())
@@ -113,6 +168,72 @@ var _ = t.Describe("Inspect", func() {
 			Expect(recorder.Code).To(BeEquivalentTo(http.StatusNotFound))
 		})
 
+		It("should succeed with /shutdown route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest("POST", "/shutdown", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+			Expect(sut.ShutdownRequestedChan()).To(BeClosed())
+		})
+
+		It("should succeed to drain with /shutdown route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest("POST",
+				"/shutdown?drain=true&timeout=1s", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+			Expect(sut.ShutdownRequestedChan()).To(BeClosed())
+		})
+
+		It("should fail with invalid drain timeout on /shutdown route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest("POST",
+				"/shutdown?drain=true&timeout=invalid", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusBadRequest))
+			Expect(sut.ShutdownRequestedChan()).NotTo(BeClosed())
+		})
+
+		It("should succeed with /events route", func() {
+			// Given
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			// When
+			request, err := http.NewRequest("GET",
+				"/events?replay=true&type=container_exited", nil)
+			mux.ServeHTTP(recorder, request.WithContext(ctx))
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+			Expect(recorder.Header().Get("Content-Type")).
+				To(Equal("application/x-ndjson"))
+		})
+
+		It("should fail with invalid filter on /events route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest("GET", "/events?type=invalid", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusBadRequest))
+		})
+
 		It("should fail with invalid container ID on /containers route", func() {
 			// Given
 			// When
@@ -125,5 +246,31 @@ var _ = t.Describe("Inspect", func() {
 			Expect(recorder.Code).To(BeEquivalentTo(http.StatusNotFound))
 		})
 
+		It("should succeed with /containers/:id/seccomp route", func() {
+			// Given
+			Expect(os.MkdirAll(filepath.Join("test", "seccomp"), 0755)).To(BeNil())
+			Expect(ioutil.WriteFile(filepath.Join("test", "seccomp", "123.json"),
+				[]byte(`{"defaultAction":"SCMP_ACT_ERRNO"}`), 0644)).To(BeNil())
+
+			// When
+			request, err := http.NewRequest("GET", "/containers/123/seccomp", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusOK))
+			Expect(recorder.Body.String()).To(ContainSubstring("SCMP_ACT_ERRNO"))
+		})
+
+		It("should fail without profile on /containers/:id/seccomp route", func() {
+			// Given
+			// When
+			request, err := http.NewRequest("GET", "/containers/123/seccomp", nil)
+			mux.ServeHTTP(recorder, request)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(recorder.Code).To(BeEquivalentTo(http.StatusNotFound))
+		})
 	})
 })
@@ -1,6 +1,9 @@
 package server
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -9,6 +12,9 @@ import (
 	"github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
+	"github.com/cri-o/cri-o/internal/pkg/events"
+	"github.com/cri-o/cri-o/pkg/types"
 	specs "github.com/opencontainers/runtime-spec/specs-go"
 )
 
@@ -26,6 +32,9 @@ func TestGetInfo(t *testing.T) {
 	if ci.CgroupDriver != "systemd" {
 		t.Fatalf("expected 'systemd', got %q", ci.CgroupDriver)
 	}
+	if ci.CgroupVersion != "v1" && ci.CgroupVersion != "v2" {
+		t.Fatalf("expected 'v1' or 'v2', got %q", ci.CgroupVersion)
+	}
 	if ci.StorageDriver != "afoobarstorage" {
 		t.Fatalf("expected 'afoobarstorage', got %q", ci.StorageDriver)
 	}
@@ -34,6 +43,41 @@ func TestGetInfo(t *testing.T) {
 	}
 }
 
+func TestCheckpointRestoreRelativePath(t *testing.T) {
+	s := &Server{}
+	mux := s.GetInfoMux()
+	for _, path := range []string{
+		"/containers/id/checkpoint?path=checkpoint.tar",
+		"/sandboxes/id/restore?path=checkpoint.tar",
+	} {
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest("POST", path, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %s, got %d", http.StatusBadRequest, path, recorder.Code)
+		}
+	}
+}
+
+func TestContainerStatsInfo(t *testing.T) {
+	stats := &oci.ContainerStats{
+		Container:   "id",
+		MemUsage:    100,
+		MemStat:     map[string]uint64{"anon": 10},
+		IOStat:      map[string]map[string]uint64{"8:0": {"rbytes": 20}},
+		CPUPressure: &cgroupv2.Pressure{Some: cgroupv2.PressureValues{Total: 30}},
+	}
+	info := containerStatsInfo(stats)
+	if info.ID != "id" || info.MemoryUsage != 100 {
+		t.Fatalf("unexpected stats info %+v", info)
+	}
+	if info.MemoryStat["anon"] != 10 || info.IOStat["8:0"]["rbytes"] != 20 {
+		t.Fatalf("expected the cgroup v2 stats, got %+v", info)
+	}
+	if info.CPUPressure == nil || info.CPUPressure.Some.Total != 30 || info.IOPressure != nil {
+		t.Fatalf("expected the CPU pressure only, got %+v", info)
+	}
+}
+
 func TestGetContainerInfo(t *testing.T) {
 	s := &Server{}
 	created := time.Now()
@@ -65,7 +109,7 @@ func TestGetContainerInfo(t *testing.T) {
 	}
 	getSandboxFunc := func(id string) *sandbox.Sandbox {
 		s := &sandbox.Sandbox{}
-		s.AddIP("1.1.1.42")
+		s.AddIPs([]string{"1.1.1.42", "fd00::42"})
 		return s
 	}
 	ci, err := s.getContainerInfo("", getContainerFunc, getInfraContainerFunc, getSandboxFunc)
@@ -99,6 +143,9 @@ func TestGetContainerInfo(t *testing.T) {
 	if ci.IP != "1.1.1.42" {
 		t.Fatalf("expected ip 1.1.1.42, got %s", ci.IP)
 	}
+	if len(ci.IPs) != 2 || ci.IPs[1] != "fd00::42" {
+		t.Fatalf("expected ips [1.1.1.42 fd00::42], got %v", ci.IPs)
+	}
 	if len(ci.Annotations) == 0 {
 		t.Fatal("annotations are empty")
 	}
@@ -177,7 +224,7 @@ func TestGetContainerInfoCtrStateNil(t *testing.T) {
 	}
 	getSandboxFunc := func(id string) *sandbox.Sandbox {
 		s := &sandbox.Sandbox{}
-		s.AddIP("1.1.1.42")
+		s.AddIPs([]string{"1.1.1.42", "fd00::42"})
 		return s
 	}
 	_, err := s.getContainerInfo("", getContainerFunc, getInfraContainerFunc, getSandboxFunc)
@@ -216,3 +263,113 @@ func TestGetContainerInfoSandboxNotFound(t *testing.T) {
 		t.Fatalf("expected errSandboxNotFound error, got %v", err)
 	}
 }
+
+func TestGetSandboxContainersInfo(t *testing.T) {
+	s := &Server{}
+	sb, err := sandbox.New("testsandboxid", "", "", "", "", map[string]string{},
+		map[string]string{}, "", "", &runtime.PodSandboxMetadata{}, "", "",
+		false, "", "", "", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb.AddIPs([]string{"1.1.1.42"})
+	created := time.Now()
+	for i, id := range []string{"second", "first"} {
+		container, err := oci.NewContainer(id, id, "", "", "", map[string]string{}, map[string]string{}, map[string]string{}, "image", "imageName", "imageRef", &runtime.ContainerMetadata{}, "testsandboxid", false, false, false, false, "", "", created.Add(-time.Duration(i)*time.Second), "SIGKILL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cstate := &oci.ContainerState{}
+		cstate.Created = container.CreatedAt()
+		container.SetState(cstate)
+		sb.AddContainer(container)
+	}
+	getSandboxFunc := func(id string) *sandbox.Sandbox {
+		return sb
+	}
+	infos, err := s.getSandboxContainersInfo("testsandboxid", getSandboxFunc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].ID != "first" || infos[1].ID != "second" {
+		t.Fatalf("expected containers [first second], got %v", infos)
+	}
+	if infos[0].IP != "1.1.1.42" {
+		t.Fatalf("expected ip 1.1.1.42, got %s", infos[0].IP)
+	}
+
+	sbInfo := getSandboxInfo(sb)
+	if sbInfo.ID != "testsandboxid" {
+		t.Fatalf("expected sandbox testsandboxid, got %s", sbInfo.ID)
+	}
+	if len(sbInfo.Containers) != 2 || sbInfo.Containers[0] != "first" {
+		t.Fatalf("expected containers [first second], got %v", sbInfo.Containers)
+	}
+}
+
+func TestGetSandboxContainersInfoSandboxNotFound(t *testing.T) {
+	s := &Server{}
+	getSandboxFunc := func(id string) *sandbox.Sandbox {
+		return nil
+	}
+	_, err := s.getSandboxContainersInfo("", getSandboxFunc)
+	if err != errSbNotFound {
+		t.Fatalf("expected errSbNotFound error, got %v", err)
+	}
+}
+
+func TestParseEventFilter(t *testing.T) {
+	query := url.Values{
+		"sandbox": {"testsandboxid"},
+		"label":   {"app=test", "tier="},
+		"type":    {"container_exited", "sandbox_network_setup"},
+	}
+	filter, err := parseEventFilter(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter.SandboxID != "testsandboxid" {
+		t.Fatalf("expected sandbox testsandboxid, got %s", filter.SandboxID)
+	}
+	if len(filter.Labels) != 2 || filter.Labels["app"] != "test" {
+		t.Fatalf("expected labels app=test and tier=, got %v", filter.Labels)
+	}
+	if len(filter.Types) != 2 || filter.Types[0] != types.EventContainerExited {
+		t.Fatalf("expected types [container_exited sandbox_network_setup], got %v", filter.Types)
+	}
+
+	for _, invalid := range []url.Values{
+		{"label": {"app"}},
+		{"type": {"unknown"}},
+	} {
+		if _, err := parseEventFilter(invalid); err == nil {
+			t.Fatalf("expected error for %v", invalid)
+		}
+	}
+}
+
+func TestPublishContainerExit(t *testing.T) {
+	s := &Server{events: events.NewBus(1)}
+	container, err := oci.NewContainer("testid", "testname", "", "", "", map[string]string{"app": "test"}, map[string]string{}, map[string]string{}, "image", "imageName", "imageRef", &runtime.ContainerMetadata{}, "testsandboxid", false, false, false, false, "", "", time.Now(), "SIGKILL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cstate := &oci.ContainerState{}
+	cstate.ExitCode = 137
+	cstate.OOMKilled = true
+	container.SetState(cstate)
+
+	s.publishContainerExit(container)
+
+	exits, cancel := s.events.Subscribe(types.EventFilter{
+		Labels: map[string]string{"app": "test"},
+	}, true)
+	defer cancel()
+	event := <-exits
+	if event.Type != types.EventContainerExited || event.ContainerID != "testid" || event.SandboxID != "testsandboxid" {
+		t.Fatalf("expected exit event of testid in testsandboxid, got %+v", event)
+	}
+	if event.ExitCode == nil || *event.ExitCode != 137 || !event.OOMKilled {
+		t.Fatalf("expected OOM killed exit with code 137, got %+v", event)
+	}
+}
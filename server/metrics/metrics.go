@@ -11,9 +11,72 @@ const (
 	// CRIOOperationsKey is the key for CRI-O operation metrics.
 	CRIOOperationsKey = "crio_operations"
 	// CRIOOperationsLatencyKey is the key for the operation latency metrics.
+	// Deprecated: use CRIOOperationsLatencySecondsKey instead.
 	CRIOOperationsLatencyKey = "crio_operations_latency_microseconds"
+	// CRIOOperationsLatencySecondsKey is the key for the operation latency
+	// histogram.
+	CRIOOperationsLatencySecondsKey = "crio_operations_latency_seconds"
 	// CRIOOperationsErrorsKey is the key for the operation error metrics.
 	CRIOOperationsErrorsKey = "crio_operations_errors"
+	// CRIOImagePullsInProgressKey is the key for the in-flight image pulls
+	// metrics.
+	CRIOImagePullsInProgressKey = "crio_image_pulls_in_progress"
+	// CRIOImagePullsBytesKey is the key for the image pull bytes metrics.
+	CRIOImagePullsBytesKey = "crio_image_pulls_bytes_downloaded"
+	// CRIOImagePullsLayersKey is the key for the image pull layer metrics.
+	CRIOImagePullsLayersKey = "crio_image_pulls_layers_completed"
+	// CRIOContainersQuarantinedKey is the key for the quarantined containers
+	// metrics.
+	CRIOContainersQuarantinedKey = "crio_containers_quarantined"
+	// CRIOContainersOOMKillsKey is the key for the OOM kill metrics.
+	CRIOContainersOOMKillsKey = "crio_containers_oom_kills"
+	// CRIOContainersSeccompBlockedSyscallsKey is the key for the syscalls
+	// blocked by the seccomp profiles of containers in seccomp notifier mode.
+	CRIOContainersSeccompBlockedSyscallsKey = "crio_containers_seccomp_blocked_syscalls"
+	// CRIOContainersKey is the key for the container metrics.
+	CRIOContainersKey = "crio_containers"
+	// CRIOSandboxesKey is the key for the sandbox metrics.
+	CRIOSandboxesKey = "crio_sandboxes"
+	// CRIOContainersCreateLatencyKey is the key for the container creation
+	// latency metrics.
+	CRIOContainersCreateLatencyKey = "crio_containers_create_latency_seconds"
+	// CRIOImagesKey is the key for the image metrics.
+	CRIOImagesKey = "crio_images"
+	// CRIOImagePullsBytesTotalKey is the key for the image pull bytes
+	// metrics by registry.
+	CRIOImagePullsBytesTotalKey = "crio_image_pulls_bytes_total"
+	// CRIOImagePullsDurationKey is the key for the image pull duration
+	// metrics.
+	CRIOImagePullsDurationKey = "crio_image_pulls_duration_seconds"
+	// CRIOImagePullsFailuresKey is the key for the image pull failure
+	// metrics.
+	CRIOImagePullsFailuresKey = "crio_image_pulls_failures"
+	// CRIOImagePullsTimeoutsKey is the key for the image pulls aborted
+	// because they made no progress.
+	CRIOImagePullsTimeoutsKey = "crio_image_pulls_timeouts"
+	// CRIOImagePullsSignatureFailuresKey is the key for the image pulls
+	// rejected by a signature policy.
+	CRIOImagePullsSignatureFailuresKey = "crio_image_pulls_signature_failures"
+	// CRIOStorageUsedBytesKey is the key for the storage usage metrics.
+	CRIOStorageUsedBytesKey = "crio_storage_used_bytes"
+	// CRIOStorageInodesUsedKey is the key for the storage inode metrics.
+	CRIOStorageInodesUsedKey = "crio_storage_inodes_used"
+	// CRIOConmonProcessesKey is the key for the conmon process metrics.
+	CRIOConmonProcessesKey = "crio_conmon_processes"
+	// CRIOConmonRSSBytesKey is the key for the conmon memory metrics.
+	CRIOConmonRSSBytesKey = "crio_conmon_rss_bytes"
+	// CRIOExitMonitorLagKey is the key for the exit monitor lag metrics.
+	CRIOExitMonitorLagKey = "crio_exit_monitor_lag_seconds"
+	// CRIOCNILatencyKey is the key for the CNI latency metrics.
+	CRIOCNILatencyKey = "crio_cni_operations_latency_seconds"
+	// CRIOCNIErrorsKey is the key for the CNI error metrics.
+	CRIOCNIErrorsKey = "crio_cni_operations_errors"
+
+	// MaxLabelValues is the maximum number of distinct values of metric
+	// labels which are not bounded by themselves, like registries or pods.
+	MaxLabelValues = 100
+	// OtherLabelValue replaces the label values beyond MaxLabelValues.
+	OtherLabelValue = "other"
 
 	// TODO(runcom):
 	// timeouts
@@ -33,11 +96,24 @@ var (
 	)
 	// CRIOOperationsLatency collects operation latency numbers by operation
 	// type.
+	// Deprecated: use CRIOOperationsLatencySeconds instead.
 	CRIOOperationsLatency = prometheus.NewSummaryVec(
 		prometheus.SummaryOpts{
 			Subsystem: subsystem,
 			Name:      CRIOOperationsLatencyKey,
-			Help:      "Latency in microseconds of CRI-O operations. Broken down by operation type.",
+			Help:      "Latency in microseconds of CRI-O operations. Broken down by operation type. Deprecated, use " + CRIOOperationsLatencySecondsKey + " instead.",
+		},
+		[]string{"operation_type"},
+	)
+	// CRIOOperationsLatencySeconds collects the operation latency by
+	// operation type.
+	CRIOOperationsLatencySeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      CRIOOperationsLatencySecondsKey,
+			Help:      "Latency in seconds of CRI-O operations. Broken down by operation type.",
+			// 1ms up to about 33s
+			Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
 		},
 		[]string{"operation_type"},
 	)
@@ -51,6 +127,227 @@ var (
 		},
 		[]string{"operation_type"},
 	)
+	// CRIOImagePullsInProgress collects the number of image pulls currently
+	// in flight.
+	CRIOImagePullsInProgress = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsInProgressKey,
+			Help:      "Number of image pulls currently in progress.",
+		},
+	)
+	// CRIOImagePullsBytes collects the bytes downloaded by image pulls in
+	// progress, by image name.
+	CRIOImagePullsBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsBytesKey,
+			Help:      "Bytes downloaded by image pulls in progress. Broken down by image name.",
+		},
+		[]string{"name"},
+	)
+	// CRIOImagePullsLayers collects the completed layers of image pulls in
+	// progress, by image name.
+	CRIOImagePullsLayers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsLayersKey,
+			Help:      "Completed layers of image pulls in progress. Broken down by image name.",
+		},
+		[]string{"name"},
+	)
+	// CRIOContainersQuarantined collects the number of containers and
+	// sandboxes which could not be restored on startup.
+	CRIOContainersQuarantined = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOContainersQuarantinedKey,
+			Help:      "Number of containers and sandboxes which could not be restored and got quarantined.",
+		},
+	)
+
+	// CRIOContainersOOMKills counts the OOM killed processes of containers.
+	CRIOContainersOOMKills = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOContainersOOMKillsKey,
+			Help:      "Cumulative number of OOM killed container processes. Broken down by pod namespace and name.",
+		},
+		[]string{"namespace", "pod"},
+	)
+	// CRIOContainersSeccompBlockedSyscalls counts the syscalls blocked by the
+	// seccomp profiles of containers in seccomp notifier mode.
+	CRIOContainersSeccompBlockedSyscalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOContainersSeccompBlockedSyscallsKey,
+			Help:      "Cumulative number of syscalls blocked by the seccomp profiles of containers in seccomp notifier mode. Broken down by syscall.",
+		},
+		[]string{"syscall"},
+	)
+	// CRIOContainers collects the number of containers by state.
+	CRIOContainers = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOContainersKey,
+			Help:      "Number of containers. Broken down by state.",
+		},
+		[]string{"state"},
+	)
+	// CRIOSandboxes collects the number of sandboxes by state.
+	CRIOSandboxes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOSandboxesKey,
+			Help:      "Number of sandboxes. Broken down by state.",
+		},
+		[]string{"state"},
+	)
+	// CRIOContainersCreateLatency collects the latency of creating
+	// containers in the OCI runtime by runtime handler.
+	CRIOContainersCreateLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      CRIOContainersCreateLatencyKey,
+			Help:      "Latency in seconds of creating containers in the OCI runtime. Broken down by runtime handler.",
+			// 10ms up to about 41s
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 13),
+		},
+		[]string{"runtime_handler"},
+	)
+	// CRIOImages collects the number of images in the storage.
+	CRIOImages = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagesKey,
+			Help:      "Number of images in the storage.",
+		},
+	)
+	// CRIOImagePullsBytesTotal collects the bytes downloaded by finished
+	// image pulls by registry.
+	CRIOImagePullsBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsBytesTotalKey,
+			Help:      "Cumulative number of bytes downloaded by image pulls. Broken down by registry.",
+		},
+		[]string{"registry"},
+	)
+	// CRIOImagePullsDuration collects the duration of finished image pulls
+	// by registry.
+	CRIOImagePullsDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsDurationKey,
+			Help:      "Duration in seconds of image pulls. Broken down by registry.",
+			// 100ms up to about 14min
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 14),
+		},
+		[]string{"registry"},
+	)
+	// CRIOImagePullsFailures collects the failed image pulls by registry.
+	CRIOImagePullsFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsFailuresKey,
+			Help:      "Cumulative number of failed image pulls. Broken down by registry.",
+		},
+		[]string{"registry"},
+	)
+	// CRIOImagePullsTimeouts collects the image pulls aborted because they
+	// made no progress by registry.
+	CRIOImagePullsTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsTimeoutsKey,
+			Help:      "Cumulative number of image pulls aborted because they made no progress. Broken down by registry.",
+		},
+		[]string{"registry"},
+	)
+	// CRIOImagePullsSignatureFailures collects the image pulls rejected by a
+	// signature policy by namespace.
+	CRIOImagePullsSignatureFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOImagePullsSignatureFailuresKey,
+			Help:      "Cumulative number of image pulls rejected by the signature policy. Broken down by the namespace of the requesting pod.",
+		},
+		[]string{"namespace"},
+	)
+	// CRIOStorageUsedBytes collects the bytes used by the image storage.
+	CRIOStorageUsedBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOStorageUsedBytesKey,
+			Help:      "Bytes used by the image storage.",
+		},
+	)
+	// CRIOStorageInodesUsed collects the inodes used by the image storage.
+	CRIOStorageInodesUsed = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOStorageInodesUsedKey,
+			Help:      "Inodes used by the image storage.",
+		},
+	)
+	// CRIOConmonProcesses collects the number of conmon processes.
+	CRIOConmonProcesses = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOConmonProcessesKey,
+			Help:      "Number of conmon processes monitoring containers.",
+		},
+	)
+	// CRIOConmonRSSBytes collects the resident memory of all conmon
+	// processes.
+	CRIOConmonRSSBytes = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Subsystem: subsystem,
+			Name:      CRIOConmonRSSBytesKey,
+			Help:      "Resident memory in bytes of all conmon processes monitoring containers.",
+		},
+	)
+	// CRIOExitMonitorLag collects the time between a container exit and
+	// its processing by the exit monitor.
+	CRIOExitMonitorLag = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      CRIOExitMonitorLagKey,
+			Help:      "Time in seconds between writing the exit file of a container and updating its state.",
+			// 1ms up to about 16s
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 8),
+		},
+	)
+	// CRIOCNILatency collects the latency of CNI operations by operation.
+	CRIOCNILatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      CRIOCNILatencyKey,
+			Help:      "Latency in seconds of CNI operations. Broken down by operation ('setup' or 'teardown').",
+			// 10ms up to about 41s
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 13),
+		},
+		[]string{"operation"},
+	)
+	// CRIOCNIErrors collects the errors of CNI operations by operation.
+	CRIOCNIErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      CRIOCNIErrorsKey,
+			Help:      "Cumulative number of CNI operation errors. Broken down by operation ('setup' or 'teardown').",
+		},
+		[]string{"operation"},
+	)
+
+	// RegistryLabels limits the registry label values.
+	RegistryLabels = NewLabelLimiter(MaxLabelValues)
+	// RuntimeHandlerLabels limits the runtime handler label values.
+	RuntimeHandlerLabels = NewLabelLimiter(MaxLabelValues)
+	// NamespaceLabels limits the namespace label values.
+	NamespaceLabels = NewLabelLimiter(MaxLabelValues)
+	// PodLabels limits the pod label values, where a value is the pod
+	// namespace and name.
+	PodLabels = NewLabelLimiter(MaxLabelValues)
 )
 
 var registerMetrics sync.Once
@@ -61,6 +358,29 @@ func Register() {
 		prometheus.MustRegister(CRIOOperations)
 		prometheus.MustRegister(CRIOOperationsLatency)
 		prometheus.MustRegister(CRIOOperationsErrors)
+		prometheus.MustRegister(CRIOImagePullsInProgress)
+		prometheus.MustRegister(CRIOImagePullsBytes)
+		prometheus.MustRegister(CRIOImagePullsLayers)
+		prometheus.MustRegister(CRIOContainersQuarantined)
+		prometheus.MustRegister(CRIOContainersOOMKills)
+		prometheus.MustRegister(CRIOContainersSeccompBlockedSyscalls)
+		prometheus.MustRegister(CRIOOperationsLatencySeconds)
+		prometheus.MustRegister(CRIOContainers)
+		prometheus.MustRegister(CRIOSandboxes)
+		prometheus.MustRegister(CRIOContainersCreateLatency)
+		prometheus.MustRegister(CRIOImages)
+		prometheus.MustRegister(CRIOImagePullsBytesTotal)
+		prometheus.MustRegister(CRIOImagePullsDuration)
+		prometheus.MustRegister(CRIOImagePullsFailures)
+		prometheus.MustRegister(CRIOImagePullsSignatureFailures)
+		prometheus.MustRegister(CRIOImagePullsTimeouts)
+		prometheus.MustRegister(CRIOStorageUsedBytes)
+		prometheus.MustRegister(CRIOStorageInodesUsed)
+		prometheus.MustRegister(CRIOConmonProcesses)
+		prometheus.MustRegister(CRIOConmonRSSBytes)
+		prometheus.MustRegister(CRIOExitMonitorLag)
+		prometheus.MustRegister(CRIOCNILatency)
+		prometheus.MustRegister(CRIOCNIErrors)
 	})
 }
 
@@ -68,3 +388,42 @@ func Register() {
 func SinceInMicroseconds(start time.Time) float64 {
 	return float64(time.Since(start).Nanoseconds() / time.Microsecond.Nanoseconds())
 }
+
+// LabelLimiter bounds the number of distinct values of a metric label, which
+// keeps the cardinality of the metric bounded.
+type LabelLimiter struct {
+	lock   sync.Mutex
+	max    int
+	values map[string]struct{}
+}
+
+// NewLabelLimiter creates a new LabelLimiter allowing `max` distinct values.
+func NewLabelLimiter(max int) *LabelLimiter {
+	return &LabelLimiter{
+		max:    max,
+		values: make(map[string]struct{}),
+	}
+}
+
+// Value returns the `value` if it is already known or if the limit has not
+// been reached yet, otherwise OtherLabelValue.
+func (l *LabelLimiter) Value(value string) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if _, ok := l.values[value]; ok {
+		return value
+	}
+	if len(l.values) >= l.max {
+		return OtherLabelValue
+	}
+	l.values[value] = struct{}{}
+	return value
+}
+
+// Release frees the `value` for a new one, which should be done after
+// deleting the metrics using it.
+func (l *LabelLimiter) Release(value string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.values, value)
+}
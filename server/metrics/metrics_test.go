@@ -39,6 +39,36 @@ var _ = t.Describe("Metrics", func() {
 		})
 	})
 
+	t.Describe("LabelLimiter", func() {
+		It("should limit the label values", func() {
+			// Given
+			sut := metrics.NewLabelLimiter(2)
+
+			// When
+			first := sut.Value("first")
+			second := sut.Value("second")
+			third := sut.Value("third")
+
+			// Then
+			Expect(first).To(Equal("first"))
+			Expect(second).To(Equal("second"))
+			Expect(third).To(Equal(metrics.OtherLabelValue))
+			Expect(sut.Value("first")).To(Equal("first"))
+		})
+
+		It("should succeed to release a label value", func() {
+			// Given
+			sut := metrics.NewLabelLimiter(1)
+			Expect(sut.Value("first")).To(Equal("first"))
+
+			// When
+			sut.Release("first")
+
+			// Then
+			Expect(sut.Value("second")).To(Equal("second"))
+		})
+	})
+
 	t.Describe("SinceInMicroseconds", func() {
 		It("should succeed", func() {
 			// Given
@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/server/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// metricsUpdateInterval is the interval in which the metrics get
+	// updated which are too expensive to be updated on every change, like
+	// the storage usage
+	metricsUpdateInterval = 30 * time.Second
+
+	// sandboxReady and sandboxNotReady are the sandbox states of the metrics
+	sandboxReady    = "ready"
+	sandboxNotReady = "notready"
+
+	// containerStateUnknown is the metrics state of containers in any
+	// unexpected state
+	containerStateUnknown = "unknown"
+
+	procPath = "/proc"
+)
+
+// StartMetricsUpdater updates the container, sandbox, image, storage and
+// conmon metrics periodically until the monitors are stopped.
+func (s *Server) StartMetricsUpdater() {
+	ticker := time.NewTicker(metricsUpdateInterval)
+	defer ticker.Stop()
+	for {
+		s.updateMetrics()
+		select {
+		case <-ticker.C:
+		case <-s.monitorsChan:
+			logrus.Debug("closing metrics updater...")
+			return
+		}
+	}
+}
+
+func (s *Server) updateMetrics() {
+	// The IDs of all containers and sandboxes, for finding their conmon
+	// processes
+	ids := make(map[string]bool)
+
+	containers := map[string]float64{
+		oci.ContainerStateCreated: 0,
+		oci.ContainerStateRunning: 0,
+		oci.ContainerStateStopped: 0,
+		oci.ContainerStatePaused:  0,
+		containerStateUnknown:     0,
+	}
+	ctrs, err := s.ContainerServer.ListContainers()
+	if err != nil {
+		logrus.Warnf("unable to list containers for metrics: %v", err)
+	}
+	for _, c := range ctrs {
+		ids[c.ID()] = true
+		state := c.State().Status
+		if _, ok := containers[state]; !ok {
+			state = containerStateUnknown
+		}
+		containers[state]++
+	}
+	for state, count := range containers {
+		metrics.CRIOContainers.WithLabelValues(state).Set(count)
+	}
+
+	sandboxes := map[string]float64{sandboxReady: 0, sandboxNotReady: 0}
+	for _, sb := range s.ListSandboxes() {
+		ids[sb.ID()] = true
+		infra := sb.InfraContainer()
+		if infra != nil && infra.State().Status == oci.ContainerStateRunning {
+			sandboxes[sandboxReady]++
+		} else {
+			sandboxes[sandboxNotReady]++
+		}
+	}
+	for state, count := range sandboxes {
+		metrics.CRIOSandboxes.WithLabelValues(state).Set(count)
+	}
+
+	if images, err := s.StorageImageServer().ListImages(s.systemContext, ""); err != nil {
+		logrus.Warnf("unable to list images for metrics: %v", err)
+	} else {
+		metrics.CRIOImages.Set(float64(len(images)))
+	}
+
+	if usage, err := getStorageFsInfo(s.StorageImageServer().GetStore()); err != nil {
+		logrus.Warnf("unable to get storage usage for metrics: %v", err)
+	} else {
+		metrics.CRIOStorageUsedBytes.Set(float64(usage.UsedBytes.Value))
+		metrics.CRIOStorageInodesUsed.Set(float64(usage.InodesUsed.Value))
+	}
+
+	if count, rss, err := conmonUsage(procPath, ids); err != nil {
+		logrus.Warnf("unable to get conmon usage for metrics: %v", err)
+	} else {
+		metrics.CRIOConmonProcesses.Set(float64(count))
+		metrics.CRIOConmonRSSBytes.Set(float64(rss))
+	}
+}
+
+// conmonUsage returns the number of conmon processes found in `proc` which
+// monitor one of the containers `ids`, and their summed resident memory in
+// bytes.
+func conmonUsage(proc string, ids map[string]bool) (count int, rss uint64, err error) {
+	entries, err := ioutil.ReadDir(proc)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil || !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(proc, entry.Name())
+		// Processes can exit at any time, so errors are ignored
+		comm, err := ioutil.ReadFile(filepath.Join(dir, "comm"))
+		if err != nil || strings.TrimSpace(string(comm)) != "conmon" {
+			continue
+		}
+		cmdline, err := ioutil.ReadFile(filepath.Join(dir, "cmdline"))
+		if err != nil || !ids[conmonContainerID(cmdline)] {
+			continue
+		}
+		count++
+		if processRSS, err := readRSS(filepath.Join(dir, "status")); err == nil {
+			rss += processRSS
+		}
+	}
+	return count, rss, nil
+}
+
+// conmonContainerID returns the container ID passed to conmon in its
+// NUL separated `cmdline`.
+func conmonContainerID(cmdline []byte) string {
+	args := bytes.Split(cmdline, []byte{0})
+	for i := 0; i < len(args)-1; i++ {
+		if string(args[i]) == "-c" || string(args[i]) == "--cid" {
+			return string(args[i+1])
+		}
+	}
+	return ""
+}
+
+// readRSS reads the resident memory in bytes from the process status file
+// `path`.
+func readRSS(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// The line has the format "VmRSS:      1234 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 3 && fields[0] == "VmRSS:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			return kb * 1024, nil
+		}
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeProcess(t *testing.T, proc, pid, comm, cmdline, status string) {
+	dir := filepath.Join(proc, pid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"comm":    comm,
+		"cmdline": cmdline,
+		"status":  status,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestConmonUsage(t *testing.T) {
+	proc, err := ioutil.TempDir("", "proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(proc)
+
+	writeProcess(t, proc, "10", "conmon\n", "conmon\x00-s\x00-c\x00ctr\x00-n\x00name\x00",
+		"Name:\tconmon\nVmRSS:\t     100 kB\n")
+	writeProcess(t, proc, "11", "conmon\n", "conmon\x00-c\x00sandbox\x00",
+		"Name:\tconmon\nVmRSS:\t      28 kB\n")
+	writeProcess(t, proc, "12", "conmon\n", "conmon\x00-c\x00unknown\x00",
+		"Name:\tconmon\nVmRSS:\t    1000 kB\n")
+	writeProcess(t, proc, "13", "sleep\n", "sleep\x00-c\x00ctr\x00",
+		"Name:\tsleep\nVmRSS:\t    1000 kB\n")
+	if err := os.MkdirAll(filepath.Join(proc, "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	count, rss, err := conmonUsage(proc, map[string]bool{"ctr": true, "sandbox": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 conmon processes, got %d", count)
+	}
+	if rss != 128*1024 {
+		t.Fatalf("expected %d bytes RSS, got %d", 128*1024, rss)
+	}
+}
+
+func TestImageRegistry(t *testing.T) {
+	for image, registry := range map[string]string{
+		"docker.io/library/busybox:latest": "docker.io",
+		"quay.io/crio/redis:alpine":        "quay.io",
+		"localhost:5000/image":             "localhost:5000",
+		"busybox":                          "docker.io",
+		"Invalid":                          "unknown",
+	} {
+		if result := imageRegistry(image); result != registry {
+			t.Fatalf("expected registry %s of %s, got %s", registry, image, result)
+		}
+	}
+}
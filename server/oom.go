@@ -0,0 +1,74 @@
+package server
+
+import (
+	"time"
+
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/oom"
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/cri-o/cri-o/server/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// watchOOMKills starts watching the memory cgroup of the started container
+// `c` for OOM kills.
+func (s *Server) watchOOMKills(c *oci.Container) {
+	if c == nil {
+		return
+	}
+	state := c.State()
+	if state.Status == oci.ContainerStateStopped || state.Pid <= 0 {
+		return
+	}
+	dir, err := oom.CgroupDir(state.Pid)
+	if err != nil {
+		logrus.Warnf("unable to find memory cgroup of container %s: %v", c.ID(), err)
+		return
+	}
+	if err := s.oomWatcher.Add(c.ID(), dir, state.OOMKillCount); err != nil {
+		logrus.Warnf("unable to watch container %s for OOM kills: %v", c.ID(), err)
+	}
+}
+
+// handleOOMKills records the `kills` new OOM kills of the container or infra
+// container `id`, where the last one happened at the time `at`.
+func (s *Server) handleOOMKills(id string, kills uint64, at time.Time) {
+	c := s.GetContainer(id)
+	if c == nil {
+		c = s.getInfraContainer(id)
+	}
+	if c == nil {
+		logrus.Debugf("got OOM kills of unknown container %s", id)
+		return
+	}
+
+	c.AddOOMKills(kills, at)
+	if err := s.ContainerStateToDisk(c); err != nil {
+		logrus.Warnf("unable to write containers %s state to disk: %v", c.ID(), err)
+	}
+	if sb := s.getSandbox(c.Sandbox()); sb != nil {
+		metrics.CRIOContainersOOMKills.WithLabelValues(podMetricLabels(sb)...).
+			Add(float64(kills))
+	}
+
+	event := newContainerEvent(types.EventContainerOOMKilled, c)
+	event.Timestamp = at.UnixNano()
+	event.OOMKilled = true
+	s.events.Publish(event)
+}
+
+// podMetricLabels returns the namespace and name labels of the per-pod
+// metrics for the sandbox `sb`, which are limited by metrics.PodLabels.
+func podMetricLabels(sb *sandbox.Sandbox) []string {
+	if metrics.PodLabels.Value(sb.Namespace()+"/"+sb.KubeName()) == metrics.OtherLabelValue {
+		return []string{metrics.OtherLabelValue, metrics.OtherLabelValue}
+	}
+	return []string{sb.Namespace(), sb.KubeName()}
+}
+
+// deletePodMetrics deletes the per-pod metrics of the removed sandbox `sb`.
+func deletePodMetrics(sb *sandbox.Sandbox) {
+	metrics.CRIOContainersOOMKills.DeleteLabelValues(sb.Namespace(), sb.KubeName())
+	metrics.PodLabels.Release(sb.Namespace() + "/" + sb.KubeName())
+}
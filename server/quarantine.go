@@ -0,0 +1,75 @@
+package server
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cri-o/cri-o/internal/pkg/storage"
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/cri-o/cri-o/server/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// quarantinePrefix is prepended to the storage names of quarantined
+// containers, which frees the original names for new containers.
+const quarantinePrefix = "quarantined-"
+
+// quarantine keeps track of the containers and sandboxes which could not be
+// restored on startup. Their storage is kept for inspection instead of being
+// deleted.
+type quarantine struct {
+	lock  sync.Mutex
+	infos []types.QuarantineInfo
+}
+
+// add records the quarantined container `info`.
+func (q *quarantine) add(info types.QuarantineInfo) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.infos = append(q.infos, info)
+	metrics.CRIOContainersQuarantined.Set(float64(len(q.infos)))
+}
+
+// list returns all quarantined containers, ordered by their ID.
+func (q *quarantine) list() []types.QuarantineInfo {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	infos := make([]types.QuarantineInfo, len(q.infos))
+	copy(infos, q.infos)
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// quarantineContainer quarantines the container or sandbox `id`, which could
+// not be restored because of `reason`. Its storage names are prefixed and its
+// names get released, so that they can be used by new containers.
+func (s *Server) quarantineContainer(id string, metadata *storage.RuntimeContainerMetadata, names []string, reason error) {
+	logrus.Warnf("quarantining container %s: %v", id, reason)
+	if len(names) > 0 {
+		quarantinedNames := make([]string, 0, len(names))
+		for _, n := range names {
+			quarantinedNames = append(quarantinedNames, quarantinePrefix+n)
+		}
+		if err := s.Store().SetNames(id, quarantinedNames); err != nil {
+			logrus.Warnf("unable to rename quarantined container %s: %v", id, err)
+		}
+	}
+	for _, n := range names {
+		// Release the infra container name and the pod name for future use
+		if metadata.Pod && !strings.Contains(n, infraName) {
+			s.ReleasePodName(n)
+		} else {
+			s.ReleaseContainerName(n)
+		}
+	}
+	s.quarantine.add(types.QuarantineInfo{
+		ID:      id,
+		Names:   names,
+		Sandbox: metadata.PodID,
+		Pod:     metadata.Pod,
+		Reason:  reason.Error(),
+	})
+}
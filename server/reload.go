@@ -0,0 +1,136 @@
+package server
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/containers/image/pkg/sysregistriesv2"
+	"github.com/containers/libpod/pkg/apparmor"
+	libconfig "github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/utils"
+	"github.com/pkg/errors"
+	seccomp "github.com/seccomp/containers-golang"
+	"github.com/sirupsen/logrus"
+)
+
+// ReloadConfig reloads the configuration at the provided `fileName` and
+// applies all reloadable options to the server. The options take effect for
+// newly created sandboxes and containers. No option gets applied if any of
+// them is invalid or cannot be prepared.
+func (s *Server) ReloadConfig(fileName string) error {
+	newConfig, err := s.config.ReadReloadConfig(fileName)
+	if err != nil {
+		return err
+	}
+	if err := s.config.ValidateReload(newConfig); err != nil {
+		return err
+	}
+	if err := s.validateRuntimesInUse(newConfig); err != nil {
+		return err
+	}
+	registriesChanged := !utils.StringSlicesEqual(s.config.Registries, newConfig.Registries) ||
+		!utils.StringSlicesEqual(s.config.InsecureRegistries, newConfig.InsecureRegistries)
+	if registriesChanged && len(newConfig.Registries) == 0 {
+		// The system registries are used instead
+		if _, err := sysregistriesv2.UnqualifiedSearchRegistries(s.systemContext); err != nil {
+			return errors.Wrap(err, "invalid registries")
+		}
+	}
+	seccompProfile, err := s.prepareSeccompProfile(newConfig)
+	if err != nil {
+		return err
+	}
+	if err := s.prepareAppArmorProfile(newConfig); err != nil {
+		return err
+	}
+
+	// Block the creation of new sandboxes and containers as well as image
+	// pulls until the new configuration is applied
+	s.updateLock.Lock()
+	defer s.updateLock.Unlock()
+
+	pinnedImagesChanged := !utils.StringSlicesEqual(s.config.AllPinnedImages(), newConfig.AllPinnedImages())
+	mirrorsChanged := !reflect.DeepEqual(s.config.RegistryMirrors, newConfig.RegistryMirrors) ||
+		!reflect.DeepEqual(s.config.ShortNameAliases, newConfig.ShortNameAliases)
+
+	if err := s.config.ApplyReload(newConfig); err != nil {
+		return err
+	}
+
+	if registriesChanged {
+		if err := s.StorageImageServer().UpdateRegistries(
+			s.systemContext, s.config.InsecureRegistries, s.config.Registries,
+		); err != nil {
+			return err
+		}
+	}
+	if pinnedImagesChanged {
+		s.StorageImageServer().UpdatePinnedImages(s.config.AllPinnedImages())
+	}
+	if mirrorsChanged {
+		s.StorageImageServer().UpdateRegistryMirrors(
+			s.config.RegistryMirrors, s.config.ShortNameAliases)
+	}
+
+	// The OCI runtime uses the configuration of the container server
+	s.ContainerServer.UpdateRuntimes(s.config.Runtimes)
+	s.systemContext.SignaturePolicyPath = s.config.SignaturePolicyPath
+	if seccompProfile != nil {
+		s.seccompProfile = seccompProfile
+	}
+	s.appArmorProfile = s.config.ApparmorProfile
+
+	return nil
+}
+
+// validateRuntimesInUse errors if a runtime handler which is still used by a
+// sandbox would be removed by the `newConfig`.
+func (s *Server) validateRuntimesInUse(newConfig *libconfig.Config) error {
+	for _, sb := range s.ContainerServer.ListSandboxes() {
+		handler := sb.RuntimeHandler()
+		if handler == "" {
+			continue
+		}
+		if _, ok := newConfig.Runtimes[handler]; !ok {
+			return fmt.Errorf(
+				"runtime handler %q cannot be removed because it is used by sandbox %s",
+				handler, sb.ID(),
+			)
+		}
+	}
+	return nil
+}
+
+// prepareSeccompProfile loads the seccomp profile of the `newConfig` if it
+// changed. It returns nil if the current profile should be kept.
+func (s *Server) prepareSeccompProfile(newConfig *libconfig.Config) (*seccomp.Seccomp, error) {
+	if !s.seccompEnabled || s.config.SeccompProfile == newConfig.SeccompProfile {
+		return nil, nil
+	}
+	return loadSeccompProfile(newConfig.SeccompProfile)
+}
+
+// prepareAppArmorProfile ensures that the apparmor profile of the `newConfig`
+// is usable if it changed. The default profile gets installed if required,
+// whereas user-provided profiles have to be loaded already.
+func (s *Server) prepareAppArmorProfile(newConfig *libconfig.Config) error {
+	if !s.appArmorEnabled || s.config.ApparmorProfile == newConfig.ApparmorProfile {
+		return nil
+	}
+	if newConfig.ApparmorProfile == libconfig.DefaultApparmorProfile {
+		logrus.Infof("installing default apparmor profile: %v", libconfig.DefaultApparmorProfile)
+		if err := apparmor.InstallDefault(libconfig.DefaultApparmorProfile); err != nil {
+			return fmt.Errorf("ensuring the default apparmor profile %q is installed failed: %v",
+				libconfig.DefaultApparmorProfile, err)
+		}
+		return nil
+	}
+	isLoaded, err := apparmor.IsLoaded(newConfig.ApparmorProfile)
+	if err != nil {
+		return err
+	}
+	if !isLoaded {
+		return fmt.Errorf("apparmor profile %q is not loaded", newConfig.ApparmorProfile)
+	}
+	return nil
+}
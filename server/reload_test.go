@@ -0,0 +1,187 @@
+package server_test
+
+import (
+	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/golang/mock/gomock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+	"k8s.io/kubernetes/pkg/kubelet/dockershim/network/hostport"
+)
+
+// The actual test suite
+var _ = t.Describe("ReloadConfig", func() {
+	// Prepare the sut
+	BeforeEach(func() {
+		beforeEach()
+		setupSUT()
+	})
+
+	AfterEach(afterEach)
+
+	var writeConfig = func(modify func(*config.Config)) string {
+		newConfig, err := config.DefaultConfig()
+		Expect(err).To(BeNil())
+		modify(newConfig)
+		filePath := t.MustTempFile("config")
+		Expect(newConfig.ToFile(filePath)).To(BeNil())
+		return filePath
+	}
+
+	It("should succeed without any config change", func() {
+		// Given
+		filePath := writeConfig(func(*config.Config) {})
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).To(BeNil())
+	})
+
+	It("should succeed to add a runtime handler", func() {
+		// Given
+		filePath := writeConfig(func(c *config.Config) {
+			c.Runtimes["new"] = &config.RuntimeHandler{
+				RuntimePath: "/bin/sh",
+			}
+		})
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).To(BeNil())
+		Expect(serverConfig.Runtimes).To(HaveKey("new"))
+	})
+
+	It("should succeed to update the registries", func() {
+		// Given
+		registries := []string{"quay.io"}
+		filePath := writeConfig(func(c *config.Config) {
+			c.Registries = registries
+		})
+		gomock.InOrder(
+			imageServerMock.EXPECT().UpdateRegistries(
+				gomock.Any(), gomock.Any(), registries).Return(nil),
+		)
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).To(BeNil())
+	})
+
+	It("should succeed to update the pinned images", func() {
+		// Given
+		filePath := writeConfig(func(c *config.Config) {
+			c.PinnedImages = []string{"quay.io/crio/*"}
+		})
+		gomock.InOrder(
+			imageServerMock.EXPECT().UpdatePinnedImages(
+				[]string{"quay.io/crio/*", serverConfig.PauseImage}),
+		)
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).To(BeNil())
+	})
+
+	It("should succeed to update the registry mirrors", func() {
+		// Given
+		mirrors := map[string][]string{"docker.io": {"mirror.example.com"}}
+		filePath := writeConfig(func(c *config.Config) {
+			c.RegistryMirrors = mirrors
+		})
+		gomock.InOrder(
+			imageServerMock.EXPECT().UpdateRegistryMirrors(
+				mirrors, gomock.Any()),
+		)
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).To(BeNil())
+	})
+
+	It("should fail if the registries update fails", func() {
+		// Given
+		filePath := writeConfig(func(c *config.Config) {
+			c.Registries = []string{"quay.io"}
+			c.PidsLimit = 2048
+		})
+		gomock.InOrder(
+			imageServerMock.EXPECT().UpdateRegistries(
+				gomock.Any(), gomock.Any(), gomock.Any()).Return(t.TestError),
+		)
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should not update the registries if any option is invalid", func() {
+		// Given
+		filePath := writeConfig(func(c *config.Config) {
+			c.Registries = []string{"quay.io"}
+			c.PinnedImages = []string{"quay.io/crio/*"}
+			c.DefaultCapabilities = []string{"CAP_INVALID"}
+		})
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should fail to remove a runtime handler in use", func() {
+		// Given
+		serverConfig.Runtimes["used"] = &config.RuntimeHandler{
+			RuntimePath: "/bin/sh",
+		}
+		sb, err := sandbox.New(sandboxID, "", "", "", "",
+			make(map[string]string), make(map[string]string), "", "",
+			&pb.PodSandboxMetadata{}, "", "", false, "used", "", "",
+			[]*hostport.PortMapping{}, false)
+		Expect(err).To(BeNil())
+		Expect(sut.AddSandbox(sb)).To(BeNil())
+		filePath := writeConfig(func(*config.Config) {})
+
+		// When
+		err = sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+		Expect(serverConfig.Runtimes).To(HaveKey("used"))
+	})
+
+	It("should fail with invalid config option", func() {
+		// Given
+		filePath := writeConfig(func(c *config.Config) {
+			c.DefaultCapabilities = []string{"CAP_INVALID"}
+		})
+
+		// When
+		err := sut.ReloadConfig(filePath)
+
+		// Then
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("should fail with invalid config path", func() {
+		// Given
+		// When
+		err := sut.ReloadConfig("")
+
+		// Then
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -3,20 +3,46 @@ package server
 import (
 	"fmt"
 	"net"
-	"strings"
+	"time"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 	cnicurrent "github.com/containernetworking/cni/pkg/types/current"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/cri-o/cri-o/server/metrics"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
 	"k8s.io/kubernetes/pkg/kubelet/dockershim/network/hostport"
 )
 
-// networkStart sets up the sandbox's network and returns the pod IP on success
+// The CNI operations recorded in the metrics
+const (
+	cniSetUp    = "setup"
+	cniTearDown = "teardown"
+)
+
+// recordCNIOperation records the latency and the error of the CNI
+// `operation`, which started at `start`.
+func recordCNIOperation(operation string, start time.Time, err error) {
+	metrics.CRIOCNILatency.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		metrics.CRIOCNIErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// networkStart sets up the sandbox's network and returns the pod IPs on success
 // or an error
-func (s *Server) networkStart(sb *sandbox.Sandbox) (podIP string, result cnitypes.Result, err error) {
+func (s *Server) networkStart(ctx context.Context, sb *sandbox.Sandbox) (podIPs []string, result cnitypes.Result, err error) {
+	_, span := tracing.StartSpan(ctx, "cni.networkStart")
+	span.SetAttribute("sandbox.id", sb.ID())
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
 	if sb.HostNetwork() {
-		return s.hostIP, nil, nil
+		return []string{s.hostIP}, nil, nil
 	}
 
 	podNetwork, err := newPodNetwork(sb)
@@ -28,11 +54,13 @@ func (s *Server) networkStart(sb *sandbox.Sandbox) (podIP string, result cnitype
 	// but an error happened between plugin success and the end of networkStart()
 	defer func() {
 		if err != nil {
-			s.networkStop(sb)
+			s.networkStop(sb, podIPs)
 		}
 	}()
 
+	startSetUp := time.Now()
 	_, err = s.netPlugin.SetUpPod(podNetwork)
+	recordCNIOperation(cniSetUp, startSetUp, err)
 	if err != nil {
 		err = fmt.Errorf("failed to create pod network sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
 		return
@@ -54,56 +82,103 @@ func (s *Server) networkStart(sb *sandbox.Sandbox) (podIP string, result cnitype
 		return
 	}
 
-	podIP = strings.Split(network.IPs[0].Address.String(), "/")[0]
+	podIPs = resultIPs(network)
 
 	if len(sb.PortMappings()) > 0 {
-		ip := net.ParseIP(podIP)
-		if ip == nil {
-			err = fmt.Errorf("failed to get valid ip address for sandbox %s(%s)", sb.Name(), sb.ID())
+		ips, ipErr := hostportIPs(podIPs)
+		if ipErr != nil {
+			err = fmt.Errorf("failed to get valid ip address for sandbox %s(%s): %v", sb.Name(), sb.ID(), ipErr)
 			return
 		}
-
-		err = s.hostportManager.Add(sb.ID(), &hostport.PodPortMapping{
-			Name:         sb.Name(),
-			PortMappings: sb.PortMappings(),
-			IP:           ip,
-			HostNetwork:  false,
-		}, "lo")
-		if err != nil {
-			err = fmt.Errorf("failed to add hostport mapping for sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
-			return
+		for _, ip := range ips {
+			err = s.hostportManagerFor(ip).Add(sb.ID(), &hostport.PodPortMapping{
+				Name:         sb.Name(),
+				PortMappings: sb.PortMappings(),
+				IP:           ip,
+				HostNetwork:  false,
+			}, "lo")
+			if err != nil {
+				err = fmt.Errorf("failed to add hostport mapping for sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
+				return
+			}
 		}
-
 	}
-	return podIP, result, err
+	s.publishSandboxEvent(types.EventSandboxNetworkSetUp, sb)
+	return podIPs, result, err
 }
 
-// getSandboxIP retrieves the IP address for the sandbox
-func (s *Server) getSandboxIP(sb *sandbox.Sandbox) (string, error) {
+// getSandboxIPs retrieves the IP addresses for the sandbox
+func (s *Server) getSandboxIPs(sb *sandbox.Sandbox) ([]string, error) {
 	if sb.HostNetwork() {
-		return s.hostIP, nil
+		return []string{s.hostIP}, nil
 	}
 
 	podNetwork, err := newPodNetwork(sb)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	result, err := s.netPlugin.GetPodNetworkStatus(podNetwork)
 	if err != nil {
-		return "", fmt.Errorf("failed to get network status for pod sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
+		return nil, fmt.Errorf("failed to get network status for pod sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
 	}
 
 	res, err := cnicurrent.GetResult(result[0])
 	if err != nil {
-		return "", fmt.Errorf("failed to get network JSON for pod sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
+		return nil, fmt.Errorf("failed to get network JSON for pod sandbox %s(%s): %v", sb.Name(), sb.ID(), err)
+	}
+
+	return resultIPs(res), nil
+}
+
+// resultIPs returns the addresses of all IPs of the CNI result without their
+// prefix length
+func resultIPs(result *cnicurrent.Result) []string {
+	ips := make([]string, 0, len(result.IPs))
+	for _, ip := range result.IPs {
+		ips = append(ips, ip.Address.IP.String())
 	}
+	return ips
+}
 
-	return strings.Split(res.IPs[0].Address.String(), "/")[0], nil
+// hostportIPs returns the first IP of each address family of the `podIPs`,
+// which are the ones the host ports of a sandbox get mapped to
+func hostportIPs(podIPs []string) ([]net.IP, error) {
+	var ipv4, ipv6 net.IP
+	for _, podIP := range podIPs {
+		ip := net.ParseIP(podIP)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid ip address %q", podIP)
+		}
+		if ip.To4() == nil {
+			if ipv6 == nil {
+				ipv6 = ip
+			}
+		} else if ipv4 == nil {
+			ipv4 = ip
+		}
+	}
+	ips := []net.IP{}
+	for _, ip := range []net.IP{ipv4, ipv6} {
+		if ip != nil {
+			ips = append(ips, ip)
+		}
+	}
+	return ips, nil
+}
+
+// hostportManagerFor returns the hostport manager for the address family of
+// the `ip`
+func (s *Server) hostportManagerFor(ip net.IP) hostport.HostPortManager {
+	if ip.To4() == nil {
+		return s.hostportManagerIPv6
+	}
+	return s.hostportManager
 }
 
-// networkStop cleans up and removes a pod's network.  It is best-effort and
-// must call the network plugin even if the network namespace is already gone
-func (s *Server) networkStop(sb *sandbox.Sandbox) {
+// networkStop cleans up and removes a pod's network, whose host ports are
+// mapped to the `podIPs`.  It is best-effort and must call the network plugin
+// even if the network namespace is already gone
+func (s *Server) networkStop(sb *sandbox.Sandbox, podIPs []string) {
 	if sb.HostNetwork() {
 		return
 	}
@@ -116,14 +191,32 @@ func (s *Server) networkStop(sb *sandbox.Sandbox) {
 		logrus.Warnf("failed to remove hostport for pod sandbox %s(%s): %v",
 			sb.Name(), sb.ID(), err)
 	}
+	for _, podIP := range podIPs {
+		if ip := net.ParseIP(podIP); ip != nil && ip.To4() == nil {
+			if err := s.hostportManagerIPv6.Remove(sb.ID(), &hostport.PodPortMapping{
+				Name:         sb.Name(),
+				PortMappings: sb.PortMappings(),
+				HostNetwork:  false,
+			}); err != nil {
+				logrus.Warnf("failed to remove IPv6 hostport for pod sandbox %s(%s): %v",
+					sb.Name(), sb.ID(), err)
+			}
+			break
+		}
+	}
 
 	podNetwork, err := newPodNetwork(sb)
 	if err != nil {
 		logrus.Warnf(err.Error())
 		return
 	}
-	if err := s.netPlugin.TearDownPod(podNetwork); err != nil {
+	startTearDown := time.Now()
+	err = s.netPlugin.TearDownPod(podNetwork)
+	recordCNIOperation(cniTearDown, startTearDown, err)
+	if err != nil {
 		logrus.Warnf("failed to destroy network for pod sandbox %s(%s): %v",
 			sb.Name(), sb.ID(), err)
+		return
 	}
+	s.publishSandboxEvent(types.EventSandboxNetworkTornDown, sb)
 }
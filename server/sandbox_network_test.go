@@ -0,0 +1,27 @@
+package server
+
+import (
+	"testing"
+)
+
+func TestHostportIPs(t *testing.T) {
+	ips, err := hostportIPs([]string{"10.0.0.1", "fd00::1", "10.0.0.2", "fd00::2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 2 || ips[0].String() != "10.0.0.1" || ips[1].String() != "fd00::1" {
+		t.Fatalf("expected the first IP of each family, got %v", ips)
+	}
+
+	ips, err = hostportIPs([]string{"fd00::1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ips) != 1 || ips[0].String() != "fd00::1" {
+		t.Fatalf("expected only the IPv6 address, got %v", ips)
+	}
+
+	if _, err := hostportIPs([]string{"invalid"}); err == nil {
+		t.Fatal("expected an error for an invalid address")
+	}
+}
@@ -18,8 +18,9 @@ import (
 // sandbox, they should be force deleted.
 func (s *Server) RemovePodSandbox(ctx context.Context, req *pb.RemovePodSandboxRequest) (resp *pb.RemovePodSandboxResponse, err error) {
 	const operation = "remove_pod_sandbox"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
@@ -88,8 +89,10 @@ func (s *Server) RemovePodSandbox(ctx context.Context, req *pb.RemovePodSandboxR
 	podInfraContainer.CleanupConmonCgroup()
 
 	// Remove the files related to the sandbox
-	if err := s.StorageRuntimeServer().StopContainer(sb.ID()); err != nil && errors.Cause(err) != storage.ErrContainerUnknown {
-		logrus.Warnf("failed to stop sandbox container in pod sandbox %s: %v", sb.ID(), err)
+	if !podInfraContainer.Spoofed() {
+		if err := s.StorageRuntimeServer().StopContainer(sb.ID()); err != nil && errors.Cause(err) != storage.ErrContainerUnknown {
+			logrus.Warnf("failed to stop sandbox container in pod sandbox %s: %v", sb.ID(), err)
+		}
 	}
 	if err := s.StorageRuntimeServer().RemovePodSandbox(sb.ID()); err != nil && err != pkgstorage.ErrInvalidSandboxID {
 		return nil, fmt.Errorf("failed to remove pod sandbox %s: %v", sb.ID(), err)
@@ -101,6 +104,8 @@ func (s *Server) RemovePodSandbox(ctx context.Context, req *pb.RemovePodSandboxR
 	}
 
 	s.ReleasePodName(sb.Name())
+	s.releaseUsernsRange(sb.ID())
+	deletePodMetrics(sb)
 	if err := s.removeSandbox(sb.ID()); err != nil {
 		logrus.Warnf("failed to remove sandbox: %v", err)
 	}
@@ -5,7 +5,10 @@ import (
 	"os"
 	"path"
 
+	libconfig "github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/oci"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	v1 "k8s.io/api/core/v1"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -70,6 +73,34 @@ func (s *Server) runtimeHandler(req *pb.RunPodSandboxRequest) (string, error) {
 	return handler, nil
 }
 
+// runtimeHandlerConfig returns the configuration of the runtime handler
+// `handler`, where an empty handler refers to the default runtime. It returns
+// nil for unknown handlers. The caller has to hold the updateLock, since the
+// runtime handlers change on configuration reloads.
+func (s *Server) runtimeHandlerConfig(handler string) *libconfig.RuntimeHandler {
+	if handler == "" {
+		handler = s.config.DefaultRuntime
+	}
+	return s.config.Runtimes[handler]
+}
+
+// filterDisallowedAnnotations returns a copy of `annotations` without the
+// experimental annotations which are not allowed for the runtime handler
+// `handler`.
+func (s *Server) filterDisallowedAnnotations(handler string, annotations map[string]string) map[string]string {
+	runtimeHandler := s.runtimeHandlerConfig(handler)
+	filtered := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		if crioannotations.IsExperimental(key) &&
+			(runtimeHandler == nil || !runtimeHandler.AllowsAnnotation(key)) {
+			logrus.Debugf("ignoring annotation %s, which is not allowed for runtime handler %q", key, handler)
+			continue
+		}
+		filtered[key] = value
+	}
+	return filtered
+}
+
 // RunPodSandbox creates and runs a pod-level sandbox.
 func (s *Server) RunPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest) (resp *pb.RunPodSandboxResponse, err error) {
 	// platform dependent call
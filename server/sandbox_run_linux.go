@@ -18,6 +18,9 @@ import (
 	"github.com/containers/storage"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/cgroupv2"
+	"github.com/cri-o/cri-o/internal/pkg/tracing"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
 	"github.com/opencontainers/runc/libcontainer/cgroups/systemd"
 	runtimespec "github.com/opencontainers/runtime-spec/specs-go"
 	spec "github.com/opencontainers/runtime-spec/specs-go"
@@ -36,8 +39,9 @@ const cgroupMemorySubsystemMountPath = "/sys/fs/cgroup/memory"
 
 func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest) (resp *pb.RunPodSandboxResponse, err error) {
 	const operation = "run_pod_sandbox"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
@@ -83,24 +87,52 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 		}
 	}()
 
+	usernsRange, err := s.allocateUsernsRange(id,
+		s.filterDisallowedAnnotations(req.GetRuntimeHandler(), req.GetConfig().GetAnnotations()))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			s.releaseUsernsRange(id)
+		}
+	}()
+	idMappings := s.defaultIDMappings
+	if usernsRange != nil {
+		idMappings = usernsRange.IDMappings()
+	}
+
 	var labelOptions []string
 	securityContext := req.GetConfig().GetLinux().GetSecurityContext()
 	selinuxConfig := securityContext.GetSelinuxOptions()
 	if selinuxConfig != nil {
 		labelOptions = getLabelOptions(selinuxConfig)
 	}
+
+	// The infra container can only be dropped if no process is needed to
+	// keep the shared PID namespace alive
+	dropInfra := s.config.DropInfraCtr &&
+		securityContext.GetNamespaceOptions().GetPid() != pb.NamespaceMode_POD
+	pauseImage, pauseImageAuthFile := s.config.PauseImage, s.config.PauseImageAuthFile
+	if dropInfra {
+		pauseImage, pauseImageAuthFile = "", ""
+	}
+	_, storageSpan := tracing.StartSpan(ctx, "storage.CreatePodSandbox")
+	storageSpan.SetAttribute("sandbox.id", id)
 	podContainer, err := s.StorageRuntimeServer().CreatePodSandbox(s.systemContext,
 		name, id,
-		s.config.PauseImage,
-		s.config.PauseImageAuthFile,
+		pauseImage,
+		pauseImageAuthFile,
 		"",
 		containerName,
 		req.GetConfig().GetMetadata().GetName(),
 		req.GetConfig().GetMetadata().GetUid(),
 		namespace,
 		attempt,
-		s.defaultIDMappings,
+		idMappings,
 		labelOptions)
+	storageSpan.RecordError(err)
+	storageSpan.End()
 	mountLabel = podContainer.MountLabel
 	if !s.privilegedSandbox(req) {
 		processLabel = podContainer.ProcessLabel
@@ -119,6 +151,12 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 		}
 	}()
 
+	if usernsRange != nil {
+		if err := s.persistUsernsRange(id, usernsRange); err != nil {
+			return nil, err
+		}
+	}
+
 	// TODO: factor generating/updating the spec into something other projects can vendor
 
 	// creates a spec Generator with the default spec.
@@ -336,9 +374,14 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 	g.AddAnnotation(annotations.NamespaceOptions, string(nsOptsJSON))
 	g.AddAnnotation(annotations.KubeName, kubeName)
 	g.AddAnnotation(annotations.HostNetwork, fmt.Sprintf("%v", hostNetwork))
-	if podContainer.Config.Config.StopSignal != "" {
+	g.AddAnnotation(crioannotations.SpoofedContainer, fmt.Sprintf("%v", dropInfra))
+	var stopSignal string
+	if podContainer.Config != nil {
+		stopSignal = podContainer.Config.Config.StopSignal
+	}
+	if stopSignal != "" {
 		// this key is defined in image-spec conversion document at https://github.com/opencontainers/image-spec/pull/492/files#diff-8aafbe2c3690162540381b8cdb157112R57
-		g.AddAnnotation("org.opencontainers.image.stopSignal", podContainer.Config.Config.StopSignal)
+		g.AddAnnotation("org.opencontainers.image.stopSignal", stopSignal)
 	}
 
 	created := time.Now()
@@ -351,20 +394,24 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 	}
 	g.AddAnnotation(annotations.PortMappings, string(portMappingsJSON))
 
-	cgroupParent, err := AddCgroupAnnotation(g, cgroupMemorySubsystemMountPath,
+	memoryMountPath := cgroupMemorySubsystemMountPath
+	if cgroupv2.Enabled() {
+		memoryMountPath = cgroupv2.Root
+	}
+	cgroupParent, err := AddCgroupAnnotation(g, memoryMountPath,
 		s.config.CgroupManager, req.GetConfig().GetLinux().GetCgroupParent(), id)
 	if err != nil {
 		return nil, err
 	}
 
-	if s.defaultIDMappings != nil && !s.defaultIDMappings.Empty() {
+	if idMappings != nil && !idMappings.Empty() {
 		if err := g.AddOrReplaceLinuxNamespace(spec.UserNamespace, ""); err != nil {
 			return nil, errors.Wrapf(err, "add or replace linux namespace")
 		}
-		for _, uidmap := range s.defaultIDMappings.UIDs() {
+		for _, uidmap := range idMappings.UIDs() {
 			g.AddLinuxUIDMapping(uint32(uidmap.HostID), uint32(uidmap.ContainerID), uint32(uidmap.Size))
 		}
-		for _, gidmap := range s.defaultIDMappings.GIDs() {
+		for _, gidmap := range idMappings.GIDs() {
 			g.AddLinuxGIDMapping(uint32(gidmap.HostID), uint32(gidmap.ContainerID), uint32(gidmap.Size))
 		}
 	}
@@ -472,14 +519,42 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 		}
 	}
 
+	if dropInfra {
+		// Without infra container process, the namespaces shared by the
+		// containers of the sandbox have to be pinned by us
+		if err := sb.PinNamespaces(hostIPC); err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err != nil {
+				if err2 := sb.UnpinNamespaces(); err2 != nil {
+					logrus.Warnf("failed to unpin namespaces of sandbox %s: %v", id, err2)
+				}
+			}
+		}()
+
+		if !hostIPC {
+			if err := g.AddOrReplaceLinuxNamespace(string(runtimespec.IPCNamespace), sb.IpcNsPath()); err != nil {
+				return nil, err
+			}
+		}
+		if err := g.AddOrReplaceLinuxNamespace(string(runtimespec.UTSNamespace), sb.UtsNsPath()); err != nil {
+			return nil, err
+		}
+	}
+
 	if !s.seccompEnabled {
 		g.Config.Linux.Seccomp = nil
 	}
 
 	saveOptions := generate.ExportOptions{}
-	mountPoint, err := s.StorageRuntimeServer().StartContainer(id)
-	if err != nil {
-		return nil, fmt.Errorf("failed to mount container %s in pod sandbox %s(%s): %v", containerName, sb.Name(), id, err)
+	// The placeholder infra container has no root filesystem to be mounted
+	var mountPoint string
+	if !dropInfra {
+		mountPoint, err = s.StorageRuntimeServer().StartContainer(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to mount container %s in pod sandbox %s(%s): %v", containerName, sb.Name(), id, err)
+		}
 	}
 	g.AddAnnotation(annotations.MountPoint, mountPoint)
 
@@ -500,16 +575,22 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 	g.AddMount(mnt)
 	g.AddAnnotation(annotations.HostnamePath, hostnamePath)
 	sb.AddHostnamePath(hostnamePath)
+	if usernsRange != nil {
+		sb.SetIDMappings(idMappings)
+	}
 
-	container, err := oci.NewContainer(id, containerName, podContainer.RunDir, logPath, sb.NetNs().Path(), labels, g.Config.Annotations, kubeAnnotations, "", "", "", nil, id, false, false, false, sb.Privileged(), sb.RuntimeHandler(), podContainer.Dir, created, podContainer.Config.Config.StopSignal)
+	container, err := oci.NewContainer(id, containerName, podContainer.RunDir, logPath, sb.NetNs().Path(), labels, g.Config.Annotations, kubeAnnotations, "", "", "", nil, id, false, false, false, sb.Privileged(), sb.RuntimeHandler(), podContainer.Dir, created, stopSignal)
 	if err != nil {
 		return nil, err
 	}
 	container.SetMountPoint(mountPoint)
+	if dropInfra {
+		container.SetSpoofed()
+	}
 
-	container.SetIDMappings(s.defaultIDMappings)
+	container.SetIDMappings(idMappings)
 
-	if s.defaultIDMappings != nil && !s.defaultIDMappings.Empty() {
+	if idMappings != nil && !idMappings.Empty() {
 		if securityContext.GetNamespaceOptions().GetIpc() == pb.NamespaceMode_NODE {
 			g.RemoveMount("/dev/mqueue")
 			mqueue := runtimespec.Mount{
@@ -554,11 +635,11 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 		return nil, err
 	}
 
-	var ip string
+	var ips []string
 	var result cnitypes.Result
 
 	if s.config.ManageNetworkNSLifecycle {
-		ip, result, err = s.networkStart(sb)
+		ips, result, err = s.networkStart(ctx, sb)
 		if err != nil {
 			return nil, err
 		}
@@ -567,13 +648,18 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 		}
 		defer func() {
 			if err != nil {
-				s.networkStop(sb)
+				s.networkStop(sb, ips)
 			}
 		}()
 	}
 
-	g.AddAnnotation(annotations.IP, ip)
-	sb.AddIP(ip)
+	sb.AddIPs(ips)
+	g.AddAnnotation(annotations.IP, sb.IP())
+	ipsJSON, err := json.Marshal(ips)
+	if err != nil {
+		return nil, err
+	}
+	g.AddAnnotation(crioannotations.IPs, string(ipsJSON))
 	sb.SetNamespaceOptions(securityContext.GetNamespaceOptions())
 
 	spp := securityContext.GetSeccompProfilePath()
@@ -600,8 +686,8 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 		}
 	}()
 
-	if s.defaultIDMappings != nil && !s.defaultIDMappings.Empty() {
-		rootPair := s.defaultIDMappings.RootPair()
+	if idMappings != nil && !idMappings.Empty() {
+		rootPair := idMappings.RootPair()
 		for _, path := range pathsToChown {
 			if err := os.Chown(path, rootPair.UID, rootPair.GID); err != nil {
 				return nil, errors.Wrapf(err, "cannot chown %s to %d:%d", path, rootPair.UID, rootPair.GID)
@@ -610,7 +696,7 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 
 	}
 
-	if err := s.createContainerPlatform(container, nil, sb.CgroupParent()); err != nil {
+	if err := s.createContainerPlatform(ctx, container, nil, sb.CgroupParent()); err != nil {
 		return nil, err
 	}
 
@@ -642,19 +728,20 @@ func (s *Server) runPodSandbox(ctx context.Context, req *pb.RunPodSandboxRequest
 	}
 
 	if !s.config.ManageNetworkNSLifecycle {
-		ip, _, err = s.networkStart(sb)
+		ips, _, err = s.networkStart(ctx, sb)
 		if err != nil {
 			return nil, err
 		}
 		defer func() {
 			if err != nil {
-				s.networkStop(sb)
+				s.networkStop(sb, ips)
 			}
 		}()
 	}
-	sb.AddIP(ip)
+	sb.AddIPs(ips)
 
 	sb.SetCreated()
+	s.watchOOMKills(container)
 
 	logrus.Infof("Ran pod sandbox with infra container: %s", container.Description())
 	resp = &pb.RunPodSandboxResponse{PodSandboxId: id}
@@ -691,18 +778,24 @@ func AddCgroupAnnotation(g generate.Generator, mountPath, cgroupManager, cgroupP
 			if err != nil {
 				return "", errors.Wrapf(err, "error expanding systemd slice path for %q", cgroupParent)
 			}
-			// read in the memory limit from the memory.limit_in_bytes file
-			fileData, err := ioutil.ReadFile(filepath.Join(mountPath, slicePath, "memory.limit_in_bytes"))
+			// read in the memory limit from the memory.limit_in_bytes file, or
+			// from the memory.max file on the cgroup v2 unified hierarchy
+			limitFile := "memory.limit_in_bytes"
+			fileData, err := ioutil.ReadFile(filepath.Join(mountPath, slicePath, limitFile))
+			if os.IsNotExist(err) {
+				limitFile = "memory.max"
+				fileData, err = ioutil.ReadFile(filepath.Join(mountPath, slicePath, limitFile))
+			}
 			if err != nil {
 				if os.IsNotExist(err) {
 					logrus.Warnf("Failed to find memory.limit_in_bytes for slice: %q", cgroupParent)
 				} else {
-					return "", errors.Wrapf(err, "error reading memory.limit_in_bytes file for slice %q", cgroupParent)
+					return "", errors.Wrapf(err, "error reading %s file for slice %q", limitFile, cgroupParent)
 				}
 			} else {
 				// strip off the newline character and convert it to an int
 				strMemory := strings.TrimRight(string(fileData), "\n")
-				if strMemory != "" {
+				if strMemory != "" && strMemory != "max" {
 					memoryLimit, err := strconv.Atoi(strMemory)
 					if err != nil {
 						return "", errors.Wrapf(err, "error converting cgroup memory value from string to int %q", strMemory)
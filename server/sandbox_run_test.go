@@ -9,6 +9,7 @@ import (
 	"github.com/containers/libpod/pkg/annotations"
 	"github.com/cri-o/cri-o/internal/oci"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
 	"github.com/cri-o/cri-o/server"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -137,6 +138,102 @@ var _ = t.Describe("RunPodSandbox", func() {
 		})
 	})
 
+	t.Describe("RunPodSandbox with user namespace", func() {
+		var config = func(mode string) *pb.PodSandboxConfig {
+			return &pb.PodSandboxConfig{
+				Metadata: &pb.PodSandboxMetadata{
+					Name:      "name",
+					Namespace: "default",
+				},
+				Annotations: map[string]string{
+					crioannotations.UsernsMode: mode,
+				},
+			}
+		}
+
+		BeforeEach(func() {
+			serverConfig.Runtimes[serverConfig.DefaultRuntime].AllowedAnnotations =
+				[]string{crioannotations.UsernsMode}
+		})
+
+		It("should fail without user namespace pool", func() {
+			// Given
+			// When
+			response, err := sut.RunPodSandbox(context.Background(),
+				&pb.RunPodSandboxRequest{Config: config("auto")})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(response).To(BeNil())
+		})
+
+		It("should fail with invalid user namespace mode", func() {
+			// Given
+			serverConfig.UsernsPool = "100000:65536"
+			setupSUT()
+
+			// When
+			response, err := sut.RunPodSandbox(context.Background(),
+				&pb.RunPodSandboxRequest{Config: config("invalid")})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(response).To(BeNil())
+		})
+
+		It("should persist the allocated range", func() {
+			// Given
+			serverConfig.UsernsPool = "100000:65536"
+			setupSUT()
+			gomock.InOrder(
+				runtimeServerMock.EXPECT().CreatePodSandbox(gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(storage.ContainerInfo{}, nil),
+				runtimeServerMock.EXPECT().GetContainerMetadata(gomock.Any()).
+					Return(storage.RuntimeContainerMetadata{}, nil),
+				runtimeServerMock.EXPECT().SetContainerMetadata(gomock.Any(),
+					&storage.RuntimeContainerMetadata{
+						UsernsHostID: 100000,
+						UsernsSize:   1024,
+					}).Return(nil),
+				runtimeServerMock.EXPECT().RemovePodSandbox(gomock.Any()).
+					Return(nil),
+			)
+
+			// When
+			response, err := sut.RunPodSandbox(context.Background(),
+				&pb.RunPodSandboxRequest{Config: config("auto:size=1024")})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(response).To(BeNil())
+		})
+
+		It("should ignore the mode if not allowed by the runtime handler", func() {
+			// Given
+			serverConfig.Runtimes[serverConfig.DefaultRuntime].AllowedAnnotations = nil
+			gomock.InOrder(
+				runtimeServerMock.EXPECT().CreatePodSandbox(gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any(),
+					gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+					Return(storage.ContainerInfo{}, nil),
+				runtimeServerMock.EXPECT().RemovePodSandbox(gomock.Any()).
+					Return(nil),
+			)
+
+			// When
+			response, err := sut.RunPodSandbox(context.Background(),
+				&pb.RunPodSandboxRequest{Config: config("invalid")})
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(response).To(BeNil())
+		})
+	})
+
 	t.Describe("AddCgroupAnnotation", func() {
 		var g generate.Generator
 
@@ -296,5 +393,41 @@ var _ = t.Describe("RunPodSandbox", func() {
 			Expect(err).NotTo(BeNil())
 			Expect(res).To(Equal(""))
 		})
+
+		var prepareUnifiedCgroupDirs = func(content string) (string, string) {
+			const cgroup = "some.slice"
+			tmpDir := t.MustTempDir("cgroup")
+			Expect(os.MkdirAll(filepath.Join(tmpDir, cgroup), 0755)).To(BeNil())
+			Expect(ioutil.WriteFile(
+				filepath.Join(tmpDir, cgroup, "memory.max"),
+				[]byte(content), 0644)).To(BeNil())
+			return cgroup, tmpDir
+		}
+
+		It("should succeed with systemd manager with unlimited cgroup v2 memory", func() {
+			// Given
+			cgroup, tmpDir := prepareUnifiedCgroupDirs("max\n")
+
+			// When
+			res, err := server.AddCgroupAnnotation(g, tmpDir,
+				oci.SystemdCgroupsManager, cgroup, "id")
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(res).To(Equal(cgroup))
+		})
+
+		It("should fail with systemd manager with too low cgroup v2 memory", func() {
+			// Given
+			cgroup, tmpDir := prepareUnifiedCgroupDirs("10\n")
+
+			// When
+			res, err := server.AddCgroupAnnotation(g, tmpDir,
+				oci.SystemdCgroupsManager, cgroup, "id")
+
+			// Then
+			Expect(err).NotTo(BeNil())
+			Expect(res).To(Equal(""))
+		})
 	})
 })
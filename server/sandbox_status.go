@@ -1,6 +1,7 @@
 package server
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/cri-o/cri-o/internal/oci"
@@ -12,8 +13,9 @@ import (
 // PodSandboxStatus returns the Status of the PodSandbox.
 func (s *Server) PodSandboxStatus(ctx context.Context, req *pb.PodSandboxStatusRequest) (resp *pb.PodSandboxStatusResponse, err error) {
 	const operation = "pod_sandbox_status"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
@@ -55,6 +57,18 @@ func (s *Server) PodSandboxStatus(ctx context.Context, req *pb.PodSandboxStatusR
 		},
 	}
 
+	if req.GetVerbose() {
+		// The CRI only knows about a single pod IP, so all IPs of dual-stack
+		// sandboxes are provided as additional information
+		ips, err := json.Marshal(sb.IPs())
+		if err != nil {
+			return nil, err
+		}
+		resp.Info = map[string]string{
+			"ips": string(ips),
+		}
+	}
+
 	logrus.Debugf("PodSandboxStatusResponse: %+v", resp)
 	return resp, nil
 }
@@ -37,6 +37,28 @@ var _ = t.Describe("PodSandboxStatus", func() {
 			Expect(response).NotTo(BeNil())
 		})
 
+		It("should succeed with all IPs in verbose mode", func() {
+			// Given
+			addContainerAndSandbox()
+			testSandbox.AddIPs([]string{"10.0.0.42", "fd00::42"})
+			testContainer.SetState(&oci.ContainerState{
+				State: specs.State{Status: oci.ContainerStateRunning},
+			})
+
+			// When
+			response, err := sut.PodSandboxStatus(context.Background(),
+				&pb.PodSandboxStatusRequest{
+					PodSandboxId: testSandbox.ID(),
+					Verbose:      true,
+				})
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(response).NotTo(BeNil())
+			Expect(response.Status.Network.Ip).To(Equal("10.0.0.42"))
+			Expect(response.Info["ips"]).To(Equal(`["10.0.0.42","fd00::42"]`))
+		})
+
 		It("should fail with empty sandbox ID", func() {
 			// Given
 			// When
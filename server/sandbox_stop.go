@@ -1,6 +1,11 @@
 package server
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	pb "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
@@ -13,15 +18,48 @@ func (s *Server) StopPodSandbox(ctx context.Context, req *pb.StopPodSandboxReque
 	return s.stopPodSandbox(ctx, req)
 }
 
-// stopAllPodSandboxes removes all pod sandboxes
-func (s *Server) stopAllPodSandboxes(ctx context.Context) {
-	logrus.Debugf("stopAllPodSandboxes")
-	for _, sb := range s.ContainerServer.ListSandboxes() {
-		pod := &pb.StopPodSandboxRequest{
-			PodSandboxId: sb.ID(),
-		}
-		if _, err := s.StopPodSandbox(ctx, pod); err != nil {
-			logrus.Warnf("could not StopPodSandbox %s: %v", sb.ID(), err)
-		}
+// DefaultDrainTimeout is the default time to wait for all pod sandboxes to
+// stop when draining the node.
+const DefaultDrainTimeout = time.Minute
+
+// DrainPodSandboxes stops all pod sandboxes in parallel. It returns an error
+// if not all of them could be stopped within `timeout`.
+func (s *Server) DrainPodSandboxes(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	sandboxes := s.ContainerServer.ListSandboxes()
+	logrus.Infof("draining %d pod sandboxes", len(sandboxes))
+	var (
+		wg     sync.WaitGroup
+		failed int32
+	)
+	for _, sb := range sandboxes {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			pod := &pb.StopPodSandboxRequest{
+				PodSandboxId: id,
+			}
+			if _, err := s.StopPodSandbox(ctx, pod); err != nil {
+				logrus.Warnf("could not StopPodSandbox %s: %v", id, err)
+				atomic.AddInt32(&failed, 1)
+			}
+		}(sb.ID())
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return fmt.Errorf("pod sandboxes not stopped within %v", timeout)
+	}
+	if failed > 0 {
+		return fmt.Errorf("could not stop %d of %d pod sandboxes", failed, len(sandboxes))
 	}
+	return nil
 }
@@ -21,8 +21,9 @@ import (
 
 func (s *Server) stopPodSandbox(ctx context.Context, req *pb.StopPodSandboxRequest) (resp *pb.StopPodSandboxResponse, err error) {
 	const operation = "stop_pod_sandbox"
+	start := time.Now()
 	defer func() {
-		recordOperation(operation, time.Now())
+		recordOperation(operation, start)
 		recordError(operation, err)
 	}()
 
@@ -59,7 +60,7 @@ func (s *Server) stopPodSandbox(ctx context.Context, req *pb.StopPodSandboxReque
 	}
 
 	// Clean up sandbox networking and close its network namespace.
-	s.networkStop(sb)
+	s.networkStop(sb, sb.IPs())
 
 	const maxWorkers = 128
 	var waitGroup errgroup.Group
@@ -114,6 +115,9 @@ func (s *Server) stopPodSandbox(ctx context.Context, req *pb.StopPodSandboxReque
 			return nil, err
 		}
 	}
+	if err := sb.UnpinNamespaces(); err != nil {
+		return nil, err
+	}
 
 	// unmount the shm for the pod
 	if sb.ShmPath() != "/dev/shm" {
@@ -132,8 +136,11 @@ func (s *Server) stopPodSandbox(ctx context.Context, req *pb.StopPodSandboxReque
 		}
 	}
 
-	if err := s.StorageRuntimeServer().StopContainer(sb.ID()); err != nil && errors.Cause(err) != storage.ErrContainerUnknown {
-		logrus.Warnf("failed to stop sandbox container in pod sandbox %s: %v", sb.ID(), err)
+	// The placeholder infra container has never been mounted
+	if !podInfraContainer.Spoofed() {
+		if err := s.StorageRuntimeServer().StopContainer(sb.ID()); err != nil && errors.Cause(err) != storage.ErrContainerUnknown {
+			logrus.Warnf("failed to stop sandbox container in pod sandbox %s: %v", sb.ID(), err)
+		}
 	}
 	if err := s.ContainerStateToDisk(podInfraContainer); err != nil {
 		logrus.Warnf("error writing pod infra container %q state to disk: %v", podInfraContainer.ID(), err)
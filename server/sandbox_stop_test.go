@@ -128,6 +128,34 @@ var _ = t.Describe("PodSandboxStatus", func() {
 			Expect(response).To(BeNil())
 		})
 
+		It("should succeed with spoofed infra container", func() {
+			// Given
+			sut.SetRuntime(ociRuntimeMock)
+			addContainerAndSandbox()
+			testContainer.SetSpoofed()
+			testContainer.SetState(&oci.ContainerState{
+				State: specs.State{Status: oci.ContainerStateRunning},
+			})
+			gomock.InOrder(
+				cniPluginMock.EXPECT().TearDownPod(gomock.Any()).
+					Return(nil),
+				ociRuntimeMock.EXPECT().StopContainer(gomock.Any(),
+					gomock.Any(), gomock.Any()).Return(nil),
+				ociRuntimeMock.EXPECT().WaitContainerStateStopped(gomock.Any(),
+					gomock.Any()).Return(nil),
+				ociRuntimeMock.EXPECT().UpdateContainerStatus(gomock.Any()).
+					Return(nil),
+			)
+
+			// When
+			response, err := sut.StopPodSandbox(context.Background(),
+				&pb.StopPodSandboxRequest{PodSandboxId: testSandbox.ID()})
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(response).NotTo(BeNil())
+		})
+
 		It("should succeed with already stopped sandbox", func() {
 			// Given
 			addContainerAndSandbox()
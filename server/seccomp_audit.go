@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/seccompaudit"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	"github.com/opencontainers/runtime-tools/generate"
+	seccomp "github.com/seccomp/containers-golang"
+	"github.com/sirupsen/logrus"
+)
+
+// seccompAuditRequested returns whether the containers of the sandbox `sb`
+// run in seccomp audit mode, which has to be allowed for its runtime handler.
+func (s *Server) seccompAuditRequested(sb *sandbox.Sandbox) bool {
+	annotations := s.filterDisallowedAnnotations(sb.RuntimeHandler(), sb.Annotations())
+	return annotations[crioannotations.SeccompAudit] == "true"
+}
+
+// setupSeccompAudit replaces the seccomp profile of the container with one
+// logging every syscall, and marks the container for the collection of its
+// syscalls.
+func (s *Server) setupSeccompAudit(specgen *generate.Generator) error {
+	if !s.seccompEnabled {
+		return fmt.Errorf("seccomp is not enabled in your kernel, cannot run in seccomp audit mode")
+	}
+	profile := &seccomp.Seccomp{DefaultAction: seccompaudit.ActLog}
+	if s.seccompProfile != nil {
+		profile.Architectures = s.seccompProfile.Architectures
+		profile.ArchMap = s.seccompProfile.ArchMap
+	}
+	linuxSpecs, err := seccomp.LoadProfileFromConfig(profile, specgen.Config)
+	if err != nil {
+		return err
+	}
+	specgen.Config.Linux.Seccomp = linuxSpecs
+	specgen.AddAnnotation(crioannotations.SeccompAudit, "true")
+	return nil
+}
+
+// auditSeccomp starts collecting the syscalls of the container `c` if it runs
+// in seccomp audit mode. It has to be called before the container gets
+// created by the runtime to cover the syscalls of its process from the start.
+func (s *Server) auditSeccomp(c *oci.Container) {
+	if c == nil || c.CrioAnnotations()[crioannotations.SeccompAudit] != "true" {
+		return
+	}
+	if c.State().Status == oci.ContainerStateStopped {
+		return
+	}
+	if err := s.seccompAudit.Add(c.ID()); err != nil {
+		logrus.Warnf("unable to collect the syscalls of container %s: %v", c.ID(), err)
+	}
+}
+
+// finishSeccompAudit writes the seccomp profile generated from the syscalls
+// of the exited container `c` to the seccomp_audit_profile_dir.
+func (s *Server) finishSeccompAudit(c *oci.Container) {
+	profile, err := s.seccompAudit.Finish(c.ID())
+	if err != nil {
+		logrus.Warnf("unable to write seccomp profile of container %s: %v", c.ID(), err)
+		return
+	}
+	if profile != nil {
+		logrus.Infof("wrote seccomp profile of container %s to %s", c.ID(), s.seccompAudit.ProfilePath(c.ID()))
+	}
+}
+
+// seccompAuditProfile returns the seccomp profile generated for the
+// container `id`, which covers the syscalls observed so far if the
+// container is still running.
+func (s *Server) seccompAuditProfile(id string) ([]byte, error) {
+	if profile := s.seccompAudit.Profile(id); profile != nil {
+		return json.MarshalIndent(profile, "", "  ")
+	}
+	return ioutil.ReadFile(s.seccompAudit.ProfilePath(id))
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/cri-o/cri-o/internal/lib/config"
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/pkg/seccompaudit"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	"github.com/opencontainers/runtime-tools/generate"
+	seccomp "github.com/seccomp/containers-golang"
+	runtime "k8s.io/cri-api/pkg/apis/runtime/v1alpha2"
+)
+
+func TestSeccompAuditRequested(t *testing.T) {
+	c, err := config.DefaultConfig()
+	if err != nil {
+		t.Fatal("error loading default config")
+	}
+	c.Runtimes["audit"] = &config.RuntimeHandler{
+		AllowedAnnotations: []string{crioannotations.SeccompAudit},
+	}
+	s := &Server{config: *c}
+
+	for _, tc := range []struct {
+		handler  string
+		expected bool
+	}{
+		{handler: "audit", expected: true},
+		{handler: "", expected: false},
+	} {
+		sb, err := sandbox.New("testsandboxid", "", "", "", "", map[string]string{},
+			map[string]string{crioannotations.SeccompAudit: "true"}, "", "",
+			&runtime.PodSandboxMetadata{}, "", "", false, tc.handler, "", "", nil, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requested := s.seccompAuditRequested(sb); requested != tc.expected {
+			t.Fatalf("expected %v for runtime handler %q, got %v", tc.expected, tc.handler, requested)
+		}
+	}
+}
+
+func TestSetupSeccompAudit(t *testing.T) {
+	s := &Server{}
+	specgen, err := generate.New("linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.setupSeccompAudit(&specgen); err == nil {
+		t.Fatal("expected an error without seccomp support")
+	}
+
+	if !seccomp.IsEnabled() {
+		t.Skip("seccomp support is not compiled in")
+	}
+	s.seccompEnabled = true
+	if err := s.setupSeccompAudit(&specgen); err != nil {
+		t.Fatal(err)
+	}
+	if specgen.Config.Linux.Seccomp == nil {
+		t.Fatal("expected a seccomp profile")
+	}
+	if action := specgen.Config.Linux.Seccomp.DefaultAction; string(action) != string(seccompaudit.ActLog) {
+		t.Fatalf("expected default action %s, got %s", seccompaudit.ActLog, action)
+	}
+	if len(specgen.Config.Linux.Seccomp.Syscalls) != 0 {
+		t.Fatalf("expected no syscall rules, got %v", specgen.Config.Linux.Seccomp.Syscalls)
+	}
+	if value := specgen.Config.Annotations[crioannotations.SeccompAudit]; value != "true" {
+		t.Fatalf("expected the seccomp audit annotation, got %q", value)
+	}
+
+}
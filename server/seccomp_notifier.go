@@ -0,0 +1,172 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/pkg/seccompaudit"
+	"github.com/cri-o/cri-o/internal/pkg/seccompnotify"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	"github.com/cri-o/cri-o/pkg/types"
+	"github.com/cri-o/cri-o/server/metrics"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-tools/generate"
+	"github.com/pkg/errors"
+	seccomp "github.com/seccomp/containers-golang"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/context"
+)
+
+// seccompNotifierStopTimeout is the grace period in seconds of the
+// containers stopped for exceeding the seccomp_notifier_stop_threshold.
+const seccompNotifierStopTimeout = 10
+
+// notifyExcludedSyscall is the syscall the runtimes refuse to notify on,
+// because they use it to sync with their parent after installing the profile.
+const notifyExcludedSyscall = "write"
+
+// seccompNotifierRequested returns whether the syscalls blocked for the
+// containers of the sandbox `sb` get recorded, which has to be allowed for
+// its runtime handler.
+func (s *Server) seccompNotifierRequested(sb *sandbox.Sandbox) bool {
+	annotations := s.filterDisallowedAnnotations(sb.RuntimeHandler(), sb.Annotations())
+	return annotations[crioannotations.SeccompNotifier] == "true"
+}
+
+// setupSeccompNotifier hands the syscalls denied by the seccomp profile of the
+// container over to the seccomp notifier. The runtimes refuse notifying as
+// default action, so the default action is kept and the syscalls it denies get
+// notifying rules of their own. It returns false if the container has no
+// seccomp profile.
+func (s *Server) setupSeccompNotifier(specgen *generate.Generator) (bool, error) {
+	if specgen.Config.Linux == nil || specgen.Config.Linux.Seccomp == nil {
+		return false, nil
+	}
+	profile := specgen.Config.Linux.Seccomp
+	notify := specs.LinuxSeccompAction(seccompnotify.ActNotify)
+	covered := make(map[string]bool)
+	for i := range profile.Syscalls {
+		notifiable := true
+		for _, name := range profile.Syscalls[i].Names {
+			covered[name] = true
+			notifiable = notifiable && name != notifyExcludedSyscall
+		}
+		if profile.Syscalls[i].Action == specs.ActErrno && notifiable {
+			profile.Syscalls[i].Action = notify
+		}
+	}
+	if profile.DefaultAction == specs.ActErrno {
+		arches := make([]seccomp.Arch, 0, len(profile.Architectures))
+		for _, arch := range profile.Architectures {
+			arches = append(arches, seccomp.Arch(arch))
+		}
+		names, err := seccompaudit.SyscallNames(arches)
+		if err != nil {
+			return false, errors.Wrap(err, "unable to list the syscalls denied by the seccomp profile")
+		}
+		denied := []string{}
+		for _, name := range names {
+			if !covered[name] && name != notifyExcludedSyscall {
+				denied = append(denied, name)
+			}
+		}
+		if len(denied) > 0 {
+			profile.Syscalls = append(profile.Syscalls, specs.LinuxSyscall{Names: denied, Action: notify})
+		}
+	}
+	if err := s.seccompNotifier.Listen(); err != nil {
+		return false, errors.Wrap(err, "unable to start seccomp notifier")
+	}
+	specgen.AddAnnotation(crioannotations.SeccompNotifier, "true")
+	return true, nil
+}
+
+// addSeccompListenerPath sets the linux.seccomp.listenerPath of the OCI
+// configuration at `path`, which the vendored runtime-spec does not know
+// about yet.
+func addSeccompListenerPath(path, listenerPath string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var config map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// Keep large numbers like rlimits intact
+	decoder.UseNumber()
+	if err := decoder.Decode(&config); err != nil {
+		return err
+	}
+	linux, ok := config["linux"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("no linux configuration in %s", path)
+	}
+	seccomp, ok := linux["seccomp"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("no seccomp configuration in %s", path)
+	}
+	seccomp["listenerPath"] = listenerPath
+	if data, err = json.Marshal(config); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, info.Mode())
+}
+
+// handleBlockedSyscall records the blocked syscall `syscall` of the container
+// `id`, and stops the container once it exceeds the
+// seccomp_notifier_stop_threshold.
+func (s *Server) handleBlockedSyscall(id, syscall string, count, total uint64) {
+	metrics.CRIOContainersSeccompBlockedSyscalls.WithLabelValues(syscall).Inc()
+	if count == 1 {
+		logrus.Warnf("seccomp profile of container %s blocked syscall %s", id, syscall)
+	} else {
+		logrus.Debugf("seccomp profile of container %s blocked syscall %s %d times", id, syscall, count)
+	}
+
+	threshold := s.config.SeccompNotifierStopThreshold
+	if threshold == 0 || total != threshold+1 {
+		return
+	}
+	c := s.GetContainer(id)
+	if c == nil {
+		return
+	}
+	logrus.Warnf("stopping container %s after %d blocked syscalls", c.Description(), total)
+	go func() {
+		if _, err := s.ContainerServer.ContainerStop(context.Background(), id, seccompNotifierStopTimeout); err != nil {
+			logrus.Warnf("unable to stop container %s: %v", c.Description(), err)
+			return
+		}
+		s.publishContainerEvent(types.EventContainerStopped, c)
+	}()
+}
+
+// withBlockedSyscalls returns a copy of the container status `annotations`
+// with the blocked syscalls of the container, or `annotations` if none got
+// blocked.
+func withBlockedSyscalls(annotations map[string]string, blocked map[string]uint64) map[string]string {
+	if len(blocked) == 0 {
+		return annotations
+	}
+	syscalls := make([]string, 0, len(blocked))
+	for syscall, count := range blocked {
+		syscalls = append(syscalls, fmt.Sprintf("%s=%d", syscall, count))
+	}
+	sort.Strings(syscalls)
+
+	result := make(map[string]string, len(annotations)+1)
+	for k, v := range annotations {
+		result[k] = v
+	}
+	result[crioannotations.SeccompBlockedSyscalls] = strings.Join(syscalls, ",")
+	return result
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cri-o/cri-o/internal/pkg/seccompnotify"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/opencontainers/runtime-tools/generate"
+)
+
+func TestSetupSeccompNotifier(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seccompnotify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := &Server{seccompNotifier: seccompnotify.New(filepath.Join(dir, "seccomp.sock"), nil)}
+	defer s.seccompNotifier.Close()
+	specgen, err := generate.New("linux")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	specgen.Config.Linux.Seccomp = nil
+	if enabled, err := s.setupSeccompNotifier(&specgen); err != nil || enabled {
+		t.Fatalf("expected no notifier without seccomp profile, got %v, %v", enabled, err)
+	}
+
+	specgen.Config.Linux.Seccomp = &specs.LinuxSeccomp{
+		DefaultAction: specs.ActErrno,
+		Syscalls: []specs.LinuxSyscall{
+			{Names: []string{"read"}, Action: specs.ActAllow},
+			{Names: []string{"mkdir"}, Action: specs.ActErrno},
+		},
+	}
+	if enabled, err := s.setupSeccompNotifier(&specgen); err != nil || !enabled {
+		t.Fatalf("expected the notifier to be set up, got %v, %v", enabled, err)
+	}
+	notify := specs.LinuxSeccompAction(seccompnotify.ActNotify)
+	profile := specgen.Config.Linux.Seccomp
+	if profile.DefaultAction != specs.ActErrno || profile.Syscalls[0].Action != specs.ActAllow ||
+		profile.Syscalls[1].Action != notify {
+		t.Fatalf("expected the denied syscalls to notify, got %+v", profile)
+	}
+	if len(profile.Syscalls) != 3 || profile.Syscalls[2].Action != notify {
+		t.Fatalf("expected a notifying rule for the syscalls denied by default, got %+v", profile)
+	}
+	denied := strings.Join(profile.Syscalls[2].Names, ",")
+	for _, name := range []string{"unshare", "mount"} {
+		if !strings.Contains(denied, name) {
+			t.Fatalf("expected %s to notify, got %s", name, denied)
+		}
+	}
+	for _, name := range []string{"read", "mkdir", "write"} {
+		for _, deniedName := range profile.Syscalls[2].Names {
+			if deniedName == name {
+				t.Fatalf("expected no notifying rule for %s", name)
+			}
+		}
+	}
+	if value := specgen.Config.Annotations[crioannotations.SeccompNotifier]; value != "true" {
+		t.Fatalf("expected the seccomp notifier annotation, got %q", value)
+	}
+	if _, err := os.Stat(s.seccompNotifier.Path()); err != nil {
+		t.Fatalf("expected the notifier to listen: %v", err)
+	}
+}
+
+func TestAddSeccompListenerPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seccompnotify")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.json")
+	config := `{"process":{"rlimits":[{"type":"RLIMIT_NOFILE","hard":18446744073709551615,"soft":1024}]},` +
+		`"linux":{"seccomp":{"defaultAction":"SCMP_ACT_NOTIFY"}}}`
+	if err := ioutil.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := addSeccompListenerPath(path, "/run/seccomp.sock"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{`"listenerPath":"/run/seccomp.sock"`, `18446744073709551615`} {
+		if !strings.Contains(string(data), expected) {
+			t.Fatalf("expected %s in %s", expected, data)
+		}
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"linux":{}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := addSeccompListenerPath(path, "/run/seccomp.sock"); err == nil {
+		t.Fatal("expected an error without seccomp configuration")
+	}
+}
+
+func TestWithBlockedSyscalls(t *testing.T) {
+	annotations := map[string]string{"a": "b"}
+
+	if result := withBlockedSyscalls(annotations, map[string]uint64{}); len(result) != 1 {
+		t.Fatalf("expected unchanged annotations, got %v", result)
+	}
+
+	result := withBlockedSyscalls(annotations, map[string]uint64{"unshare": 1, "mkdir": 3})
+	if value := result[crioannotations.SeccompBlockedSyscalls]; value != "mkdir=3,unshare=1" {
+		t.Fatalf("expected the blocked syscalls, got %q", value)
+	}
+	if result["a"] != "b" || len(annotations) != 1 {
+		t.Fatalf("expected a copy of the annotations, got %v and %v", result, annotations)
+	}
+}
@@ -25,8 +25,14 @@ import (
 	libconfig "github.com/cri-o/cri-o/internal/lib/config"
 	"github.com/cri-o/cri-o/internal/lib/sandbox"
 	"github.com/cri-o/cri-o/internal/oci"
+	"github.com/cri-o/cri-o/internal/pkg/events"
+	"github.com/cri-o/cri-o/internal/pkg/hostport6"
+	"github.com/cri-o/cri-o/internal/pkg/oom"
+	"github.com/cri-o/cri-o/internal/pkg/seccompaudit"
+	"github.com/cri-o/cri-o/internal/pkg/seccompnotify"
 	"github.com/cri-o/cri-o/internal/pkg/signals"
 	"github.com/cri-o/cri-o/internal/pkg/storage"
+	"github.com/cri-o/cri-o/internal/pkg/userns"
 	"github.com/cri-o/cri-o/server/metrics"
 	"github.com/cri-o/cri-o/server/useragent"
 	"github.com/cri-o/ocicni/pkg/ocicni"
@@ -46,8 +52,11 @@ import (
 )
 
 const (
-	shutdownFile        = "/var/lib/crio/crio.shutdown"
 	certRefreshInterval = time.Minute * 5
+
+	// restoreParallelism is the number of sandboxes which get restored
+	// concurrently on startup
+	restoreParallelism = 16
 )
 
 // StreamService implements streaming.Runtime.
@@ -65,6 +74,8 @@ type Server struct {
 	stream          StreamService
 	netPlugin       ocicni.CNIPlugin
 	hostportManager hostport.HostPortManager
+	// hostportManagerIPv6 sets up the hostports of IPv6 pod addresses
+	hostportManagerIPv6 hostport.HostPortManager
 
 	appArmorProfile string
 	hostIP          string
@@ -75,10 +86,44 @@ type Server struct {
 	defaultIDMappings *idtools.IDMappings
 	systemContext     *types.SystemContext // Never nil
 
+	// usernsPool allocates the ID ranges of pods with their own user
+	// namespace, it is nil if no pool is configured
+	usernsPool *userns.Pool
+
+	// updateLock guards the options of the config and the systemContext
+	// which change on configuration reloads. A reload holds it for writing.
 	updateLock sync.RWMutex
 
+	// pullOperationsInProgress is used to avoid pulling the same image in
+	// parallel. Callers of an identical pull wait for the first one.
+	pullOperationsInProgress map[pullArguments]*pullOperation
+	// pullOperationsLock is used to synchronize pull operations.
+	pullOperationsLock sync.Mutex
+
 	seccompEnabled  bool
 	appArmorEnabled bool
+
+	// quarantine keeps track of the containers which could not be restored
+	quarantine quarantine
+
+	// events publishes the lifecycle events of containers and sandboxes
+	events *events.Bus
+
+	// oomWatcher watches the memory cgroups of running containers for OOM
+	// kills
+	oomWatcher *oom.Watcher
+
+	// seccompAudit collects the syscalls of running containers in seccomp
+	// audit mode
+	seccompAudit *seccompaudit.Collector
+
+	// seccompNotifier records the syscalls blocked for containers in seccomp
+	// notifier mode
+	seccompNotifier *seccompnotify.Notifier
+
+	// shutdownRequested gets closed by RequestShutdown
+	shutdownRequested chan struct{}
+	shutdownOnce      sync.Once
 }
 
 type certConfigCache struct {
@@ -149,8 +194,8 @@ func (s *Server) restore() {
 	}
 	pods := map[string]*storage.RuntimeContainerMetadata{}
 	podContainers := map[string]*storage.RuntimeContainerMetadata{}
+	containersOfPod := map[string][]string{}
 	names := map[string][]string{}
-	deletedPods := map[string]bool{}
 	for i := range containers {
 		metadata, err2 := s.StorageRuntimeServer().GetContainerMetadata(containers[i].ID)
 		if err2 != nil {
@@ -162,98 +207,111 @@ func (s *Server) restore() {
 			pods[containers[i].ID] = &metadata
 		} else {
 			podContainers[containers[i].ID] = &metadata
+			containersOfPod[metadata.PodID] = append(containersOfPod[metadata.PodID], containers[i].ID)
 		}
 	}
 
-	// Go through all the pods and check if it can be restored. If an error occurs, delete the pod and any containers
-	// associated with it. Release the pod and container names as well.
+	// Restore the pods and their containers concurrently. Nothing gets
+	// deleted if an error occurs, but the pod or container is quarantined
+	// for later inspection.
+	var wg sync.WaitGroup
+	parallel := make(chan struct{}, restoreParallelism)
 	for sbID, metadata := range pods {
-		if err = s.LoadSandbox(sbID); err == nil {
-			continue
-		}
-		logrus.Warnf("could not restore sandbox %s container %s: %v", metadata.PodID, sbID, err)
-		for _, n := range names[sbID] {
-			if err := s.Store().DeleteContainer(n); err != nil {
-				logrus.Warnf("unable to delete container %s: %v", n, err)
-			}
-			// Release the infra container name and the pod name for future use
-			if strings.Contains(n, infraName) {
-				s.ReleaseContainerName(n)
-			} else {
-				s.ReleasePodName(n)
-			}
-
-		}
-		// Go through the containers and delete any container that was under the deleted pod
-		logrus.Warnf("deleting all containers under sandbox %s since it could not be restored", sbID)
-		for k, v := range podContainers {
-			if v.PodID == sbID {
-				for _, n := range names[k] {
-					if err := s.Store().DeleteContainer(n); err != nil {
-						logrus.Warnf("unable to delete container %s: %v", n, err)
-					}
-					// Release the container name for future use
-					s.ReleaseContainerName(n)
-				}
-			}
-		}
-		// Add the pod id to the list of deletedPods so we don't try to restore IPs for it later on
-		deletedPods[sbID] = true
-	}
-
-	// Go through all the containers and check if it can be restored. If an error occurs, delete the conainer and
-	// release the name associated with you.
-	for containerID := range podContainers {
-		if err := s.LoadContainer(containerID); err != nil {
-			logrus.Warnf("could not restore container %s: %v", containerID, err)
-			for _, n := range names[containerID] {
-				if err := s.Store().DeleteContainer(n); err != nil {
-					logrus.Warnf("unable to delete container %s: %v", n, err)
-				}
-				// Release the container name
-				s.ReleaseContainerName(n)
-			}
+		wg.Add(1)
+		parallel <- struct{}{}
+		go func(sbID string, metadata *storage.RuntimeContainerMetadata) {
+			defer func() {
+				<-parallel
+				wg.Done()
+			}()
+			s.restoreSandbox(sbID, metadata, containersOfPod[sbID], podContainers, names)
+		}(sbID, metadata)
+	}
+	wg.Wait()
+
+	// Containers whose pod does not exist at all can't be restored either,
+	// but we try anyway to report the actual error.
+	for containerID, metadata := range podContainers {
+		if _, ok := pods[metadata.PodID]; !ok {
+			s.restoreContainer(containerID, metadata, names[containerID])
 		}
 	}
+}
 
-	// Restore sandbox IPs
-	for _, sb := range s.ListSandboxes() {
-		// Move on if pod was deleted
-		if ok := deletedPods[sb.ID()]; ok {
-			continue
-		}
-		ip, err := s.getSandboxIP(sb)
-		if err != nil {
-			logrus.Warnf("could not restore sandbox IP for %v: %v", sb.ID(), err)
+// restoreSandbox restores the sandbox `sbID` together with its containers
+// `containerIDs`. The sandbox and all of its containers get quarantined if
+// the sandbox can't be restored.
+func (s *Server) restoreSandbox(sbID string, metadata *storage.RuntimeContainerMetadata, containerIDs []string, podContainers map[string]*storage.RuntimeContainerMetadata, names map[string][]string) {
+	if err := s.LoadSandbox(sbID); err != nil {
+		err = errors.Wrapf(err, "could not restore sandbox %s", metadata.PodName)
+		s.quarantineContainer(sbID, metadata, names[sbID], err)
+		for _, containerID := range containerIDs {
+			s.quarantineContainer(containerID, podContainers[containerID], names[containerID], err)
 		}
-		sb.AddIP(ip)
+		return
+	}
+	if err := s.restoreUsernsRange(sbID, metadata); err != nil {
+		logrus.Warnf("could not restore user namespace of sandbox %s: %v", sbID, err)
+	}
+	for _, containerID := range containerIDs {
+		s.restoreContainer(containerID, podContainers[containerID], names[containerID])
 	}
-}
 
-// cleanupSandboxesOnShutdown Remove all running Sandboxes on system shutdown
-func (s *Server) cleanupSandboxesOnShutdown(ctx context.Context) {
-	_, err := os.Stat(shutdownFile)
-	if err == nil || !os.IsNotExist(err) {
-		logrus.Debugf("shutting down all sandboxes, on shutdown")
-		s.stopAllPodSandboxes(ctx)
-		err = os.Remove(shutdownFile)
-		if err != nil {
-			logrus.Warnf("Failed to remove %q", shutdownFile)
-		}
+	sb := s.getSandbox(sbID)
+	if sb == nil {
+		return
+	}
+	s.watchOOMKills(sb.InfraContainer())
 
+	// Skip the network plugin if the IPs have been stored within the
+	// sandbox annotations
+	if len(sb.IPs()) > 0 {
+		return
 	}
+	ips, err := s.getSandboxIPs(sb)
+	if err != nil {
+		logrus.Warnf("could not restore sandbox IP for %v: %v", sbID, err)
+	}
+	sb.AddIPs(ips)
+}
+
+// restoreContainer restores the container `containerID` or quarantines it if
+// that fails.
+func (s *Server) restoreContainer(containerID string, metadata *storage.RuntimeContainerMetadata, names []string) {
+	if err := s.LoadContainer(containerID); err != nil {
+		s.quarantineContainer(containerID, metadata, names,
+			errors.Wrap(err, "could not restore container"))
+		return
+	}
+	s.watchOOMKills(s.GetContainer(containerID))
+	// The collection continues with the persisted profile, the syscalls
+	// called while the daemon was not running are not covered
+	s.auditSeccomp(s.GetContainer(containerID))
 }
 
-// Shutdown attempts to shut down the server's storage cleanly
+// Shutdown attempts to shut down the server's storage cleanly. The pods are
+// kept running, use DrainPodSandboxes to stop them beforehand.
 func (s *Server) Shutdown(ctx context.Context) error {
-	// why do this on clean shutdown! we want containers left running when crio
-	// is down for whatever reason no?!
-	// notice this won't trigger just on system halt but also on normal
-	// crio.service restart!!!
-	s.cleanupSandboxesOnShutdown(ctx)
+	s.oomWatcher.Close()
+	s.seccompAudit.Close()
+	s.seccompNotifier.Close()
 	return s.ContainerServer.Shutdown()
 }
 
+// RequestShutdown asks the daemon to shut down by closing the channel
+// returned by ShutdownRequestedChan.
+func (s *Server) RequestShutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdownRequested)
+	})
+}
+
+// ShutdownRequestedChan returns the channel which gets closed if a shutdown
+// of the daemon has been requested
+func (s *Server) ShutdownRequestedChan() chan struct{} {
+	return s.shutdownRequested
+}
+
 // configureMaxThreads sets the Go runtime max threads threshold
 // which is 90% of the kernel setting from /proc/sys/kernel/threads-max
 func configureMaxThreads() error {
@@ -335,42 +393,54 @@ func New(
 		logrus.Warnf("unable to ensure iptables chain: %v", err)
 	}
 	hostportManager := hostport.NewHostportManager(iptInterface)
+	hostportManagerIPv6 := hostport6.NewHostportManager(
+		utiliptables.New(utilexec.New(), utildbus.New(), utiliptables.ProtocolIpv6))
 
 	idMappings, err := getIDMappings(config)
 	if err != nil {
 		return nil, err
 	}
 
-	s := &Server{
-		ContainerServer:   containerServer,
-		netPlugin:         netPlugin,
-		hostportManager:   hostportManager,
-		config:            *config,
-		seccompEnabled:    seccomp.IsEnabled(),
-		appArmorEnabled:   apparmor.IsEnabled(),
-		appArmorProfile:   config.ApparmorProfile,
-		monitorsChan:      make(chan struct{}),
-		defaultIDMappings: idMappings,
-		systemContext:     systemContext,
+	var usernsPool *userns.Pool
+	if config.UsernsPool != "" {
+		poolRange, err := userns.ParseRange(config.UsernsPool)
+		if err != nil {
+			return nil, err
+		}
+		usernsPool = userns.NewPool(poolRange)
 	}
 
+	s := &Server{
+		ContainerServer:     containerServer,
+		netPlugin:           netPlugin,
+		hostportManager:     hostportManager,
+		hostportManagerIPv6: hostportManagerIPv6,
+		config:              *config,
+		seccompEnabled:      seccomp.IsEnabled(),
+		appArmorEnabled:     apparmor.IsEnabled(),
+		appArmorProfile:     config.ApparmorProfile,
+		monitorsChan:        make(chan struct{}),
+		shutdownRequested:   make(chan struct{}),
+		events:              events.NewBus(events.DefaultReplaySize),
+		defaultIDMappings:   idMappings,
+		systemContext:       systemContext,
+		usernsPool:          usernsPool,
+
+		pullOperationsInProgress: make(map[pullArguments]*pullOperation),
+	}
+	s.oomWatcher = oom.New(s.handleOOMKills)
+	s.seccompAudit = seccompaudit.New(config.SeccompAuditSource, config.SeccompAuditProfileDir, seccompaudit.ProcessInContainer)
+	s.seccompNotifier = seccompnotify.New(
+		filepath.Join(config.ContainerAttachSocketDir, "seccomp-notifier.sock"),
+		s.handleBlockedSyscall,
+	)
+
 	if s.seccompEnabled {
-		if config.SeccompProfile != "" {
-			seccompProfile, fileErr := ioutil.ReadFile(config.SeccompProfile)
-			if fileErr != nil {
-				return nil, fmt.Errorf("opening seccomp profile (%s) failed: %v",
-					config.SeccompProfile, fileErr)
-			}
-			var seccompConfig seccomp.Seccomp
-			if jsonErr := json.Unmarshal(seccompProfile, &seccompConfig); jsonErr != nil {
-				return nil, fmt.Errorf("decoding seccomp profile failed: %v", jsonErr)
-			}
-			logrus.Infof("using seccomp profile %q", config.SeccompProfile)
-			s.seccompProfile = &seccompConfig
-		} else {
-			logrus.Infof("no seccomp profile specified, using the internal default")
-			s.seccompProfile = seccomp.DefaultProfile()
+		seccompProfile, err := loadSeccompProfile(config.SeccompProfile)
+		if err != nil {
+			return nil, err
 		}
+		s.seccompProfile = seccompProfile
 	}
 
 	if s.appArmorEnabled && config.ApparmorProfile == libconfig.DefaultApparmorProfile {
@@ -387,7 +457,6 @@ func New(
 	}
 
 	s.restore()
-	s.cleanupSandboxesOnShutdown(ctx)
 
 	hostIP := net.ParseIP(config.HostIP)
 	if hostIP == nil {
@@ -446,7 +515,7 @@ func New(
 	logrus.Debugf("sandboxes: %v", s.ContainerServer.ListSandboxes())
 
 	// Start a configuration watcher for the default config
-	if _, err := s.StartConfigWatcher(configPath, s.config.Reload); err != nil {
+	if _, err := s.StartConfigWatcher(configPath, s.ReloadConfig); err != nil {
 		logrus.Warnf("unable to start config watcher for file %q: %v",
 			configPath, err)
 	}
@@ -454,6 +523,26 @@ func New(
 	return s, nil
 }
 
+// loadSeccompProfile reads and decodes the seccomp profile at the provided
+// `path`. It returns the internal default profile if the path is empty.
+func loadSeccompProfile(path string) (*seccomp.Seccomp, error) {
+	if path == "" {
+		logrus.Infof("no seccomp profile specified, using the internal default")
+		return seccomp.DefaultProfile(), nil
+	}
+	seccompProfile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening seccomp profile (%s) failed: %v",
+			path, err)
+	}
+	var seccompConfig seccomp.Seccomp
+	if err := json.Unmarshal(seccompProfile, &seccompConfig); err != nil {
+		return nil, fmt.Errorf("decoding seccomp profile failed: %v", err)
+	}
+	logrus.Infof("using seccomp profile %q", path)
+	return &seccompConfig, nil
+}
+
 func (s *Server) addSandbox(sb *sandbox.Sandbox) error {
 	return s.ContainerServer.AddSandbox(sb)
 }
@@ -479,10 +568,14 @@ func (s *Server) getInfraContainer(id string) *oci.Container {
 }
 
 func (s *Server) removeContainer(c *oci.Container) {
+	s.oomWatcher.Remove(c.ID())
+	s.finishSeccompAudit(c)
+	s.seccompNotifier.Remove(c.ID())
 	s.ContainerServer.RemoveContainer(c)
 }
 
 func (s *Server) removeInfraContainer(c *oci.Container) {
+	s.oomWatcher.Remove(c.ID())
 	s.ContainerServer.RemoveInfraContainer(c)
 }
 
@@ -546,9 +639,14 @@ func (s *Server) StartExitMonitor() {
 						err := s.Runtime().UpdateContainerStatus(c)
 						if err != nil {
 							logrus.Warnf("Failed to update container status %s: %v", containerID, err)
-						} else if err := s.ContainerStateToDisk(c); err != nil {
-							logrus.Warnf("unable to write containers %s state to disk: %v", c.ID(), err)
+						} else {
+							if err := s.ContainerStateToDisk(c); err != nil {
+								logrus.Warnf("unable to write containers %s state to disk: %v", c.ID(), err)
+							}
+							s.publishContainerExit(c)
 						}
+						s.finishSeccompAudit(c)
+						observeExitMonitorLag(event.Name)
 					} else {
 						sb := s.GetSandbox(containerID)
 						if sb != nil {
@@ -564,6 +662,7 @@ func (s *Server) StartExitMonitor() {
 							} else if err := s.ContainerStateToDisk(c); err != nil {
 								logrus.Warnf("unable to write containers %s state to disk: %v", c.ID(), err)
 							}
+							observeExitMonitorLag(event.Name)
 						}
 					}
 				}
@@ -584,6 +683,16 @@ func (s *Server) StartExitMonitor() {
 	<-done
 }
 
+// observeExitMonitorLag records the time since conmon wrote the exit file
+// `path` of a container.
+func observeExitMonitorLag(path string) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	metrics.CRIOExitMonitorLag.Observe(time.Since(fi.ModTime()).Seconds())
+}
+
 // StartConfigWatcher starts a new watching go routine for the provided
 // `fileName` and `reloadFunc`. The method errors if the given fileName does
 // not exist or is not accessible.
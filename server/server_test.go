@@ -2,11 +2,15 @@ package server_test
 
 import (
 	"context"
+	"encoding/json"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 
 	cstorage "github.com/containers/storage"
 	"github.com/cri-o/cri-o/internal/pkg/signals"
+	"github.com/cri-o/cri-o/pkg/types"
 	"github.com/cri-o/cri-o/server"
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo"
@@ -115,9 +119,6 @@ var _ = t.Describe("Server", func() {
 				storeMock.EXPECT().
 					FromContainerDirectory(gomock.Any(), gomock.Any()).
 					Return([]byte{}, nil),
-				storeMock.EXPECT().
-					FromContainerDirectory(gomock.Any(), gomock.Any()).
-					Return([]byte{}, nil),
 			)
 
 			// When
@@ -128,6 +129,43 @@ var _ = t.Describe("Server", func() {
 			Expect(server).NotTo(BeNil())
 		})
 
+		It("should quarantine containers which can't be restored", func() {
+			// Given
+			gomock.InOrder(
+				libMock.EXPECT().GetData().Times(2).Return(serverConfig),
+				libMock.EXPECT().GetStore().Return(storeMock, nil),
+				libMock.EXPECT().GetData().Return(serverConfig),
+				storeMock.EXPECT().Containers().
+					Return([]cstorage.Container{
+						{ID: "ctr", Names: []string{"name"}},
+					}, nil),
+				storeMock.EXPECT().Metadata("ctr").
+					Return(`{"pod-id": "missing"}`, nil),
+				storeMock.EXPECT().
+					FromContainerDirectory("ctr", gomock.Any()).
+					Return([]byte{}, nil),
+				storeMock.EXPECT().
+					SetNames("ctr", []string{"quarantined-name"}).
+					Return(nil),
+			)
+
+			// When
+			sut, err := server.New(context.Background(), nil, "", libMock)
+
+			// Then
+			Expect(err).To(BeNil())
+			Expect(sut).NotTo(BeNil())
+			recorder := httptest.NewRecorder()
+			request, err := http.NewRequest("GET", "/info", nil)
+			Expect(err).To(BeNil())
+			sut.GetInfoMux().ServeHTTP(recorder, request)
+			info := types.CrioInfo{}
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &info)).To(BeNil())
+			Expect(info.Quarantined).To(HaveLen(1))
+			Expect(info.Quarantined[0].ID).To(Equal("ctr"))
+			Expect(info.Quarantined[0].Sandbox).To(Equal("missing"))
+		})
+
 		It("should fail when provided config is nil", func() {
 			// Given
 			// When
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/containers/storage/pkg/idtools"
+	"github.com/cri-o/cri-o/internal/lib/sandbox"
+	"github.com/cri-o/cri-o/internal/pkg/storage"
+	"github.com/cri-o/cri-o/internal/pkg/userns"
+	crioannotations "github.com/cri-o/cri-o/pkg/annotations"
+)
+
+// allocateUsernsRange allocates the host ID range of the new pod sandbox
+// `id`, if it requests its own user namespace via its annotations. It returns
+// nil if the pod uses the default ID mappings.
+func (s *Server) allocateUsernsRange(id string, annotations map[string]string) (*userns.Range, error) {
+	mode, ok := annotations[crioannotations.UsernsMode]
+	if !ok {
+		return nil, nil
+	}
+	size, err := userns.ParseMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	if s.usernsPool == nil {
+		return nil, fmt.Errorf("user namespace mode %q requested, but no userns_pool is configured", mode)
+	}
+	r, err := s.usernsPool.Allocate(id, size)
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// persistUsernsRange stores the allocated range `r` within the metadata of
+// the pod sandbox `id`, so that it can be reserved again after a restart.
+func (s *Server) persistUsernsRange(id string, r *userns.Range) error {
+	metadata, err := s.StorageRuntimeServer().GetContainerMetadata(id)
+	if err != nil {
+		return err
+	}
+	metadata.UsernsHostID = r.HostID
+	metadata.UsernsSize = r.Size
+	return s.StorageRuntimeServer().SetContainerMetadata(id, &metadata)
+}
+
+// restoreUsernsRange applies the persisted ID mappings to the restored pod
+// sandbox `id` and reserves its range within the pool. The mappings are kept
+// even if the range cannot be reserved, because the existing containers of
+// the pod already use them.
+func (s *Server) restoreUsernsRange(id string, metadata *storage.RuntimeContainerMetadata) error {
+	if metadata.UsernsSize == 0 {
+		return nil
+	}
+	r := userns.Range{HostID: metadata.UsernsHostID, Size: metadata.UsernsSize}
+	if sb := s.GetSandbox(id); sb != nil {
+		sb.SetIDMappings(r.IDMappings())
+	}
+	if s.usernsPool == nil {
+		return fmt.Errorf("sandbox uses user namespace range %d:%d, but no userns_pool is configured", r.HostID, r.Size)
+	}
+	return s.usernsPool.Reserve(id, r)
+}
+
+// releaseUsernsRange frees the range of the removed pod sandbox `id`, if any.
+func (s *Server) releaseUsernsRange(id string) {
+	if s.usernsPool != nil {
+		s.usernsPool.Release(id)
+	}
+}
+
+// sandboxIDMappings returns the ID mappings used for the containers of `sb`.
+func (s *Server) sandboxIDMappings(sb *sandbox.Sandbox) *idtools.IDMappings {
+	if idMappings := sb.IDMappings(); idMappings != nil {
+		return idMappings
+	}
+	return s.defaultIDMappings
+}
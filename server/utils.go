@@ -172,6 +172,7 @@ func getOCICapabilitiesList() []string {
 func recordOperation(operation string, start time.Time) {
 	metrics.CRIOOperations.WithLabelValues(operation).Inc()
 	metrics.CRIOOperationsLatency.WithLabelValues(operation).Observe(metrics.SinceInMicroseconds(start))
+	metrics.CRIOOperationsLatencySeconds.WithLabelValues(operation).Observe(time.Since(start).Seconds())
 }
 
 // recordError records error for metric if an error occurred.
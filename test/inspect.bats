@@ -69,3 +69,79 @@ function teardown() {
 
 	stop_crio
 }
+
+@test "status subcommands" {
+	start_crio
+	run crictl runp "$TESTDATA"/sandbox_config.json
+	echo "$output"
+	[ "$status" -eq 0 ]
+	pod_id="$output"
+	run crictl create "$pod_id" "$TESTDATA"/container_config.json "$TESTDATA"/sandbox_config.json
+	echo "$output"
+	[ "$status" -eq 0 ]
+	ctr_id="$output"
+
+	run crio status --socket "$CRIO_SOCKET" --output json info
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "\"cgroup_driver\": \"$CGROUP_MANAGER\"" ]]
+
+	run crio status containers "$ctr_id"
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "$pod_id" ]]
+
+	run crio status sandboxes
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "$pod_id" ]]
+
+	run crio status --output json sandboxes "$pod_id"
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "\"id\": \"$ctr_id\"" ]]
+
+	run crio status goroutines
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "goroutine" ]]
+
+	run crio status containers notexists
+	echo "$output"
+	[ "$status" -ne 0 ]
+	[[ "$output" =~ "can't find the container with id notexists" ]]
+
+	cleanup_ctrs
+	cleanup_pods
+	stop_crio
+}
+
+@test "status events" {
+	start_crio
+	run crictl runp "$TESTDATA"/sandbox_config.json
+	echo "$output"
+	[ "$status" -eq 0 ]
+	pod_id="$output"
+	run crictl create "$pod_id" "$TESTDATA"/container_config.json "$TESTDATA"/sandbox_config.json
+	echo "$output"
+	[ "$status" -eq 0 ]
+	ctr_id="$output"
+	run crictl start "$ctr_id"
+	echo "$output"
+	[ "$status" -eq 0 ]
+
+	run timeout 5 crio status --output json events --replay --sandbox "$pod_id" --type container_created --type container_started
+	echo "$output"
+	[[ "$output" =~ "\"type\":\"container_created\",\"timestamp\"" ]]
+	[[ "$output" =~ "\"type\":\"container_started\",\"timestamp\"" ]]
+	[[ ! "$output" =~ "sandbox_network_setup" ]]
+
+	run crio status events --type invalid
+	echo "$output"
+	[ "$status" -ne 0 ]
+	[[ "$output" =~ "unknown event type" ]]
+
+	cleanup_ctrs
+	cleanup_pods
+	stop_crio
+}
@@ -5,12 +5,14 @@
 package criostoragemock
 
 import (
+	context "context"
 	copy "github.com/containers/image/copy"
 	types "github.com/containers/image/types"
 	storage "github.com/containers/storage"
 	idtools "github.com/containers/storage/pkg/idtools"
 	storage0 "github.com/cri-o/cri-o/internal/pkg/storage"
 	gomock "github.com/golang/mock/gomock"
+	io "io"
 	reflect "reflect"
 )
 
@@ -82,33 +84,33 @@ func (mr *MockImageServerMockRecorder) ListImages(arg0, arg1 interface{}) *gomoc
 }
 
 // PrepareImage mocks base method
-func (m *MockImageServer) PrepareImage(arg0 *types.SystemContext, arg1 string) (types.ImageCloser, error) {
+func (m *MockImageServer) PrepareImage(arg0 context.Context, arg1 *types.SystemContext, arg2 string) (types.ImageCloser, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PrepareImage", arg0, arg1)
+	ret := m.ctrl.Call(m, "PrepareImage", arg0, arg1, arg2)
 	ret0, _ := ret[0].(types.ImageCloser)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // PrepareImage indicates an expected call of PrepareImage
-func (mr *MockImageServerMockRecorder) PrepareImage(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockImageServerMockRecorder) PrepareImage(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareImage", reflect.TypeOf((*MockImageServer)(nil).PrepareImage), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PrepareImage", reflect.TypeOf((*MockImageServer)(nil).PrepareImage), arg0, arg1, arg2)
 }
 
 // PullImage mocks base method
-func (m *MockImageServer) PullImage(arg0 *types.SystemContext, arg1 string, arg2 *copy.Options) (types.ImageReference, error) {
+func (m *MockImageServer) PullImage(arg0 context.Context, arg1 *types.SystemContext, arg2 string, arg3 *copy.Options) (types.ImageReference, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "PullImage", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "PullImage", arg0, arg1, arg2, arg3)
 	ret0, _ := ret[0].(types.ImageReference)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // PullImage indicates an expected call of PullImage
-func (mr *MockImageServerMockRecorder) PullImage(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockImageServerMockRecorder) PullImage(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullImage", reflect.TypeOf((*MockImageServer)(nil).PullImage), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PullImage", reflect.TypeOf((*MockImageServer)(nil).PullImage), arg0, arg1, arg2, arg3)
 }
 
 // ResolveNames mocks base method
@@ -140,6 +142,58 @@ func (mr *MockImageServerMockRecorder) UntagImage(arg0, arg1 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UntagImage", reflect.TypeOf((*MockImageServer)(nil).UntagImage), arg0, arg1)
 }
 
+// UpdatePinnedImages mocks base method
+func (m *MockImageServer) UpdatePinnedImages(arg0 []string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdatePinnedImages", arg0)
+}
+
+// UpdatePinnedImages indicates an expected call of UpdatePinnedImages
+func (mr *MockImageServerMockRecorder) UpdatePinnedImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePinnedImages", reflect.TypeOf((*MockImageServer)(nil).UpdatePinnedImages), arg0)
+}
+
+// UpdateRegistryMirrors mocks base method
+func (m *MockImageServer) UpdateRegistryMirrors(arg0 map[string][]string, arg1 map[string]string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "UpdateRegistryMirrors", arg0, arg1)
+}
+
+// UpdateRegistryMirrors indicates an expected call of UpdateRegistryMirrors
+func (mr *MockImageServerMockRecorder) UpdateRegistryMirrors(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRegistryMirrors", reflect.TypeOf((*MockImageServer)(nil).UpdateRegistryMirrors), arg0, arg1)
+}
+
+// UpdateRegistries mocks base method
+func (m *MockImageServer) UpdateRegistries(arg0 *types.SystemContext, arg1, arg2 []string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRegistries", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRegistries indicates an expected call of UpdateRegistries
+func (mr *MockImageServerMockRecorder) UpdateRegistries(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRegistries", reflect.TypeOf((*MockImageServer)(nil).UpdateRegistries), arg0, arg1, arg2)
+}
+
+// VerifyImage mocks base method
+func (m *MockImageServer) VerifyImage(arg0 *types.SystemContext, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyImage", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyImage indicates an expected call of VerifyImage
+func (mr *MockImageServerMockRecorder) VerifyImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyImage", reflect.TypeOf((*MockImageServer)(nil).VerifyImage), arg0, arg1)
+}
+
 // MockRuntimeServer is a mock of RuntimeServer interface
 type MockRuntimeServer struct {
 	ctrl     *gomock.Controller
@@ -163,6 +217,20 @@ func (m *MockRuntimeServer) EXPECT() *MockRuntimeServerMockRecorder {
 	return m.recorder
 }
 
+// ApplyContainerRootfsDiff mocks base method
+func (m *MockRuntimeServer) ApplyContainerRootfsDiff(arg0 string, arg1 io.Reader) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyContainerRootfsDiff", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyContainerRootfsDiff indicates an expected call of ApplyContainerRootfsDiff
+func (mr *MockRuntimeServerMockRecorder) ApplyContainerRootfsDiff(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyContainerRootfsDiff", reflect.TypeOf((*MockRuntimeServer)(nil).ApplyContainerRootfsDiff), arg0, arg1)
+}
+
 // CreateContainer mocks base method
 func (m *MockRuntimeServer) CreateContainer(arg0 *types.SystemContext, arg1, arg2, arg3, arg4, arg5, arg6, arg7 string, arg8 uint32, arg9 *idtools.IDMappings, arg10 []string) (storage0.ContainerInfo, error) {
 	m.ctrl.T.Helper()
@@ -222,6 +290,21 @@ func (mr *MockRuntimeServerMockRecorder) GetContainerMetadata(arg0 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerMetadata", reflect.TypeOf((*MockRuntimeServer)(nil).GetContainerMetadata), arg0)
 }
 
+// GetContainerRootfsDiff mocks base method
+func (m *MockRuntimeServer) GetContainerRootfsDiff(arg0 string) (io.ReadCloser, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContainerRootfsDiff", arg0)
+	ret0, _ := ret[0].(io.ReadCloser)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContainerRootfsDiff indicates an expected call of GetContainerRootfsDiff
+func (mr *MockRuntimeServerMockRecorder) GetContainerRootfsDiff(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContainerRootfsDiff", reflect.TypeOf((*MockRuntimeServer)(nil).GetContainerRootfsDiff), arg0)
+}
+
 // GetRunDir mocks base method
 func (m *MockRuntimeServer) GetRunDir(arg0 string) (string, error) {
 	m.ctrl.T.Helper()
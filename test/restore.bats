@@ -301,6 +301,12 @@ function teardown() {
 	echo "$output"
 	[ "$status" -ne 0 ]
 
+	run crio status --output json info
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "\"id\": \"$pod_id\"" ]]
+	[[ "$output" =~ "\"id\": \"$ctr_id\"" ]]
+
 	run crictl runp "$TESTDATA"/sandbox_config.json
 	echo "$output"
 	[ "$status" -eq 0 ]
@@ -322,3 +328,53 @@ function teardown() {
 	cleanup_pods
 	stop_crio
 }
+
+@test "crio shutdown keeps pods running" {
+	start_crio
+	run crictl runp "$TESTDATA"/sandbox_config.json
+	echo "$output"
+	[ "$status" -eq 0 ]
+	pod_id="$output"
+
+	run crio shutdown
+	echo "$output"
+	[ "$status" -eq 0 ]
+	wait "$CRIO_PID"
+	CRIO_PID=
+
+	run "$RUNTIME" state "$pod_id"
+	echo "$output"
+	[[ "$output" =~ "\"status\": \"running\"" ]]
+
+	start_crio
+	run crictl inspectp "$pod_id"
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "SANDBOX_READY" ]]
+
+	cleanup_pods
+	stop_crio
+}
+
+@test "crio shutdown with drain stops pods" {
+	start_crio
+	run crictl runp "$TESTDATA"/sandbox_config.json
+	echo "$output"
+	[ "$status" -eq 0 ]
+	pod_id="$output"
+
+	run crio shutdown --drain --timeout 30s
+	echo "$output"
+	[ "$status" -eq 0 ]
+	wait "$CRIO_PID"
+	CRIO_PID=
+
+	start_crio
+	run crictl inspectp "$pod_id"
+	echo "$output"
+	[ "$status" -eq 0 ]
+	[[ "$output" =~ "SANDBOX_NOTREADY" ]]
+
+	cleanup_pods
+	stop_crio
+}
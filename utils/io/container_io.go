@@ -18,6 +18,8 @@ package io
 
 import (
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 
@@ -71,6 +73,14 @@ func WithNewFIFOs(root string, tty, stdin bool) ContainerIOOpts {
 	}
 }
 
+// WithExistingFIFOs reuses the fifos of `config` created by WithNewFIFOs
+// before, for example by a previous instance of CRI-O.
+func WithExistingFIFOs(config cio.Config) ContainerIOOpts {
+	return WithFIFOs(cio.NewFIFOSet(config, func() error {
+		return os.RemoveAll(filepath.Dir(config.Stdout))
+	}))
+}
+
 // NewContainerIO creates container io.
 func NewContainerIO(id string, opts ...ContainerIOOpts) (_ *ContainerIO, err error) {
 	c := &ContainerIO{
@@ -206,6 +206,20 @@ func GenerateID() (string, error) {
 	return hex.EncodeToString(b), nil
 }
 
+// StringSlicesEqual returns true if both provided slices contain the same
+// strings in the same order.
+func StringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // openContainerFile opens a file inside a container rootfs safely
 func openContainerFile(rootfs, path string) (io.ReadCloser, error) {
 	fp, err := symlink.FollowSymlinkInScope(filepath.Join(rootfs, path), rootfs)
@@ -251,6 +251,44 @@ var _ = t.Describe("Utils", func() {
 		})
 	})
 
+	t.Describe("StringSlicesEqual", func() {
+		It("should succeed with equal slices", func() {
+			// Given
+			// When
+			res := utils.StringSlicesEqual([]string{"a", "b"}, []string{"a", "b"})
+
+			// Then
+			Expect(res).To(BeTrue())
+		})
+
+		It("should succeed with nil and empty slices", func() {
+			// Given
+			// When
+			res := utils.StringSlicesEqual(nil, []string{})
+
+			// Then
+			Expect(res).To(BeTrue())
+		})
+
+		It("should fail with different order", func() {
+			// Given
+			// When
+			res := utils.StringSlicesEqual([]string{"a", "b"}, []string{"b", "a"})
+
+			// Then
+			Expect(res).To(BeFalse())
+		})
+
+		It("should fail with different length", func() {
+			// Given
+			// When
+			res := utils.StringSlicesEqual([]string{"a"}, []string{"a", "b"})
+
+			// Then
+			Expect(res).To(BeFalse())
+		})
+	})
+
 	t.Describe("GetUserInfo and GeneratePasswd", func() {
 		It("should succeed with nothing set i.e user=root", func() {
 			dir := createEtcFiles()